@@ -0,0 +1,93 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backend starts and manages the runners that jobs are dispatched to
+// in an execution environment such as Cloud Build.
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Pool is the worker pool a runner is started on.
+type Pool struct {
+	Name           string
+	ProjectID      string
+	Location       string
+	ServiceAccount string
+	Type           string
+
+	// RemoteConfig is the config applied to runners on trusted pools.
+	RemoteConfig string
+}
+
+// StartRequest describes a runner to start.
+type StartRequest struct {
+	RunnerName string
+
+	// JITConfig is the encoded just-in-time config the runner registers with
+	// GitHub. It is empty when a runner is only described.
+	JITConfig string
+
+	ImageName string
+	ImageTag  string
+
+	// IdleTimeout is how long the runner waits for a job before it exits, and
+	// ExecutionTimeout is how long it is allowed to run in total.
+	IdleTimeout      time.Duration
+	ExecutionTimeout time.Duration
+
+	// Tags are attached to the runner to make it easier to find.
+	Tags []string
+
+	Pool *Pool
+}
+
+// Runner identifies a runner started by a backend.
+type Runner struct {
+	Name      string
+	ID        string
+	ProjectID string
+	Location  string
+}
+
+// Status is the state of a runner in its backend.
+type Status struct {
+	// Active reports whether the runner has not finished yet.
+	Active bool
+	// State is the backend's name for the runner's state.
+	State string
+}
+
+// RunnerBackend starts runners in an execution environment and manages them
+// once they are running.
+type RunnerBackend interface {
+	// StartRunner starts a runner and returns it.
+	StartRunner(ctx context.Context, req *StartRequest) (*Runner, error)
+	// CancelRunner stops a runner that has not finished yet.
+	CancelRunner(ctx context.Context, runner *Runner) error
+	// RunnerStatus returns the current state of a runner.
+	RunnerStatus(ctx context.Context, runner *Runner) (*Status, error)
+}
+
+// Describer is implemented by backends that can describe the request they
+// would send to start a runner without sending it, as is done in dry-run
+// mode.
+type Describer interface {
+	// DescribeRunner returns the request that StartRunner would send for req
+	// as JSON, and the runner it would start without an ID.
+	DescribeRunner(ctx context.Context, req *StartRequest) (*Runner, json.RawMessage, error)
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"context"
+	"fmt"
+)
+
+var _ RunnerBackend = (*MockBackend)(nil)
+
+// MockBackend is a mock of the RunnerBackend interface.
+type MockBackend struct {
+	// StartRunnerReqs records the runners started, which are given the
+	// StartRunnerID.
+	StartRunnerErr  error
+	StartRunnerReqs []*StartRequest
+	StartRunnerID   string
+
+	// Statuses are returned by RunnerStatus, keyed by runner ID.
+	Statuses         map[string]*Status
+	RunnerStatusErr  error
+	RunnerStatusReqs []*Runner
+	CancelRunnerErr  error
+	CancelRunnerReqs []*Runner
+}
+
+// StartRunner is a mock of the StartRunner method.
+func (m *MockBackend) StartRunner(ctx context.Context, req *StartRequest) (*Runner, error) {
+	m.StartRunnerReqs = append(m.StartRunnerReqs, req)
+	if m.StartRunnerErr != nil {
+		return nil, m.StartRunnerErr
+	}
+
+	runner := &Runner{
+		Name: req.RunnerName,
+		ID:   m.StartRunnerID,
+	}
+	if req.Pool != nil {
+		runner.ProjectID = req.Pool.ProjectID
+		runner.Location = req.Pool.Location
+	}
+	return runner, nil
+}
+
+// CancelRunner is a mock of the CancelRunner method.
+func (m *MockBackend) CancelRunner(ctx context.Context, runner *Runner) error {
+	m.CancelRunnerReqs = append(m.CancelRunnerReqs, runner)
+	if m.CancelRunnerErr != nil {
+		return m.CancelRunnerErr
+	}
+	status, ok := m.Statuses[runner.ID]
+	if !ok {
+		return fmt.Errorf("runner %s not found", runner.ID)
+	}
+	status.Active = false
+	status.State = "CANCELLED"
+	return nil
+}
+
+// RunnerStatus is a mock of the RunnerStatus method.
+func (m *MockBackend) RunnerStatus(ctx context.Context, runner *Runner) (*Status, error) {
+	m.RunnerStatusReqs = append(m.RunnerStatusReqs, runner)
+	if m.RunnerStatusErr != nil {
+		return nil, m.RunnerStatusErr
+	}
+	status, ok := m.Statuses[runner.ID]
+	if !ok {
+		return nil, fmt.Errorf("runner %s not found", runner.ID)
+	}
+	return status, nil
+}
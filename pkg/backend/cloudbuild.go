@@ -0,0 +1,259 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var (
+	_ RunnerBackend = (*CloudBuild)(nil)
+	_ Describer     = (*CloudBuild)(nil)
+)
+
+// CloudBuild starts each runner as a Cloud Build build on its worker pool.
+type CloudBuild struct {
+	client       cloudbuild.Client
+	repositoryID string
+
+	// consumer is set for trusted pools, whose builds are created with their
+	// remote config through the Cloud Build consumer API.
+	consumer bool
+}
+
+// NewCloudBuild creates a backend that starts runners from images in the
+// Artifact Registry repository with the given ID.
+func NewCloudBuild(client cloudbuild.Client, repositoryID string) *CloudBuild {
+	return &CloudBuild{
+		client:       client,
+		repositoryID: repositoryID,
+	}
+}
+
+// NewTrustedCloudBuild creates a backend for trusted pools. Runners are
+// started like those of NewCloudBuild, but with their pool's remote config
+// through the Cloud Build consumer API.
+func NewTrustedCloudBuild(client cloudbuild.Client, repositoryID string) *CloudBuild {
+	return &CloudBuild{
+		client:       client,
+		repositoryID: repositoryID,
+		consumer:     true,
+	}
+}
+
+// StartRunner creates the build that runs the runner.
+func (b *CloudBuild) StartRunner(ctx context.Context, req *StartRequest) (*Runner, error) {
+	buildReq, err := b.buildRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var buildID string
+	if b.consumer {
+		logging.FromContext(ctx).DebugContext(ctx, "creating build on trusted worker pool",
+			"worker_pool", buildReq.GetBuild().GetOptions().GetPool().GetName(),
+			"remote_config", buildReq.GetBuild().GetRemoteConfig())
+		buildID, err = b.client.CreateBuildConsumer(ctx, &cloudbuildpb.CreateBuildConsumerRequest{
+			Parent: buildReq.GetParent(),
+			Build:  buildReq.GetBuild(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create consumer build: %w", err)
+		}
+	} else {
+		buildID, err = b.client.CreateBuild(ctx, buildReq)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	runner := newRunner(req.RunnerName, buildReq)
+	runner.ID = buildID
+	return runner, nil
+}
+
+// DescribeRunner returns the request that StartRunner would send to create the
+// runner's build.
+func (b *CloudBuild) DescribeRunner(ctx context.Context, req *StartRequest) (*Runner, json.RawMessage, error) {
+	buildReq, err := b.buildRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	reqJSON, err := protojson.Marshal(buildReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal build request: %w", err)
+	}
+	return newRunner(req.RunnerName, buildReq), reqJSON, nil
+}
+
+// CancelRunner cancels the runner's build.
+func (b *CloudBuild) CancelRunner(ctx context.Context, runner *Runner) error {
+	if _, err := b.client.CancelBuild(ctx, &cloudbuildpb.CancelBuildRequest{
+		Name:      buildName(runner),
+		ProjectId: runner.ProjectID,
+		Id:        runner.ID,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RunnerStatus returns the status of the runner's build. The runner is active
+// until its build has finished.
+func (b *CloudBuild) RunnerStatus(ctx context.Context, runner *Runner) (*Status, error) {
+	build, err := b.client.GetBuild(ctx, &cloudbuildpb.GetBuildRequest{
+		Name:      buildName(runner),
+		ProjectId: runner.ProjectID,
+		Id:        runner.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Status{
+		Active: isBuildActive(build.GetStatus()),
+		State:  build.GetStatus().String(),
+	}, nil
+}
+
+// buildRequest creates the request for the build that runs a runner.
+func (b *CloudBuild) buildRequest(req *StartRequest) (*cloudbuildpb.CreateBuildRequest, error) {
+	// Sometimes JITConfig has exceeded the 4,000-character limit for
+	// substitutions. It has nested base64 encoded data, so it is very
+	// compressible.
+	var compressedJIT string
+	if req.JITConfig != "" {
+		var err error
+		compressedJIT, err = compressAndBase64EncodeString(req.JITConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress JIT config: %w", err)
+		}
+	}
+
+	build := &cloudbuildpb.Build{
+		Timeout: durationpb.New(req.ExecutionTimeout),
+		Steps: []*cloudbuildpb.BuildStep{
+			{
+				Id:   "run",
+				Name: "$_REPOSITORY_ID/$_IMAGE_NAME:$_IMAGE_TAG",
+				Env: []string{
+					"ENCODED_JIT_CONFIG=${_ENCODED_JIT_CONFIG}",
+					"IDLE_TIMEOUT_SECONDS=${_IDLE_TIMEOUT_SECONDS}",
+					"CREATE_BUILD_REQUEST_TIME_UTC=${_CREATE_BUILD_REQUEST_TIME_UTC}",
+				},
+			},
+		},
+		Options: &cloudbuildpb.BuildOptions{
+			Logging: cloudbuildpb.BuildOptions_CLOUD_LOGGING_ONLY,
+		},
+		Substitutions: map[string]string{
+			"_ENCODED_JIT_CONFIG":            compressedJIT,
+			"_IDLE_TIMEOUT_SECONDS":          strconv.Itoa(int(req.IdleTimeout / time.Second)),
+			"_REPOSITORY_ID":                 b.repositoryID,
+			"_IMAGE_NAME":                    req.ImageName,
+			"_IMAGE_TAG":                     req.ImageTag,
+			"_CREATE_BUILD_REQUEST_TIME_UTC": time.Now().UTC().Format(time.RFC3339),
+		},
+		Tags: req.Tags,
+	}
+
+	var projectID, location, serviceAccount string
+
+	if pool := req.Pool; pool != nil && pool.ProjectID != "" {
+		projectID = pool.ProjectID
+		location = pool.Location
+		serviceAccount = pool.ServiceAccount
+		if pool.Name != "" {
+			build.Options.Pool = &cloudbuildpb.BuildOptions_PoolOption{Name: pool.Name}
+		}
+		if b.consumer {
+			build.RemoteConfig = pool.RemoteConfig
+		}
+	}
+
+	// Ensure the service account is in the full resource name format.
+	if serviceAccount != "" && !strings.HasPrefix(serviceAccount, "projects/") {
+		serviceAccount = fmt.Sprintf("projects/%s/serviceAccounts/%s", projectID, serviceAccount)
+	}
+
+	build.ServiceAccount = serviceAccount
+
+	return &cloudbuildpb.CreateBuildRequest{
+		Parent:    fmt.Sprintf("projects/%s/locations/%s", projectID, location),
+		ProjectId: projectID,
+		Build:     build,
+	}, nil
+}
+
+// newRunner returns the runner started by a CreateBuildRequest, without the ID
+// of its build.
+func newRunner(runnerName string, buildReq *cloudbuildpb.CreateBuildRequest) *Runner {
+	return &Runner{
+		Name:      runnerName,
+		ProjectID: buildReq.GetProjectId(),
+		Location:  strings.TrimPrefix(buildReq.GetParent(), fmt.Sprintf("projects/%s/locations/", buildReq.GetProjectId())),
+	}
+}
+
+// buildName returns the resource name of a runner's build.
+func buildName(runner *Runner) string {
+	return fmt.Sprintf("projects/%s/locations/%s/builds/%s", runner.ProjectID, runner.Location, runner.ID)
+}
+
+// isBuildActive reports whether a build has not finished yet.
+func isBuildActive(status cloudbuildpb.Build_Status) bool {
+	switch status {
+	case cloudbuildpb.Build_PENDING, cloudbuildpb.Build_QUEUED, cloudbuildpb.Build_WORKING:
+		return true
+	default:
+		return false
+	}
+}
+
+// compressAndBase64EncodeString compresses the input string using gzip
+// and then encodes the compressed data into a base64 string.
+//
+// This is used to reduce the size of the JIT config for Cloud Build substitutions.
+// It returns the base64 encoded string or an error if compression or encoding fails.
+func compressAndBase64EncodeString(input string) (string, error) {
+	var compressedJIT bytes.Buffer
+	gzipWriter, err := gzip.NewWriterLevel(&compressedJIT, gzip.BestCompression)
+	if err != nil {
+		return "", fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	_, err = gzipWriter.Write([]byte(input))
+	if err != nil {
+		return "", fmt.Errorf("failed to write to gzip writer: %w", err)
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(compressedJIT.Bytes()), nil
+}
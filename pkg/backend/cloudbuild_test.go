@@ -0,0 +1,286 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const testBuildID = "test-build-id"
+
+func TestCloudBuild_StartRunner(t *testing.T) {
+	t.Parallel()
+
+	pool := &Pool{
+		Name:           "projects/1/locations/us-west1/workerPools/pool",
+		ProjectID:      "pool-project",
+		Location:       "us-west1",
+		ServiceAccount: "runner@pool-project.iam.gserviceaccount.com",
+		RemoteConfig:   "https://example.com/trusted-config.yaml",
+	}
+	req := &StartRequest{
+		RunnerName:       "runner-1",
+		JITConfig:        "jit-config",
+		ImageName:        "image",
+		ImageTag:         "tag",
+		IdleTimeout:      5 * time.Minute,
+		ExecutionTimeout: time.Hour,
+		Tags:             []string{"e2e-test"},
+		Pool:             pool,
+	}
+
+	cases := []struct {
+		name            string
+		trusted         bool
+		expRemoteConfig string
+	}{
+		{
+			name: "private",
+		},
+		{
+			name:            "trusted",
+			trusted:         true,
+			expRemoteConfig: "https://example.com/trusted-config.yaml",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			client := &cloudbuild.MockClient{CreateBuildID: testBuildID}
+			b := NewCloudBuild(client, "repository")
+			if tc.trusted {
+				b = NewTrustedCloudBuild(client, "repository")
+			}
+
+			runner, err := b.StartRunner(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &Runner{Name: "runner-1", ID: testBuildID, ProjectID: "pool-project", Location: "us-west1"}
+			if diff := cmp.Diff(want, runner); diff != "" {
+				t.Errorf("runner (-want, +got):\n%s", diff)
+			}
+
+			var parent string
+			var build *cloudbuildpb.Build
+			if tc.trusted {
+				if got, want := len(client.CreateBuildConsumerReqs), 1; got != want {
+					t.Fatalf("expected %d consumer builds, got %d", want, got)
+				}
+				parent, build = client.CreateBuildConsumerReqs[0].GetParent(), client.CreateBuildConsumerReqs[0].GetBuild()
+			} else {
+				if got, want := len(client.CreateBuildReqs), 1; got != want {
+					t.Fatalf("expected %d builds, got %d", want, got)
+				}
+				parent, build = client.CreateBuildReqs[0].GetParent(), client.CreateBuildReqs[0].GetBuild()
+			}
+
+			if got, want := parent, "projects/pool-project/locations/us-west1"; got != want {
+				t.Errorf("expected parent %q to be %q", got, want)
+			}
+			if got, want := build.GetOptions().GetPool().GetName(), pool.Name; got != want {
+				t.Errorf("expected worker pool %q to be %q", got, want)
+			}
+			if got, want := build.GetRemoteConfig(), tc.expRemoteConfig; got != want {
+				t.Errorf("expected remote config %q to be %q", got, want)
+			}
+			if got, want := build.GetServiceAccount(), "projects/pool-project/serviceAccounts/runner@pool-project.iam.gserviceaccount.com"; got != want {
+				t.Errorf("expected service account %q to be %q", got, want)
+			}
+			if got, want := build.GetTimeout().AsDuration(), time.Hour; got != want {
+				t.Errorf("expected timeout %s to be %s", got, want)
+			}
+			if diff := cmp.Diff([]string{"e2e-test"}, build.GetTags()); diff != "" {
+				t.Errorf("tags (-want, +got):\n%s", diff)
+			}
+
+			subs := build.GetSubstitutions()
+			for key, want := range map[string]string{
+				"_IDLE_TIMEOUT_SECONDS": "300",
+				"_REPOSITORY_ID":        "repository",
+				"_IMAGE_NAME":           "image",
+				"_IMAGE_TAG":            "tag",
+			} {
+				if got := subs[key]; got != want {
+					t.Errorf("expected substitution %s %q to be %q", key, got, want)
+				}
+			}
+			if got, want := decompressJIT(t, subs["_ENCODED_JIT_CONFIG"]), "jit-config"; got != want {
+				t.Errorf("expected JIT config %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCloudBuild_StartRunner_Error(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	client := &cloudbuild.MockClient{CreateBuildErr: errors.New("quota exceeded")}
+	b := NewCloudBuild(client, "repository")
+
+	if _, err := b.StartRunner(ctx, &StartRequest{RunnerName: "runner-1", Pool: &Pool{ProjectID: "pool-project"}}); err == nil {
+		t.Errorf("expected an error starting the runner")
+	}
+}
+
+func TestCloudBuild_DescribeRunner(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	client := &cloudbuild.MockClient{}
+	b := NewCloudBuild(client, "repository")
+
+	runner, reqJSON, err := b.DescribeRunner(ctx, &StartRequest{
+		RunnerName: "runner-1",
+		ImageName:  "image",
+		ImageTag:   "tag",
+		Pool:       &Pool{ProjectID: "pool-project", Location: "us-west1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(client.CreateBuildReqs), 0; got != want {
+		t.Errorf("expected %d builds to be created, got %d", want, got)
+	}
+	if diff := cmp.Diff(&Runner{Name: "runner-1", ProjectID: "pool-project", Location: "us-west1"}, runner); diff != "" {
+		t.Errorf("runner (-want, +got):\n%s", diff)
+	}
+
+	var buildReq cloudbuildpb.CreateBuildRequest
+	if err := protojson.Unmarshal(reqJSON, &buildReq); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buildReq.GetParent(), "projects/pool-project/locations/us-west1"; got != want {
+		t.Errorf("expected parent %q to be %q", got, want)
+	}
+	if got := buildReq.GetBuild().GetSubstitutions()["_ENCODED_JIT_CONFIG"]; got != "" {
+		t.Errorf("expected no JIT config, got %q", got)
+	}
+}
+
+func TestCloudBuild_RunnerStatus(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		status    cloudbuildpb.Build_Status
+		expStatus *Status
+	}{
+		{
+			name:      "queued",
+			status:    cloudbuildpb.Build_QUEUED,
+			expStatus: &Status{Active: true, State: "QUEUED"},
+		},
+		{
+			name:      "working",
+			status:    cloudbuildpb.Build_WORKING,
+			expStatus: &Status{Active: true, State: "WORKING"},
+		},
+		{
+			name:      "success",
+			status:    cloudbuildpb.Build_SUCCESS,
+			expStatus: &Status{Active: false, State: "SUCCESS"},
+		},
+		{
+			name:      "timeout",
+			status:    cloudbuildpb.Build_TIMEOUT,
+			expStatus: &Status{Active: false, State: "TIMEOUT"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			client := &cloudbuild.MockClient{
+				Builds: map[string]*cloudbuildpb.Build{testBuildID: {Id: testBuildID, Status: tc.status}},
+			}
+			b := NewCloudBuild(client, "repository")
+
+			status, err := b.RunnerStatus(ctx, &Runner{Name: "runner-1", ID: testBuildID, ProjectID: "pool-project", Location: "us-west1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expStatus, status); diff != "" {
+				t.Errorf("status (-want, +got):\n%s", diff)
+			}
+			if got, want := client.GetBuildReqs[0].GetName(), "projects/pool-project/locations/us-west1/builds/test-build-id"; got != want {
+				t.Errorf("expected build name %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCloudBuild_CancelRunner(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	client := &cloudbuild.MockClient{
+		Builds: map[string]*cloudbuildpb.Build{testBuildID: {Id: testBuildID, Status: cloudbuildpb.Build_QUEUED}},
+	}
+	b := NewCloudBuild(client, "repository")
+
+	if err := b.CancelRunner(ctx, &Runner{Name: "runner-1", ID: testBuildID, ProjectID: "pool-project", Location: "us-west1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(client.CancelBuildReqs), 1; got != want {
+		t.Fatalf("expected %d cancelled builds, got %d", want, got)
+	}
+	if got, want := client.CancelBuildReqs[0].GetName(), "projects/pool-project/locations/us-west1/builds/test-build-id"; got != want {
+		t.Errorf("expected build name %q to be %q", got, want)
+	}
+}
+
+// decompressJIT decodes a JIT config substitution.
+func decompressJIT(tb testing.TB, encoded string) string {
+	tb.Helper()
+
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return string(b)
+}
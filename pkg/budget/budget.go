@@ -0,0 +1,45 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package budget accumulates the runner usage of each organization per
+// calendar month so that it can be compared against a budget.
+package budget
+
+import (
+	"context"
+	"time"
+)
+
+// Usage is the runner time used by an organization and its estimated cost.
+type Usage struct {
+	Minutes float64 `json:"minutes"`
+	Dollars float64 `json:"dollars"`
+}
+
+// Store accumulates usage per organization and month.
+type Store interface {
+	// Add adds the usage of a job to its organization's total for the month.
+	// It reports whether the usage was added, which it is not if the job has
+	// already been counted.
+	Add(ctx context.Context, org, month, jobID string, usage *Usage) (bool, error)
+	// Get returns the organization's total usage for the month. It returns
+	// zero usage if none has been recorded.
+	Get(ctx context.Context, org, month string) (*Usage, error)
+}
+
+// Month returns the calendar month that a time falls in, in UTC, for example
+// "2025-01".
+func Month(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-memory Store. Usage is not shared between instances of
+// the webhook service and is lost when it restarts.
+type MemoryStore struct {
+	mu    sync.Mutex
+	usage map[string]*Usage
+	jobs  map[string]bool
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		usage: make(map[string]*Usage),
+		jobs:  make(map[string]bool),
+	}
+}
+
+// Add adds the usage of a job to its organization's total for the month.
+func (s *MemoryStore) Add(ctx context.Context, org, month, jobID string, usage *Usage) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.jobs[jobID] {
+		return false, nil
+	}
+	s.jobs[jobID] = true
+
+	key := org + "/" + month
+	total, ok := s.usage[key]
+	if !ok {
+		total = &Usage{}
+		s.usage[key] = total
+	}
+	total.Minutes += usage.Minutes
+	total.Dollars += usage.Dollars
+	return true, nil
+}
+
+// Get returns the organization's total usage for the month.
+func (s *MemoryStore) Get(ctx context.Context, org, month string) (*Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total, ok := s.usage[org+"/"+month]
+	if !ok {
+		return &Usage{}, nil
+	}
+	cp := *total
+	return &cp, nil
+}
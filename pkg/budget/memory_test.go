@@ -0,0 +1,73 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	s := NewMemoryStore()
+
+	for _, jobID := range []string{"1", "2"} {
+		added, err := s.Add(ctx, "google", "2025-01", jobID, &Usage{Minutes: 10, Dollars: 0.5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !added {
+			t.Errorf("expected job %s to be added", jobID)
+		}
+	}
+
+	// Redelivered jobs are not counted twice.
+	added, err := s.Add(ctx, "google", "2025-01", "1", &Usage{Minutes: 10, Dollars: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added {
+		t.Errorf("expected job 1 not to be added again")
+	}
+
+	got, err := s.Get(ctx, "google", "2025-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Usage{Minutes: 20, Dollars: 1}, got); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+
+	got, err = s.Get(ctx, "google", "2025-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Usage{}, got); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMonth(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("UTC-8", -8*60*60)
+	if got, want := Month(time.Date(2025, 1, 31, 20, 0, 0, 0, loc)), "2025-02"; got != want {
+		t.Errorf("expected month %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// usageTTL is how long the usage of a month is kept. It outlives the
+	// month so that the previous month can still be reported.
+	usageTTL = 62 * 24 * time.Hour
+
+	// jobTTL is how long a counted job is remembered, which is longer than
+	// GitHub keeps redelivering its events.
+	jobTTL = 7 * 24 * time.Hour
+
+	minutesField = "minutes"
+	dollarsField = "dollars"
+)
+
+var _ Store = (*RedisStore)(nil)
+
+// addScript claims a job and adds its usage to the month's total in one step,
+// so that a failure cannot leave the job claimed without its usage counted.
+//
+// KEYS are the job and the usage hash. ARGV are the month, the job TTL in
+// milliseconds, the minutes and dollars to add and the usage TTL in
+// milliseconds. It returns 1 if the usage was added or 0 if the job was
+// already counted.
+var addScript = redis.NewScript(`
+if not redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return 0
+end
+redis.call('HINCRBYFLOAT', KEYS[2], 'minutes', ARGV[3])
+redis.call('HINCRBYFLOAT', KEYS[2], 'dollars', ARGV[4])
+redis.call('PEXPIRE', KEYS[2], ARGV[5])
+return 1
+`)
+
+// RedisStore is a Store backed by Redis so that usage is shared by every
+// instance of the webhook service. The usage of each organization and month
+// is stored in a hash, and every counted job is remembered so that redelivered
+// events are not counted twice.
+type RedisStore struct {
+	rc     *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a RedisStore that stores its state under keys
+// prefixed with prefix.
+func NewRedisStore(rc *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{
+		rc:     rc,
+		prefix: prefix,
+	}
+}
+
+// Add adds the usage of a job to its organization's total for the month. The
+// job is claimed and its usage added atomically.
+func (s *RedisStore) Add(ctx context.Context, org, month, jobID string, usage *Usage) (bool, error) {
+	added, err := addScript.Run(ctx, s.rc, []string{s.jobKey(jobID), s.usageKey(org, month)},
+		month, jobTTL.Milliseconds(), formatFloat(usage.Minutes), formatFloat(usage.Dollars), usageTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to add usage of job %s: %w", jobID, err)
+	}
+	return added == 1, nil
+}
+
+// Get returns the organization's total usage for the month.
+func (s *RedisStore) Get(ctx context.Context, org, month string) (*Usage, error) {
+	vals, err := s.rc.HGetAll(ctx, s.usageKey(org, month)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage of org %s: %w", org, err)
+	}
+
+	var usage Usage
+	for field, target := range map[string]*float64{
+		minutesField: &usage.Minutes,
+		dollarsField: &usage.Dollars,
+	} {
+		val, ok := vals[field]
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s usage of org %s: %w", field, org, err)
+		}
+		*target = f
+	}
+	return &usage, nil
+}
+
+func (s *RedisStore) usageKey(org, month string) string {
+	return fmt.Sprintf("%s/usage/%s/%s", s.prefix, org, month)
+}
+
+func (s *RedisStore) jobKey(jobID string) string {
+	return fmt.Sprintf("%s/jobs/%s", s.prefix, jobID)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
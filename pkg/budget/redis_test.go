@@ -0,0 +1,118 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRedisStore(t *testing.T) {
+	t.Parallel()
+
+	const (
+		usageKey = "dispatcher/budgets/usage/google/2025-01"
+		jobKey   = "dispatcher/budgets/jobs/1"
+	)
+
+	cases := []struct {
+		name   string
+		setup  func(m redismock.ClientMock)
+		run    func(ctx context.Context, s *RedisStore) (any, error)
+		exp    any
+		expErr string
+	}{
+		{
+			name: "add",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(addScript.Hash(), []string{jobKey, usageKey}, "2025-01", jobTTL.Milliseconds(), "10", "0.5", usageTTL.Milliseconds()).
+					SetVal(int64(1))
+			},
+			run: func(ctx context.Context, s *RedisStore) (any, error) {
+				return s.Add(ctx, "google", "2025-01", "1", &Usage{Minutes: 10, Dollars: 0.5})
+			},
+			exp: true,
+		},
+		{
+			name: "add_counted_job",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(addScript.Hash(), []string{jobKey, usageKey}, "2025-01", jobTTL.Milliseconds(), "10", "0.5", usageTTL.Milliseconds()).
+					SetVal(int64(0))
+			},
+			run: func(ctx context.Context, s *RedisStore) (any, error) {
+				return s.Add(ctx, "google", "2025-01", "1", &Usage{Minutes: 10, Dollars: 0.5})
+			},
+			exp: false,
+		},
+		{
+			name: "add_error",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(addScript.Hash(), []string{jobKey, usageKey}, "2025-01", jobTTL.Milliseconds(), "10", "0.5", usageTTL.Milliseconds()).
+					SetErr(fmt.Errorf("connection refused"))
+			},
+			run: func(ctx context.Context, s *RedisStore) (any, error) {
+				return s.Add(ctx, "google", "2025-01", "1", &Usage{Minutes: 10, Dollars: 0.5})
+			},
+			exp:    false,
+			expErr: "failed to add usage of job 1: connection refused",
+		},
+		{
+			name: "get",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectHGetAll(usageKey).SetVal(map[string]string{minutesField: "30", dollarsField: "1.5"})
+			},
+			run: func(ctx context.Context, s *RedisStore) (any, error) {
+				return s.Get(ctx, "google", "2025-01")
+			},
+			exp: &Usage{Minutes: 30, Dollars: 1.5},
+		},
+		{
+			name: "get_empty",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectHGetAll(usageKey).SetVal(map[string]string{})
+			},
+			run: func(ctx context.Context, s *RedisStore) (any, error) {
+				return s.Get(ctx, "google", "2025-01")
+			},
+			exp: &Usage{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock := redismock.NewClientMock()
+			tc.setup(mock)
+
+			got, err := tc.run(t.Context(), NewRedisStore(db, "dispatcher/budgets"))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("result (-want, +got):\n%s", diff)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
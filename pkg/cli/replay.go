@@ -0,0 +1,243 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
+	"github.com/abcxyz/github-action-dispatcher/pkg/version"
+	"github.com/abcxyz/github-action-dispatcher/pkg/webhook"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*WebhookReplayCommand)(nil)
+
+// replayFakeWebhookSecret is the webhook secret of a server with fake clients.
+const replayFakeWebhookSecret = "replay-webhook-secret"
+
+type WebhookReplayCommand struct {
+	cli.BaseCommand
+
+	cfg         *webhook.Config
+	registryCfg *registry.RegistryConfig
+
+	flagFakeClients bool
+
+	// only used for testing
+	testFlagSetOpts []cli.Option
+}
+
+func (c *WebhookReplayCommand) Desc() string {
+	return `Replay saved webhook payloads and print how they are routed`
+}
+
+func (c *WebhookReplayCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options] PATH...
+
+  Replay saved webhook payloads through the webhook server in dry-run mode and
+  print the routing decision, resolved labels, chosen worker pools and the
+  build requests that would have been created. Each PATH is a payload file or a
+  directory whose .json payload files are replayed in order.
+
+  Like a delivery to /webhook, a payload is replayed by the GitHub host named in
+  its X-GitHub-Enterprise-Host header, or else by the GitHub App whose ID is in
+  its X-GitHub-Hook-Installation-Target-ID header, and the chosen host and app
+  are printed with the result.
+
+  A payload file is a JSON object with the "headers" and "body" of a webhook
+  delivery:
+
+      {
+        "headers": {"X-GitHub-Event": "workflow_job", "X-GitHub-Delivery": "..."},
+        "body": {"action": "queued", "workflow_job": {...}, ...}
+      }
+
+  The server is configured with the same options as the webhook server. With
+  -fake-clients, no GitHub, Cloud Build, KMS or registry clients are created
+  and no credentials are needed, but no worker pools are found in the
+  registry, and jobs are denied by dispatch policies with rules on
+  workflows.
+`
+}
+
+func (c *WebhookReplayCommand) Flags() *cli.FlagSet {
+	c.cfg = &webhook.Config{}
+	c.registryCfg = &registry.RegistryConfig{}
+	set := cli.NewFlagSet(c.testFlagSetOpts...)
+	c.cfg.ToFlags(set)
+	c.registryCfg.ToFlags(set)
+
+	f := set.NewSection("REPLAY OPTIONS")
+
+	f.BoolVar(&cli.BoolVar{
+		Name:   "fake-clients",
+		Target: &c.flagFakeClients,
+		Usage:  `Whether to replay payloads with fake GitHub, Cloud Build and KMS clients and without a registry, instead of the clients configured for the webhook server.`,
+	})
+
+	return set
+}
+
+func (c *WebhookReplayCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one payload file or directory")
+	}
+
+	// Replayed payloads never start runners.
+	c.cfg.DryRun = true
+	if c.flagFakeClients {
+		// Fake clients do not use credentials, so placeholders are used for
+		// any that are not set.
+		for _, v := range []*string{&c.cfg.GitHubAppID, &c.cfg.GitHubWebhookKeyMountPath, &c.cfg.GitHubWebhookKeyName, &c.cfg.KMSAppPrivateKeyID} {
+			if *v == "" {
+				*v = "fake"
+			}
+		}
+	}
+	if err := c.cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	paths, err := replayPaths(args)
+	if err != nil {
+		return err
+	}
+
+	server, err := c.newServer(ctx)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(c.Stdout())
+	enc.SetIndent("", "  ")
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read payload %s: %w", path, err)
+		}
+		var payload webhook.ReplayPayload
+		if err := json.Unmarshal(b, &payload); err != nil {
+			return fmt.Errorf("failed to parse payload %s: %w", path, err)
+		}
+
+		result, err := server.Replay(ctx, &payload)
+		if err != nil {
+			return fmt.Errorf("failed to replay payload %s: %w", path, err)
+		}
+
+		if err := enc.Encode(&replayOutput{File: path, ReplayResult: result}); err != nil {
+			return fmt.Errorf("failed to write result for payload %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// replayOutput is the result printed for a replayed payload file.
+type replayOutput struct {
+	File string `json:"file"`
+	*webhook.ReplayResult
+}
+
+// newServer creates a webhook server in dry-run mode, with either the clients
+// configured for the webhook server or fakes.
+func (c *WebhookReplayCommand) newServer(ctx context.Context) (*webhook.Server, error) {
+	logger := logging.FromContext(ctx)
+
+	var rc *redis.Client
+	webhookClientOptions := &webhook.WebhookClientOptions{}
+	if c.flagFakeClients {
+		webhookClientOptions.CloudBuildClientOverride = &cloudbuild.MockClient{}
+		ghc := &gh.MockClient{
+			WorkflowRunPathF: func(ctx context.Context, installationID int64, org, repo string, runID int64) (string, error) {
+				return "", fmt.Errorf("workflow runs are not available with fake clients: %w", gh.ErrNotFound)
+			},
+		}
+		webhookClientOptions.GitHubClientOverride = ghc
+		webhookClientOptions.GitHubHostClientOverrides = make(map[string]gh.Client, len(c.cfg.GitHubHosts))
+		for _, host := range c.cfg.GitHubHosts {
+			webhookClientOptions.GitHubHostClientOverrides[host.Name] = ghc
+		}
+		webhookClientOptions.GitHubAppClientOverrides = make(map[string]gh.Client, len(c.cfg.GitHubApps))
+		for _, app := range c.cfg.GitHubApps {
+			webhookClientOptions.GitHubAppClientOverrides[app.Name] = ghc
+		}
+		webhookClientOptions.KeyManagementClientOverride = &webhook.MockKMSClient{}
+		webhookClientOptions.OSFileReaderOverride = &webhook.MockFileReader{
+			ReadFileMock: &webhook.ReadFileResErr{Res: []byte(replayFakeWebhookSecret)},
+		}
+	} else {
+		registryClient, err := registry.NewRunnerRegistry(ctx, c.registryCfg)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to create registry client, no worker pools will be found", "error", err)
+		} else {
+			rc = registryClient
+		}
+
+		agent := fmt.Sprintf("google:github-action-dispatcher/%s", version.Version)
+		webhookClientOptions.KeyManagementClientOpts = []option.ClientOption{option.WithUserAgent(agent)}
+	}
+
+	server, err := webhook.NewServer(ctx, nil, c.cfg, rc, webhookClientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+	return server, nil
+}
+
+// replayPaths returns the payload files to replay. Directories are expanded to
+// the .json files they contain, in lexical order.
+func replayPaths(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload path: %w", err)
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			paths = append(paths, filepath.Join(arg, entry.Name()))
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,225 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-envconfig"
+
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestWebhookReplayCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	env := map[string]string{
+		"RUNNER_LOCATION":            "us-central1",
+		"RUNNER_PROJECT_ID":          "runner-project-id",
+		"RUNNER_REPOSITORY_ID":       "runner-repo-id",
+		"RUNNER_SERVICE_ACCOUNT":     "runner-service-account",
+		"RUNNER_LABEL_ALIASES":       "self-hosted=sh-ubuntu-latest",
+		"SUPPORTED_RUNNER_LABELS":    "sh-ubuntu-latest",
+		"GITHUB_HOSTS":               "ghes1=hostname=github.example.com;api_base_url=https://github.example.com/api/v3",
+		"RUNNER_404_ENABLED":         "true",
+		"RUNNER_404_IMAGE_TAG":       "latest",
+		"RUNNER_404_LOCATION":        "us-east1",
+		"RUNNER_404_PROJECT_ID":      "404-project-id",
+		"RUNNER_404_SERVICE_ACCOUNT": "404-service-account",
+	}
+
+	dir := t.TempDir()
+	writeReplayPayload(t, filepath.Join(dir, "01-queued.json"), "queued", "self-hosted", "")
+	writeReplayPayload(t, filepath.Join(dir, "02-completed.json"), "completed", "self-hosted", "")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a payload"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hostDir := t.TempDir()
+	writeReplayPayload(t, filepath.Join(hostDir, "01-queued.json"), "queued", "self-hosted", "github.example.com")
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"headers": {}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		expErr   string
+		expFiles []string
+		expCodes []int
+		expHost  string
+	}{
+		{
+			name:   "no_args",
+			args:   []string{"-fake-clients"},
+			expErr: "expected at least one payload file or directory",
+		},
+		{
+			name:   "missing_file",
+			args:   []string{"-fake-clients", filepath.Join(dir, "missing.json")},
+			expErr: "failed to read payload path",
+		},
+		{
+			name:   "missing_body",
+			args:   []string{"-fake-clients", invalid},
+			expErr: "payload is missing a body",
+		},
+		{
+			name:     "file",
+			args:     []string{"-fake-clients", filepath.Join(dir, "01-queued.json")},
+			expFiles: []string{filepath.Join(dir, "01-queued.json")},
+			expCodes: []int{200},
+		},
+		{
+			name:     "directory",
+			args:     []string{"-fake-clients", dir},
+			expFiles: []string{filepath.Join(dir, "01-queued.json"), filepath.Join(dir, "02-completed.json")},
+			expCodes: []int{200, 200},
+		},
+		{
+			name:     "github_host",
+			args:     []string{"-fake-clients", hostDir},
+			expFiles: []string{filepath.Join(hostDir, "01-queued.json")},
+			expCodes: []int{200},
+			expHost:  "ghes1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cmd WebhookReplayCommand
+			cmd.testFlagSetOpts = []cli.Option{cli.WithLookupEnv(envconfig.MapLookuper(env).Lookup)}
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			var gotFiles []string
+			var gotCodes []int
+			var queued *replayTestOutput
+			dec := json.NewDecoder(strings.NewReader(stdout.String()))
+			for dec.More() {
+				var out replayTestOutput
+				if err := dec.Decode(&out); err != nil {
+					t.Fatal(err)
+				}
+				gotFiles = append(gotFiles, out.File)
+				gotCodes = append(gotCodes, out.Code)
+				if strings.HasSuffix(out.File, "01-queued.json") {
+					queued = &out
+				}
+			}
+			if diff := cmp.Diff(tc.expFiles, gotFiles); diff != "" {
+				t.Errorf("files (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expCodes, gotCodes); diff != "" {
+				t.Errorf("codes (-want, +got):\n%s", diff)
+			}
+
+			if got, want := queued.GitHubHost, tc.expHost; got != want {
+				t.Errorf("expected github host %q, got %q", want, got)
+			}
+
+			// Without a registry, the job is sent to the 404 runner.
+			if diff := cmp.Diff([]string{"sh-ubuntu-latest"}, queued.ResolvedLabels); diff != "" {
+				t.Errorf("resolved labels (-want, +got):\n%s", diff)
+			}
+			var resp replayTestResponse
+			if err := json.Unmarshal(queued.Response, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(resp.DryRunBuilds), 1; got != want {
+				t.Fatalf("expected %d dry run builds, got %d", want, got)
+			}
+			if got, want := resp.DryRunBuilds[0].WorkerPool.ProjectID, "404-project-id"; got != want {
+				t.Errorf("expected worker pool project %q, got %q", want, got)
+			}
+			if got, want := resp.DryRunBuilds[0].CreateBuildRequest.ProjectID, "404-project-id"; got != want {
+				t.Errorf("expected build request project %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+type replayTestOutput struct {
+	File           string          `json:"file"`
+	Code           int             `json:"code"`
+	GitHubHost     string          `json:"githubHost"`
+	ResolvedLabels []string        `json:"resolvedLabels"`
+	Response       json.RawMessage `json:"response"`
+}
+
+type replayTestResponse struct {
+	DryRunBuilds []struct {
+		WorkerPool struct {
+			ProjectID string `json:"projectID"`
+		} `json:"workerPool"`
+		CreateBuildRequest struct {
+			ProjectID string `json:"projectId"`
+		} `json:"createBuildRequest"`
+	} `json:"dryRunBuilds"`
+}
+
+func writeReplayPayload(tb testing.TB, path, action, label, host string) {
+	tb.Helper()
+
+	body, err := json.Marshal(map[string]any{
+		"action": action,
+		"workflow_job": map[string]any{
+			"id":     789,
+			"labels": []string{label},
+		},
+		"installation": map[string]any{"id": 123},
+		"organization": map[string]any{"login": "google"},
+		"repository":   map[string]any{"name": "webhook"},
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	headers := map[string]string{
+		"X-GitHub-Event":      "workflow_job",
+		"X-GitHub-Delivery":   "delivery-id",
+		"X-Hub-Signature-256": "sha256=signed-with-another-secret",
+	}
+	if host != "" {
+		headers["X-GitHub-Enterprise-Host"] = host
+	}
+	payload, err := json.Marshal(map[string]any{
+		"headers": headers,
+		"body":    json.RawMessage(body),
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.WriteFile(path, payload, 0o600); err != nil {
+		tb.Fatal(err)
+	}
+}
@@ -35,6 +35,12 @@ var rootCmd = func() cli.Command {
 						"server": func() cli.Command {
 							return &WebhookServerCommand{}
 						},
+						"replay": func() cli.Command {
+							return &WebhookReplayCommand{}
+						},
+						"send": func() cli.Command {
+							return &WebhookSendCommand{}
+						},
 					},
 				}
 			},
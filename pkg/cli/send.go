@@ -0,0 +1,302 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/google/uuid"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/version"
+	"github.com/abcxyz/github-action-dispatcher/pkg/webhook"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*WebhookSendCommand)(nil)
+
+type WebhookSendCommand struct {
+	cli.BaseCommand
+
+	flagURL                 string
+	flagWebhookKeyMountPath string
+	flagWebhookKeyName      string
+	flagAction              string
+	flagOrg                 string
+	flagRepo                string
+	flagLabels              []string
+	flagInstallationID      int64
+	flagAppID               string
+	flagJobID               int64
+	flagRunID               int64
+	flagRunnerName          string
+	flagConclusion          string
+	flagTimeout             time.Duration
+
+	// only used for testing
+	testFlagSetOpts []cli.Option
+}
+
+func (c *WebhookSendCommand) Desc() string {
+	return `Send a signed workflow_job webhook to a dispatcher`
+}
+
+func (c *WebhookSendCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Build a workflow_job webhook event for a job in a repository, sign it with
+  the webhook secret as GitHub does and POST it to a dispatcher. The response
+  from the dispatcher is printed.
+
+  Send a queued event for a job requesting the self-hosted label:
+
+      {{ COMMAND }} -url https://dispatcher.example.com/webhook \
+        -org my-org -repo my-repo -labels self-hosted
+`
+}
+
+func (c *WebhookSendCommand) Flags() *cli.FlagSet {
+	set := cli.NewFlagSet(c.testFlagSetOpts...)
+
+	f := set.NewSection("SEND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "url",
+		Target:  &c.flagURL,
+		Example: "https://dispatcher.example.com/webhook",
+		Usage:   `The URL of the dispatcher's webhook endpoint.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "webhook-key-mount-path",
+		Target: &c.flagWebhookKeyMountPath,
+		EnvVar: "WEBHOOK_KEY_MOUNT_PATH",
+		Usage:  `The directory that the webhook secret is read from.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "webhook-key-name",
+		Target: &c.flagWebhookKeyName,
+		EnvVar: "WEBHOOK_KEY_NAME",
+		Usage:  `The name of the file holding the webhook secret.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "timeout",
+		Target:  &c.flagTimeout,
+		Default: 30 * time.Second,
+		Usage:   `How long to wait for the dispatcher to respond.`,
+	})
+
+	ef := set.NewSection("EVENT OPTIONS")
+
+	ef.StringVar(&cli.StringVar{
+		Name:    "action",
+		Target:  &c.flagAction,
+		Default: "queued",
+		Usage:   `The action of the workflow_job event, one of "queued", "in_progress" or "completed".`,
+	})
+
+	ef.StringVar(&cli.StringVar{
+		Name:   "org",
+		Target: &c.flagOrg,
+		Usage:  `The org that the job's repository belongs to.`,
+	})
+
+	ef.StringVar(&cli.StringVar{
+		Name:   "repo",
+		Target: &c.flagRepo,
+		Usage:  `The name of the job's repository.`,
+	})
+
+	ef.StringSliceVar(&cli.StringSliceVar{
+		Name:    "labels",
+		Target:  &c.flagLabels,
+		Example: "self-hosted,linux",
+		Usage:   `The runner labels requested by the job.`,
+	})
+
+	ef.Int64Var(&cli.Int64Var{
+		Name:   "installation-id",
+		Target: &c.flagInstallationID,
+		Usage:  `The ID of the GitHub App installation that the event is for.`,
+	})
+
+	ef.StringVar(&cli.StringVar{
+		Name:   "app-id",
+		Target: &c.flagAppID,
+		Usage:  `The ID of the GitHub App that the event is for, sent in the X-GitHub-Hook-Installation-Target-ID header. The header is not sent if unset.`,
+	})
+
+	ef.Int64Var(&cli.Int64Var{
+		Name:   "job-id",
+		Target: &c.flagJobID,
+		Usage:  `The ID of the workflow job. A random ID is used if unset.`,
+	})
+
+	ef.Int64Var(&cli.Int64Var{
+		Name:   "run-id",
+		Target: &c.flagRunID,
+		Usage:  `The ID of the workflow run. A random ID is used if unset.`,
+	})
+
+	ef.StringVar(&cli.StringVar{
+		Name:   "runner-name",
+		Target: &c.flagRunnerName,
+		Usage:  `The name of the runner assigned to the job, for in_progress and completed events.`,
+	})
+
+	ef.StringVar(&cli.StringVar{
+		Name:    "conclusion",
+		Target:  &c.flagConclusion,
+		Default: "success",
+		Usage:   `The conclusion of the job, for completed events.`,
+	})
+
+	return set
+}
+
+func (c *WebhookSendCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagURL == "" {
+		return fmt.Errorf("-url is required")
+	}
+	if c.flagWebhookKeyMountPath == "" || c.flagWebhookKeyName == "" {
+		return fmt.Errorf("-webhook-key-mount-path and -webhook-key-name are required")
+	}
+	if c.flagOrg == "" || c.flagRepo == "" {
+		return fmt.Errorf("-org and -repo are required")
+	}
+	if c.flagInstallationID == 0 {
+		return fmt.Errorf("-installation-id is required")
+	}
+	switch c.flagAction {
+	case "queued", "in_progress", "completed":
+	default:
+		return fmt.Errorf(`-action must be one of "queued", "in_progress" or "completed", got %q`, c.flagAction)
+	}
+
+	secret, err := os.ReadFile(fmt.Sprintf("%s/%s", c.flagWebhookKeyMountPath, c.flagWebhookKeyName))
+	if err != nil {
+		return fmt.Errorf("failed to read webhook secret: %w", err)
+	}
+
+	payload, err := json.Marshal(c.workflowJobEvent(time.Now().UTC()))
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.flagTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.flagURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("github-action-dispatcher/%s", version.Version))
+	req.Header.Set(github.EventTypeHeader, "workflow_job")
+	req.Header.Set(github.DeliveryIDHeader, uuid.New().String())
+	req.Header.Set(github.SHA256SignatureHeader, webhook.SignPayload(secret, payload))
+	if c.flagAppID != "" {
+		req.Header.Set("X-GitHub-Hook-Installation-Target-ID", c.flagAppID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	c.Outf("%s %s", resp.Status, body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("dispatcher responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// workflowJobEvent builds the event GitHub would send for the job when it
+// reaches the action at now. Timestamps for the earlier actions are set to
+// shortly before now.
+func (c *WebhookSendCommand) workflowJobEvent(now time.Time) *github.WorkflowJobEvent {
+	jobID := c.flagJobID
+	if jobID == 0 {
+		jobID = int64(uuid.New().ID())
+	}
+	runID := c.flagRunID
+	if runID == 0 {
+		runID = int64(uuid.New().ID())
+	}
+
+	repoURL := fmt.Sprintf("https://github.com/%s/%s", c.flagOrg, c.flagRepo)
+	job := &github.WorkflowJob{
+		ID:         github.Ptr(jobID),
+		RunID:      github.Ptr(runID),
+		RunAttempt: github.Ptr(int64(1)),
+		Name:       github.Ptr("build"),
+		HTMLURL:    github.Ptr(fmt.Sprintf("%s/actions/runs/%d/job/%d", repoURL, runID, jobID)),
+		Status:     github.Ptr(c.flagAction),
+		Labels:     c.flagLabels,
+		CreatedAt:  &github.Timestamp{Time: now},
+	}
+	switch c.flagAction {
+	case "in_progress":
+		job.CreatedAt = &github.Timestamp{Time: now.Add(-time.Minute)}
+		job.StartedAt = &github.Timestamp{Time: now}
+		job.RunnerName = github.Ptr(c.flagRunnerName)
+	case "completed":
+		job.CreatedAt = &github.Timestamp{Time: now.Add(-2 * time.Minute)}
+		job.StartedAt = &github.Timestamp{Time: now.Add(-time.Minute)}
+		job.CompletedAt = &github.Timestamp{Time: now}
+		job.Conclusion = github.Ptr(c.flagConclusion)
+		job.RunnerName = github.Ptr(c.flagRunnerName)
+	}
+
+	event := &github.WorkflowJobEvent{
+		Action:       github.Ptr(c.flagAction),
+		WorkflowJob:  job,
+		Org:          &github.Organization{Login: github.Ptr(c.flagOrg)},
+		Installation: &github.Installation{ID: github.Ptr(c.flagInstallationID)},
+		Repo: &github.Repository{
+			Name:       github.Ptr(c.flagRepo),
+			FullName:   github.Ptr(fmt.Sprintf("%s/%s", c.flagOrg, c.flagRepo)),
+			Owner:      &github.User{Login: github.Ptr(c.flagOrg)},
+			HTMLURL:    github.Ptr(repoURL),
+			Visibility: github.Ptr("private"),
+		},
+	}
+	return event
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/sethvargo/go-envconfig"
+
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestWebhookSendCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	const secret = "webhook-secret"
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "key"), []byte(secret), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name          string
+		args          []string
+		statusCode    int
+		expErr        string
+		expAction     string
+		expLabels     []string
+		expRunnerName string
+		expConclusion string
+		expAppID      string
+	}{
+		{
+			name:   "missing_url",
+			args:   []string{"-org", "google", "-repo", "webhook"},
+			expErr: "-url is required",
+		},
+		{
+			name:   "missing_org",
+			args:   []string{"-url", "URL", "-webhook-key-mount-path", dir, "-webhook-key-name", "key", "-repo", "webhook"},
+			expErr: "-org and -repo are required",
+		},
+		{
+			name:   "invalid_action",
+			args:   []string{"-url", "URL", "-webhook-key-mount-path", dir, "-webhook-key-name", "key", "-org", "google", "-repo", "webhook", "-installation-id", "123", "-action", "waiting"},
+			expErr: `-action must be one of "queued", "in_progress" or "completed", got "waiting"`,
+		},
+		{
+			name:   "missing_secret",
+			args:   []string{"-url", "URL", "-webhook-key-mount-path", dir, "-webhook-key-name", "missing", "-org", "google", "-repo", "webhook", "-installation-id", "123"},
+			expErr: "failed to read webhook secret",
+		},
+		{
+			name:       "queued",
+			args:       []string{"-url", "URL", "-webhook-key-mount-path", dir, "-webhook-key-name", "key", "-org", "google", "-repo", "webhook", "-installation-id", "123", "-labels", "self-hosted,linux", "-app-id", "456"},
+			statusCode: http.StatusOK,
+			expAction:  "queued",
+			expLabels:  []string{"self-hosted", "linux"},
+			expAppID:   "456",
+		},
+		{
+			name:          "completed",
+			args:          []string{"-url", "URL", "-webhook-key-mount-path", dir, "-webhook-key-name", "key", "-org", "google", "-repo", "webhook", "-installation-id", "123", "-labels", "self-hosted", "-action", "completed", "-runner-name", "runner-1", "-conclusion", "cancelled"},
+			statusCode:    http.StatusOK,
+			expAction:     "completed",
+			expLabels:     []string{"self-hosted"},
+			expRunnerName: "runner-1",
+			expConclusion: "cancelled",
+		},
+		{
+			name:       "error_response",
+			args:       []string{"-url", "URL", "-webhook-key-mount-path", dir, "-webhook-key-name", "key", "-org", "google", "-repo", "webhook", "-installation-id", "123"},
+			statusCode: http.StatusBadRequest,
+			expErr:     "dispatcher responded with 400 Bad Request",
+			expAction:  "queued",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got *github.WorkflowJobEvent
+			var gotAppID string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				payload, err := github.ValidatePayload(r, []byte(secret))
+				if err != nil {
+					t.Errorf("failed to validate payload: %v", err)
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				event, err := github.ParseWebHook(github.WebHookType(r), payload)
+				if err != nil {
+					t.Errorf("failed to parse webhook: %v", err)
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				got = event.(*github.WorkflowJobEvent)
+				gotAppID = r.Header.Get("X-GitHub-Hook-Installation-Target-ID")
+				w.WriteHeader(tc.statusCode)
+				fmt.Fprint(w, "handled")
+			}))
+			t.Cleanup(srv.Close)
+
+			args := make([]string, len(tc.args))
+			for i, arg := range tc.args {
+				args[i] = strings.ReplaceAll(arg, "URL", srv.URL)
+			}
+
+			var cmd WebhookSendCommand
+			cmd.testFlagSetOpts = []cli.Option{cli.WithLookupEnv(envconfig.MapLookuper(nil).Lookup)}
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if tc.expAction == "" {
+				return
+			}
+
+			if got, want := stdout.String(), "handled"; !strings.Contains(got, want) {
+				t.Errorf("expected output %q to contain %q", got, want)
+			}
+			if got, want := got.GetAction(), tc.expAction; got != want {
+				t.Errorf("expected action %q, got %q", want, got)
+			}
+			if diff := cmp.Diff(tc.expLabels, got.GetWorkflowJob().Labels); diff != "" {
+				t.Errorf("labels (-want, +got):\n%s", diff)
+			}
+			if got, want := got.GetOrg().GetLogin(), "google"; got != want {
+				t.Errorf("expected org %q, got %q", want, got)
+			}
+			if got, want := got.GetRepo().GetName(), "webhook"; got != want {
+				t.Errorf("expected repo %q, got %q", want, got)
+			}
+			if got, want := got.GetInstallation().GetID(), int64(123); got != want {
+				t.Errorf("expected installation %d, got %d", want, got)
+			}
+			if got.GetWorkflowJob().GetID() == 0 {
+				t.Errorf("expected a job ID")
+			}
+			if got, want := got.GetWorkflowJob().GetRunnerName(), tc.expRunnerName; got != want {
+				t.Errorf("expected runner name %q, got %q", want, got)
+			}
+			if got, want := got.GetWorkflowJob().GetConclusion(), tc.expConclusion; got != want {
+				t.Errorf("expected conclusion %q, got %q", want, got)
+			}
+			if got, want := gotAppID, tc.expAppID; got != want {
+				t.Errorf("expected app ID header %q, got %q", want, got)
+			}
+		})
+	}
+}
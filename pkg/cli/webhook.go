@@ -39,6 +39,8 @@ type WebhookServerCommand struct {
 	cfg         *webhook.Config
 	registryCfg *registry.RegistryConfig
 
+	webhookServer *webhook.Server
+
 	// only used for testing
 	testFlagSetOpts []cli.Option
 
@@ -78,6 +80,11 @@ func (c *WebhookServerCommand) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	c.webhookServer.StartDispatchWorkers(ctx)
+	c.webhookServer.StartWarmPools(ctx)
+	c.webhookServer.StartWebhookSecretReloader(ctx)
+	c.webhookServer.StartInFlightReconciler(ctx)
+
 	return server.StartHTTPHandler(ctx, mux)
 }
 
@@ -141,6 +148,7 @@ func (c *WebhookServerCommand) RunUnstarted(ctx context.Context, args []string)
 		return nil, nil, fmt.Errorf("failed to create server: %w", err)
 	}
 
+	c.webhookServer = webhookServer
 	mux := webhookServer.Routes(ctx)
 
 	server, err := serving.New(c.cfg.Port)
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	cloudbuild "cloud.google.com/go/cloudbuild/apiv1/v2"
@@ -33,14 +34,23 @@ import (
 type Client interface {
 	ListWorkerPools(ctx context.Context, projectID, location string) ([]*cloudbuildpb.WorkerPool, error)
 	CreateBuild(ctx context.Context, req *cloudbuildpb.CreateBuildRequest) (string, error)
+	CreateBuildConsumer(ctx context.Context, req *cloudbuildpb.CreateBuildConsumerRequest) (string, error)
+	GetBuild(ctx context.Context, req *cloudbuildpb.GetBuildRequest) (*cloudbuildpb.Build, error)
+	CancelBuild(ctx context.Context, req *cloudbuildpb.CancelBuildRequest) (*cloudbuildpb.Build, error)
 	Close() error
 }
 
 // cloudbuildClient is the implementation of the Client interface.
 type cloudbuildClient struct {
 	client              *cloudbuild.Client
+	opts                []option.ClientOption
 	backoffInitialDelay time.Duration
 	maxRetryAttempts    int
+
+	// ccfeClient is only created when the first build is created on a trusted
+	// pool, as most deployments do not use trusted pools.
+	ccfeClientLock sync.Mutex
+	ccfeClient     *cloudbuild.CloudBuildInternalCCFEClient
 }
 
 // NewClient creates a new Cloud Build client.
@@ -52,6 +62,7 @@ func NewClient(ctx context.Context, backoffInitialDelay time.Duration, maxRetryA
 
 	return &cloudbuildClient{
 		client:              client,
+		opts:                opts,
 		backoffInitialDelay: backoffInitialDelay,
 		maxRetryAttempts:    maxRetryAttempts,
 	}, nil
@@ -125,11 +136,101 @@ func (c *cloudbuildClient) CreateBuild(ctx context.Context, req *cloudbuildpb.Cr
 	return buildID, nil
 }
 
+// CreateBuildConsumer creates a new build on a trusted pool through the CCFE
+// consumer API.
+func (c *cloudbuildClient) CreateBuildConsumer(ctx context.Context, req *cloudbuildpb.CreateBuildConsumerRequest) (string, error) {
+	ccfeClient, err := c.getCCFEClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	logger := logging.FromContext(ctx)
+	backoff := c.newBackoff()
+
+	var buildID string
+	if err := goretry.Do(ctx, backoff, func(ctx context.Context) error {
+		build, err := ccfeClient.CreateBuildConsumer(ctx, req)
+		if err != nil {
+			logger.WarnContext(ctx, "retrying due to CreateBuildConsumer failure", "error", err)
+			return goretry.RetryableError(fmt.Errorf("failed to create Cloud Build consumer build: %w", err))
+		}
+		buildID = build.GetId()
+		return nil
+	}); err != nil {
+		return "", fmt.Errorf("failed to create Cloud Build consumer build after retries: %w", err)
+	}
+	return buildID, nil
+}
+
+// getCCFEClient returns the CCFE client, creating it if this is the first
+// build created on a trusted pool.
+func (c *cloudbuildClient) getCCFEClient(ctx context.Context) (*cloudbuild.CloudBuildInternalCCFEClient, error) {
+	c.ccfeClientLock.Lock()
+	defer c.ccfeClientLock.Unlock()
+
+	if c.ccfeClient == nil {
+		ccfeClient, err := cloudbuild.NewCloudBuildInternalCCFEClient(ctx, c.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new cloud build ccfe client: %w", err)
+		}
+		c.ccfeClient = ccfeClient
+	}
+	return c.ccfeClient, nil
+}
+
+// GetBuild returns information about a previously requested build.
+func (c *cloudbuildClient) GetBuild(ctx context.Context, req *cloudbuildpb.GetBuildRequest) (*cloudbuildpb.Build, error) {
+	logger := logging.FromContext(ctx)
+	backoff := c.newBackoff()
+
+	var build *cloudbuildpb.Build
+	if err := goretry.Do(ctx, backoff, func(ctx context.Context) error {
+		b, err := c.client.GetBuild(ctx, req)
+		if err != nil {
+			logger.WarnContext(ctx, "retrying due to GetBuild failure", "error", err)
+			return goretry.RetryableError(fmt.Errorf("failed to get Cloud Build build: %w", err))
+		}
+		build = b
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get Cloud Build build after retries: %w", err)
+	}
+	return build, nil
+}
+
+// CancelBuild cancels a build in progress.
+func (c *cloudbuildClient) CancelBuild(ctx context.Context, req *cloudbuildpb.CancelBuildRequest) (*cloudbuildpb.Build, error) {
+	logger := logging.FromContext(ctx)
+	backoff := c.newBackoff()
+
+	var build *cloudbuildpb.Build
+	if err := goretry.Do(ctx, backoff, func(ctx context.Context) error {
+		b, err := c.client.CancelBuild(ctx, req)
+		if err != nil {
+			logger.WarnContext(ctx, "retrying due to CancelBuild failure", "error", err)
+			return goretry.RetryableError(fmt.Errorf("failed to cancel Cloud Build build: %w", err))
+		}
+		build = b
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to cancel Cloud Build build after retries: %w", err)
+	}
+	return build, nil
+}
+
 // Close closes the client.
 func (c *cloudbuildClient) Close() error {
 	if err := c.client.Close(); err != nil {
 		return fmt.Errorf("failed to close CloudBuild client: %w", err)
 	}
+
+	c.ccfeClientLock.Lock()
+	defer c.ccfeClientLock.Unlock()
+	if c.ccfeClient != nil {
+		if err := c.ccfeClient.Close(); err != nil {
+			return fmt.Errorf("failed to close CloudBuild CCFE client: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -16,6 +16,7 @@ package cloudbuild
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
@@ -30,6 +31,22 @@ type MockClient struct {
 	WorkerPools        []*cloudbuildpb.WorkerPool
 	CreateBuildReqs    []*cloudbuildpb.CreateBuildRequest
 	CreateBuildID      string
+
+	// CreateBuildProjectErrs are returned by CreateBuild for builds in the
+	// project with the given ID.
+	CreateBuildProjectErrs map[string]error
+
+	// CreateBuildConsumerReqs records the builds created on trusted pools,
+	// which are given the CreateBuildID.
+	CreateBuildConsumerErr  error
+	CreateBuildConsumerReqs []*cloudbuildpb.CreateBuildConsumerRequest
+
+	// Builds are returned by GetBuild and CancelBuild, keyed by build ID.
+	Builds          map[string]*cloudbuildpb.Build
+	GetBuildErr     error
+	GetBuildReqs    []*cloudbuildpb.GetBuildRequest
+	CancelBuildErr  error
+	CancelBuildReqs []*cloudbuildpb.CancelBuildRequest
 }
 
 // ListWorkerPools is a mock of the ListWorkerPools method.
@@ -53,9 +70,48 @@ func (m *MockClient) CreateBuild(ctx context.Context, req *cloudbuildpb.CreateBu
 	if m.CreateBuildErr != nil {
 		return "", m.CreateBuildErr
 	}
+	if err := m.CreateBuildProjectErrs[req.GetProjectId()]; err != nil {
+		return "", err
+	}
+	return m.CreateBuildID, nil
+}
+
+// CreateBuildConsumer is a mock of the CreateBuildConsumer method.
+func (m *MockClient) CreateBuildConsumer(ctx context.Context, req *cloudbuildpb.CreateBuildConsumerRequest) (string, error) {
+	m.CreateBuildConsumerReqs = append(m.CreateBuildConsumerReqs, req)
+	if m.CreateBuildConsumerErr != nil {
+		return "", m.CreateBuildConsumerErr
+	}
 	return m.CreateBuildID, nil
 }
 
+// GetBuild is a mock of the GetBuild method.
+func (m *MockClient) GetBuild(ctx context.Context, req *cloudbuildpb.GetBuildRequest) (*cloudbuildpb.Build, error) {
+	m.GetBuildReqs = append(m.GetBuildReqs, req)
+	if m.GetBuildErr != nil {
+		return nil, m.GetBuildErr
+	}
+	build, ok := m.Builds[req.GetId()]
+	if !ok {
+		return nil, fmt.Errorf("build %s not found", req.GetId())
+	}
+	return build, nil
+}
+
+// CancelBuild is a mock of the CancelBuild method.
+func (m *MockClient) CancelBuild(ctx context.Context, req *cloudbuildpb.CancelBuildRequest) (*cloudbuildpb.Build, error) {
+	m.CancelBuildReqs = append(m.CancelBuildReqs, req)
+	if m.CancelBuildErr != nil {
+		return nil, m.CancelBuildErr
+	}
+	build, ok := m.Builds[req.GetId()]
+	if !ok {
+		return nil, fmt.Errorf("build %s not found", req.GetId())
+	}
+	build.Status = cloudbuildpb.Build_CANCELLED
+	return build, nil
+}
+
 // Close is a mock of the Close method.
 func (m *MockClient) Close() error {
 	return nil
@@ -22,6 +22,7 @@ import (
 
 	"github.com/sethvargo/go-envconfig"
 
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
 	"github.com/abcxyz/pkg/cfgloader"
 )
 
@@ -32,10 +33,20 @@ const (
 	poolAvailabilityGCPProjectLabelKey    = "pool-availability"
 	poolTypeGCPProjectLabelKey            = "pool-type"
 	trustedRemoteConfigGCPProjectLabelKey = "trusted-remote-config"
+	runnerCapabilitiesGCPProjectLabelKey  = "runner-capabilities"
+	githubHostGCPProjectLabelKey          = "gh-host"
+	poolWeightGCPProjectLabelKey          = "pool-weight"
+	poolPriorityGCPProjectLabelKey        = "pool-priority"
+	poolMaxConcurrencyGCPProjectLabelKey  = "pool-max-concurrency"
 	poolAvailabilityAvailable             = "available"
 	poolAvailabilityUnavailable           = "unavailable"
-	poolTypeTrusted                       = "trusted"
+	poolTypeTrusted                       = registry.PoolTypeTrusted
 	poolTypePrivate                       = "private"
+
+	// runnerCapabilitiesDelimiter separates the values of the
+	// runner-capabilities project label. GCP label values cannot contain
+	// commas, so underscores are used instead.
+	runnerCapabilitiesDelimiter = "_"
 )
 
 // Config defines the set of environment variables required
@@ -52,6 +63,7 @@ type Config struct {
 	MaxRetryAttempts               int           `env:"MAX_RETRY_ATTEMPTS,default=3"`
 	BackoffInitialDelay            time.Duration `env:"BACKOFF_INITIAL_DELAY,default=500ms"`
 	RunnerRegistryDefaultKeyPrefix string        `env:"RUNNER_REGISTRY_DEFAULT_KEY_PREFIX,default=default"`
+	RunnerBaseCapabilities         string        `env:"RUNNER_BASE_CAPABILITIES"`
 }
 
 // Validate validates the runner-discovery config after load.
@@ -119,6 +131,19 @@ func (c *Config) GetAllowedTrustedRemoteConfigs() []string {
 	return c.AllowedTrustedRemoteConfigs
 }
 
+// GetRunnerBaseCapabilities returns the runner labels that every discovered
+// worker pool is able to serve, in addition to its job-runs-on label.
+func (c *Config) GetRunnerBaseCapabilities() []string {
+	if c.RunnerBaseCapabilities == "" {
+		return nil
+	}
+	capabilities := strings.Split(c.RunnerBaseCapabilities, ",")
+	for i, capability := range capabilities {
+		capabilities[i] = strings.TrimSpace(capability)
+	}
+	return capabilities
+}
+
 func (c *Config) GetIgnoredGCPProjectLabels() []string {
 	if c.IgnoredGCPProjectLabels == "" {
 		return nil
@@ -141,5 +166,10 @@ func (c *Config) GetIgnoredGCPProjectLabelsSet() map[string]struct{} {
 func (c *Config) GetOptionalGCPProjectLabelsSet() map[string]struct{} {
 	return map[string]struct{}{
 		trustedRemoteConfigGCPProjectLabelKey: {},
+		runnerCapabilitiesGCPProjectLabelKey:  {},
+		githubHostGCPProjectLabelKey:          {},
+		poolWeightGCPProjectLabelKey:          {},
+		poolPriorityGCPProjectLabelKey:        {},
+		poolMaxConcurrencyGCPProjectLabelKey:  {},
 	}
 }
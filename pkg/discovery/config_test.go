@@ -120,6 +120,7 @@ func TestNewConfig_Parsing(t *testing.T) {
 		expIgnoredGCPProjectLabels     []string
 		expIgnoredGCPProjectLabelsSet  map[string]struct{}
 		expAllowedTrustedRemoteConfigs []string
+		expRunnerBaseCapabilities      []string
 	}{
 		{
 			name: "valid_config",
@@ -132,6 +133,7 @@ func TestNewConfig_Parsing(t *testing.T) {
 				"GCP_ALLOWED_PROJECT_LABEL_TRUSTED_REMOTE_CONFIG_VALUES": "some/repo/*",
 				"GCP_IGNORED_PROJECT_LABELS":                             "foo,bar",
 				"GCP_FOLDER_ID":                                          "12345",
+				"RUNNER_BASE_CAPABILITIES":                               "self-hosted, linux",
 			},
 			expAllowedGithubOrgScopes:      []string{"default", "my-org"},
 			expAllowedJobRunsOn:            []string{"ubuntu-latest", "windows-latest"},
@@ -141,6 +143,7 @@ func TestNewConfig_Parsing(t *testing.T) {
 			expIgnoredGCPProjectLabels:     []string{"foo", "bar"},
 			expIgnoredGCPProjectLabelsSet:  map[string]struct{}{"foo": {}, "bar": {}},
 			expAllowedTrustedRemoteConfigs: []string{"some/repo/*"},
+			expRunnerBaseCapabilities:      []string{"self-hosted", "linux"},
 		},
 	}
 
@@ -180,6 +183,9 @@ func TestNewConfig_Parsing(t *testing.T) {
 			if diff := cmp.Diff(tc.expIgnoredGCPProjectLabelsSet, cfg.GetIgnoredGCPProjectLabelsSet()); diff != "" {
 				t.Errorf("IgnoredGCPProjectLabelsSet (-want,+got):\n%s", diff)
 			}
+			if diff := cmp.Diff(tc.expRunnerBaseCapabilities, cfg.GetRunnerBaseCapabilities()); diff != "" {
+				t.Errorf("RunnerBaseCapabilities (-want,+got):\n%s", diff)
+			}
 		})
 	}
 }
@@ -190,6 +196,11 @@ func TestConfig_GetOptionalGCPProjectLabelsSet(t *testing.T) {
 	cfg := &Config{}
 	expected := map[string]struct{}{
 		"trusted-remote-config": {},
+		"runner-capabilities":   {},
+		"gh-host":               {},
+		"pool-weight":           {},
+		"pool-priority":         {},
+		"pool-max-concurrency":  {},
 	}
 
 	if diff := cmp.Diff(expected, cfg.GetOptionalGCPProjectLabelsSet()); diff != "" {
@@ -19,7 +19,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	redisapi "github.com/go-redis/redis/v8"
@@ -100,7 +102,9 @@ func (rd *RunnerDiscovery) Run(ctx context.Context) error {
 // buildRegistry processes the list of projects to find and group worker pools.
 // The key for Redis should be constructed from gh-org-scope and job-runs-on from project labels.
 // For example, if gh-org-scope is "default" and job-runs-on is "ubuntu-latest",
-// the key will be "default:ubuntu-latest".
+// the key will be "default:ubuntu-latest". Projects with a gh-host label serve
+// that GitHub host instead of the default one, and their keys are prefixed with
+// it, for example "ghes1/default:ubuntu-latest".
 func (rd *RunnerDiscovery) buildRegistry(ctx context.Context, projects []*assetinventory.ProjectInfo) (map[string][]registry.WorkerPoolInfo, error) {
 	logger := logging.FromContext(ctx)
 	poolsByRegistryKey := make(map[string][]registry.WorkerPoolInfo)
@@ -124,6 +128,10 @@ func (rd *RunnerDiscovery) buildRegistry(ctx context.Context, projects []*asseti
 		jobRunsOn := projectLabels[jobRunsOnGCPProjectLabelKey]
 		location := projectLabels[poolLocationGCPProjectLabelKey]
 		poolType := projectLabels[poolTypeGCPProjectLabelKey]
+		capabilities := rd.poolCapabilities(jobRunsOn, project)
+		weight := projectLabelInt(projectLabels, poolWeightGCPProjectLabelKey)
+		priority := projectLabelInt(projectLabels, poolPriorityGCPProjectLabelKey)
+		maxConcurrency := projectLabelInt(projectLabels, poolMaxConcurrencyGCPProjectLabelKey)
 
 		wps, err := rd.cbc.ListWorkerPools(ctx, project.ProjectID, location)
 		if err != nil {
@@ -160,6 +168,9 @@ func (rd *RunnerDiscovery) buildRegistry(ctx context.Context, projects []*asseti
 			}
 
 			registryKey := fmt.Sprintf("%s:%s", githubOrgScope, jobRunsOn)
+			if githubHost := project.Labels[githubHostGCPProjectLabelKey]; githubHost != "" {
+				registryKey = githubHost + "/" + registryKey
+			}
 
 			// Parse project number and location from the full resource name to ensure accuracy.
 			// Format: projects/{PROJECT_NUMBER}/locations/{LOCATION}/workerPools/{WORKERPOOL}
@@ -174,11 +185,15 @@ func (rd *RunnerDiscovery) buildRegistry(ctx context.Context, projects []*asseti
 			}
 
 			poolInfo := registry.WorkerPoolInfo{
-				Name:          wp.GetName(),
-				ProjectID:     project.ProjectID,
-				ProjectNumber: poolProjectNumber,
-				Location:      poolLocation,
-				PoolType:      poolType,
+				Name:           wp.GetName(),
+				ProjectID:      project.ProjectID,
+				ProjectNumber:  poolProjectNumber,
+				Location:       poolLocation,
+				PoolType:       poolType,
+				Labels:         capabilities,
+				Weight:         weight,
+				Priority:       priority,
+				MaxConcurrency: maxConcurrency,
 			}
 			if val, ok := project.Labels[trustedRemoteConfigGCPProjectLabelKey]; ok {
 				poolInfo.RemoteConfig = val
@@ -189,6 +204,32 @@ func (rd *RunnerDiscovery) buildRegistry(ctx context.Context, projects []*asseti
 	return poolsByRegistryKey, nil
 }
 
+// poolCapabilities returns the sorted set of runner labels that worker pools
+// in the given project are able to serve. This is the project's job-runs-on
+// label, the base capabilities shared by every pool, and any additional
+// capabilities listed in the project's runner-capabilities label.
+func (rd *RunnerDiscovery) poolCapabilities(jobRunsOn string, project *assetinventory.ProjectInfo) []string {
+	capabilities := []string{jobRunsOn}
+	capabilities = append(capabilities, rd.config.GetRunnerBaseCapabilities()...)
+	if val, ok := project.Labels[runnerCapabilitiesGCPProjectLabelKey]; ok && val != "" {
+		capabilities = append(capabilities, strings.Split(val, runnerCapabilitiesDelimiter)...)
+	}
+
+	capabilities = slices.DeleteFunc(capabilities, func(s string) bool { return s == "" })
+	slices.Sort(capabilities)
+	return slices.Compact(capabilities)
+}
+
+// projectLabelInt returns the value of a validated numeric project label, or 0
+// if the project does not have it.
+func projectLabelInt(projectLabels map[string]string, key string) int {
+	n, err := strconv.Atoi(projectLabels[key])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
 // updateRegistry handles all interactions with the Redis cache.
 func (rd *RunnerDiscovery) updateRegistry(ctx context.Context, poolsByRegistryKey map[string][]registry.WorkerPoolInfo) error {
 	logger := logging.FromContext(ctx)
@@ -334,6 +375,25 @@ func (rd *RunnerDiscovery) filterAndValidateProjectLabels(ctx context.Context, p
 		projectLabels[key] = projectLabelValue
 	}
 
+	// The optional weight, priority and max concurrency labels must be numbers
+	// so that pools can be compared when a job is dispatched. Pools must have
+	// a positive weight.
+	for _, key := range []string{poolWeightGCPProjectLabelKey, poolPriorityGCPProjectLabelKey, poolMaxConcurrencyGCPProjectLabelKey} {
+		projectLabelValue, ok := project.Labels[key]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(projectLabelValue)
+		if err != nil || n < 0 || (n == 0 && key == poolWeightGCPProjectLabelKey) {
+			logger.WarnContext(ctx, "project has invalid numeric label",
+				"project_id", project.ProjectID,
+				"label", key,
+				"project_label_value", projectLabelValue)
+			return nil, false
+		}
+		projectLabels[key] = projectLabelValue
+	}
+
 	if projectLabels[poolTypeGCPProjectLabelKey] == poolTypeTrusted {
 		projectLabelValue, ok := project.Labels[trustedRemoteConfigGCPProjectLabelKey]
 		if !ok {
@@ -142,12 +142,14 @@ func TestRunnerDiscovery_Run(t *testing.T) {
 							ProjectID:     testProjectID1,
 							ProjectNumber: testProjectNumber1,
 							Location:      testLocation,
+							Labels:        []string{testJobRunsOnE2Medium},
 						},
 						{
 							Name:          newMockWorkerPool(testProjectNumber2, testLocation, testWorkerPoolID2, testJobRunsOnE2Medium).GetName(),
 							ProjectID:     testProjectID2,
 							ProjectNumber: testProjectNumber2,
 							Location:      testLocation,
+							Labels:        []string{testJobRunsOnE2Medium},
 						},
 					}
 					sort.Slice(pools, func(i, j int) bool {
@@ -161,6 +163,7 @@ func TestRunnerDiscovery_Run(t *testing.T) {
 						ProjectID:     testProjectID3,
 						ProjectNumber: testProjectNumber3,
 						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Small},
 					},
 				},
 			},
@@ -229,6 +232,7 @@ func TestRunnerDiscovery_Run(t *testing.T) {
 						ProjectID:     testProjectID1,
 						ProjectNumber: testProjectNumber1,
 						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Medium},
 						RemoteConfig:  "remote/path/to/config/file",
 					},
 				},
@@ -238,6 +242,7 @@ func TestRunnerDiscovery_Run(t *testing.T) {
 						ProjectID:     testProjectID3,
 						ProjectNumber: testProjectNumber3,
 						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Small},
 					},
 				},
 			},
@@ -279,6 +284,7 @@ func TestRunnerDiscovery_Run(t *testing.T) {
 						ProjectID:     testProjectID1,
 						ProjectNumber: testProjectNumber1,
 						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Medium},
 					},
 				},
 			},
@@ -342,6 +348,7 @@ func TestRunnerDiscovery_Run(t *testing.T) {
 						ProjectID:     testProjectID1,
 						ProjectNumber: testProjectNumber1,
 						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Medium},
 					},
 				},
 				testRegistryKey(testSemiWildcardOrg, testJobRunsOnE2Medium): {
@@ -350,6 +357,7 @@ func TestRunnerDiscovery_Run(t *testing.T) {
 						ProjectID:     testProjectID2,
 						ProjectNumber: testProjectNumber2,
 						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Medium},
 					},
 				},
 				testRegistryKey(testWildcardOrg, testJobRunsOnE2Small): {
@@ -358,6 +366,200 @@ func TestRunnerDiscovery_Run(t *testing.T) {
 						ProjectID:     testProjectID3,
 						ProjectNumber: testProjectNumber3,
 						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Small},
+					},
+				},
+			},
+			expectRedis: true,
+		},
+		{
+			name: "success_with_capabilities",
+			config: &Config{
+				AllowedGithubOrgScopes:         "default",
+				AllowedJobRunsOn:               testJobRunsOnE2Medium,
+				AllowedPoolLocations:           "us-central1",
+				AllowedPoolAvailabilities:      strings.Join([]string{poolAvailabilityAvailable, poolAvailabilityUnavailable}, ","),
+				GCPFolderID:                    testGCPFolderID,
+				RunnerRegistryDefaultKeyPrefix: testRunnerRegistryDefaultKeyPrefix,
+				RunnerBaseCapabilities:         "self-hosted, linux",
+			},
+			cloudbuildMock: &cloudbuild.MockClient{
+				WorkerPools: []*cloudbuildpb.WorkerPool{
+					newMockWorkerPool(testProjectNumber1, testLocation, testWorkerPoolID1, testJobRunsOnE2Medium),
+				},
+			},
+			assetInventoryMock: &assetinventory.MockClient{
+				StubProjects: []*assetinventory.ProjectInfo{
+					{
+						ProjectID: testProjectID1,
+						Labels: map[string]string{
+							githubOrgScopeGCPProjectLabelKey:     testRunnerRegistryDefaultKeyPrefix,
+							jobRunsOnGCPProjectLabelKey:          testJobRunsOnE2Medium,
+							poolLocationGCPProjectLabelKey:       testLocation,
+							poolAvailabilityGCPProjectLabelKey:   poolAvailabilityAvailable,
+							runnerCapabilitiesGCPProjectLabelKey: "x64_gpu_linux",
+						},
+					},
+				},
+			},
+			expRegistrySets: map[string][]registry.WorkerPoolInfo{
+				testRegistryKey(testRunnerRegistryDefaultKeyPrefix, testJobRunsOnE2Medium): {
+					{
+						Name:          newMockWorkerPool(testProjectNumber1, testLocation, testWorkerPoolID1, testJobRunsOnE2Medium).GetName(),
+						ProjectID:     testProjectID1,
+						ProjectNumber: testProjectNumber1,
+						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Medium, "gpu", "linux", "self-hosted", "x64"},
+					},
+				},
+			},
+			expectRedis: true,
+		},
+		{
+			name: "success_with_github_host",
+			config: &Config{
+				AllowedGithubOrgScopes:         "default",
+				AllowedJobRunsOn:               testJobRunsOnE2Medium,
+				AllowedPoolLocations:           "us-central1",
+				AllowedPoolAvailabilities:      strings.Join([]string{poolAvailabilityAvailable, poolAvailabilityUnavailable}, ","),
+				GCPFolderID:                    testGCPFolderID,
+				RunnerRegistryDefaultKeyPrefix: testRunnerRegistryDefaultKeyPrefix,
+			},
+			cloudbuildMock: &cloudbuild.MockClient{
+				WorkerPools: []*cloudbuildpb.WorkerPool{
+					newMockWorkerPool(testProjectNumber1, testLocation, testWorkerPoolID1, testJobRunsOnE2Medium),
+				},
+			},
+			assetInventoryMock: &assetinventory.MockClient{
+				StubProjects: []*assetinventory.ProjectInfo{
+					{
+						ProjectID: testProjectID1,
+						Labels: map[string]string{
+							githubOrgScopeGCPProjectLabelKey:   testRunnerRegistryDefaultKeyPrefix,
+							jobRunsOnGCPProjectLabelKey:        testJobRunsOnE2Medium,
+							poolLocationGCPProjectLabelKey:     testLocation,
+							poolAvailabilityGCPProjectLabelKey: poolAvailabilityAvailable,
+							githubHostGCPProjectLabelKey:       "ghes1",
+						},
+					},
+				},
+			},
+			expRegistrySets: map[string][]registry.WorkerPoolInfo{
+				"ghes1/" + testRegistryKey(testRunnerRegistryDefaultKeyPrefix, testJobRunsOnE2Medium): {
+					{
+						Name:          newMockWorkerPool(testProjectNumber1, testLocation, testWorkerPoolID1, testJobRunsOnE2Medium).GetName(),
+						ProjectID:     testProjectID1,
+						ProjectNumber: testProjectNumber1,
+						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Medium},
+					},
+				},
+			},
+			expectRedis: true,
+		},
+		{
+			name: "success_with_weight_and_priority",
+			config: &Config{
+				AllowedGithubOrgScopes:         "default",
+				AllowedJobRunsOn:               testJobRunsOnE2Medium,
+				AllowedPoolLocations:           "us-central1",
+				AllowedPoolAvailabilities:      strings.Join([]string{poolAvailabilityAvailable, poolAvailabilityUnavailable}, ","),
+				GCPFolderID:                    testGCPFolderID,
+				RunnerRegistryDefaultKeyPrefix: testRunnerRegistryDefaultKeyPrefix,
+			},
+			cloudbuildMock: &cloudbuild.MockClient{
+				WorkerPools: []*cloudbuildpb.WorkerPool{
+					newMockWorkerPool(testProjectNumber1, testLocation, testWorkerPoolID1, testJobRunsOnE2Medium),
+				},
+			},
+			assetInventoryMock: &assetinventory.MockClient{
+				StubProjects: []*assetinventory.ProjectInfo{
+					{
+						ProjectID: testProjectID1,
+						Labels: map[string]string{
+							githubOrgScopeGCPProjectLabelKey:   testRunnerRegistryDefaultKeyPrefix,
+							jobRunsOnGCPProjectLabelKey:        testJobRunsOnE2Medium,
+							poolLocationGCPProjectLabelKey:     testLocation,
+							poolAvailabilityGCPProjectLabelKey: poolAvailabilityAvailable,
+							poolWeightGCPProjectLabelKey:       "3",
+							poolPriorityGCPProjectLabelKey:     "10",
+						},
+					},
+					{
+						ProjectID: testProjectID2,
+						Labels: map[string]string{
+							githubOrgScopeGCPProjectLabelKey:   testRunnerRegistryDefaultKeyPrefix,
+							jobRunsOnGCPProjectLabelKey:        testJobRunsOnE2Medium,
+							poolLocationGCPProjectLabelKey:     testLocation,
+							poolAvailabilityGCPProjectLabelKey: poolAvailabilityAvailable,
+							poolWeightGCPProjectLabelKey:       "0",
+						},
+					},
+				},
+			},
+			expRegistrySets: map[string][]registry.WorkerPoolInfo{
+				testRegistryKey(testRunnerRegistryDefaultKeyPrefix, testJobRunsOnE2Medium): {
+					{
+						Name:          newMockWorkerPool(testProjectNumber1, testLocation, testWorkerPoolID1, testJobRunsOnE2Medium).GetName(),
+						ProjectID:     testProjectID1,
+						ProjectNumber: testProjectNumber1,
+						Location:      testLocation,
+						Labels:        []string{testJobRunsOnE2Medium},
+						Weight:        3,
+						Priority:      10,
+					},
+				},
+			},
+			expectRedis: true,
+		},
+		{
+			name: "success_with_max_concurrency",
+			config: &Config{
+				AllowedGithubOrgScopes:         "default",
+				AllowedJobRunsOn:               testJobRunsOnE2Medium,
+				AllowedPoolLocations:           "us-central1",
+				AllowedPoolAvailabilities:      strings.Join([]string{poolAvailabilityAvailable, poolAvailabilityUnavailable}, ","),
+				GCPFolderID:                    testGCPFolderID,
+				RunnerRegistryDefaultKeyPrefix: testRunnerRegistryDefaultKeyPrefix,
+			},
+			cloudbuildMock: &cloudbuild.MockClient{
+				WorkerPools: []*cloudbuildpb.WorkerPool{
+					newMockWorkerPool(testProjectNumber1, testLocation, testWorkerPoolID1, testJobRunsOnE2Medium),
+				},
+			},
+			assetInventoryMock: &assetinventory.MockClient{
+				StubProjects: []*assetinventory.ProjectInfo{
+					{
+						ProjectID: testProjectID1,
+						Labels: map[string]string{
+							githubOrgScopeGCPProjectLabelKey:     testRunnerRegistryDefaultKeyPrefix,
+							jobRunsOnGCPProjectLabelKey:          testJobRunsOnE2Medium,
+							poolLocationGCPProjectLabelKey:       testLocation,
+							poolAvailabilityGCPProjectLabelKey:   poolAvailabilityAvailable,
+							poolMaxConcurrencyGCPProjectLabelKey: "20",
+						},
+					},
+					{
+						ProjectID: testProjectID2,
+						Labels: map[string]string{
+							githubOrgScopeGCPProjectLabelKey:     testRunnerRegistryDefaultKeyPrefix,
+							jobRunsOnGCPProjectLabelKey:          testJobRunsOnE2Medium,
+							poolLocationGCPProjectLabelKey:       testLocation,
+							poolAvailabilityGCPProjectLabelKey:   poolAvailabilityAvailable,
+							poolMaxConcurrencyGCPProjectLabelKey: "unlimited",
+						},
+					},
+				},
+			},
+			expRegistrySets: map[string][]registry.WorkerPoolInfo{
+				testRegistryKey(testRunnerRegistryDefaultKeyPrefix, testJobRunsOnE2Medium): {
+					{
+						Name:           newMockWorkerPool(testProjectNumber1, testLocation, testWorkerPoolID1, testJobRunsOnE2Medium).GetName(),
+						ProjectID:      testProjectID1,
+						ProjectNumber:  testProjectNumber1,
+						Location:       testLocation,
+						Labels:         []string{testJobRunsOnE2Medium},
+						MaxConcurrency: 20,
 					},
 				},
 			},
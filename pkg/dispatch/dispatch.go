@@ -0,0 +1,97 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dispatch stores records linking GitHub workflow jobs to the runners
+// and Cloud Build builds that were dispatched for them.
+package dispatch
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// StatusDispatched is the status of a job whose runners have been started.
+	StatusDispatched = "dispatched"
+	// StatusInProgress is the status of a job that a runner has picked up.
+	StatusInProgress = "in_progress"
+	// StatusCompleted is the status of a job that has finished.
+	StatusCompleted = "completed"
+)
+
+// Record describes the runners dispatched for a single workflow job and what
+// happened to the job afterwards.
+type Record struct {
+	JobID      string `json:"job_id"`
+	RunID      string `json:"run_id,omitempty"`
+	JobName    string `json:"job_name,omitempty"`
+	DeliveryID string `json:"delivery_id,omitempty"`
+	Org        string `json:"org"`
+	Repo       string `json:"repo"`
+
+	// Labels are the labels requested by the job and ResolvedLabels are the
+	// same labels after alias resolution.
+	Labels         []string `json:"labels"`
+	ResolvedLabels []string `json:"resolved_labels,omitempty"`
+
+	Runners []*Runner `json:"runners"`
+
+	// AssignedRunnerName is the runner GitHub assigned the job to. It may not
+	// be one of the dispatched runners.
+	AssignedRunnerName string `json:"assigned_runner_name,omitempty"`
+
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+
+	QueuedAt     time.Time `json:"queued_at,omitzero"`
+	DispatchedAt time.Time `json:"dispatched_at,omitzero"`
+	StartedAt    time.Time `json:"started_at,omitzero"`
+	CompletedAt  time.Time `json:"completed_at,omitzero"`
+}
+
+// Runner is a runner started for a job, the build running it and the worker
+// pool it was started on. Runners of a job may be started on different pools
+// if a build could not be created on the first one.
+type Runner struct {
+	Name      string `json:"name"`
+	BuildID   string `json:"build_id,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	Location  string `json:"location,omitempty"`
+	Pool      string `json:"pool,omitempty"`
+	PoolType  string `json:"pool_type,omitempty"`
+	// Label is the registry label the pool was selected for.
+	Label string `json:"label,omitempty"`
+
+	// Warm is set for a warm runner, which was started before the job was
+	// queued and so has no build of its own in the record.
+	Warm bool `json:"warm,omitempty"`
+}
+
+// Store persists dispatch records. Lookups return nil when no record exists.
+type Store interface {
+	// Put creates or replaces the record for a job.
+	Put(ctx context.Context, r *Record) error
+	// Get returns the record for a job ID.
+	Get(ctx context.Context, jobID string) (*Record, error)
+	// GetByRunnerName returns the record for the job a runner was dispatched for.
+	GetByRunnerName(ctx context.Context, runnerName string) (*Record, error)
+	// GetByBuildID returns the record for the job a build was dispatched for.
+	GetByBuildID(ctx context.Context, buildID string) (*Record, error)
+	// List returns up to limit of the most recently dispatched records, newest
+	// first.
+	List(ctx context.Context, limit int) ([]*Record, error)
+	// Update applies fn to the record for a job and stores the result. It
+	// returns the updated record, or nil if no record exists.
+	Update(ctx context.Context, jobID string, fn func(r *Record)) (*Record, error)
+}
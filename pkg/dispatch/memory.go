@@ -0,0 +1,151 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is an in-process Store intended for tests.
+type MemoryStore struct {
+	mu       sync.Mutex
+	records  map[string][]byte
+	byRunner map[string]string
+	byBuild  map[string]string
+}
+
+// NewMemoryStore creates a new, empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records:  make(map[string][]byte),
+		byRunner: make(map[string]string),
+		byBuild:  make(map[string]string),
+	}
+}
+
+// Put creates or replaces the record for a job.
+func (s *MemoryStore) Put(ctx context.Context, r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dispatch record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[r.JobID] = b
+	for _, runner := range r.Runners {
+		s.byRunner[runner.Name] = r.JobID
+		if runner.BuildID != "" {
+			s.byBuild[runner.BuildID] = r.JobID
+		}
+	}
+	return nil
+}
+
+// Get returns the record for a job ID.
+func (s *MemoryStore) Get(ctx context.Context, jobID string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(jobID)
+}
+
+// GetByRunnerName returns the record for the job a runner was dispatched for.
+func (s *MemoryStore) GetByRunnerName(ctx context.Context, runnerName string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobID, ok := s.byRunner[runnerName]
+	if !ok {
+		return nil, nil
+	}
+	return s.get(jobID)
+}
+
+// GetByBuildID returns the record for the job a build was dispatched for.
+func (s *MemoryStore) GetByBuildID(ctx context.Context, buildID string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobID, ok := s.byBuild[buildID]
+	if !ok {
+		return nil, nil
+	}
+	return s.get(jobID)
+}
+
+// List returns up to limit of the most recently dispatched records, newest
+// first.
+func (s *MemoryStore) List(ctx context.Context, limit int) ([]*Record, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]*Record, 0, len(s.records))
+	for jobID := range s.records {
+		r, err := s.get(jobID)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	slices.SortFunc(records, func(a, b *Record) int {
+		return b.DispatchedAt.Compare(a.DispatchedAt)
+	})
+	return records[:min(limit, len(records))], nil
+}
+
+// Update applies fn to the record for a job and stores the result.
+func (s *MemoryStore) Update(ctx context.Context, jobID string, fn func(r *Record)) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.get(jobID)
+	if err != nil || r == nil {
+		return nil, err
+	}
+
+	fn(r)
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dispatch record: %w", err)
+	}
+	s.records[jobID] = b
+	return r, nil
+}
+
+func (s *MemoryStore) get(jobID string) (*Record, error) {
+	b, ok := s.records[jobID]
+	if !ok {
+		return nil, nil
+	}
+
+	var r Record
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dispatch record: %w", err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := NewMemoryStore()
+	record := &Record{
+		JobID:        "789",
+		Org:          "google",
+		Repo:         "webhook",
+		Labels:       []string{"self-hosted"},
+		Runners:      []*Runner{{Name: "runner-1", BuildID: "build-1", ProjectID: "project", Location: "us-central1", Pool: "pool-1", Label: "self-hosted"}},
+		Status:       StatusDispatched,
+		DispatchedAt: now,
+	}
+	if err := s.Put(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, get := range map[string]func() (*Record, error){
+		"job":    func() (*Record, error) { return s.Get(ctx, "789") },
+		"runner": func() (*Record, error) { return s.GetByRunnerName(ctx, "runner-1") },
+		"build":  func() (*Record, error) { return s.GetByBuildID(ctx, "build-1") },
+	} {
+		got, err := get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(record, got); diff != "" {
+			t.Errorf("lookup by %s mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+
+	older := &Record{JobID: "788", DispatchedAt: now.Add(-time.Minute)}
+	if err := s.Put(ctx, older); err != nil {
+		t.Fatal(err)
+	}
+	recent, err := s.List(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*Record{record}, recent); diff != "" {
+		t.Errorf("recent records mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := s.Update(ctx, "789", func(r *Record) {
+		r.Status = StatusCompleted
+		r.Conclusion = "success"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.Conclusion, "success"; got != want {
+		t.Errorf("expected conclusion %q to be %q", got, want)
+	}
+
+	stored, err := s.Get(ctx, "789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stored.Status, StatusCompleted; got != want {
+		t.Errorf("expected status %q to be %q", got, want)
+	}
+
+	missing, err := s.Update(ctx, "missing", func(r *Record) {
+		t.Error("expected update not to be called for missing record")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != nil {
+		t.Errorf("expected nil record, got %#v", missing)
+	}
+}
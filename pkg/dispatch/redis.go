@@ -0,0 +1,221 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// maxUpdateAttempts is the number of times Update retries when the record is
+// modified concurrently.
+const maxUpdateAttempts = 5
+
+var _ Store = (*RedisStore)(nil)
+
+// RedisStore is a Store backed by Redis. Records are stored as JSON under their
+// job ID, with secondary keys mapping runner names and build IDs to the job ID
+// and a sorted set of job IDs ordered by dispatch time. All keys expire after
+// the configured TTL.
+type RedisStore struct {
+	rc     *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisStore creates a RedisStore that stores records under keys prefixed
+// with prefix and expires them after ttl.
+func NewRedisStore(rc *redis.Client, prefix string, ttl time.Duration) *RedisStore {
+	return &RedisStore{
+		rc:     rc,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Put creates or replaces the record for a job.
+func (s *RedisStore) Put(ctx context.Context, r *Record) error {
+	if r.JobID == "" {
+		return fmt.Errorf("job id is required")
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dispatch record: %w", err)
+	}
+
+	pipe := s.rc.TxPipeline()
+	pipe.Set(ctx, s.jobKey(r.JobID), string(b), s.ttl)
+	for _, runner := range r.Runners {
+		pipe.Set(ctx, s.runnerKey(runner.Name), r.JobID, s.ttl)
+		if runner.BuildID != "" {
+			pipe.Set(ctx, s.buildKey(runner.BuildID), r.JobID, s.ttl)
+		}
+	}
+	pipe.ZAdd(ctx, s.recentKey(), &redis.Z{Score: unixMilli(r.DispatchedAt), Member: r.JobID})
+	pipe.ZRemRangeByScore(ctx, s.recentKey(), "-inf", fmt.Sprintf("(%d", r.DispatchedAt.Add(-s.ttl).UnixMilli()))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store dispatch record for job %s: %w", r.JobID, err)
+	}
+	return nil
+}
+
+// Get returns the record for a job ID.
+func (s *RedisStore) Get(ctx context.Context, jobID string) (*Record, error) {
+	return s.get(ctx, s.rc, jobID)
+}
+
+// GetByRunnerName returns the record for the job a runner was dispatched for.
+func (s *RedisStore) GetByRunnerName(ctx context.Context, runnerName string) (*Record, error) {
+	return s.getByIndex(ctx, s.runnerKey(runnerName))
+}
+
+// GetByBuildID returns the record for the job a build was dispatched for.
+func (s *RedisStore) GetByBuildID(ctx context.Context, buildID string) (*Record, error) {
+	return s.getByIndex(ctx, s.buildKey(buildID))
+}
+
+// List returns up to limit of the most recently dispatched records, newest
+// first. Records that have expired are skipped.
+func (s *RedisStore) List(ctx context.Context, limit int) ([]*Record, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	jobIDs, err := s.rc.ZRevRange(ctx, s.recentKey(), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent dispatch records: %w", err)
+	}
+	if len(jobIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(jobIDs))
+	for _, jobID := range jobIDs {
+		keys = append(keys, s.jobKey(jobID))
+	}
+	vals, err := s.rc.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent dispatch records: %w", err)
+	}
+
+	records := make([]*Record, 0, len(vals))
+	for i, val := range vals {
+		str, ok := val.(string)
+		if !ok {
+			continue
+		}
+
+		var r Record
+		if err := json.Unmarshal([]byte(str), &r); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dispatch record for job %s: %w", jobIDs[i], err)
+		}
+		records = append(records, &r)
+	}
+	return records, nil
+}
+
+// Update applies fn to the record for a job and stores the result, keeping the
+// existing expiry. The record is watched so that concurrent updates are not
+// lost.
+func (s *RedisStore) Update(ctx context.Context, jobID string, fn func(r *Record)) (*Record, error) {
+	key := s.jobKey(jobID)
+
+	for range maxUpdateAttempts {
+		var updated *Record
+		err := s.rc.Watch(ctx, func(tx *redis.Tx) error {
+			r, err := s.get(ctx, tx, jobID)
+			if err != nil || r == nil {
+				return err
+			}
+
+			fn(r)
+
+			b, err := json.Marshal(r)
+			if err != nil {
+				return fmt.Errorf("failed to marshal dispatch record: %w", err)
+			}
+
+			if _, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, string(b), redis.KeepTTL)
+				return nil
+			}); err != nil {
+				return fmt.Errorf("failed to write dispatch record: %w", err)
+			}
+			updated = r
+			return nil
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update dispatch record for job %s: %w", jobID, err)
+		}
+		return updated, nil
+	}
+	return nil, fmt.Errorf("failed to update dispatch record for job %s: too many concurrent updates", jobID)
+}
+
+func (s *RedisStore) getByIndex(ctx context.Context, key string) (*Record, error) {
+	jobID, err := s.rc.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read dispatch record index %s: %w", key, err)
+	}
+	return s.get(ctx, s.rc, jobID)
+}
+
+func (s *RedisStore) get(ctx context.Context, c redis.Cmdable, jobID string) (*Record, error) {
+	val, err := c.Get(ctx, s.jobKey(jobID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read dispatch record for job %s: %w", jobID, err)
+	}
+
+	var r Record
+	if err := json.Unmarshal([]byte(val), &r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dispatch record for job %s: %w", jobID, err)
+	}
+	return &r, nil
+}
+
+func (s *RedisStore) jobKey(jobID string) string {
+	return fmt.Sprintf("%s/job/%s", s.prefix, jobID)
+}
+
+func (s *RedisStore) runnerKey(runnerName string) string {
+	return fmt.Sprintf("%s/runner/%s", s.prefix, runnerName)
+}
+
+func (s *RedisStore) buildKey(buildID string) string {
+	return fmt.Sprintf("%s/build/%s", s.prefix, buildID)
+}
+
+func (s *RedisStore) recentKey() string {
+	return s.prefix + "/recent"
+}
+
+func unixMilli(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
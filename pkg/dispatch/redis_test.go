@@ -0,0 +1,252 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRedisStore(t *testing.T) {
+	t.Parallel()
+
+	const ttl = time.Hour
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	record := &Record{
+		JobID:        "789",
+		Org:          "google",
+		Repo:         "webhook",
+		Labels:       []string{"self-hosted"},
+		Runners:      []*Runner{{Name: "runner-1", BuildID: "build-1", ProjectID: "project", Location: "us-central1", Pool: "pool-1", Label: "self-hosted"}},
+		Status:       StatusDispatched,
+		DispatchedAt: now,
+	}
+	body, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warm := *record
+	warm.JobID = "790"
+	warm.Runners = []*Runner{{Name: "warm-1", Warm: true}}
+	warmBody, err := json.Marshal(&warm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	completed := *record
+	completed.Status = StatusCompleted
+	completed.Conclusion = "success"
+	completedBody, err := json.Marshal(&completed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		setup      func(m redismock.ClientMock)
+		run        func(ctx context.Context, s *RedisStore) (*Record, error)
+		list       func(ctx context.Context, s *RedisStore) ([]*Record, error)
+		expRecord  *Record
+		expRecords []*Record
+		expErr     string
+	}{
+		{
+			name: "put",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectSet("dispatcher/records/job/789", string(body), ttl).SetVal("OK")
+				m.ExpectSet("dispatcher/records/runner/runner-1", "789", ttl).SetVal("OK")
+				m.ExpectSet("dispatcher/records/build/build-1", "789", ttl).SetVal("OK")
+				m.ExpectZAdd("dispatcher/records/recent", &redis.Z{Score: float64(now.UnixMilli()), Member: "789"}).SetVal(1)
+				m.ExpectZRemRangeByScore("dispatcher/records/recent", "-inf", fmt.Sprintf("(%d", now.Add(-ttl).UnixMilli())).SetVal(0)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return nil, s.Put(ctx, record)
+			},
+		},
+		{
+			name: "put_runner_without_build",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectSet("dispatcher/records/job/790", string(warmBody), ttl).SetVal("OK")
+				m.ExpectSet("dispatcher/records/runner/warm-1", "790", ttl).SetVal("OK")
+				m.ExpectZAdd("dispatcher/records/recent", &redis.Z{Score: float64(now.UnixMilli()), Member: "790"}).SetVal(1)
+				m.ExpectZRemRangeByScore("dispatcher/records/recent", "-inf", fmt.Sprintf("(%d", now.Add(-ttl).UnixMilli())).SetVal(0)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return nil, s.Put(ctx, &warm)
+			},
+		},
+		{
+			name: "put_requires_job_id",
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return nil, s.Put(ctx, &Record{})
+			},
+			expErr: "job id is required",
+		},
+		{
+			name: "get",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectGet("dispatcher/records/job/789").SetVal(string(body))
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return s.Get(ctx, "789")
+			},
+			expRecord: record,
+		},
+		{
+			name: "get_missing",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectGet("dispatcher/records/job/789").RedisNil()
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return s.Get(ctx, "789")
+			},
+		},
+		{
+			name: "get_by_runner_name",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectGet("dispatcher/records/runner/runner-1").SetVal("789")
+				m.ExpectGet("dispatcher/records/job/789").SetVal(string(body))
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return s.GetByRunnerName(ctx, "runner-1")
+			},
+			expRecord: record,
+		},
+		{
+			name: "get_by_build_id",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectGet("dispatcher/records/build/build-1").SetVal("789")
+				m.ExpectGet("dispatcher/records/job/789").SetVal(string(body))
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return s.GetByBuildID(ctx, "build-1")
+			},
+			expRecord: record,
+		},
+		{
+			name: "get_by_build_id_missing",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectGet("dispatcher/records/build/build-1").RedisNil()
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return s.GetByBuildID(ctx, "build-1")
+			},
+		},
+		{
+			name: "get_error",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectGet("dispatcher/records/job/789").SetErr(fmt.Errorf("connection refused"))
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return s.Get(ctx, "789")
+			},
+			expErr: "connection refused",
+		},
+		{
+			name: "list",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectZRevRange("dispatcher/records/recent", 0, 9).SetVal([]string{"789", "expired"})
+				m.ExpectMGet("dispatcher/records/job/789", "dispatcher/records/job/expired").SetVal([]any{string(body), nil})
+			},
+			list: func(ctx context.Context, s *RedisStore) ([]*Record, error) {
+				return s.List(ctx, 10)
+			},
+			expRecords: []*Record{record},
+		},
+		{
+			name: "list_empty",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectZRevRange("dispatcher/records/recent", 0, 9).SetVal(nil)
+			},
+			list: func(ctx context.Context, s *RedisStore) ([]*Record, error) {
+				return s.List(ctx, 10)
+			},
+		},
+		{
+			name: "update",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectWatch("dispatcher/records/job/789")
+				m.ExpectGet("dispatcher/records/job/789").SetVal(string(body))
+				m.ExpectTxPipeline()
+				m.ExpectSet("dispatcher/records/job/789", string(completedBody), redis.KeepTTL).SetVal("OK")
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return s.Update(ctx, "789", func(r *Record) {
+					r.Status = StatusCompleted
+					r.Conclusion = "success"
+				})
+			},
+			expRecord: &completed,
+		},
+		{
+			name: "update_missing",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectWatch("dispatcher/records/job/789")
+				m.ExpectGet("dispatcher/records/job/789").RedisNil()
+			},
+			run: func(ctx context.Context, s *RedisStore) (*Record, error) {
+				return s.Update(ctx, "789", func(r *Record) {})
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock := redismock.NewClientMock()
+			if tc.setup != nil {
+				tc.setup(mock)
+			}
+
+			s := NewRedisStore(db, "dispatcher/records", ttl)
+
+			if tc.list != nil {
+				got, err := tc.list(t.Context(), s)
+				if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+					t.Error(diff)
+				}
+				if diff := cmp.Diff(tc.expRecords, got); diff != "" {
+					t.Errorf("records mismatch (-want +got):\n%s", diff)
+				}
+			} else {
+				got, err := tc.run(t.Context(), s)
+				if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+					t.Error(diff)
+				}
+				if diff := cmp.Diff(tc.expRecord, got); diff != "" {
+					t.Errorf("record mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
@@ -16,9 +16,11 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v69/github"
@@ -29,72 +31,101 @@ import (
 	"github.com/abcxyz/pkg/logging"
 )
 
+// DefaultRunnerGroupID is the ID of the Default runner group, which every
+// organization has and repository-level runners always belong to.
+const DefaultRunnerGroupID int64 = 1
+
+// ErrNotFound is returned when the requested resource does not exist.
+var ErrNotFound = errors.New("not found")
+
 // Client is an interface for mocking the GitHub client.
 type Client interface {
-	GenerateRepoJITConfig(ctx context.Context, installationID int64, org, repo, runnerName, runnerLabel string) (*github.JITRunnerConfig, error)
-	GenerateOrgJITConfig(ctx context.Context, installationID int64, org, runnerName, runnerLabel string) (*github.JITRunnerConfig, error)
+	GenerateRepoJITConfig(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error)
+	GenerateOrgJITConfig(ctx context.Context, installationID int64, org, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error)
+	GenerateEnterpriseJITConfig(ctx context.Context, installationID int64, enterprise, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error)
+	OrgInstallationID(ctx context.Context, org string) (int64, error)
+	RunnerGroupIDByName(ctx context.Context, installationID int64, org, name string) (int64, error)
+	EnterpriseRunnerGroupIDByName(ctx context.Context, installationID int64, enterprise, name string) (int64, error)
+	WorkflowRunPath(ctx context.Context, installationID int64, org, repo string, runID int64) (string, error)
+	RepoRunnerBusy(ctx context.Context, installationID int64, org, repo, runnerName string) (bool, error)
+	OrgRunnerBusy(ctx context.Context, installationID int64, org, runnerName string) (bool, error)
+	EnterpriseRunnerBusy(ctx context.Context, installationID int64, enterprise, runnerName string) (bool, error)
 }
 
 // githubClient implements the Client interface.
 type githubClient struct {
 	appClient           *githubauth.App
 	ghAPIBaseURL        string
+	ghUploadURL         string
 	backoffInitialDelay time.Duration
 	maxRetryAttempts    int
 }
 
-// NewClient creates a new GitHub client.
-func NewClient(appClient *githubauth.App, ghAPIBaseURL string, backoffInitialDelay time.Duration, maxRetryAttempts int) Client {
+// NewClient creates a new GitHub client. The upload URL defaults to the API
+// base URL when empty.
+func NewClient(appClient *githubauth.App, ghAPIBaseURL, ghUploadURL string, backoffInitialDelay time.Duration, maxRetryAttempts int) Client {
+	if ghUploadURL == "" {
+		ghUploadURL = ghAPIBaseURL
+	}
 	return &githubClient{
 		appClient:           appClient,
 		ghAPIBaseURL:        ghAPIBaseURL,
+		ghUploadURL:         ghUploadURL,
 		backoffInitialDelay: backoffInitialDelay,
 		maxRetryAttempts:    maxRetryAttempts,
 	}
 }
 
 // GenerateRepoJITConfig creates a JIT config for a repository-level runner.
-func (g *githubClient) GenerateRepoJITConfig(ctx context.Context, installationID int64, org, repo, runnerName, runnerLabel string) (*github.JITRunnerConfig, error) {
-	return g.generateJITConfig(ctx, installationID, org, &repo, runnerName, runnerLabel)
-}
-
-// GenerateOrgJITConfig creates a JIT config for an organization-level runner.
-func (g *githubClient) GenerateOrgJITConfig(ctx context.Context, installationID int64, org, runnerName, runnerLabel string) (*github.JITRunnerConfig, error) {
-	return g.generateJITConfig(ctx, installationID, org, nil, runnerName, runnerLabel)
+func (g *githubClient) GenerateRepoJITConfig(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error) {
+	permissions := map[string]string{
+		"administration": "write",
+	}
+	return g.generateJITConfig(ctx, installationID, permissions, runnerName, DefaultRunnerGroupID, runnerLabels,
+		func(ctx context.Context, gh *github.Client, req *github.GenerateJITConfigRequest) (*github.JITRunnerConfig, *github.Response, error) {
+			return gh.Actions.GenerateRepoJITConfig(ctx, org, repo, req)
+		})
 }
 
-func (g *githubClient) generateJITConfig(ctx context.Context, installationID int64, org string, repo *string, runnerName, runnerLabel string) (*github.JITRunnerConfig, error) {
-	logger := logging.FromContext(ctx)
-
-	installation, err := g.appClient.InstallationForID(ctx, fmt.Sprintf("%d", installationID))
-	if err != nil {
-		return nil, fmt.Errorf("failed to setup installation client: %w", err)
+// GenerateOrgJITConfig creates a JIT config for an organization-level runner
+// in the given runner group.
+func (g *githubClient) GenerateOrgJITConfig(ctx context.Context, installationID int64, org, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
+	permissions := map[string]string{
+		"administration": "write",
 	}
+	return g.generateJITConfig(ctx, installationID, permissions, runnerName, runnerGroupID, runnerLabels,
+		func(ctx context.Context, gh *github.Client, req *github.GenerateJITConfigRequest) (*github.JITRunnerConfig, *github.Response, error) {
+			return gh.Actions.GenerateOrgJITConfig(ctx, org, req)
+		})
+}
 
-	oauthTransport := &oauth2.Transport{
-		Base: http.DefaultTransport,
-		Source: oauth2.ReuseTokenSource(nil, (*installation).AllReposOAuth2TokenSource(ctx, map[string]string{
-			"administration": "write",
-		})),
-	}
-
-	httpClient := &http.Client{
-		Transport: oauthTransport,
+// GenerateEnterpriseJITConfig creates a JIT config for an enterprise-level
+// runner in the given runner group. The installation must be the app's
+// installation on the enterprise.
+func (g *githubClient) GenerateEnterpriseJITConfig(ctx context.Context, installationID int64, enterprise, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
+	permissions := map[string]string{
+		"enterprise_self_hosted_runners": "write",
 	}
+	return g.generateJITConfig(ctx, installationID, permissions, runnerName, runnerGroupID, runnerLabels,
+		func(ctx context.Context, gh *github.Client, req *github.GenerateJITConfigRequest) (*github.JITRunnerConfig, *github.Response, error) {
+			return gh.Enterprise.GenerateEnterpriseJITConfig(ctx, enterprise, req)
+		})
+}
 
-	gh := github.NewClient(httpClient)
+// jitConfigGenerator calls the GitHub API to generate a JIT config at a
+// particular level.
+type jitConfigGenerator func(ctx context.Context, gh *github.Client, req *github.GenerateJITConfigRequest) (*github.JITRunnerConfig, *github.Response, error)
 
-	baseURL, err := url.Parse(fmt.Sprintf("%s/", g.ghAPIBaseURL))
+func (g *githubClient) generateJITConfig(ctx context.Context, installationID int64, permissions map[string]string, runnerName string, runnerGroupID int64, runnerLabels []string, generate jitConfigGenerator) (*github.JITRunnerConfig, error) {
+	gh, err := g.newInstallationClient(ctx, installationID, permissions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to set github base URL: %w", err)
+		return nil, err
 	}
-	gh.BaseURL = baseURL
-	gh.UploadURL = baseURL
 
 	jitRequest := &github.GenerateJITConfigRequest{
 		Name:          runnerName,
-		RunnerGroupID: 1,
-		Labels:        []string{runnerLabel},
+		RunnerGroupID: runnerGroupID,
+		Labels:        runnerLabels,
 	}
 
 	var jitConfig *github.JITRunnerConfig
@@ -103,41 +134,270 @@ func (g *githubClient) generateJITConfig(ctx context.Context, installationID int
 	if err := goretry.Do(ctx, backoff, func(ctx context.Context) error {
 		var resp *github.Response
 		var err error
-		if repo != nil {
-			jitConfig, resp, err = gh.Actions.GenerateRepoJITConfig(ctx, org, *repo, jitRequest)
-		} else {
-			jitConfig, resp, err = gh.Actions.GenerateOrgJITConfig(ctx, org, jitRequest)
+		jitConfig, resp, err = generate(ctx, gh, jitRequest)
+		return classifyResponse(ctx, resp, err)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to generate jitconfig after retries: %w", err)
+	}
+
+	return jitConfig, nil
+}
+
+// OrgInstallationID returns the ID of the app's installation on an
+// organization.
+func (g *githubClient) OrgInstallationID(ctx context.Context, org string) (int64, error) {
+	appToken, err := g.appClient.AppToken()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create app token: %w", err)
+	}
+
+	gh, err := g.newGitHubClient(nil)
+	if err != nil {
+		return 0, err
+	}
+	gh = gh.WithAuthToken(appToken)
+
+	var installation *github.Installation
+	if err := goretry.Do(ctx, g.newBackoff(), func(ctx context.Context) error {
+		var resp *github.Response
+		installation, resp, err = gh.Apps.FindOrganizationInstallation(ctx, org)
+		return classifyResponse(ctx, resp, err)
+	}); err != nil {
+		return 0, fmt.Errorf("failed to find installation for org %s after retries: %w", org, err)
+	}
+
+	return installation.GetID(), nil
+}
+
+// RunnerGroupIDByName returns the ID of the organization's runner group with
+// the given name.
+func (g *githubClient) RunnerGroupIDByName(ctx context.Context, installationID int64, org, name string) (int64, error) {
+	gh, err := g.newInstallationClient(ctx, installationID, map[string]string{
+		"organization_self_hosted_runners": "read",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	opts := &github.ListOrgRunnerGroupOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		var groups *github.RunnerGroups
+		var resp *github.Response
+		if err := goretry.Do(ctx, g.newBackoff(), func(ctx context.Context) error {
+			groups, resp, err = gh.Actions.ListOrganizationRunnerGroups(ctx, org, opts)
+			return classifyResponse(ctx, resp, err)
+		}); err != nil {
+			return 0, fmt.Errorf("failed to list runner groups for org %s after retries: %w", org, err)
 		}
 
-		if err != nil {
-			logger.WarnContext(ctx, "retrying due to GitHub API call failure", "error", err)
-			// Network errors or other client errors from go-github itself are retryable.
-			return goretry.RetryableError(fmt.Errorf("GitHub API call failed: %w", err))
+		for _, group := range groups.RunnerGroups {
+			if group.GetName() == name {
+				return group.GetID(), nil
+			}
 		}
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			// Success, stop retrying.
-			return nil
+		if resp.NextPage == 0 {
+			return 0, fmt.Errorf("runner group %q not found in org %s", name, org)
 		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// EnterpriseRunnerGroupIDByName returns the ID of the enterprise's runner group
+// with the given name.
+func (g *githubClient) EnterpriseRunnerGroupIDByName(ctx context.Context, installationID int64, enterprise, name string) (int64, error) {
+	gh, err := g.newInstallationClient(ctx, installationID, map[string]string{
+		"enterprise_self_hosted_runners": "read",
+	})
+	if err != nil {
+		return 0, err
+	}
 
-		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-			logger.WarnContext(ctx, "retrying due to server error", "status_code", resp.StatusCode)
-			// Retry on 429 and 5xx errors.
-			return goretry.RetryableError(fmt.Errorf("server responded with %d status code", resp.StatusCode))
+	opts := &github.ListEnterpriseRunnerGroupOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		var groups *github.EnterpriseRunnerGroups
+		var resp *github.Response
+		if err := goretry.Do(ctx, g.newBackoff(), func(ctx context.Context) error {
+			groups, resp, err = gh.Enterprise.ListRunnerGroups(ctx, enterprise, opts)
+			return classifyResponse(ctx, resp, err)
+		}); err != nil {
+			return 0, fmt.Errorf("failed to list runner groups for enterprise %s after retries: %w", enterprise, err)
 		}
 
-		// Other 4xx errors are not retryable. Propagate immediately.
-		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-			return fmt.Errorf("server responded with non-retryable client error: %d", resp.StatusCode)
+		for _, group := range groups.RunnerGroups {
+			if group.GetName() == name {
+				return group.GetID(), nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return 0, fmt.Errorf("runner group %q not found in enterprise %s", name, enterprise)
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// RepoRunnerBusy reports whether the repository-level runner with the given
+// name is running a job. A runner that is not registered is not busy.
+func (g *githubClient) RepoRunnerBusy(ctx context.Context, installationID int64, org, repo, runnerName string) (bool, error) {
+	permissions := map[string]string{
+		"administration": "read",
+	}
+	return g.runnerBusy(ctx, installationID, permissions, runnerName,
+		func(ctx context.Context, gh *github.Client, opts *github.ListRunnersOptions) (*github.Runners, *github.Response, error) {
+			return gh.Actions.ListRunners(ctx, org, repo, opts)
+		})
+}
+
+// OrgRunnerBusy reports whether the organization-level runner with the given
+// name is running a job. A runner that is not registered is not busy.
+func (g *githubClient) OrgRunnerBusy(ctx context.Context, installationID int64, org, runnerName string) (bool, error) {
+	permissions := map[string]string{
+		"organization_self_hosted_runners": "read",
+	}
+	return g.runnerBusy(ctx, installationID, permissions, runnerName,
+		func(ctx context.Context, gh *github.Client, opts *github.ListRunnersOptions) (*github.Runners, *github.Response, error) {
+			return gh.Actions.ListOrganizationRunners(ctx, org, opts)
+		})
+}
+
+// EnterpriseRunnerBusy reports whether the enterprise-level runner with the
+// given name is running a job. A runner that is not registered is not busy.
+// The installation must be the app's installation on the enterprise.
+func (g *githubClient) EnterpriseRunnerBusy(ctx context.Context, installationID int64, enterprise, runnerName string) (bool, error) {
+	permissions := map[string]string{
+		"enterprise_self_hosted_runners": "read",
+	}
+	return g.runnerBusy(ctx, installationID, permissions, runnerName,
+		func(ctx context.Context, gh *github.Client, opts *github.ListRunnersOptions) (*github.Runners, *github.Response, error) {
+			return gh.Enterprise.ListRunners(ctx, enterprise, opts)
+		})
+}
+
+type runnerLister func(ctx context.Context, gh *github.Client, opts *github.ListRunnersOptions) (*github.Runners, *github.Response, error)
+
+func (g *githubClient) runnerBusy(ctx context.Context, installationID int64, permissions map[string]string, runnerName string, list runnerLister) (bool, error) {
+	gh, err := g.newInstallationClient(ctx, installationID, permissions)
+	if err != nil {
+		return false, err
+	}
+
+	opts := &github.ListRunnersOptions{
+		Name:        github.Ptr(runnerName),
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var runners *github.Runners
+	if err := goretry.Do(ctx, g.newBackoff(), func(ctx context.Context) error {
+		var resp *github.Response
+		runners, resp, err = list(ctx, gh, opts)
+		return classifyResponse(ctx, resp, err)
+	}); err != nil {
+		return false, fmt.Errorf("failed to list runners named %s after retries: %w", runnerName, err)
+	}
+
+	for _, runner := range runners.Runners {
+		if runner.GetName() == runnerName {
+			return runner.GetBusy(), nil
+		}
+	}
+	return false, nil
+}
+
+// WorkflowRunPath returns the path of the workflow file of a workflow run, for
+// example ".github/workflows/ci.yml".
+func (g *githubClient) WorkflowRunPath(ctx context.Context, installationID int64, org, repo string, runID int64) (string, error) {
+	gh, err := g.newInstallationClient(ctx, installationID, map[string]string{
+		"actions": "read",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var run *github.WorkflowRun
+	if err := goretry.Do(ctx, g.newBackoff(), func(ctx context.Context) error {
+		var resp *github.Response
+		run, resp, err = gh.Actions.GetWorkflowRunByID(ctx, org, repo, runID)
+		return classifyResponse(ctx, resp, err)
+	}); err != nil {
+		return "", fmt.Errorf("failed to get workflow run %d for repo %s/%s after retries: %w", runID, org, repo, err)
+	}
+
+	return run.GetPath(), nil
+}
+
+// classifyResponse returns the error to return from a retried GitHub API call.
+// Network errors and 429 and 5xx responses are retryable, while other
+// unsuccessful responses are not. A 404 response wraps ErrNotFound.
+func classifyResponse(ctx context.Context, resp *github.Response, err error) error {
+	logger := logging.FromContext(ctx)
+
+	if resp == nil {
+		if err != nil {
+			// Network errors or other client errors from go-github itself are
+			// retryable.
+			logger.WarnContext(ctx, "retrying due to GitHub API call failure", "error", err)
+			return goretry.RetryableError(fmt.Errorf("GitHub API call failed: %w", err))
 		}
+		return fmt.Errorf("GitHub API call returned no response")
+	}
 
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300 && err == nil:
+		// Success, stop retrying.
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		logger.WarnContext(ctx, "retrying due to server error", "status_code", resp.StatusCode, "error", err)
+		return goretry.RetryableError(fmt.Errorf("server responded with %d status code", resp.StatusCode))
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("server responded with %d status code: %w", resp.StatusCode, ErrNotFound)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// Other 4xx errors are not retryable. Propagate immediately.
+		return fmt.Errorf("server responded with non-retryable client error: %d", resp.StatusCode)
+	case err != nil:
+		logger.WarnContext(ctx, "retrying due to GitHub API call failure", "error", err)
+		return goretry.RetryableError(fmt.Errorf("GitHub API call failed: %w", err))
+	default:
 		// Fallback for unexpected status codes, treat as non-retryable.
 		return fmt.Errorf("server responded with unexpected status code: %d", resp.StatusCode)
-	}); err != nil {
-		return nil, fmt.Errorf("failed to generate jitconfig after retries: %w", err)
+	}
+}
+
+// newInstallationClient creates a go-github client authenticated as an app
+// installation with the given permissions.
+func (g *githubClient) newInstallationClient(ctx context.Context, installationID int64, permissions map[string]string) (*github.Client, error) {
+	installation, err := g.appClient.InstallationForID(ctx, fmt.Sprintf("%d", installationID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup installation client: %w", err)
 	}
 
-	return jitConfig, nil
+	oauthTransport := &oauth2.Transport{
+		Base:   http.DefaultTransport,
+		Source: oauth2.ReuseTokenSource(nil, (*installation).AllReposOAuth2TokenSource(ctx, permissions)),
+	}
+
+	httpClient := &http.Client{
+		Transport: oauthTransport,
+	}
+
+	return g.newGitHubClient(httpClient)
+}
+
+// newGitHubClient creates a go-github client for the configured API base and
+// upload URLs.
+func (g *githubClient) newGitHubClient(httpClient *http.Client) (*github.Client, error) {
+	gh := github.NewClient(httpClient)
+
+	baseURL, err := url.Parse(fmt.Sprintf("%s/", strings.TrimSuffix(g.ghAPIBaseURL, "/")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set github base URL: %w", err)
+	}
+	uploadURL, err := url.Parse(fmt.Sprintf("%s/", strings.TrimSuffix(g.ghUploadURL, "/")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set github upload URL: %w", err)
+	}
+	gh.BaseURL = baseURL
+	gh.UploadURL = uploadURL
+	return gh, nil
 }
 
 func (g *githubClient) newBackoff() goretry.Backoff {
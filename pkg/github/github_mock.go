@@ -24,20 +24,84 @@ var _ Client = (*MockClient)(nil)
 
 // MockClient is a mock of the GitHub client.
 type MockClient struct {
-	GenerateRepoJITConfigF     func(ctx context.Context, installationID int64, org, repo, runnerName, runnerLabel string) (*github.JITRunnerConfig, error)
-	GenerateRepoJITConfigCalls int
-	GenerateOrgJITConfigF      func(ctx context.Context, installationID int64, org, runnerName, runnerLabel string) (*github.JITRunnerConfig, error)
-	GenerateOrgJITConfigCalls  int
+	GenerateRepoJITConfigF             func(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error)
+	GenerateRepoJITConfigCalls         int
+	GenerateOrgJITConfigF              func(ctx context.Context, installationID int64, org, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error)
+	GenerateOrgJITConfigCalls          int
+	GenerateEnterpriseJITConfigF       func(ctx context.Context, installationID int64, enterprise, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error)
+	GenerateEnterpriseJITConfigCalls   int
+	OrgInstallationIDF                 func(ctx context.Context, org string) (int64, error)
+	OrgInstallationIDCalls             int
+	RunnerGroupIDByNameF               func(ctx context.Context, installationID int64, org, name string) (int64, error)
+	RunnerGroupIDByNameCalls           int
+	EnterpriseRunnerGroupIDByNameF     func(ctx context.Context, installationID int64, enterprise, name string) (int64, error)
+	EnterpriseRunnerGroupIDByNameCalls int
+	WorkflowRunPathF                   func(ctx context.Context, installationID int64, org, repo string, runID int64) (string, error)
+	WorkflowRunPathCalls               int
+	RepoRunnerBusyF                    func(ctx context.Context, installationID int64, org, repo, runnerName string) (bool, error)
+	RepoRunnerBusyCalls                int
+	OrgRunnerBusyF                     func(ctx context.Context, installationID int64, org, runnerName string) (bool, error)
+	OrgRunnerBusyCalls                 int
+	EnterpriseRunnerBusyF              func(ctx context.Context, installationID int64, enterprise, runnerName string) (bool, error)
+	EnterpriseRunnerBusyCalls          int
 }
 
 // GenerateRepoJITConfig is a mock of the GenerateRepoJITConfig method.
-func (m *MockClient) GenerateRepoJITConfig(ctx context.Context, installationID int64, org, repo, runnerName, runnerLabel string) (*github.JITRunnerConfig, error) {
+func (m *MockClient) GenerateRepoJITConfig(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error) {
 	m.GenerateRepoJITConfigCalls++
-	return m.GenerateRepoJITConfigF(ctx, installationID, org, repo, runnerName, runnerLabel)
+	return m.GenerateRepoJITConfigF(ctx, installationID, org, repo, runnerName, runnerLabels)
 }
 
 // GenerateOrgJITConfig is a mock of the GenerateOrgJITConfig method.
-func (m *MockClient) GenerateOrgJITConfig(ctx context.Context, installationID int64, org, runnerName, runnerLabel string) (*github.JITRunnerConfig, error) {
+func (m *MockClient) GenerateOrgJITConfig(ctx context.Context, installationID int64, org, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
 	m.GenerateOrgJITConfigCalls++
-	return m.GenerateOrgJITConfigF(ctx, installationID, org, runnerName, runnerLabel)
+	return m.GenerateOrgJITConfigF(ctx, installationID, org, runnerName, runnerGroupID, runnerLabels)
+}
+
+// GenerateEnterpriseJITConfig is a mock of the GenerateEnterpriseJITConfig method.
+func (m *MockClient) GenerateEnterpriseJITConfig(ctx context.Context, installationID int64, enterprise, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
+	m.GenerateEnterpriseJITConfigCalls++
+	return m.GenerateEnterpriseJITConfigF(ctx, installationID, enterprise, runnerName, runnerGroupID, runnerLabels)
+}
+
+// OrgInstallationID is a mock of the OrgInstallationID method.
+func (m *MockClient) OrgInstallationID(ctx context.Context, org string) (int64, error) {
+	m.OrgInstallationIDCalls++
+	return m.OrgInstallationIDF(ctx, org)
+}
+
+// RunnerGroupIDByName is a mock of the RunnerGroupIDByName method.
+func (m *MockClient) RunnerGroupIDByName(ctx context.Context, installationID int64, org, name string) (int64, error) {
+	m.RunnerGroupIDByNameCalls++
+	return m.RunnerGroupIDByNameF(ctx, installationID, org, name)
+}
+
+// EnterpriseRunnerGroupIDByName is a mock of the EnterpriseRunnerGroupIDByName method.
+func (m *MockClient) EnterpriseRunnerGroupIDByName(ctx context.Context, installationID int64, enterprise, name string) (int64, error) {
+	m.EnterpriseRunnerGroupIDByNameCalls++
+	return m.EnterpriseRunnerGroupIDByNameF(ctx, installationID, enterprise, name)
+}
+
+// WorkflowRunPath is a mock of the WorkflowRunPath method.
+func (m *MockClient) WorkflowRunPath(ctx context.Context, installationID int64, org, repo string, runID int64) (string, error) {
+	m.WorkflowRunPathCalls++
+	return m.WorkflowRunPathF(ctx, installationID, org, repo, runID)
+}
+
+// RepoRunnerBusy is a mock of the RepoRunnerBusy method.
+func (m *MockClient) RepoRunnerBusy(ctx context.Context, installationID int64, org, repo, runnerName string) (bool, error) {
+	m.RepoRunnerBusyCalls++
+	return m.RepoRunnerBusyF(ctx, installationID, org, repo, runnerName)
+}
+
+// OrgRunnerBusy is a mock of the OrgRunnerBusy method.
+func (m *MockClient) OrgRunnerBusy(ctx context.Context, installationID int64, org, runnerName string) (bool, error) {
+	m.OrgRunnerBusyCalls++
+	return m.OrgRunnerBusyF(ctx, installationID, org, runnerName)
+}
+
+// EnterpriseRunnerBusy is a mock of the EnterpriseRunnerBusy method.
+func (m *MockClient) EnterpriseRunnerBusy(ctx context.Context, installationID int64, enterprise, runnerName string) (bool, error) {
+	m.EnterpriseRunnerBusyCalls++
+	return m.EnterpriseRunnerBusyF(ctx, installationID, enterprise, runnerName)
 }
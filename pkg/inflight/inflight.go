@@ -0,0 +1,49 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inflight tracks the runner builds running on each worker pool so
+// that jobs can be sent to the least-loaded pool.
+package inflight
+
+import (
+	"context"
+	"time"
+)
+
+// Build is a runner build in flight on a worker pool.
+type Build struct {
+	RunnerName string    `json:"runner_name"`
+	BuildID    string    `json:"build_id"`
+	ProjectID  string    `json:"project_id"`
+	Location   string    `json:"location"`
+	Pool       string    `json:"pool"`
+	PoolType   string    `json:"pool_type,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Tracker records the builds in flight on each worker pool. Builds are tracked
+// until they are removed, or until they expire because the runner has exited
+// after its execution and idle timeouts.
+type Tracker interface {
+	// Add records a build started on its pool.
+	Add(ctx context.Context, build *Build) error
+	// Remove stops tracking the build of a runner, for example because its
+	// job completed. It is a no-op if the runner is not tracked.
+	Remove(ctx context.Context, runnerName string) error
+	// Counts returns the number of builds in flight on each of the pools that
+	// have not expired by now.
+	Counts(ctx context.Context, pools []string, now time.Time) (map[string]int, error)
+	// List returns every build in flight that has not expired by now.
+	List(ctx context.Context, now time.Time) ([]*Build, error)
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inflight
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ Tracker = (*MemoryTracker)(nil)
+
+// MemoryTracker is an in-process Tracker. It is used when no registry is
+// configured and in tests.
+type MemoryTracker struct {
+	mu     sync.Mutex
+	builds map[string]*Build
+}
+
+// NewMemoryTracker creates a new, empty MemoryTracker.
+func NewMemoryTracker() *MemoryTracker {
+	return &MemoryTracker{
+		builds: make(map[string]*Build),
+	}
+}
+
+// Add records a build started on its pool.
+func (t *MemoryTracker) Add(ctx context.Context, build *Build) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.builds[build.RunnerName] = build
+	return nil
+}
+
+// Remove stops tracking the build of a runner.
+func (t *MemoryTracker) Remove(ctx context.Context, runnerName string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.builds, runnerName)
+	return nil
+}
+
+// Counts returns the number of builds in flight on each of the pools that
+// have not expired by now.
+func (t *MemoryTracker) Counts(ctx context.Context, pools []string, now time.Time) (map[string]int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(now)
+	counts := make(map[string]int, len(pools))
+	for _, pool := range pools {
+		counts[pool] = 0
+	}
+	for _, build := range t.builds {
+		if _, ok := counts[build.Pool]; ok {
+			counts[build.Pool]++
+		}
+	}
+	return counts, nil
+}
+
+// List returns every build in flight that has not expired by now, ordered by
+// runner name.
+func (t *MemoryTracker) List(ctx context.Context, now time.Time) ([]*Build, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(now)
+	builds := make([]*Build, 0, len(t.builds))
+	for _, build := range t.builds {
+		builds = append(builds, build)
+	}
+	slices.SortFunc(builds, func(a, b *Build) int {
+		return strings.Compare(a.RunnerName, b.RunnerName)
+	})
+	return builds, nil
+}
+
+func (t *MemoryTracker) prune(now time.Time) {
+	for name, build := range t.builds {
+		if !build.ExpiresAt.After(now) {
+			delete(t.builds, name)
+		}
+	}
+}
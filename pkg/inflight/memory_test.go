@@ -0,0 +1,68 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inflight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemoryTracker(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tr := NewMemoryTracker()
+	for _, b := range []*Build{
+		{RunnerName: "expired", Pool: "pool-a", ExpiresAt: now.Add(-time.Second)},
+		{RunnerName: "runner-1", Pool: "pool-a", ExpiresAt: now.Add(time.Hour)},
+		{RunnerName: "runner-2", Pool: "pool-a", ExpiresAt: now.Add(time.Hour)},
+		{RunnerName: "runner-3", Pool: "pool-b", ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := tr.Add(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts, err := tr.Counts(ctx, []string{"pool-a", "pool-b", "pool-c"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]int{"pool-a": 2, "pool-b": 1, "pool-c": 0}, counts); diff != "" {
+		t.Errorf("counts (-want, +got):\n%s", diff)
+	}
+
+	if err := tr.Remove(ctx, "runner-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Remove(ctx, "unknown"); err != nil {
+		t.Fatal(err)
+	}
+
+	builds, err := tr.List(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Build{
+		{RunnerName: "runner-2", Pool: "pool-a", ExpiresAt: now.Add(time.Hour)},
+		{RunnerName: "runner-3", Pool: "pool-b", ExpiresAt: now.Add(time.Hour)},
+	}
+	if diff := cmp.Diff(want, builds); diff != "" {
+		t.Errorf("builds (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var _ Tracker = (*RedisTracker)(nil)
+
+// RedisTracker is a Tracker backed by Redis so that pool load is shared by
+// every instance of the webhook service. The runners of each pool are stored
+// in a sorted set scored by when they expire, and every build is stored in a
+// hash keyed by runner name so that it can be found when its job completes.
+type RedisTracker struct {
+	rc     *redis.Client
+	prefix string
+}
+
+// NewRedisTracker creates a RedisTracker that stores its state under keys
+// prefixed with prefix.
+func NewRedisTracker(rc *redis.Client, prefix string) *RedisTracker {
+	return &RedisTracker{
+		rc:     rc,
+		prefix: prefix,
+	}
+}
+
+// Add records a build started on its pool.
+func (t *RedisTracker) Add(ctx context.Context, build *Build) error {
+	b, err := json.Marshal(build)
+	if err != nil {
+		return fmt.Errorf("failed to marshal build: %w", err)
+	}
+
+	pipe := t.rc.TxPipeline()
+	pipe.ZAdd(ctx, t.poolKey(build.Pool), &redis.Z{Score: unixMilli(build.ExpiresAt), Member: build.RunnerName})
+	pipe.HSet(ctx, t.buildsKey(), build.RunnerName, string(b))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to add build for runner %s: %w", build.RunnerName, err)
+	}
+	return nil
+}
+
+// Remove stops tracking the build of a runner.
+func (t *RedisTracker) Remove(ctx context.Context, runnerName string) error {
+	val, err := t.rc.HGet(ctx, t.buildsKey(), runnerName).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return fmt.Errorf("failed to get build for runner %s: %w", runnerName, err)
+	}
+
+	var build Build
+	if err := json.Unmarshal([]byte(val), &build); err != nil {
+		return fmt.Errorf("failed to unmarshal build for runner %s: %w", runnerName, err)
+	}
+
+	pipe := t.rc.TxPipeline()
+	pipe.ZRem(ctx, t.poolKey(build.Pool), runnerName)
+	pipe.HDel(ctx, t.buildsKey(), runnerName)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove build for runner %s: %w", runnerName, err)
+	}
+	return nil
+}
+
+// Counts returns the number of builds in flight on each of the pools that
+// have not expired by now.
+func (t *RedisTracker) Counts(ctx context.Context, pools []string, now time.Time) (map[string]int, error) {
+	pipe := t.rc.TxPipeline()
+	cards := make([]*redis.IntCmd, 0, len(pools))
+	for _, pool := range pools {
+		pipe.ZRemRangeByScore(ctx, t.poolKey(pool), "-inf", formatUnixMilli(now))
+		cards = append(cards, pipe.ZCard(ctx, t.poolKey(pool)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to count builds: %w", err)
+	}
+
+	counts := make(map[string]int, len(pools))
+	for i, pool := range pools {
+		counts[pool] = int(cards[i].Val())
+	}
+	return counts, nil
+}
+
+// List returns every build in flight that has not expired by now, ordered by
+// runner name. Expired builds are removed.
+func (t *RedisTracker) List(ctx context.Context, now time.Time) ([]*Build, error) {
+	vals, err := t.rc.HGetAll(ctx, t.buildsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list builds: %w", err)
+	}
+
+	builds := make([]*Build, 0, len(vals))
+	var expired []string
+	for runnerName, val := range vals {
+		var build Build
+		if err := json.Unmarshal([]byte(val), &build); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal build for runner %s: %w", runnerName, err)
+		}
+		if !build.ExpiresAt.After(now) {
+			expired = append(expired, runnerName)
+			continue
+		}
+		builds = append(builds, &build)
+	}
+
+	if len(expired) > 0 {
+		slices.Sort(expired)
+		if err := t.rc.HDel(ctx, t.buildsKey(), expired...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to remove expired builds: %w", err)
+		}
+	}
+
+	slices.SortFunc(builds, func(a, b *Build) int {
+		return strings.Compare(a.RunnerName, b.RunnerName)
+	})
+	return builds, nil
+}
+
+func (t *RedisTracker) poolKey(pool string) string {
+	return fmt.Sprintf("%s/pools/%s", t.prefix, pool)
+}
+
+func (t *RedisTracker) buildsKey() string {
+	return t.prefix + "/builds"
+}
+
+func unixMilli(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+func formatUnixMilli(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inflight
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRedisTracker(t *testing.T) {
+	t.Parallel()
+
+	const (
+		buildsKey = "dispatcher/inflight/builds"
+		poolKey   = "dispatcher/inflight/pools/pool-a"
+		otherKey  = "dispatcher/inflight/pools/pool-b"
+	)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowMilli := fmt.Sprintf("%d", now.UnixMilli())
+	build := &Build{
+		RunnerName: "runner-1",
+		BuildID:    "build-1",
+		ProjectID:  "project-a",
+		Location:   "us-central1",
+		Pool:       "pool-a",
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	buildJSON := `{"runner_name":"runner-1","build_id":"build-1","project_id":"project-a","location":"us-central1","pool":"pool-a","expires_at":"2025-01-01T01:00:00Z"}`
+	expiredJSON := `{"runner_name":"runner-0","pool":"pool-a","expires_at":"2024-12-31T23:00:00Z"}`
+
+	cases := []struct {
+		name   string
+		setup  func(m redismock.ClientMock)
+		run    func(ctx context.Context, tr *RedisTracker) (any, error)
+		exp    any
+		expErr string
+	}{
+		{
+			name: "add",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectZAdd(poolKey, &redis.Z{Score: float64(now.Add(time.Hour).UnixMilli()), Member: "runner-1"}).SetVal(1)
+				m.ExpectHSet(buildsKey, "runner-1", buildJSON).SetVal(1)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return nil, tr.Add(ctx, build)
+			},
+		},
+		{
+			name: "remove",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectHGet(buildsKey, "runner-1").SetVal(buildJSON)
+				m.ExpectTxPipeline()
+				m.ExpectZRem(poolKey, "runner-1").SetVal(1)
+				m.ExpectHDel(buildsKey, "runner-1").SetVal(1)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return nil, tr.Remove(ctx, "runner-1")
+			},
+		},
+		{
+			name: "remove_untracked",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectHGet(buildsKey, "runner-1").RedisNil()
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return nil, tr.Remove(ctx, "runner-1")
+			},
+		},
+		{
+			name: "remove_error",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectHGet(buildsKey, "runner-1").SetErr(fmt.Errorf("connection refused"))
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return nil, tr.Remove(ctx, "runner-1")
+			},
+			expErr: "failed to get build for runner runner-1: connection refused",
+		},
+		{
+			name: "counts",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectZRemRangeByScore(poolKey, "-inf", nowMilli).SetVal(1)
+				m.ExpectZCard(poolKey).SetVal(2)
+				m.ExpectZRemRangeByScore(otherKey, "-inf", nowMilli).SetVal(0)
+				m.ExpectZCard(otherKey).SetVal(0)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return tr.Counts(ctx, []string{"pool-a", "pool-b"}, now)
+			},
+			exp: map[string]int{"pool-a": 2, "pool-b": 0},
+		},
+		{
+			name: "list",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectHGetAll(buildsKey).SetVal(map[string]string{
+					"runner-0": expiredJSON,
+					"runner-1": buildJSON,
+				})
+				m.ExpectHDel(buildsKey, "runner-0").SetVal(1)
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return tr.List(ctx, now)
+			},
+			exp: []*Build{build},
+		},
+		{
+			name: "list_error",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectHGetAll(buildsKey).SetErr(fmt.Errorf("connection refused"))
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return tr.List(ctx, now)
+			},
+			expErr: "failed to list builds: connection refused",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock := redismock.NewClientMock()
+			tc.setup(mock)
+
+			got, err := tc.run(t.Context(), NewRedisTracker(db, "dispatcher/inflight"))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+			if tc.expErr == "" {
+				if diff := cmp.Diff(tc.exp, got); diff != "" {
+					t.Errorf("result (-want, +got):\n%s", diff)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
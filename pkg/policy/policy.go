@@ -0,0 +1,193 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy decides which jobs may be dispatched using a declarative list
+// of allow and deny rules.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+)
+
+const (
+	// EffectAllow allows the jobs matched by a rule to be dispatched.
+	EffectAllow = "allow"
+	// EffectDeny stops the jobs matched by a rule from being dispatched.
+	EffectDeny = "deny"
+)
+
+// visibilities are the repository visibilities reported by GitHub.
+var visibilities = []string{"public", "private", "internal"}
+
+// Policy is an ordered list of rules. The first rule that matches a job
+// decides whether it may be dispatched. Jobs that match no rule are given the
+// default effect, which is to allow them unless set otherwise.
+type Policy struct {
+	DefaultEffect string  `json:"default_effect,omitempty"`
+	Rules         []*Rule `json:"rules"`
+}
+
+// Rule allows or denies the jobs that it matches. A job matches a rule when it
+// matches every condition that the rule sets. Repos, workflows and labels are
+// glob patterns as understood by path.Match.
+type Rule struct {
+	Name   string `json:"name"`
+	Effect string `json:"effect"`
+
+	// Orgs matches the job's organization.
+	Orgs []string `json:"orgs,omitempty"`
+	// Repos matches the job's repository name, without its organization.
+	Repos []string `json:"repos,omitempty"`
+	// Visibilities matches the job's repository visibility.
+	Visibilities []string `json:"visibilities,omitempty"`
+	// Workflows matches the path of the job's workflow file, for example
+	// ".github/workflows/release.yml".
+	Workflows []string `json:"workflows,omitempty"`
+	// Labels matches when any of the job's labels matches.
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Job is the information about a job that rules are evaluated against.
+type Job struct {
+	Org          string
+	Repo         string
+	Visibility   string
+	WorkflowPath string
+	Labels       []string
+}
+
+// Decision is the outcome of evaluating a policy for a job.
+type Decision struct {
+	Allowed bool
+	// Rule is the name of the rule that matched the job, or empty if the
+	// default effect was applied.
+	Rule   string
+	Effect string
+}
+
+// Parse parses and validates a JSON policy.
+func Parse(b []byte) (*Policy, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Policy) validate() error {
+	if p.DefaultEffect == "" {
+		p.DefaultEffect = EffectAllow
+	}
+	if !validEffect(p.DefaultEffect) {
+		return fmt.Errorf("policy default_effect must be %q or %q, got %q", EffectAllow, EffectDeny, p.DefaultEffect)
+	}
+
+	seen := make(map[string]bool, len(p.Rules))
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("policy rule %d is missing a name", i)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("duplicate policy rule %q", r.Name)
+		}
+		seen[r.Name] = true
+
+		if !validEffect(r.Effect) {
+			return fmt.Errorf("policy rule %q effect must be %q or %q, got %q", r.Name, EffectAllow, EffectDeny, r.Effect)
+		}
+		for _, v := range r.Visibilities {
+			if !slices.Contains(visibilities, v) {
+				return fmt.Errorf("policy rule %q has invalid visibility %q", r.Name, v)
+			}
+		}
+		for _, patterns := range [][]string{r.Repos, r.Workflows, r.Labels} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("policy rule %q has invalid pattern %q: %w", r.Name, pattern, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validEffect(effect string) bool {
+	return effect == EffectAllow || effect == EffectDeny
+}
+
+// UsesWorkflows reports whether any rule matches on the workflow path, which
+// is not part of a workflow_job event and must be looked up.
+func (p *Policy) UsesWorkflows() bool {
+	return slices.ContainsFunc(p.Rules, func(r *Rule) bool {
+		return len(r.Workflows) > 0
+	})
+}
+
+// Evaluate returns the decision of the first rule that matches the job, or of
+// the default effect if no rule matches.
+func (p *Policy) Evaluate(job *Job) *Decision {
+	for _, r := range p.Rules {
+		if r.matches(job) {
+			return &Decision{
+				Allowed: r.Effect == EffectAllow,
+				Rule:    r.Name,
+				Effect:  r.Effect,
+			}
+		}
+	}
+	return &Decision{
+		Allowed: p.DefaultEffect == EffectAllow,
+		Effect:  p.DefaultEffect,
+	}
+}
+
+func (r *Rule) matches(job *Job) bool {
+	if len(r.Orgs) > 0 && !slices.Contains(r.Orgs, job.Org) {
+		return false
+	}
+	if len(r.Repos) > 0 && !matchAny(r.Repos, job.Repo) {
+		return false
+	}
+	if len(r.Visibilities) > 0 && !slices.Contains(r.Visibilities, job.Visibility) {
+		return false
+	}
+	if len(r.Workflows) > 0 && !matchAny(r.Workflows, job.WorkflowPath) {
+		return false
+	}
+	if len(r.Labels) > 0 && !slices.ContainsFunc(job.Labels, func(label string) bool {
+		return matchAny(r.Labels, label)
+	}) {
+		return false
+	}
+	return true
+}
+
+// matchAny reports whether the value matches any of the patterns. Patterns
+// are validated when the policy is parsed.
+func matchAny(patterns []string, value string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, _ := path.Match(pattern, value)
+		return ok
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const testPolicy = `{
+  "rules": [
+    {
+      "name": "deny-gpu-public",
+      "effect": "deny",
+      "visibilities": ["public"],
+      "labels": ["gpu*"]
+    },
+    {
+      "name": "allow-release-workflows",
+      "effect": "allow",
+      "orgs": ["google"],
+      "workflows": [".github/workflows/release-*.yml"]
+    },
+    {
+      "name": "deny-sandbox-repos",
+      "effect": "deny",
+      "orgs": ["google"],
+      "repos": ["sandbox-*"]
+    }
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		policy string
+		expErr string
+	}{
+		{
+			name:   "valid",
+			policy: testPolicy,
+		},
+		{
+			name:   "invalid_json",
+			policy: `{"rules": [`,
+			expErr: "failed to parse policy",
+		},
+		{
+			name:   "unknown_field",
+			policy: `{"rules": [{"name": "r", "effect": "allow", "branches": ["main"]}]}`,
+			expErr: `unknown field "branches"`,
+		},
+		{
+			name:   "invalid_default_effect",
+			policy: `{"default_effect": "maybe", "rules": []}`,
+			expErr: `policy default_effect must be "allow" or "deny", got "maybe"`,
+		},
+		{
+			name:   "missing_name",
+			policy: `{"rules": [{"effect": "allow"}]}`,
+			expErr: "policy rule 0 is missing a name",
+		},
+		{
+			name:   "duplicate_name",
+			policy: `{"rules": [{"name": "r", "effect": "allow"}, {"name": "r", "effect": "deny"}]}`,
+			expErr: `duplicate policy rule "r"`,
+		},
+		{
+			name:   "invalid_effect",
+			policy: `{"rules": [{"name": "r", "effect": "block"}]}`,
+			expErr: `policy rule "r" effect must be "allow" or "deny", got "block"`,
+		},
+		{
+			name:   "invalid_visibility",
+			policy: `{"rules": [{"name": "r", "effect": "deny", "visibilities": ["secret"]}]}`,
+			expErr: `policy rule "r" has invalid visibility "secret"`,
+		},
+		{
+			name:   "invalid_pattern",
+			policy: `{"rules": [{"name": "r", "effect": "deny", "repos": ["["]}]}`,
+			expErr: `policy rule "r" has invalid pattern "["`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse([]byte(tc.policy))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		job  *Job
+		exp  *Decision
+	}{
+		{
+			name: "default_allow",
+			job:  &Job{Org: "google", Repo: "app", Visibility: "private", Labels: []string{"self-hosted"}},
+			exp:  &Decision{Allowed: true, Effect: EffectAllow},
+		},
+		{
+			name: "deny_label_glob",
+			job:  &Job{Org: "google", Repo: "app", Visibility: "public", Labels: []string{"self-hosted", "gpu-large"}},
+			exp:  &Decision{Allowed: false, Rule: "deny-gpu-public", Effect: EffectDeny},
+		},
+		{
+			name: "label_allowed_for_private_repo",
+			job:  &Job{Org: "google", Repo: "app", Visibility: "private", Labels: []string{"gpu-large"}},
+			exp:  &Decision{Allowed: true, Effect: EffectAllow},
+		},
+		{
+			name: "first_matching_rule_wins",
+			job:  &Job{Org: "google", Repo: "sandbox-1", WorkflowPath: ".github/workflows/release-prod.yml"},
+			exp:  &Decision{Allowed: true, Rule: "allow-release-workflows", Effect: EffectAllow},
+		},
+		{
+			name: "deny_repo_glob",
+			job:  &Job{Org: "google", Repo: "sandbox-1", WorkflowPath: ".github/workflows/ci.yml"},
+			exp:  &Decision{Allowed: false, Rule: "deny-sandbox-repos", Effect: EffectDeny},
+		},
+		{
+			name: "other_org",
+			job:  &Job{Org: "other", Repo: "sandbox-1"},
+			exp:  &Decision{Allowed: true, Effect: EffectAllow},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.exp, p.Evaluate(tc.job)); diff != "" {
+				t.Errorf("decision (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEvaluate_DefaultDeny(t *testing.T) {
+	t.Parallel()
+
+	p, err := Parse([]byte(`{"default_effect": "deny", "rules": [{"name": "allow-google", "effect": "allow", "orgs": ["google"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(&Decision{Allowed: false, Effect: EffectDeny}, p.Evaluate(&Job{Org: "other"})); diff != "" {
+		t.Errorf("decision (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(&Decision{Allowed: true, Rule: "allow-google", Effect: EffectAllow}, p.Evaluate(&Job{Org: "google"})); diff != "" {
+		t.Errorf("decision (-want, +got):\n%s", diff)
+	}
+	if p.UsesWorkflows() {
+		t.Errorf("expected policy not to use workflows")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ Queue = (*MemoryQueue)(nil)
+
+// MemoryQueue is an in-process Queue. Messages do not survive a restart, so
+// it is intended for tests and for running without a registry.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	entries []*memoryEntry
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	msg       Message
+	visibleAt time.Time
+}
+
+// NewMemoryQueue creates a new, empty MemoryQueue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		now: time.Now,
+	}
+}
+
+// Enqueue adds a message to the queue.
+func (q *MemoryQueue) Enqueue(ctx context.Context, msg *Message) error {
+	if msg.ID == "" {
+		return fmt.Errorf("message id is required")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.entries = append(q.entries, &memoryEntry{
+		msg:       *msg,
+		visibleAt: q.now(),
+	})
+	return nil
+}
+
+// Dequeue claims the oldest visible message.
+func (q *MemoryQueue) Dequeue(ctx context.Context, visibilityTimeout time.Duration) (*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	var next *memoryEntry
+	for _, e := range q.entries {
+		if e.visibleAt.After(now) {
+			continue
+		}
+		if next == nil || e.visibleAt.Before(next.visibleAt) {
+			next = e
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	next.visibleAt = now.Add(visibilityTimeout)
+	msg := next.msg
+	next.msg.Attempts++
+	return &msg, nil
+}
+
+// Ack removes a message from the queue.
+func (q *MemoryQueue) Ack(ctx context.Context, msg *Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, e := range q.entries {
+		if e.msg.ID == msg.ID {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Nack returns a message to the queue to become visible after delay.
+func (q *MemoryQueue) Nack(ctx context.Context, msg *Message, delay time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, e := range q.entries {
+		if e.msg.ID == msg.ID {
+			e.msg = *msg
+			e.visibleAt = q.now().Add(delay)
+			return nil
+		}
+	}
+	return fmt.Errorf("message %s not found", msg.ID)
+}
+
+// Len returns the number of messages in the queue, including those that are
+// not currently visible.
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.entries)
+}
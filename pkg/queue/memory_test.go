@@ -0,0 +1,116 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemoryQueue(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := NewMemoryQueue()
+	q.now = func() time.Time { return now }
+
+	first := &Message{ID: "first", Payload: json.RawMessage(`{"n":1}`)}
+	second := &Message{ID: "second", Payload: json.RawMessage(`{"n":2}`)}
+	if err := q.Enqueue(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Second)
+	if err := q.Enqueue(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+
+	// The oldest message is claimed first.
+	got, err := q.Dequeue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(first, got); diff != "" {
+		t.Errorf("first dequeue mismatch (-want +got):\n%s", diff)
+	}
+
+	// A claimed message is hidden until its visibility timeout expires.
+	got, err = q.Dequeue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(second, got); diff != "" {
+		t.Errorf("second dequeue mismatch (-want +got):\n%s", diff)
+	}
+	got, err = q.Dequeue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("expected no visible messages, got %v", got)
+	}
+
+	// An unacknowledged message is redelivered after the visibility timeout,
+	// with the expired claim counted as a failed attempt.
+	now = now.Add(2 * time.Minute)
+	got, err = q.Dequeue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redelivered := *first
+	redelivered.Attempts = 1
+	if diff := cmp.Diff(&redelivered, got); diff != "" {
+		t.Errorf("redelivery mismatch (-want +got):\n%s", diff)
+	}
+	if err := q.Ack(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+
+	// A nacked message keeps its updates and is hidden for the delay. Its
+	// attempts replace the claims counted on dequeue.
+	got, err = q.Dequeue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.Attempts, 1; got != want {
+		t.Errorf("expected attempts %d to be %d", got, want)
+	}
+	got.Attempts = 3
+	if err := q.Nack(ctx, got, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := q.Dequeue(ctx, time.Minute); err != nil || got != nil {
+		t.Errorf("expected no visible messages, got %v, %v", got, err)
+	}
+	now = now.Add(10 * time.Second)
+	got, err = q.Dequeue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.Attempts, 3; got != want {
+		t.Errorf("expected attempts %d to be %d", got, want)
+	}
+	if err := q.Ack(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := q.Len(), 0; got != want {
+		t.Errorf("expected queue length %d to be %d", got, want)
+	}
+}
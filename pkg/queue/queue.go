@@ -0,0 +1,59 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package queue provides a durable work queue used to dispatch runners outside
+// of the webhook request.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Message is a unit of work stored in a Queue.
+type Message struct {
+	// ID uniquely identifies the message within the queue.
+	ID string `json:"id"`
+	// DeliveryID is the GitHub delivery that produced the message.
+	DeliveryID string `json:"delivery_id,omitempty"`
+	// Payload is the JSON encoded work item.
+	Payload json.RawMessage `json:"payload"`
+	// Attempts is the number of times processing the message has failed. A
+	// delivery that is not acknowledged or negatively acknowledged before its
+	// visibility timeout counts as a failure.
+	Attempts int `json:"attempts"`
+	// EnqueuedAt is when the message was first added to the queue.
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// Queue is a work queue with at-least-once delivery. A dequeued message is
+// hidden from other consumers for the visibility timeout. If it is neither
+// acknowledged nor negatively acknowledged before then, it becomes visible
+// again so that work held by a consumer that exited is not lost.
+type Queue interface {
+	// Enqueue adds a message to the queue, making it immediately visible.
+	Enqueue(ctx context.Context, msg *Message) error
+	// Dequeue claims the next visible message. It returns nil if there are
+	// no visible messages. The claim is counted in the message's Attempts for
+	// later deliveries unless the message is negatively acknowledged, so a
+	// message whose consumer keeps exiting while processing it is not
+	// redelivered forever.
+	Dequeue(ctx context.Context, visibilityTimeout time.Duration) (*Message, error)
+	// Ack removes a message from the queue once it has been processed.
+	Ack(ctx context.Context, msg *Message) error
+	// Nack returns a message to the queue, making it visible again after
+	// delay. Any changes to the message, such as Attempts, are persisted.
+	Nack(ctx context.Context, msg *Message, delay time.Duration) error
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var _ Queue = (*RedisQueue)(nil)
+
+// dequeueScript atomically claims the oldest visible message by pushing its
+// visibility score out by the visibility timeout and counting the claim, and
+// returns its body and the number of times it has been claimed. Messages whose
+// body is missing are removed.
+//
+// KEYS[1] - sorted set of message IDs scored by when they become visible.
+// KEYS[2] - hash of message ID to message body.
+// KEYS[3] - hash of message ID to the number of failed attempts.
+// ARGV[1] - the current time in unix milliseconds.
+// ARGV[2] - the time the claimed message becomes visible again.
+var dequeueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+local body = redis.call('HGET', KEYS[2], ids[1])
+if not body then
+	redis.call('ZREM', KEYS[1], ids[1])
+	redis.call('HDEL', KEYS[3], ids[1])
+	return false
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ids[1])
+local claims = redis.call('HINCRBY', KEYS[3], ids[1], 1)
+return {body, claims}
+`)
+
+// RedisQueue is a Queue backed by Redis. Message bodies are stored in a hash
+// and their visibility in a sorted set, so messages survive restarts of the
+// process and are redelivered if a consumer exits without acknowledging them.
+// Attempts are counted in a separate hash so that claims can be counted
+// without rewriting the message body.
+type RedisQueue struct {
+	rc          *redis.Client
+	pendingKey  string
+	messagesKey string
+	attemptsKey string
+	now         func() time.Time
+}
+
+// NewRedisQueue creates a RedisQueue that stores its state under keys prefixed
+// with name.
+func NewRedisQueue(rc *redis.Client, name string) *RedisQueue {
+	return &RedisQueue{
+		rc:          rc,
+		pendingKey:  name + "/pending",
+		messagesKey: name + "/messages",
+		attemptsKey: name + "/attempts",
+		now:         time.Now,
+	}
+}
+
+// Enqueue adds a message to the queue.
+func (q *RedisQueue) Enqueue(ctx context.Context, msg *Message) error {
+	if msg.ID == "" {
+		return fmt.Errorf("message id is required")
+	}
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	pipe := q.rc.TxPipeline()
+	pipe.HSet(ctx, q.messagesKey, msg.ID, string(b))
+	pipe.HSet(ctx, q.attemptsKey, msg.ID, msg.Attempts)
+	pipe.ZAdd(ctx, q.pendingKey, &redis.Z{Score: unixMilli(q.now()), Member: msg.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to enqueue message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Dequeue claims the oldest visible message. Its Attempts are the claims made
+// before this one that were not given back by Nack.
+func (q *RedisQueue) Dequeue(ctx context.Context, visibilityTimeout time.Duration) (*Message, error) {
+	now := q.now()
+	vals, err := dequeueScript.Run(ctx, q.rc,
+		[]string{q.pendingKey, q.messagesKey, q.attemptsKey},
+		strconv.FormatInt(now.UnixMilli(), 10),
+		strconv.FormatInt(now.Add(visibilityTimeout).UnixMilli(), 10),
+	).Slice()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to dequeue message: %w", err)
+	}
+	if len(vals) != 2 {
+		return nil, fmt.Errorf("failed to dequeue message: unexpected result %v", vals)
+	}
+	body, ok := vals[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to dequeue message: unexpected body %v", vals[0])
+	}
+	claims, ok := vals[1].(int64)
+	if !ok {
+		return nil, fmt.Errorf("failed to dequeue message: unexpected claim count %v", vals[1])
+	}
+
+	var msg Message
+	if err := json.Unmarshal([]byte(body), &msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+	msg.Attempts = int(claims) - 1
+	return &msg, nil
+}
+
+// Ack removes a message from the queue.
+func (q *RedisQueue) Ack(ctx context.Context, msg *Message) error {
+	pipe := q.rc.TxPipeline()
+	pipe.ZRem(ctx, q.pendingKey, msg.ID)
+	pipe.HDel(ctx, q.messagesKey, msg.ID)
+	pipe.HDel(ctx, q.attemptsKey, msg.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Nack stores the updated message and makes it visible again after delay. The
+// message's Attempts replace the claims counted by Dequeue.
+func (q *RedisQueue) Nack(ctx context.Context, msg *Message, delay time.Duration) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	pipe := q.rc.TxPipeline()
+	pipe.HSet(ctx, q.messagesKey, msg.ID, string(b))
+	pipe.HSet(ctx, q.attemptsKey, msg.ID, msg.Attempts)
+	pipe.ZAdd(ctx, q.pendingKey, &redis.Z{Score: unixMilli(q.now().Add(delay)), Member: msg.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to nack message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+func unixMilli(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
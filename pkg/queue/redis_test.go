@@ -0,0 +1,167 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRedisQueue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg := &Message{
+		ID:         "msg-1",
+		DeliveryID: "delivery-1",
+		Payload:    json.RawMessage(`{"n":1}`),
+		EnqueuedAt: now,
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retried := *msg
+	retried.Attempts = 1
+	retriedBody, err := json.Marshal(&retried)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		setup  func(m redismock.ClientMock)
+		run    func(ctx context.Context, q *RedisQueue) (*Message, error)
+		expMsg *Message
+		expErr string
+	}{
+		{
+			name: "enqueue",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectHSet("dispatcher/queue/messages", "msg-1", string(body)).SetVal(1)
+				m.ExpectHSet("dispatcher/queue/attempts", "msg-1", 0).SetVal(1)
+				m.ExpectZAdd("dispatcher/queue/pending", &redis.Z{Score: float64(now.UnixMilli()), Member: "msg-1"}).SetVal(1)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, q *RedisQueue) (*Message, error) {
+				return nil, q.Enqueue(ctx, msg)
+			},
+		},
+		{
+			name: "dequeue",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(dequeueScript.Hash(), []string{"dispatcher/queue/pending", "dispatcher/queue/messages", "dispatcher/queue/attempts"},
+					fmt.Sprintf("%d", now.UnixMilli()), fmt.Sprintf("%d", now.Add(time.Minute).UnixMilli())).SetVal([]interface{}{string(body), int64(1)})
+			},
+			run: func(ctx context.Context, q *RedisQueue) (*Message, error) {
+				return q.Dequeue(ctx, time.Minute)
+			},
+			expMsg: msg,
+		},
+		{
+			// A message redelivered after its visibility timeout expired has
+			// the expired claim counted as a failed attempt.
+			name: "dequeue_redelivered",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(dequeueScript.Hash(), []string{"dispatcher/queue/pending", "dispatcher/queue/messages", "dispatcher/queue/attempts"},
+					fmt.Sprintf("%d", now.UnixMilli()), fmt.Sprintf("%d", now.Add(time.Minute).UnixMilli())).SetVal([]interface{}{string(body), int64(2)})
+			},
+			run: func(ctx context.Context, q *RedisQueue) (*Message, error) {
+				return q.Dequeue(ctx, time.Minute)
+			},
+			expMsg: &retried,
+		},
+		{
+			name: "dequeue_empty",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(dequeueScript.Hash(), []string{"dispatcher/queue/pending", "dispatcher/queue/messages", "dispatcher/queue/attempts"},
+					fmt.Sprintf("%d", now.UnixMilli()), fmt.Sprintf("%d", now.Add(time.Minute).UnixMilli())).RedisNil()
+			},
+			run: func(ctx context.Context, q *RedisQueue) (*Message, error) {
+				return q.Dequeue(ctx, time.Minute)
+			},
+		},
+		{
+			name: "dequeue_error",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(dequeueScript.Hash(), []string{"dispatcher/queue/pending", "dispatcher/queue/messages", "dispatcher/queue/attempts"},
+					fmt.Sprintf("%d", now.UnixMilli()), fmt.Sprintf("%d", now.Add(time.Minute).UnixMilli())).SetErr(fmt.Errorf("connection refused"))
+			},
+			run: func(ctx context.Context, q *RedisQueue) (*Message, error) {
+				return q.Dequeue(ctx, time.Minute)
+			},
+			expErr: "failed to dequeue message: connection refused",
+		},
+		{
+			name: "ack",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectZRem("dispatcher/queue/pending", "msg-1").SetVal(1)
+				m.ExpectHDel("dispatcher/queue/messages", "msg-1").SetVal(1)
+				m.ExpectHDel("dispatcher/queue/attempts", "msg-1").SetVal(1)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, q *RedisQueue) (*Message, error) {
+				return nil, q.Ack(ctx, msg)
+			},
+		},
+		{
+			name: "nack",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectHSet("dispatcher/queue/messages", "msg-1", string(retriedBody)).SetVal(0)
+				m.ExpectHSet("dispatcher/queue/attempts", "msg-1", 1).SetVal(0)
+				m.ExpectZAdd("dispatcher/queue/pending", &redis.Z{Score: float64(now.Add(30 * time.Second).UnixMilli()), Member: "msg-1"}).SetVal(0)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, q *RedisQueue) (*Message, error) {
+				return nil, q.Nack(ctx, &retried, 30*time.Second)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock := redismock.NewClientMock()
+			tc.setup(mock)
+
+			q := NewRedisQueue(db, "dispatcher/queue")
+			q.now = func() time.Time { return now }
+
+			got, err := tc.run(context.Background(), q)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.expMsg, got); diff != "" {
+				t.Errorf("message mismatch (-want +got):\n%s", diff)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
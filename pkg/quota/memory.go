@@ -0,0 +1,125 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Limiter = (*MemoryLimiter)(nil)
+
+// MemoryLimiter is an in-memory Limiter. Slots are not shared between
+// instances of the webhook service.
+type MemoryLimiter struct {
+	mu sync.Mutex
+	// slots maps each key to the slots held by each holder.
+	slots map[string]map[string]*heldSlots
+}
+
+// heldSlots is the number of slots a holder has under a key and when they
+// expire.
+type heldSlots struct {
+	count     int
+	expiresAt time.Time
+}
+
+// NewMemoryLimiter creates an empty MemoryLimiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		slots: make(map[string]map[string]*heldSlots),
+	}
+}
+
+// Acquire takes the given number of slots for the holder under every one of
+// the limits, but only if all of them have room.
+func (l *MemoryLimiter) Acquire(ctx context.Context, holder string, slots int, limits []*Limit, expiresAt, now time.Time) (bool, []*Usage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	acquired := true
+	for _, limit := range limits {
+		holders := l.unexpired(limit.Key, now)
+		if _, ok := holders[holder]; !ok && used(holders)+min(slots, limit.Max) > limit.Max {
+			acquired = false
+		}
+	}
+
+	if acquired {
+		for _, limit := range limits {
+			holders, ok := l.slots[limit.Key]
+			if !ok {
+				holders = make(map[string]*heldSlots)
+				l.slots[limit.Key] = holders
+			}
+			holders[holder] = &heldSlots{count: min(slots, limit.Max), expiresAt: expiresAt}
+		}
+	}
+	return acquired, l.usage(limits, now), nil
+}
+
+// Release gives up the holder's slots under the keys.
+func (l *MemoryLimiter) Release(ctx context.Context, holder string, keys []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		delete(l.slots[key], holder)
+	}
+	return nil
+}
+
+// Usage returns the number of slots held under each of the limits that have
+// not expired by now.
+func (l *MemoryLimiter) Usage(ctx context.Context, limits []*Limit, now time.Time) ([]*Usage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.usage(limits, now), nil
+}
+
+func (l *MemoryLimiter) usage(limits []*Limit, now time.Time) []*Usage {
+	usage := make([]*Usage, 0, len(limits))
+	for _, limit := range limits {
+		usage = append(usage, &Usage{
+			Key:   limit.Key,
+			Limit: limit.Max,
+			Used:  used(l.unexpired(limit.Key, now)),
+		})
+	}
+	return usage
+}
+
+// unexpired removes the expired slots under a key and returns the rest. The
+// caller must hold the lock.
+func (l *MemoryLimiter) unexpired(key string, now time.Time) map[string]*heldSlots {
+	holders := l.slots[key]
+	for holder, held := range holders {
+		if !held.expiresAt.After(now) {
+			delete(holders, holder)
+		}
+	}
+	return holders
+}
+
+// used returns the number of slots held by the holders.
+func used(holders map[string]*heldSlots) int {
+	var n int
+	for _, held := range holders {
+		n += held.count
+	}
+	return n
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	limits := []*Limit{
+		{Key: "google", Max: 3},
+		{Key: "google/repo", Max: 1},
+	}
+
+	l := NewMemoryLimiter()
+
+	acquired, usage, err := l.Acquire(ctx, "job-1", 1, limits, expiresAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Errorf("expected job-1 to acquire quota")
+	}
+	if diff := cmp.Diff([]*Usage{{Key: "google", Limit: 3, Used: 1}, {Key: "google/repo", Limit: 1, Used: 1}}, usage); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+
+	// Acquiring again is idempotent.
+	acquired, _, err = l.Acquire(ctx, "job-1", 1, limits, expiresAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Errorf("expected job-1 to keep its quota")
+	}
+
+	// The repo limit is full, so the org slot is not taken either.
+	acquired, usage, err = l.Acquire(ctx, "job-2", 1, limits, expiresAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acquired {
+		t.Errorf("expected job-2 to be over quota")
+	}
+	if diff := cmp.Diff([]*Usage{{Key: "google", Limit: 3, Used: 1}, {Key: "google/repo", Limit: 1, Used: 1}}, usage); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+
+	// Another repo only shares the org limit.
+	acquired, _, err = l.Acquire(ctx, "job-3", 1, []*Limit{{Key: "google", Max: 3}, {Key: "google/other", Max: 1}}, expiresAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Errorf("expected job-3 to acquire quota")
+	}
+
+	if err := l.Release(ctx, "job-1", []string{"google", "google/repo"}); err != nil {
+		t.Fatal(err)
+	}
+	acquired, _, err = l.Acquire(ctx, "job-2", 1, limits, expiresAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Errorf("expected job-2 to acquire released quota")
+	}
+
+	// A job with extra runners takes a slot for each of them, and a job that
+	// needs more slots than a limit allows takes all of them.
+	runnerLimits := []*Limit{{Key: "runners", Max: 3}}
+	acquired, usage, err = l.Acquire(ctx, "job-4", 2, runnerLimits, expiresAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Errorf("expected job-4 to acquire quota")
+	}
+	if diff := cmp.Diff([]*Usage{{Key: "runners", Limit: 3, Used: 2}}, usage); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+	acquired, _, err = l.Acquire(ctx, "job-5", 2, runnerLimits, expiresAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acquired {
+		t.Errorf("expected job-5 to be over quota")
+	}
+	if err := l.Release(ctx, "job-4", []string{"runners"}); err != nil {
+		t.Fatal(err)
+	}
+	acquired, usage, err = l.Acquire(ctx, "job-6", 5, runnerLimits, expiresAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Errorf("expected job-6 to acquire the whole quota")
+	}
+	if diff := cmp.Diff([]*Usage{{Key: "runners", Limit: 3, Used: 3}}, usage); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+
+	// Slots expire.
+	usage, err = l.Usage(ctx, limits, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*Usage{{Key: "google", Limit: 3, Used: 0}, {Key: "google/repo", Limit: 1, Used: 0}}, usage); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+}
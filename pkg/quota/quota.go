@@ -0,0 +1,54 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota limits the number of runners that are started at once for the
+// jobs of an organization or repository.
+package quota
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is the maximum number of slots that can be held at once under a key.
+type Limit struct {
+	Key string `json:"key"`
+	Max int    `json:"max"`
+}
+
+// Usage is the number of slots held under a key.
+type Usage struct {
+	Key   string `json:"key"`
+	Limit int    `json:"limit"`
+	Used  int    `json:"used"`
+}
+
+// Limiter hands out slots under concurrency limits. Slots are held until they
+// are released, or until they expire because the job they were acquired for
+// can no longer be running.
+type Limiter interface {
+	// Acquire takes the given number of slots for the holder under every one
+	// of the limits, but only if all of them have room. A holder that needs
+	// more slots than a limit allows takes all of them, so that it can run
+	// once nothing else holds the limit. It reports whether the slots were
+	// acquired and the usage of each limit afterwards. Acquiring slots that
+	// the holder already has succeeds without taking more.
+	Acquire(ctx context.Context, holder string, slots int, limits []*Limit, expiresAt, now time.Time) (bool, []*Usage, error)
+	// Release gives up the holder's slots under the keys. It is a no-op for
+	// keys the holder has no slot under.
+	Release(ctx context.Context, holder string, keys []string) error
+	// Usage returns the number of slots held under each of the limits that
+	// have not expired by now.
+	Usage(ctx context.Context, limits []*Limit, now time.Time) ([]*Usage, error)
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var _ Limiter = (*RedisLimiter)(nil)
+
+// acquireScript takes slots for a holder under every key, but only if none of
+// them would be over its limit, so that concurrent dispatches cannot exceed a
+// limit. Each key is a sorted set of slots scored by when they expire. The
+// slots of a holder are the members "<holder>#1" to "<holder>#<n>".
+//
+// KEYS are the sorted sets of the limits. ARGV are the holder, the number of
+// slots, the expiry and the current time in Unix milliseconds, followed by the
+// maximum of each limit. It returns 1 if the slots were acquired or 0 if not,
+// followed by the number of slots held under each key afterwards.
+var acquireScript = redis.NewScript(`
+local holder = ARGV[1]
+local used = {}
+local needed = {}
+local acquired = 1
+for i, key in ipairs(KEYS) do
+  local max = tonumber(ARGV[4 + i])
+  redis.call('ZREMRANGEBYSCORE', key, '-inf', ARGV[4])
+  used[i] = redis.call('ZCARD', key)
+  needed[i] = math.min(tonumber(ARGV[2]), max)
+  if not redis.call('ZSCORE', key, holder .. '#1') and used[i] + needed[i] > max then
+    acquired = 0
+  end
+end
+if acquired == 1 then
+  for i, key in ipairs(KEYS) do
+    for n = 1, needed[i] do
+      used[i] = used[i] + redis.call('ZADD', key, ARGV[3], holder .. '#' .. n)
+    end
+  end
+end
+table.insert(used, 1, acquired)
+return used
+`)
+
+// releaseScript removes the slots of a holder from every key.
+//
+// KEYS are the sorted sets of the limits. ARGV is the holder.
+var releaseScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+  local n = 1
+  while redis.call('ZREM', key, ARGV[1] .. '#' .. n) == 1 do
+    n = n + 1
+  end
+end
+return 0
+`)
+
+// RedisLimiter is a Limiter backed by Redis so that limits are enforced
+// across every instance of the webhook service.
+type RedisLimiter struct {
+	rc     *redis.Client
+	prefix string
+}
+
+// NewRedisLimiter creates a RedisLimiter that stores its state under keys
+// prefixed with prefix.
+func NewRedisLimiter(rc *redis.Client, prefix string) *RedisLimiter {
+	return &RedisLimiter{
+		rc:     rc,
+		prefix: prefix,
+	}
+}
+
+// Acquire takes the given number of slots for the holder under every one of
+// the limits, but only if all of them have room. The check and the update are
+// made atomically.
+func (l *RedisLimiter) Acquire(ctx context.Context, holder string, slots int, limits []*Limit, expiresAt, now time.Time) (bool, []*Usage, error) {
+	keys := make([]string, 0, len(limits))
+	args := make([]any, 0, len(limits)+4)
+	args = append(args, holder, slots, formatUnixMilli(expiresAt), formatUnixMilli(now))
+	for _, limit := range limits {
+		keys = append(keys, l.key(limit.Key))
+		args = append(args, limit.Max)
+	}
+
+	vals, err := acquireScript.Run(ctx, l.rc, keys, args...).Int64Slice()
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to acquire quota for %s: %w", holder, err)
+	}
+	if len(vals) != len(limits)+1 {
+		return false, nil, fmt.Errorf("failed to acquire quota for %s: unexpected result %v", holder, vals)
+	}
+
+	usage := make([]*Usage, 0, len(limits))
+	for i, limit := range limits {
+		usage = append(usage, &Usage{
+			Key:   limit.Key,
+			Limit: limit.Max,
+			Used:  int(vals[i+1]),
+		})
+	}
+	return vals[0] == 1, usage, nil
+}
+
+// Release gives up the holder's slots under the keys.
+func (l *RedisLimiter) Release(ctx context.Context, holder string, keys []string) error {
+	redisKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		redisKeys = append(redisKeys, l.key(key))
+	}
+	if err := releaseScript.Run(ctx, l.rc, redisKeys, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release quota for %s: %w", holder, err)
+	}
+	return nil
+}
+
+// Usage returns the number of slots held under each of the limits that have
+// not expired by now.
+func (l *RedisLimiter) Usage(ctx context.Context, limits []*Limit, now time.Time) ([]*Usage, error) {
+	pipe := l.rc.TxPipeline()
+	cards := make([]*redis.IntCmd, 0, len(limits))
+	for _, limit := range limits {
+		pipe.ZRemRangeByScore(ctx, l.key(limit.Key), "-inf", formatUnixMilli(now))
+		cards = append(cards, pipe.ZCard(ctx, l.key(limit.Key)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get quota usage: %w", err)
+	}
+
+	usage := make([]*Usage, 0, len(limits))
+	for i, limit := range limits {
+		usage = append(usage, &Usage{
+			Key:   limit.Key,
+			Limit: limit.Max,
+			Used:  int(cards[i].Val()),
+		})
+	}
+	return usage, nil
+}
+
+func (l *RedisLimiter) key(key string) string {
+	return fmt.Sprintf("%s/%s", l.prefix, key)
+}
+
+func formatUnixMilli(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRedisLimiter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		orgKey  = "dispatcher/quotas/google"
+		repoKey = "dispatcher/quotas/google/repo"
+	)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowMilli := fmt.Sprintf("%d", now.UnixMilli())
+	expiresAt := now.Add(time.Hour)
+	expiresMilli := fmt.Sprintf("%d", expiresAt.UnixMilli())
+	limits := []*Limit{
+		{Key: "google", Max: 3},
+		{Key: "google/repo", Max: 1},
+	}
+	type acquireResult struct {
+		Acquired bool
+		Usage    []*Usage
+	}
+
+	cases := []struct {
+		name   string
+		setup  func(m redismock.ClientMock)
+		run    func(ctx context.Context, l *RedisLimiter) (any, error)
+		exp    any
+		expErr string
+	}{
+		{
+			name: "acquire",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(acquireScript.Hash(), []string{orgKey, repoKey}, "job-1", 1, expiresMilli, nowMilli, 3, 1).
+					SetVal([]any{int64(1), int64(2), int64(1)})
+			},
+			run: func(ctx context.Context, l *RedisLimiter) (any, error) {
+				acquired, usage, err := l.Acquire(ctx, "job-1", 1, limits, expiresAt, now)
+				return &acquireResult{acquired, usage}, err
+			},
+			exp: &acquireResult{
+				Acquired: true,
+				Usage:    []*Usage{{Key: "google", Limit: 3, Used: 2}, {Key: "google/repo", Limit: 1, Used: 1}},
+			},
+		},
+		{
+			name: "acquire_over_quota",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(acquireScript.Hash(), []string{orgKey, repoKey}, "job-2", 2, expiresMilli, nowMilli, 3, 1).
+					SetVal([]any{int64(0), int64(2), int64(1)})
+			},
+			run: func(ctx context.Context, l *RedisLimiter) (any, error) {
+				acquired, usage, err := l.Acquire(ctx, "job-2", 2, limits, expiresAt, now)
+				return &acquireResult{acquired, usage}, err
+			},
+			exp: &acquireResult{
+				Acquired: false,
+				Usage:    []*Usage{{Key: "google", Limit: 3, Used: 2}, {Key: "google/repo", Limit: 1, Used: 1}},
+			},
+		},
+		{
+			name: "acquire_error",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(acquireScript.Hash(), []string{orgKey, repoKey}, "job-1", 1, expiresMilli, nowMilli, 3, 1).
+					SetErr(fmt.Errorf("connection refused"))
+			},
+			run: func(ctx context.Context, l *RedisLimiter) (any, error) {
+				_, _, err := l.Acquire(ctx, "job-1", 1, limits, expiresAt, now)
+				return nil, err
+			},
+			expErr: "failed to acquire quota for job-1: connection refused",
+		},
+		{
+			name: "release",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(releaseScript.Hash(), []string{orgKey, repoKey}, "job-1").SetVal(int64(0))
+			},
+			run: func(ctx context.Context, l *RedisLimiter) (any, error) {
+				return nil, l.Release(ctx, "job-1", []string{"google", "google/repo"})
+			},
+		},
+		{
+			name: "release_error",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectEvalSha(releaseScript.Hash(), []string{orgKey}, "job-1").SetErr(fmt.Errorf("connection refused"))
+			},
+			run: func(ctx context.Context, l *RedisLimiter) (any, error) {
+				return nil, l.Release(ctx, "job-1", []string{"google"})
+			},
+			expErr: "failed to release quota for job-1: connection refused",
+		},
+		{
+			name: "usage",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectZRemRangeByScore(orgKey, "-inf", nowMilli).SetVal(1)
+				m.ExpectZCard(orgKey).SetVal(2)
+				m.ExpectZRemRangeByScore(repoKey, "-inf", nowMilli).SetVal(0)
+				m.ExpectZCard(repoKey).SetVal(1)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, l *RedisLimiter) (any, error) {
+				return l.Usage(ctx, limits, now)
+			},
+			exp: []*Usage{{Key: "google", Limit: 3, Used: 2}, {Key: "google/repo", Limit: 1, Used: 1}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock := redismock.NewClientMock()
+			tc.setup(mock)
+
+			got, err := tc.run(t.Context(), NewRedisLimiter(db, "dispatcher/quotas"))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("result (-want, +got):\n%s", diff)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
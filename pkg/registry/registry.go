@@ -29,8 +29,34 @@ type WorkerPoolInfo struct {
 	Location      string `json:"location"`
 	RemoteConfig  string `json:"remote_config,omitempty"`
 	PoolType      string `json:"pool_type,omitempty"`
+
+	// Labels is the set of runner labels the pool is able to serve. A job is
+	// only dispatched to the pool when every label it requests is present.
+	Labels []string `json:"labels,omitempty"`
+
+	// Weight is the relative share of jobs the pool is sent among pools of
+	// the same priority. A zero weight is treated as DefaultWorkerPoolWeight.
+	Weight int `json:"weight,omitempty"`
+
+	// Priority orders the pools under a registry key. Jobs are only sent to
+	// the pools with the highest priority.
+	Priority int `json:"priority,omitempty"`
+
+	// MaxConcurrency is the number of builds the pool can run at once. Jobs
+	// are not sent to a pool that is at its maximum while another pool has
+	// room. A zero value means the pool is unlimited.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
 }
 
+const (
+	// DefaultWorkerPoolWeight is the weight of a pool without one.
+	DefaultWorkerPoolWeight = 1
+
+	// PoolTypeTrusted is the PoolType of pools whose builds are created with
+	// their RemoteConfig through the Cloud Build consumer API.
+	PoolTypeTrusted = "trusted"
+)
+
 // NewRunnerRegistry creates and returns a new registry client.
 // It uses the host and port from the provided config.
 func NewRunnerRegistry(ctx context.Context, cfg *RegistryConfig) (*redis.Client, error) {
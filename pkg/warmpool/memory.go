@@ -0,0 +1,93 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package warmpool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Tracker = (*MemoryTracker)(nil)
+
+// MemoryTracker is an in-process Tracker. It is used when no registry is
+// configured and in tests.
+type MemoryTracker struct {
+	mu      sync.Mutex
+	runners map[Key]map[string]time.Time
+}
+
+// NewMemoryTracker creates a new, empty MemoryTracker.
+func NewMemoryTracker() *MemoryTracker {
+	return &MemoryTracker{
+		runners: make(map[Key]map[string]time.Time),
+	}
+}
+
+// Add records an idle runner that exits at expiresAt.
+func (t *MemoryTracker) Add(ctx context.Context, key Key, runnerName string, expiresAt time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.runners[key] == nil {
+		t.runners[key] = make(map[string]time.Time)
+	}
+	t.runners[key][runnerName] = expiresAt
+	return nil
+}
+
+// Count returns the number of idle runners that have not expired by now.
+func (t *MemoryTracker) Count(ctx context.Context, key Key, now time.Time) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(key, now)
+	return len(t.runners[key]), nil
+}
+
+// Take removes and returns the idle runner that expires soonest.
+func (t *MemoryTracker) Take(ctx context.Context, key Key, now time.Time) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(key, now)
+
+	var taken string
+	var takenExpiresAt time.Time
+	for name, expiresAt := range t.runners[key] {
+		if taken == "" || expiresAt.Before(takenExpiresAt) {
+			taken, takenExpiresAt = name, expiresAt
+		}
+	}
+	delete(t.runners[key], taken)
+	return taken, nil
+}
+
+// Remove stops tracking a runner.
+func (t *MemoryTracker) Remove(ctx context.Context, key Key, runnerName string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.runners[key], runnerName)
+	return nil
+}
+
+func (t *MemoryTracker) prune(key Key, now time.Time) {
+	for name, expiresAt := range t.runners[key] {
+		if !expiresAt.After(now) {
+			delete(t.runners[key], name)
+		}
+	}
+}
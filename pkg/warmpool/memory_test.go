@@ -0,0 +1,75 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package warmpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryTracker(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := Key{Org: "google", Label: "self-hosted"}
+
+	tr := NewMemoryTracker()
+	for name, expiresAt := range map[string]time.Time{
+		"expired": now.Add(-time.Second),
+		"soonest": now.Add(time.Minute),
+		"latest":  now.Add(time.Hour),
+	} {
+		if err := tr.Add(ctx, key, name, expiresAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := tr.Count(ctx, key, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := count, 2; got != want {
+		t.Errorf("expected count %d to be %d", got, want)
+	}
+
+	taken, err := tr.Take(ctx, key, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := taken, "soonest"; got != want {
+		t.Errorf("expected taken runner %q to be %q", got, want)
+	}
+
+	if err := tr.Remove(ctx, key, "latest"); err != nil {
+		t.Fatal(err)
+	}
+
+	taken, err = tr.Take(ctx, key, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taken != "" {
+		t.Errorf("expected no runner to be taken, got %q", taken)
+	}
+
+	count, err = tr.Count(ctx, Key{Org: "other", Label: "self-hosted"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected empty pool, got %d runners", count)
+	}
+}
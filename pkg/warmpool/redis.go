@@ -0,0 +1,102 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package warmpool
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var _ Tracker = (*RedisTracker)(nil)
+
+// RedisTracker is a Tracker backed by Redis so that warm pools are shared by
+// every instance of the webhook service. The runners of each pool are stored
+// in a sorted set scored by when they expire.
+type RedisTracker struct {
+	rc     *redis.Client
+	prefix string
+}
+
+// NewRedisTracker creates a RedisTracker that stores its state under keys
+// prefixed with prefix.
+func NewRedisTracker(rc *redis.Client, prefix string) *RedisTracker {
+	return &RedisTracker{
+		rc:     rc,
+		prefix: prefix,
+	}
+}
+
+// Add records an idle runner that exits at expiresAt.
+func (t *RedisTracker) Add(ctx context.Context, key Key, runnerName string, expiresAt time.Time) error {
+	if err := t.rc.ZAdd(ctx, t.key(key), &redis.Z{Score: unixMilli(expiresAt), Member: runnerName}).Err(); err != nil {
+		return fmt.Errorf("failed to add warm runner %s: %w", runnerName, err)
+	}
+	return nil
+}
+
+// Count returns the number of idle runners that have not expired by now.
+func (t *RedisTracker) Count(ctx context.Context, key Key, now time.Time) (int, error) {
+	pipe := t.rc.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, t.key(key), "-inf", formatUnixMilli(now))
+	card := pipe.ZCard(ctx, t.key(key))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to count warm runners: %w", err)
+	}
+	return int(card.Val()), nil
+}
+
+// Take removes and returns the idle runner that expires soonest.
+func (t *RedisTracker) Take(ctx context.Context, key Key, now time.Time) (string, error) {
+	pipe := t.rc.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, t.key(key), "-inf", formatUnixMilli(now))
+	popped := pipe.ZPopMin(ctx, t.key(key), 1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to take warm runner: %w", err)
+	}
+
+	runners := popped.Val()
+	if len(runners) == 0 {
+		return "", nil
+	}
+	name, ok := runners[0].Member.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected warm runner member type %T", runners[0].Member)
+	}
+	return name, nil
+}
+
+// Remove stops tracking a runner.
+func (t *RedisTracker) Remove(ctx context.Context, key Key, runnerName string) error {
+	if err := t.rc.ZRem(ctx, t.key(key), runnerName).Err(); err != nil {
+		return fmt.Errorf("failed to remove warm runner %s: %w", runnerName, err)
+	}
+	return nil
+}
+
+func (t *RedisTracker) key(key Key) string {
+	return fmt.Sprintf("%s/%s/%s", t.prefix, key.Org, key.Label)
+}
+
+func unixMilli(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+func formatUnixMilli(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package warmpool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRedisTracker(t *testing.T) {
+	t.Parallel()
+
+	const poolKey = "dispatcher/warm/google/self-hosted"
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowMilli := fmt.Sprintf("%d", now.UnixMilli())
+	key := Key{Org: "google", Label: "self-hosted"}
+
+	cases := []struct {
+		name   string
+		setup  func(m redismock.ClientMock)
+		run    func(ctx context.Context, tr *RedisTracker) (any, error)
+		exp    any
+		expErr string
+	}{
+		{
+			name: "add",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectZAdd(poolKey, &redis.Z{Score: float64(now.Add(time.Hour).UnixMilli()), Member: "runner-1"}).SetVal(1)
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return nil, tr.Add(ctx, key, "runner-1", now.Add(time.Hour))
+			},
+		},
+		{
+			name: "count",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectZRemRangeByScore(poolKey, "-inf", nowMilli).SetVal(1)
+				m.ExpectZCard(poolKey).SetVal(2)
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return tr.Count(ctx, key, now)
+			},
+			exp: 2,
+		},
+		{
+			name: "take",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectZRemRangeByScore(poolKey, "-inf", nowMilli).SetVal(0)
+				m.ExpectZPopMin(poolKey, 1).SetVal([]redis.Z{{Score: 1, Member: "runner-1"}})
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return tr.Take(ctx, key, now)
+			},
+			exp: "runner-1",
+		},
+		{
+			name: "take_empty",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectTxPipeline()
+				m.ExpectZRemRangeByScore(poolKey, "-inf", nowMilli).SetVal(0)
+				m.ExpectZPopMin(poolKey, 1).SetVal([]redis.Z{})
+				m.ExpectTxPipelineExec()
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return tr.Take(ctx, key, now)
+			},
+			exp: "",
+		},
+		{
+			name: "remove",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectZRem(poolKey, "runner-1").SetVal(1)
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return nil, tr.Remove(ctx, key, "runner-1")
+			},
+		},
+		{
+			name: "remove_error",
+			setup: func(m redismock.ClientMock) {
+				m.ExpectZRem(poolKey, "runner-1").SetErr(fmt.Errorf("connection refused"))
+			},
+			run: func(ctx context.Context, tr *RedisTracker) (any, error) {
+				return nil, tr.Remove(ctx, key, "runner-1")
+			},
+			expErr: "failed to remove warm runner runner-1: connection refused",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock := redismock.NewClientMock()
+			tc.setup(mock)
+
+			got, err := tc.run(t.Context(), NewRedisTracker(db, "dispatcher/warm"))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+			if got != tc.exp {
+				t.Errorf("expected %v to be %v", got, tc.exp)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
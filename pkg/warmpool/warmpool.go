@@ -0,0 +1,44 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package warmpool tracks idle, pre-registered runners that are kept warm so
+// that queued jobs do not wait for a runner to start.
+package warmpool
+
+import (
+	"context"
+	"time"
+)
+
+// Key identifies a warm pool by the organization its runners are registered
+// to and the label they serve.
+type Key struct {
+	Org   string
+	Label string
+}
+
+// Tracker records the idle runners of each warm pool. Runners are tracked
+// until they are taken or removed, or until they expire because the runner
+// exits after its idle timeout.
+type Tracker interface {
+	// Add records an idle runner that exits at expiresAt.
+	Add(ctx context.Context, key Key, runnerName string, expiresAt time.Time) error
+	// Count returns the number of idle runners that have not expired by now.
+	Count(ctx context.Context, key Key, now time.Time) (int, error)
+	// Take removes and returns an idle runner that has not expired by now, or
+	// an empty string if there are none.
+	Take(ctx context.Context, key Key, now time.Time) (string, error)
+	// Remove stops tracking a runner, for example because it picked up a job.
+	Remove(ctx context.Context, key Key, runnerName string) error
+}
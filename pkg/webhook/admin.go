@@ -0,0 +1,243 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/dispatch"
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// defaultAdminDispatchLimit is the number of recent dispatches returned
+	// when no limit is requested.
+	defaultAdminDispatchLimit = 50
+
+	// maxAdminDispatchLimit caps the number of recent dispatches returned.
+	maxAdminDispatchLimit = 500
+
+	// registryKeyPattern matches the keys written by runner discovery.
+	registryKeyPattern = "*:*"
+)
+
+// registryEntry is a registry key and the worker pools stored under it.
+type registryEntry struct {
+	Key   string                    `json:"key"`
+	Pools []registry.WorkerPoolInfo `json:"pools"`
+}
+
+type registryResponse struct {
+	Entries []*registryEntry `json:"entries"`
+}
+
+type dispatchesResponse struct {
+	Dispatches []*dispatch.Record `json:"dispatches"`
+}
+
+// labelsResponse is the effective label configuration used to route jobs.
+type labelsResponse struct {
+	SupportedLabels          []string                      `json:"supported_labels"`
+	IgnoredLabels            []string                      `json:"ignored_labels"`
+	Aliases                  map[string]string             `json:"aliases"`
+	Policies                 map[string]*RunnerLabelPolicy `json:"policies"`
+	DefaultPolicy            *RunnerLabelPolicy            `json:"default_policy"`
+	RegistryDefaultKeyPrefix string                        `json:"registry_default_key_prefix"`
+	Runner404Enabled         bool                          `json:"runner_404_enabled"`
+	Runner404DefaultDisabled bool                          `json:"runner_404_default_disabled"`
+}
+
+// handleAdmin returns the read-only admin API. All requests must present the
+// admin API key as a bearer token.
+func (s *Server) handleAdmin() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/v1/registry", s.handleAdminRegistry)
+	mux.HandleFunc("GET /admin/v1/dispatches", s.handleAdminDispatches)
+	mux.HandleFunc("GET /admin/v1/jobs/{id}", s.handleAdminRecord(func(r *http.Request) (*dispatch.Record, error) {
+		return s.records.Get(r.Context(), r.PathValue("id"))
+	}))
+	mux.HandleFunc("GET /admin/v1/runners/{name}", s.handleAdminRecord(func(r *http.Request) (*dispatch.Record, error) {
+		return s.records.GetByRunnerName(r.Context(), r.PathValue("name"))
+	}))
+	mux.HandleFunc("GET /admin/v1/builds/{id}", s.handleAdminRecord(func(r *http.Request) (*dispatch.Record, error) {
+		return s.records.GetByBuildID(r.Context(), r.PathValue("id"))
+	}))
+	mux.HandleFunc("GET /admin/v1/labels", s.handleAdminLabels)
+	mux.HandleFunc("GET /admin/v1/quotas", s.handleAdminQuotas)
+	mux.HandleFunc("GET /admin/v1/budgets", s.handleAdminBudgets)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), s.adminToken) != 1 {
+			logging.FromContext(ctx).WarnContext(ctx, "rejected unauthenticated admin request",
+				"path", r.URL.Path)
+			s.h.RenderJSON(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid bearer token"))
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// handleAdminRegistry lists every registry key and the worker pools stored
+// under it.
+func (s *Server) handleAdminRegistry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if s.rc == nil {
+		s.h.RenderJSON(w, http.StatusServiceUnavailable, fmt.Errorf("registry not configured"))
+		return
+	}
+
+	var keys []string
+	iter := s.rc.Scan(ctx, 0, registryKeyPattern, 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to scan registry keys", "error", err)
+		s.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to scan registry keys"))
+		return
+	}
+	slices.Sort(keys)
+
+	entries := make([]*registryEntry, 0, len(keys))
+	for _, key := range keys {
+		val, err := s.rc.Get(ctx, key).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				// Removed by discovery since the scan.
+				continue
+			}
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to read registry key", "error", err, "key", key)
+			s.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to read registry key %s", key))
+			return
+		}
+
+		var pools []registry.WorkerPoolInfo
+		if err := json.Unmarshal([]byte(val), &pools); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to unmarshal pools from registry", "error", err, "key", key)
+			s.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to parse registry key %s", key))
+			return
+		}
+		entries = append(entries, &registryEntry{Key: key, Pools: pools})
+	}
+
+	s.h.RenderJSON(w, http.StatusOK, &registryResponse{Entries: entries})
+}
+
+// handleAdminDispatches lists the most recent dispatches, newest first. The
+// number returned can be set with the limit query parameter.
+func (s *Server) handleAdminDispatches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if s.records == nil {
+		s.h.RenderJSON(w, http.StatusServiceUnavailable, fmt.Errorf("dispatch records not configured"))
+		return
+	}
+
+	limit := defaultAdminDispatchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAdminDispatchLimit {
+			s.h.RenderJSON(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d, got %q", maxAdminDispatchLimit, v))
+			return
+		}
+		limit = n
+	}
+
+	records, err := s.records.List(ctx, limit)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to list dispatch records", "error", err)
+		s.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to list dispatch records"))
+		return
+	}
+	if records == nil {
+		records = []*dispatch.Record{}
+	}
+
+	s.h.RenderJSON(w, http.StatusOK, &dispatchesResponse{Dispatches: records})
+}
+
+// handleAdminRecord returns a handler that renders the dispatch record found by
+// lookup.
+func (s *Server) handleAdminRecord(lookup func(r *http.Request) (*dispatch.Record, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if s.records == nil {
+			s.h.RenderJSON(w, http.StatusServiceUnavailable, fmt.Errorf("dispatch records not configured"))
+			return
+		}
+
+		record, err := lookup(r)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to read dispatch record", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to read dispatch record"))
+			return
+		}
+		if record == nil {
+			s.h.RenderJSON(w, http.StatusNotFound, fmt.Errorf("dispatch record not found"))
+			return
+		}
+
+		s.h.RenderJSON(w, http.StatusOK, record)
+	}
+}
+
+// handleAdminLabels returns the effective label and alias configuration.
+func (s *Server) handleAdminLabels(w http.ResponseWriter, r *http.Request) {
+	aliases := s.config.RunnerLabelAliases
+	if aliases == nil {
+		aliases = map[string]string{}
+	}
+
+	policies := s.config.RunnerLabelPolicies
+	if policies == nil {
+		policies = map[string]*RunnerLabelPolicy{}
+	}
+
+	s.h.RenderJSON(w, http.StatusOK, &labelsResponse{
+		SupportedLabels:          nonNilStrings(s.config.SupportedRunnerLabels),
+		IgnoredLabels:            nonNilStrings(s.config.IgnoredRunnerLabels),
+		Aliases:                  aliases,
+		Policies:                 policies,
+		DefaultPolicy:            s.runnerLabelPolicy(""),
+		RegistryDefaultKeyPrefix: s.runnerRegistryDefaultKeyPrefix,
+		Runner404Enabled:         s.config.Runner404Enabled,
+		Runner404DefaultDisabled: s.config.Runner404DefaultDisabled,
+	})
+}
+
+// nonNilStrings returns s, or an empty slice if s is nil, so that it is
+// rendered as an empty JSON array.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
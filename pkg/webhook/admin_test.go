@@ -0,0 +1,293 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/budget"
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/github-action-dispatcher/pkg/dispatch"
+	"github.com/abcxyz/github-action-dispatcher/pkg/quota"
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+func TestHandleAdmin(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	pools, err := json.Marshal([]registry.WorkerPoolInfo{
+		{Name: "projects/12345/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", Location: "us-west1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := &dispatch.Record{
+		JobID:        "789",
+		Org:          orgLogin,
+		Repo:         repoName,
+		Labels:       []string{SelfHostedRunnerLabel},
+		Runners:      []*dispatch.Runner{{Name: "runner-1", BuildID: "build-1"}},
+		Status:       dispatch.StatusDispatched,
+		DispatchedAt: now,
+	}
+
+	cases := []struct {
+		name        string
+		path        string
+		token       string
+		nilRedis    bool
+		nilRecords  bool
+		setupRedis  func(m redismock.ClientMock)
+		expCode     int
+		expContains []string
+	}{
+		{
+			name:        "missing_token",
+			path:        "/admin/v1/labels",
+			expCode:     http.StatusUnauthorized,
+			expContains: []string{"missing or invalid bearer token"},
+		},
+		{
+			name:        "invalid_token",
+			path:        "/admin/v1/labels",
+			token:       "not-the-token",
+			expCode:     http.StatusUnauthorized,
+			expContains: []string{"missing or invalid bearer token"},
+		},
+		{
+			name:    "labels",
+			path:    "/admin/v1/labels",
+			token:   serverGitHubWebhookSecret,
+			expCode: http.StatusOK,
+			expContains: []string{
+				`"supported_labels":["self-hosted"]`,
+				`"ignored_labels":["ignored"]`,
+				`"aliases":{"alias":"self-hosted"}`,
+				`"registry_default_key_prefix":"default"`,
+			},
+		},
+		{
+			name:  "registry",
+			path:  "/admin/v1/registry",
+			token: serverGitHubWebhookSecret,
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectScan(0, registryKeyPattern, 0).SetVal([]string{"google:self-hosted", "default:self-hosted", "google:gone"}, 0)
+				m.ExpectGet("default:self-hosted").SetVal(string(pools))
+				m.ExpectGet("google:gone").RedisNil()
+				m.ExpectGet("google:self-hosted").SetVal(string(pools))
+			},
+			expCode: http.StatusOK,
+			expContains: []string{
+				`{"entries":[{"key":"default:self-hosted","pools":[{"name":"projects/12345/locations/us-west1/workerPools/wp1"`,
+				`{"key":"google:self-hosted"`,
+			},
+		},
+		{
+			name:        "registry_not_configured",
+			path:        "/admin/v1/registry",
+			token:       serverGitHubWebhookSecret,
+			nilRedis:    true,
+			expCode:     http.StatusServiceUnavailable,
+			expContains: []string{"registry not configured"},
+		},
+		{
+			name:        "quotas",
+			path:        "/admin/v1/quotas",
+			token:       serverGitHubWebhookSecret,
+			expCode:     http.StatusOK,
+			expContains: []string{`{"quotas":[{"key":"google","limit":10,"used":1}]}`},
+		},
+		{
+			name:        "budgets",
+			path:        "/admin/v1/budgets",
+			token:       serverGitHubWebhookSecret,
+			expCode:     http.StatusOK,
+			expContains: []string{`"org":"google"`, `"unit":"minutes","limit":600,"used":30,"exhausted":false`},
+		},
+		{
+			name:        "dispatches",
+			path:        "/admin/v1/dispatches?limit=10",
+			token:       serverGitHubWebhookSecret,
+			expCode:     http.StatusOK,
+			expContains: []string{`{"dispatches":[{"job_id":"789"`},
+		},
+		{
+			name:        "dispatches_invalid_limit",
+			path:        "/admin/v1/dispatches?limit=0",
+			token:       serverGitHubWebhookSecret,
+			expCode:     http.StatusBadRequest,
+			expContains: []string{`limit must be between 1 and 500, got \"0\"`},
+		},
+		{
+			name:        "dispatches_not_configured",
+			path:        "/admin/v1/dispatches",
+			token:       serverGitHubWebhookSecret,
+			nilRecords:  true,
+			expCode:     http.StatusServiceUnavailable,
+			expContains: []string{"dispatch records not configured"},
+		},
+		{
+			name:        "job",
+			path:        "/admin/v1/jobs/789",
+			token:       serverGitHubWebhookSecret,
+			expCode:     http.StatusOK,
+			expContains: []string{`"job_id":"789"`, `"runners":[{"name":"runner-1","build_id":"build-1"`},
+		},
+		{
+			name:        "job_not_found",
+			path:        "/admin/v1/jobs/123",
+			token:       serverGitHubWebhookSecret,
+			expCode:     http.StatusNotFound,
+			expContains: []string{"dispatch record not found"},
+		},
+		{
+			name:        "runner",
+			path:        "/admin/v1/runners/runner-1",
+			token:       serverGitHubWebhookSecret,
+			expCode:     http.StatusOK,
+			expContains: []string{`"job_id":"789"`},
+		},
+		{
+			name:        "build",
+			path:        "/admin/v1/builds/build-1",
+			token:       serverGitHubWebhookSecret,
+			expCode:     http.StatusOK,
+			expContains: []string{`"job_id":"789"`},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			h, err := renderer.New(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var rc *redis.Client
+			db, mockRedis := redismock.NewClientMock()
+			if !tc.nilRedis {
+				rc = db
+			}
+			if tc.setupRedis != nil {
+				tc.setupRedis(mockRedis)
+			}
+
+			var records dispatch.Store
+			if !tc.nilRecords {
+				store := dispatch.NewMemoryStore()
+				if err := store.Put(ctx, record); err != nil {
+					t.Fatal(err)
+				}
+				records = store
+			}
+
+			quotas := quota.NewMemoryLimiter()
+			if _, _, err := quotas.Acquire(ctx, "789", 1, []*quota.Limit{{Key: orgLogin, Max: 10}}, time.Now().Add(time.Hour), time.Now()); err != nil {
+				t.Fatal(err)
+			}
+
+			budgets := budget.NewMemoryStore()
+			if _, err := budgets.Add(ctx, orgLogin, budget.Month(time.Now()), "789", &budget.Usage{Minutes: 30}); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := &Config{
+				AdminAPIKeyMountPath:           "admin-path",
+				Budgets:                        map[string]*Budget{orgLogin: {Unit: BudgetUnitMinutes, Limit: 600}},
+				ConcurrencyQuotas:              map[string]int{orgLogin: 10},
+				AdminAPIKeyName:                "admin-key",
+				GitHubWebhookKeyMountPath:      "test-path",
+				GitHubWebhookKeyName:           "test-key",
+				RunnerExecutionTimeoutSeconds:  3600,
+				RunnerIdleTimeoutSeconds:       300,
+				RunnerRegistryDefaultKeyPrefix: "default",
+				RunnerLabelAliases:             map[string]string{"alias": SelfHostedRunnerLabel},
+				SupportedRunnerLabels:          []string{SelfHostedRunnerLabel},
+				IgnoredRunnerLabels:            []string{"ignored"},
+			}
+			wco := &WebhookClientOptions{
+				CloudBuildClientOverride: &cloudbuild.MockClient{},
+				OSFileReaderOverride: &MockFileReader{
+					ReadFileMock: &ReadFileResErr{Res: []byte(serverGitHubWebhookSecret + "\n")},
+				},
+				KeyManagementClientOverride: &MockKMSClient{},
+				DispatchRecordStoreOverride: records,
+				QuotaLimiterOverride:        quotas,
+				BudgetStoreOverride:         budgets,
+			}
+
+			srv, err := NewServer(ctx, h, cfg, rc, wco)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			resp := httptest.NewRecorder()
+			srv.Routes(ctx).ServeHTTP(resp, req)
+
+			if got, want := resp.Code, tc.expCode; got != want {
+				t.Errorf("expected %d to be %d: %s", got, want, resp.Body.String())
+			}
+			for _, want := range tc.expContains {
+				if got := resp.Body.String(); !strings.Contains(got, want) {
+					t.Errorf("expected body %s to contain %s", got, want)
+				}
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestRoutes_AdminDisabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	cfg := &Config{}
+	wco := &WebhookClientOptions{
+		CloudBuildClientOverride: &cloudbuild.MockClient{},
+	}
+
+	srv := newTestServer(t, cfg, nil, wco)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/v1/labels", nil)
+	req.Header.Set("Authorization", "Bearer "+serverGitHubWebhookSecret)
+	resp := httptest.NewRecorder()
+	srv.Routes(ctx).ServeHTTP(resp, req)
+
+	if got, want := resp.Code, http.StatusNotFound; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import "fmt"
+
+// appQueueName returns the Redis key prefix for the dispatch queue of a GitHub
+// app other than the default one.
+func appQueueName(name string) string {
+	return fmt.Sprintf("%s/apps/%s%s", dispatchKeyPrefix, name, dispatchQueuePath)
+}
+
+// forApp returns a copy of the config for serving a GitHub app. Enterprise
+// registration, warm pools and the admin API are only served for the default
+// app.
+func (cfg *Config) forApp(app *GitHubApp) *Config {
+	appCfg := *cfg
+	appCfg.GitHubAppID = app.AppID
+	appCfg.KMSAppPrivateKeyID = app.KMSAppPrivateKeyID
+	appCfg.GitHubWebhookExtraKeyNames = app.WebhookExtraKeyNames
+	appCfg.GitHubWebhookKeyName = app.WebhookKeyName
+	appCfg.GitHubEnterprise = ""
+	appCfg.GitHubEnterpriseInstallationID = 0
+	appCfg.GitHubApps = nil
+	appCfg.GitHubHosts = nil
+	appCfg.WarmPools = nil
+	appCfg.AdminAPIKeyMountPath = ""
+	appCfg.AdminAPIKeyName = ""
+	return &appCfg
+}
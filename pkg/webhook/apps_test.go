@@ -0,0 +1,183 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v69/github"
+
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	testAppID = "12345"
+	//nolint:gosec // this is a test value
+	testAppWebhookSecret = "test-app-webhook-secret"
+	//nolint:gosec // this is a test value
+	testAppNextWebhookSecret = "test-app-next-webhook-secret"
+)
+
+func TestRouteWebhook_Apps(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		appID         string
+		secret        string
+		expStatusCode int
+		expRespBody   string
+	}{
+		{
+			name:          "default_app",
+			secret:        serverGitHubWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "app_by_target_id",
+			appID:         testAppID,
+			secret:        testAppWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "app_by_target_id_extra_key",
+			appID:         testAppID,
+			secret:        testAppNextWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "app_extra_key_rejected_by_default_app",
+			secret:        testAppNextWebhookSecret,
+			expStatusCode: http.StatusBadRequest,
+			expRespBody:   "failed to validate github payload",
+		},
+		{
+			name:          "unknown_target_id_uses_default_app",
+			appID:         "67890",
+			secret:        serverGitHubWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "app_secret_rejected_by_default_app",
+			secret:        testAppWebhookSecret,
+			expStatusCode: http.StatusBadRequest,
+			expRespBody:   "failed to validate github payload",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			srv, _ := newTestAppsServer(t)
+
+			action := "waiting"
+			payload, err := json.Marshal(&github.WorkflowJobEvent{
+				Action:      &action,
+				WorkflowJob: &github.WorkflowJob{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+			req.Header.Add(DeliveryIDHeader, "delivery-id")
+			req.Header.Add(EventTypeHeader, "workflow_job")
+			req.Header.Add(ContentTypeHeader, "application/json")
+			req.Header.Add(SHA256SignatureHeader, fmt.Sprintf("sha256=%s", createSignature([]byte(tc.secret), payload)))
+			if tc.appID != "" {
+				req.Header.Add(gitHubHookInstallationTargetIDHeader, tc.appID)
+			}
+
+			resp := httptest.NewRecorder()
+			srv.Routes(ctx).ServeHTTP(resp, req)
+
+			if got, want := resp.Code, tc.expStatusCode; got != want {
+				t.Errorf("expected %d to be %d: %s", got, want, resp.Body.String())
+			}
+			if got, want := resp.Body.String(), tc.expRespBody; !strings.Contains(got, want) {
+				t.Errorf("expected body %q to contain %q", got, want)
+			}
+		})
+	}
+}
+
+func TestNewServer_Apps(t *testing.T) {
+	t.Parallel()
+
+	srv, appClient := newTestAppsServer(t)
+
+	as, ok := srv.apps[testAppID]
+	if !ok {
+		t.Fatalf("expected a server for app %s", testAppID)
+	}
+	if as.ghc != appClient {
+		t.Errorf("expected app server to use the app's github client")
+	}
+	if srv.ghc == appClient {
+		t.Errorf("expected default server not to use the app's github client")
+	}
+	if got, want := as.keyPrefix, dispatchKeyPrefix; got != want {
+		t.Errorf("expected app key prefix %q to be %q", got, want)
+	}
+	if got, want := appQueueName("team-a"), "dispatcher/apps/team-a/queue"; got != want {
+		t.Errorf("expected app queue name %q to be %q", got, want)
+	}
+}
+
+// newTestAppsServer returns a server for the default GitHub App and a "team-a"
+// app, each with its own webhook secret, and the github client of the
+// "team-a" app. The "team-a" app also accepts a second secret while it is
+// rotated.
+func newTestAppsServer(tb testing.TB) (*Server, gh.Client) {
+	tb.Helper()
+
+	cfg := generateValidConfig()
+	cfg.GitHubAppsRaw = []string{
+		"team-a=app_id=" + testAppID + ";kms_app_private_key_id=team-a-kms-key;webhook_key_name=team-a-key;webhook_extra_key_name=team-a-key-next",
+	}
+	if err := cfg.Validate(); err != nil {
+		tb.Fatal(err)
+	}
+
+	secrets := map[string]string{
+		"/tmp/test-key":        serverGitHubWebhookSecret,
+		"/tmp/team-a-key":      testAppWebhookSecret,
+		"/tmp/team-a-key-next": testAppNextWebhookSecret,
+	}
+	appClient := &gh.MockClient{}
+	wco := &WebhookClientOptions{
+		GitHubClientOverride: &gh.MockClient{},
+		GitHubAppClientOverrides: map[string]gh.Client{
+			"team-a": appClient,
+		},
+		OSFileReaderOverride: newTestFileReader(secrets),
+	}
+
+	return newTestServer(tb, cfg, nil, wco), appClient
+}
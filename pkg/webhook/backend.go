@@ -0,0 +1,103 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/backend"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// runnerBackend returns the backend that starts runners on pools of the given
+// type, falling back to the default backend for types without one.
+func (s *Server) runnerBackend(poolType string) backend.RunnerBackend {
+	if b, ok := s.backends[poolType]; ok {
+		return b
+	}
+	return s.backends[""]
+}
+
+// runnerStartRequest describes a runner to start on a pool with the given JIT
+// config. Its timeouts come from the provisioning policy of the pool's label.
+func (s *Server) runnerStartRequest(ctx context.Context, runnerID, jitConfig, imageName, imageTag string, pool *workerPool) *backend.StartRequest {
+	var label string
+	if pool != nil {
+		label = pool.label
+	}
+	policy := s.runnerLabelPolicy(label)
+
+	req := &backend.StartRequest{
+		RunnerName:       runnerID,
+		JITConfig:        jitConfig,
+		ImageName:        imageName,
+		ImageTag:         imageTag,
+		IdleTimeout:      time.Duration(policy.RunnerIdleTimeoutSeconds) * time.Second,
+		ExecutionTimeout: time.Duration(policy.RunnerExecutionTimeoutSeconds) * time.Second,
+	}
+
+	// Check if this is an E2E test run and add appropriate tags.
+	if s.e2eTestRunID != "" {
+		req.Tags = []string{"e2e-test", fmt.Sprintf("e2e-run-id-%s", s.e2eTestRunID)}
+	}
+
+	if pool != nil && pool.projectID != "" {
+		// Use location from pool, but fall back to server default if it's missing
+		// for safety during transitions.
+		location := pool.location
+		if location == "" {
+			location = s.runnerLocation
+			logging.FromContext(ctx).WarnContext(ctx, "worker pool from registry is missing location, falling back to default",
+				"pool_name", pool.name,
+				"default_location", location)
+		}
+
+		req.Pool = &backend.Pool{
+			Name:           pool.name,
+			ProjectID:      pool.projectID,
+			Location:       location,
+			ServiceAccount: pool.serviceAccount,
+			Type:           pool.poolType,
+			RemoteConfig:   pool.remoteConfig,
+		}
+	}
+	return req
+}
+
+// newRunnerBuild returns the build of a runner started on a pool.
+func newRunnerBuild(runner *backend.Runner, pool *workerPool) *runnerBuild {
+	return &runnerBuild{
+		RunnerName: runner.Name,
+		BuildID:    runner.ID,
+		ProjectID:  runner.ProjectID,
+		Location:   runner.Location,
+		PoolType:   pool.poolType,
+		poolName:   pool.name,
+		label:      pool.label,
+	}
+}
+
+// runner returns the runner that the build was started for, as known to its
+// backend.
+func (b *runnerBuild) runner() *backend.Runner {
+	return &backend.Runner{
+		Name:      b.RunnerName,
+		ID:        b.BuildID,
+		ProjectID: b.ProjectID,
+		Location:  b.Location,
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/backend"
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/github-action-dispatcher/pkg/inflight"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const testLocalPoolType = "local"
+
+func TestCreateRunnerBuild_Backend(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+	localBackend := &backend.MockBackend{
+		StartRunnerID: "local-runner-id",
+		Statuses:      map[string]*backend.Status{"local-runner-id": {Active: false, State: "EXITED"}},
+	}
+	tracker := inflight.NewMemoryTracker()
+	srv := newTestServer(t, newTestInFlightConfig(), nil, &WebhookClientOptions{
+		CloudBuildClientOverride: mockCloudBuildClient,
+		GitHubClientOverride:     &gh.MockClient{},
+		InFlightTrackerOverride:  tracker,
+		RandOverride:             fixedRand(0),
+		RunnerBackendOverrides: map[string]backend.RunnerBackend{
+			testLocalPoolType: localBackend,
+		},
+	})
+
+	localPool := &workerPool{
+		name:      "local-pool",
+		projectID: "local-project",
+		location:  "local",
+		label:     SelfHostedRunnerLabel,
+		poolType:  testLocalPoolType,
+	}
+	build, err := srv.createRunnerBuild(ctx, "runner-1", "jit", "image", "tag", localPool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &runnerBuild{
+		RunnerName: "runner-1",
+		BuildID:    "local-runner-id",
+		ProjectID:  "local-project",
+		Location:   "local",
+		PoolType:   testLocalPoolType,
+		poolName:   "local-pool",
+		label:      SelfHostedRunnerLabel,
+	}
+	if diff := cmp.Diff(want, build, cmp.AllowUnexported(runnerBuild{})); diff != "" {
+		t.Errorf("build (-want, +got):\n%s", diff)
+	}
+
+	if got, want := len(localBackend.StartRunnerReqs), 1; got != want {
+		t.Fatalf("expected %d runners started by the local backend, got %d", want, got)
+	}
+	req := localBackend.StartRunnerReqs[0]
+	if got, want := req.JITConfig, "jit"; got != want {
+		t.Errorf("expected JIT config %q to be %q", got, want)
+	}
+	if got, want := req.ExecutionTimeout, time.Hour; got != want {
+		t.Errorf("expected execution timeout %s to be %s", got, want)
+	}
+	if got, want := len(mockCloudBuildClient.CreateBuildReqs), 0; got != want {
+		t.Errorf("expected %d cloud builds, got %d", want, got)
+	}
+
+	// Pools of other types are still started on Cloud Build.
+	if _, err := srv.createRunnerBuild(ctx, "runner-2", "jit", "image", "tag", &workerPool{
+		name:      "private-pool",
+		projectID: "private-project",
+		location:  "us-west1",
+		label:     SelfHostedRunnerLabel,
+		poolType:  "private",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(mockCloudBuildClient.CreateBuildReqs), 1; got != want {
+		t.Errorf("expected %d cloud builds, got %d", want, got)
+	}
+
+	// The status of the local runner is read from the local backend.
+	if diff := cmp.Diff([]string{"runner-1"}, srv.reconcileInFlight(ctx)); diff != "" {
+		t.Errorf("finished runners (-want, +got):\n%s", diff)
+	}
+	if got, want := len(localBackend.RunnerStatusReqs), 1; got != want {
+		t.Errorf("expected %d status requests to the local backend, got %d", want, got)
+	}
+}
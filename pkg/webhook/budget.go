@@ -0,0 +1,204 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/budget"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// budgetKeyPath is appended to a server's key prefix to form the Redis key
+// prefix for its budget usage.
+const budgetKeyPath = "/budgets"
+
+// budgetStatus is an org's budget and its usage in a month.
+type budgetStatus struct {
+	Org       string  `json:"org"`
+	Month     string  `json:"month"`
+	Unit      string  `json:"unit"`
+	Limit     float64 `json:"limit"`
+	Used      float64 `json:"used"`
+	Exhausted bool    `json:"exhausted"`
+}
+
+type budgetsResponse struct {
+	Budgets []*budgetStatus `json:"budgets"`
+}
+
+// budgetRate returns the estimated cost in dollars per runner-minute of a job,
+// which is the highest rate of its labels.
+func (s *Server) budgetRate(jobResolvedRunnerLabels []string) float64 {
+	rate, ok := 0.0, false
+	for _, label := range jobResolvedRunnerLabels {
+		if r, found := s.config.BudgetRates[label]; found {
+			rate, ok = max(rate, r), true
+		}
+	}
+	if !ok {
+		return s.config.BudgetDefaultRate
+	}
+	return rate
+}
+
+// recordBudgetUsage adds the runner time of a completed job to its org's usage
+// for the month the job completed in. Only jobs of orgs with a budget that were
+// run on runners the dispatcher handles are counted. Jobs are charged the rate
+// of the labels they requested, even if they were dispatched to a fallback
+// label.
+func (s *Server) recordBudgetUsage(ctx context.Context, event *github.WorkflowJobEvent, jobID string) {
+	if s.budgets == nil {
+		return
+	}
+	orgName := event.GetOrg().GetLogin()
+	if _, ok := s.config.Budgets[orgName]; !ok {
+		return
+	}
+
+	job := event.GetWorkflowJob()
+	if job.StartedAt == nil || job.CompletedAt == nil {
+		return
+	}
+	duration := job.CompletedAt.Sub(job.StartedAt.Time)
+	if duration <= 0 {
+		return
+	}
+
+	logger := logging.FromContext(ctx)
+
+	jobResolvedRunnerLabels, canHandle, err := s.resolveAndValidateRunnerLabels(ctx, job.Labels)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to resolve runner labels for budget usage", "error", err)
+		return
+	}
+	if !canHandle {
+		return
+	}
+
+	usage := &budget.Usage{Minutes: duration.Minutes()}
+	usage.Dollars = usage.Minutes * s.budgetRate(jobResolvedRunnerLabels)
+	month := budget.Month(job.CompletedAt.Time)
+
+	added, err := s.budgets.Add(ctx, orgName, month, jobID, usage)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to record budget usage", "error", err)
+		return
+	}
+	if added {
+		logger.InfoContext(ctx, "recorded budget usage",
+			"org", orgName,
+			"budget_month", month,
+			"budget_minutes", usage.Minutes,
+			"budget_dollars", usage.Dollars)
+	}
+}
+
+// exhaustedBudgetAction returns the action to take for a job of an org that
+// has exhausted its budget for the current month, or an empty string if it has
+// not. Jobs are dispatched as usual when usage cannot be read.
+func (s *Server) exhaustedBudgetAction(ctx context.Context, orgName string) string {
+	if s.budgets == nil {
+		return ""
+	}
+	status, err := s.budgetStatus(ctx, orgName, budget.Month(time.Now()))
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to check budget, dispatching anyway", "error", err)
+		return ""
+	}
+	if status == nil || !status.Exhausted {
+		return ""
+	}
+
+	logging.FromContext(ctx).WarnContext(ctx, "org budget exhausted",
+		"org", orgName,
+		"budget_month", status.Month,
+		"budget_unit", status.Unit,
+		"budget_limit", status.Limit,
+		"budget_used", status.Used,
+		"budget_action", s.config.BudgetExhaustedAction)
+	return s.config.BudgetExhaustedAction
+}
+
+// budgetStatus returns an org's budget and its usage in the month, or nil if
+// the org has no budget.
+func (s *Server) budgetStatus(ctx context.Context, orgName, month string) (*budgetStatus, error) {
+	b, ok := s.config.Budgets[orgName]
+	if !ok {
+		return nil, nil
+	}
+
+	usage, err := s.budgets.Get(ctx, orgName, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget usage: %w", err)
+	}
+
+	used := usage.Minutes
+	if b.Unit == BudgetUnitDollars {
+		used = usage.Dollars
+	}
+	return &budgetStatus{
+		Org:       orgName,
+		Month:     month,
+		Unit:      b.Unit,
+		Limit:     b.Limit,
+		Used:      used,
+		Exhausted: used >= b.Limit,
+	}, nil
+}
+
+// budgetFallbackLabels returns the resolved labels of a job with every label
+// that has a budget fallback alias replaced by its cheaper label.
+func (s *Server) budgetFallbackLabels(jobResolvedRunnerLabels []string) []string {
+	labels := make([]string, 0, len(jobResolvedRunnerLabels))
+	for _, label := range jobResolvedRunnerLabels {
+		if target, ok := s.config.BudgetFallbackAliases[label]; ok {
+			label = target
+		}
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// handleAdminBudgets lists every org budget and its usage in the current
+// month.
+func (s *Server) handleAdminBudgets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	statuses := make([]*budgetStatus, 0, len(s.config.Budgets))
+	if s.budgets != nil {
+		month := budget.Month(time.Now())
+		for orgName := range s.config.Budgets {
+			status, err := s.budgetStatus(ctx, orgName, month)
+			if err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "failed to get budget usage", "error", err, "org", orgName)
+				s.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to get budget usage for org %s", orgName))
+				return
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	slices.SortFunc(statuses, func(a, b *budgetStatus) int {
+		return strings.Compare(a.Org, b.Org)
+	})
+	s.h.RenderJSON(w, http.StatusOK, &budgetsResponse{Budgets: statuses})
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/budget"
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestHandleQueuedEvent_Budget(t *testing.T) {
+	t.Parallel()
+
+	const (
+		largeLabel = "self-hosted-large"
+		smallLabel = "self-hosted-small"
+	)
+	pools := testFailoverPools(t, "test-project")
+
+	cases := []struct {
+		name             string
+		action           string
+		used             float64
+		runner404Enabled bool
+		expRegistryKey   string
+		expBuildProjects []string
+	}{
+		{
+			name:             "within_budget",
+			action:           BudgetActionRunner404,
+			used:             59,
+			runner404Enabled: true,
+			expRegistryKey:   "google:" + largeLabel,
+			expBuildProjects: []string{"test-project"},
+		},
+		{
+			name:             "exhausted_warn",
+			action:           BudgetActionWarn,
+			used:             60,
+			expRegistryKey:   "google:" + largeLabel,
+			expBuildProjects: []string{"test-project"},
+		},
+		{
+			name:             "exhausted_404",
+			action:           BudgetActionRunner404,
+			used:             60,
+			runner404Enabled: true,
+			expBuildProjects: []string{"404-project"},
+		},
+		{
+			name:             "exhausted_alias",
+			action:           BudgetActionAlias,
+			used:             61,
+			expRegistryKey:   "google:" + smallLabel,
+			expBuildProjects: []string{"test-project"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			store := budget.NewMemoryStore()
+			if _, err := store.Add(ctx, orgLogin, budget.Month(time.Now()), "456", &budget.Usage{Minutes: tc.used}); err != nil {
+				t.Fatal(err)
+			}
+
+			db, mockRedis := redismock.NewClientMock()
+			if tc.expRegistryKey != "" {
+				mockRedis.ExpectGet(tc.expRegistryKey).SetVal(pools)
+			}
+
+			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+			srv := newTestBudgetServer(t, &Config{
+				SupportedRunnerLabels: []string{largeLabel, smallLabel},
+				Runner404Enabled:      tc.runner404Enabled,
+				Budgets:               map[string]*Budget{orgLogin: {Unit: BudgetUnitMinutes, Limit: 60}},
+				BudgetExhaustedAction: tc.action,
+				BudgetFallbackAliases: map[string]string{largeLabel: smallLabel},
+			}, store, mockCloudBuildClient, db)
+
+			event := &github.WorkflowJobEvent{
+				WorkflowJob: &github.WorkflowJob{
+					ID:     github.Ptr(int64(1)),
+					Labels: []string{largeLabel},
+				},
+				Installation: &github.Installation{ID: github.Ptr(int64(123))},
+				Org:          &github.Organization{Login: github.Ptr(orgLogin)},
+				Repo:         &github.Repository{Name: github.Ptr(repoName)},
+			}
+
+			resp := srv.handleQueuedEvent(ctx, event, "delivery-id", "1")
+			if got, want := resp.Code, http.StatusOK; got != want {
+				t.Errorf("expected code %d, got %d: %s", want, got, resp.Message)
+			}
+
+			var gotBuildProjects []string
+			for _, req := range mockCloudBuildClient.CreateBuildReqs {
+				gotBuildProjects = append(gotBuildProjects, req.GetProjectId())
+			}
+			if diff := cmp.Diff(tc.expBuildProjects, gotBuildProjects); diff != "" {
+				t.Errorf("builds (-want, +got):\n%s", diff)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestRecordBudgetUsage(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	store := budget.NewMemoryStore()
+	srv := newTestBudgetServer(t, &Config{
+		SupportedRunnerLabels: []string{SelfHostedRunnerLabel},
+		Budgets:               map[string]*Budget{orgLogin: {Unit: BudgetUnitDollars, Limit: 100}},
+		BudgetDefaultRate:     0.01,
+		BudgetExhaustedAction: BudgetActionWarn,
+		BudgetRates:           map[string]float64{SelfHostedRunnerLabel: 0.5},
+	}, store, &cloudbuild.MockClient{}, nil)
+
+	startedAt := time.Date(2025, time.March, 31, 23, 50, 0, 0, time.UTC)
+	completedAt := startedAt.Add(30 * time.Minute)
+	event := &github.WorkflowJobEvent{
+		Action: github.Ptr("completed"),
+		WorkflowJob: &github.WorkflowJob{
+			ID:          github.Ptr(int64(789)),
+			Labels:      []string{SelfHostedRunnerLabel},
+			StartedAt:   &github.Timestamp{Time: startedAt},
+			CompletedAt: &github.Timestamp{Time: completedAt},
+		},
+		Installation: &github.Installation{ID: github.Ptr(int64(123))},
+		Org:          &github.Organization{Login: github.Ptr(orgLogin)},
+		Repo:         &github.Repository{Name: github.Ptr(repoName)},
+	}
+
+	// Redelivered completed events are only counted once.
+	for range 2 {
+		resp := httptest.NewRecorder()
+		srv.handleWebhook().ServeHTTP(resp, newWebhookRequest(t, event))
+		if got, want := resp.Code, http.StatusOK; got != want {
+			t.Fatalf("expected %d to be %d", got, want)
+		}
+	}
+
+	got, err := store.Get(ctx, orgLogin, "2025-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&budget.Usage{Minutes: 30, Dollars: 15}, got); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+}
+
+func newTestBudgetServer(tb testing.TB, cfg *Config, store budget.Store, cbc cloudbuild.Client, rc *redis.Client) *Server {
+	tb.Helper()
+
+	cfg.RunnerExecutionTimeoutSeconds = 3600
+	cfg.RunnerIdleTimeoutSeconds = 300
+	cfg.RunnerProjectID = "test-project"
+	cfg.Runner404Location = "us-central1"
+	cfg.Runner404ProjectID = "404-project"
+	cfg.Runner404ServiceAccount = "404-sa"
+	return newTestServer(tb, cfg, rc, &WebhookClientOptions{
+		CloudBuildClientOverride: cbc,
+		BudgetStoreOverride:      store,
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/backend"
+	"github.com/abcxyz/github-action-dispatcher/pkg/dispatch"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// cancelUnusedBuilds cancels the builds dispatched for a job that completed
+// without running, as is the case when it is cancelled or skipped before a
+// runner picks it up. The builds are looked up from the job's dispatch record.
+// Builds whose runner has picked up work are left alone, as are warm runners,
+// which can serve other jobs. It returns the IDs of the builds that were
+// cancelled.
+func (s *Server) cancelUnusedBuilds(ctx context.Context, event *github.WorkflowJobEvent, jobID string) []string {
+	logger := logging.FromContext(ctx)
+
+	conclusion := event.WorkflowJob.GetConclusion()
+	if conclusion != "cancelled" && conclusion != "skipped" {
+		return nil
+	}
+
+	if s.records == nil || jobID == "" {
+		logger.DebugContext(ctx, "dispatch records not configured, unable to cancel builds")
+		return nil
+	}
+
+	record, err := s.records.Get(ctx, jobID)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to read dispatch record for job", "error", err)
+		return nil
+	}
+	if record == nil || len(record.Runners) == 0 {
+		logger.InfoContext(ctx, "no dispatched builds found for job, nothing to cancel")
+		return nil
+	}
+
+	var cancelled []string
+	for _, runner := range record.Runners {
+		if runner.BuildID == "" {
+			continue
+		}
+
+		buildLogger := logger.With(
+			"runner_name", runner.Name,
+			gcbBuildIDKey, runner.BuildID,
+			gcbProjectIDKey, runner.ProjectID)
+		buildCtx := logging.WithLogger(ctx, buildLogger)
+
+		if runner.Name == event.WorkflowJob.GetRunnerName() {
+			buildLogger.InfoContext(buildCtx, "runner was assigned the job, not cancelling build")
+			continue
+		}
+
+		runnerBackend := s.runnerBackend(runner.PoolType)
+		backendRunner := &backend.Runner{
+			Name:      runner.Name,
+			ID:        runner.BuildID,
+			ProjectID: runner.ProjectID,
+			Location:  runner.Location,
+		}
+		status, err := runnerBackend.RunnerStatus(buildCtx, backendRunner)
+		if err != nil {
+			buildLogger.ErrorContext(buildCtx, "failed to get build", "error", err)
+			continue
+		}
+
+		if !status.Active {
+			buildLogger.InfoContext(buildCtx, "build already finished, not cancelling",
+				"status", status.State)
+			continue
+		}
+
+		if s.runnerBusy(buildCtx, event, runner) {
+			buildLogger.InfoContext(buildCtx, "runner picked up work, not cancelling build")
+			continue
+		}
+
+		if err := runnerBackend.CancelRunner(buildCtx, backendRunner); err != nil {
+			buildLogger.ErrorContext(buildCtx, "failed to cancel build", "error", err)
+			continue
+		}
+
+		buildLogger.InfoContext(buildCtx, "cancelled build for job that completed without running",
+			"status", status.State,
+			"conclusion", conclusion)
+		cancelled = append(cancelled, runner.BuildID)
+		s.untrackRunner(buildCtx, runner.Name)
+	}
+	return cancelled
+}
+
+// cancelStartedBuilds cancels the builds started for a job whose dispatch
+// failed part way through. It reports whether every build was cancelled.
+func (s *Server) cancelStartedBuilds(ctx context.Context, builds []*runnerBuild) bool {
+	logger := logging.FromContext(ctx)
+
+	cancelledAll := true
+	for _, build := range builds {
+		if build.dryRun != nil {
+			continue
+		}
+
+		buildLogger := logger.With(
+			"runner_name", build.RunnerName,
+			gcbBuildIDKey, build.BuildID,
+			gcbProjectIDKey, build.ProjectID)
+		buildCtx := logging.WithLogger(ctx, buildLogger)
+
+		if err := s.runnerBackend(build.PoolType).CancelRunner(buildCtx, build.runner()); err != nil {
+			buildLogger.ErrorContext(buildCtx, "failed to cancel build of failed dispatch", "error", err)
+			cancelledAll = false
+			continue
+		}
+
+		buildLogger.InfoContext(buildCtx, "cancelled build of failed dispatch")
+		s.untrackRunner(buildCtx, build.RunnerName)
+	}
+	return cancelledAll
+}
+
+// runnerBusy reports whether GitHub has assigned the runner a job. GitHub
+// marks a runner busy when it is assigned a job, before the job's in_progress
+// event is delivered, so a runner that picked up another job is not cancelled
+// while that event is still in flight.
+func (s *Server) runnerBusy(ctx context.Context, event *github.WorkflowJobEvent, runner *dispatch.Runner) bool {
+	orgName := event.GetOrg().GetLogin()
+	installationID := event.GetInstallation().GetID()
+
+	var busy bool
+	var err error
+	switch s.runnerRegistrationScope(orgName, runner.Label) {
+	case RunnerRegistrationScopeEnterprise:
+		busy, err = s.ghc.EnterpriseRunnerBusy(ctx, s.config.GitHubEnterpriseInstallationID, s.config.GitHubEnterprise, runner.Name)
+	case RunnerRegistrationScopeOrg:
+		busy, err = s.ghc.OrgRunnerBusy(ctx, installationID, orgName, runner.Name)
+	default:
+		busy, err = s.ghc.RepoRunnerBusy(ctx, installationID, orgName, event.GetRepo().GetName(), runner.Name)
+	}
+	if err != nil {
+		// Err on the side of leaving the build running.
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to check if runner is busy", "error", err)
+		return true
+	}
+	return busy
+}
@@ -0,0 +1,220 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/github-action-dispatcher/pkg/dispatch"
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+)
+
+func TestCancelUnusedBuilds(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		action       string
+		conclusion   string
+		runnerName   string
+		scope        string
+		noRecord     bool
+		builds       map[string]cloudbuildpb.Build_Status
+		busyRunners  map[string]bool
+		busyErr      error
+		expCancelled []string
+		expGetBuilds int
+		expBusyCalls int
+	}{
+		{
+			name:       "cancels_waiting_builds",
+			action:     "completed",
+			conclusion: "cancelled",
+			builds: map[string]cloudbuildpb.Build_Status{
+				"build-1": cloudbuildpb.Build_QUEUED,
+				"build-2": cloudbuildpb.Build_WORKING,
+			},
+			expCancelled: []string{"build-1", "build-2"},
+			expGetBuilds: 2,
+			expBusyCalls: 2,
+		},
+		{
+			// runner-1 was assigned another job whose in_progress event has not
+			// been processed yet, so only GitHub knows that it is busy.
+			name:       "skips_runner_busy_with_unprocessed_job",
+			action:     "completed",
+			conclusion: "skipped",
+			builds: map[string]cloudbuildpb.Build_Status{
+				"build-1": cloudbuildpb.Build_WORKING,
+				"build-2": cloudbuildpb.Build_WORKING,
+			},
+			busyRunners:  map[string]bool{"runner-1": true},
+			expCancelled: []string{"build-2"},
+			expGetBuilds: 2,
+			expBusyCalls: 2,
+		},
+		{
+			name:       "checks_org_runners",
+			action:     "completed",
+			conclusion: "cancelled",
+			scope:      RunnerRegistrationScopeOrg,
+			builds: map[string]cloudbuildpb.Build_Status{
+				"build-1": cloudbuildpb.Build_WORKING,
+				"build-2": cloudbuildpb.Build_WORKING,
+			},
+			busyRunners:  map[string]bool{"runner-2": true},
+			expCancelled: []string{"build-1"},
+			expGetBuilds: 2,
+			expBusyCalls: 2,
+		},
+		{
+			name:       "busy_check_failure_leaves_builds_running",
+			action:     "completed",
+			conclusion: "cancelled",
+			builds: map[string]cloudbuildpb.Build_Status{
+				"build-1": cloudbuildpb.Build_WORKING,
+				"build-2": cloudbuildpb.Build_WORKING,
+			},
+			busyErr:      fmt.Errorf("server responded with 503 status code"),
+			expGetBuilds: 2,
+			expBusyCalls: 2,
+		},
+		{
+			name:       "skips_runner_assigned_to_job",
+			action:     "completed",
+			conclusion: "cancelled",
+			runnerName: "runner-1",
+			builds: map[string]cloudbuildpb.Build_Status{
+				"build-1": cloudbuildpb.Build_WORKING,
+				"build-2": cloudbuildpb.Build_SUCCESS,
+			},
+			expGetBuilds: 1,
+		},
+		{
+			name:       "no_dispatch_record",
+			action:     "completed",
+			conclusion: "cancelled",
+			noRecord:   true,
+		},
+		{
+			name:       "successful_job",
+			action:     "completed",
+			conclusion: "success",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+
+			records := dispatch.NewMemoryStore()
+			if !tc.noRecord {
+				if err := records.Put(ctx, &dispatch.Record{
+					JobID: "789",
+					Runners: []*dispatch.Runner{
+						{Name: "runner-1", BuildID: "build-1", ProjectID: "project-1", Location: "us-west1"},
+						{Name: "runner-2", BuildID: "build-2", ProjectID: "project-1", Location: "us-west1"},
+						{Name: "warm-1", Warm: true},
+					},
+					Status: dispatch.StatusDispatched,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			builds := make(map[string]*cloudbuildpb.Build, len(tc.builds))
+			for id, status := range tc.builds {
+				builds[id] = &cloudbuildpb.Build{Id: id, Status: status}
+			}
+			mockCloudBuildClient := &cloudbuild.MockClient{Builds: builds}
+
+			runnerBusy := func(runnerName string) (bool, error) {
+				if runnerName == "warm-1" {
+					t.Errorf("unexpected busy check for warm runner %q", runnerName)
+				}
+				return tc.busyRunners[runnerName], tc.busyErr
+			}
+			mockGitHubClient := &gh.MockClient{
+				RepoRunnerBusyF: func(ctx context.Context, installationID int64, org, repo, runnerName string) (bool, error) {
+					if got, want := repo, repoName; got != want {
+						t.Errorf("expected repo %q to be %q", got, want)
+					}
+					return runnerBusy(runnerName)
+				},
+				OrgRunnerBusyF: func(ctx context.Context, installationID int64, org, runnerName string) (bool, error) {
+					return runnerBusy(runnerName)
+				},
+			}
+
+			cfg := &Config{
+				RunnerRegistrationScope: tc.scope,
+			}
+			srv := newTestServer(t, cfg, nil, &WebhookClientOptions{
+				CloudBuildClientOverride:    mockCloudBuildClient,
+				GitHubClientOverride:        mockGitHubClient,
+				DispatchRecordStoreOverride: records,
+			})
+
+			jobID := int64(789)
+			installationID := int64(123)
+			event := &github.WorkflowJobEvent{
+				Action: &tc.action,
+				WorkflowJob: &github.WorkflowJob{
+					ID:         &jobID,
+					Conclusion: &tc.conclusion,
+					RunnerName: &tc.runnerName,
+				},
+				Installation: &github.Installation{ID: &installationID},
+				Org:          &github.Organization{Login: github.Ptr(orgLogin)},
+				Repo:         &github.Repository{Name: github.Ptr(repoName)},
+			}
+
+			resp := httptest.NewRecorder()
+			srv.handleWebhook().ServeHTTP(resp, newWebhookRequest(t, event))
+
+			if got, want := resp.Code, http.StatusOK; got != want {
+				t.Errorf("expected %d to be %d", got, want)
+			}
+
+			var gotCancelled []string
+			for _, req := range mockCloudBuildClient.CancelBuildReqs {
+				gotCancelled = append(gotCancelled, req.GetId())
+				if got, want := req.GetName(), "projects/project-1/locations/us-west1/builds/"+req.GetId(); got != want {
+					t.Errorf("expected build name %q to be %q", got, want)
+				}
+			}
+			if diff := cmp.Diff(tc.expCancelled, gotCancelled); diff != "" {
+				t.Errorf("cancelled builds mismatch (-want +got):\n%s", diff)
+			}
+			if got, want := len(mockCloudBuildClient.GetBuildReqs), tc.expGetBuilds; got != want {
+				t.Errorf("expected %d GetBuild calls, got %d", want, got)
+			}
+			if got, want := mockGitHubClient.RepoRunnerBusyCalls+mockGitHubClient.OrgRunnerBusyCalls, tc.expBusyCalls; got != want {
+				t.Errorf("expected %d runner busy checks, got %d", want, got)
+			}
+		})
+	}
+}
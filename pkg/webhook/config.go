@@ -17,6 +17,9 @@ package webhook
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,17 +36,88 @@ const (
 
 	minRunnerExecutionTimeoutSeconds = 1 * 60 * 60  // 1 hour
 	maxRunnerExecutionTimeoutSeconds = 24 * 60 * 60 // 24 hours
+
+	// orgLabelWildcard matches any org or any label in settings keyed by
+	// "org:label".
+	orgLabelWildcard = "*"
+)
+
+const (
+	// RunnerRegistrationScopeRepo registers runners with the repository of the
+	// job they were dispatched for.
+	RunnerRegistrationScopeRepo = "repo"
+
+	// RunnerRegistrationScopeOrg registers runners with the organization of the
+	// job they were dispatched for, so they can pick up any of its queued jobs.
+	RunnerRegistrationScopeOrg = "org"
+
+	// RunnerRegistrationScopeEnterprise registers runners with the configured
+	// enterprise, so they can pick up queued jobs from any of its orgs.
+	RunnerRegistrationScopeEnterprise = "enterprise"
+)
+
+const (
+	// BudgetUnitMinutes measures a budget in runner-minutes.
+	BudgetUnitMinutes = "minutes"
+
+	// BudgetUnitDollars measures a budget in dollars estimated from the
+	// per-minute rates of the job's labels.
+	BudgetUnitDollars = "dollars"
+
+	// BudgetActionWarn only logs a warning for jobs of an org that has
+	// exhausted its budget.
+	BudgetActionWarn = "warn"
+
+	// BudgetActionRunner404 sends the jobs of an org that has exhausted its
+	// budget to the 404 runner.
+	BudgetActionRunner404 = "404"
+
+	// BudgetActionAlias dispatches the jobs of an org that has exhausted its
+	// budget to the cheaper labels configured as budget fallback aliases.
+	BudgetActionAlias = "alias"
 )
 
 // Config defines the set of environment variables required
 // for running the webhook service.
 type Config struct {
+	AdminAPIKeyMountPath           string        `env:"ADMIN_API_KEY_MOUNT_PATH"`
+	AdminAPIKeyName                string        `env:"ADMIN_API_KEY_NAME"`
 	BackoffInitialDelay            time.Duration `env:"BACKOFF_INITIAL_DELAY,default=500ms"`
+	BudgetsRaw                     []string      `env:"BUDGETS"`
+	Budgets                        map[string]*Budget
+	BudgetDefaultRate              float64  `env:"BUDGET_DEFAULT_RATE,default=0"`
+	BudgetExhaustedAction          string   `env:"BUDGET_EXHAUSTED_ACTION,default=warn"`
+	BudgetFallbackAliasesRaw       []string `env:"BUDGET_FALLBACK_ALIASES"`
+	BudgetFallbackAliases          map[string]string
+	BudgetRatesRaw                 []string `env:"BUDGET_RATES"`
+	BudgetRates                    map[string]float64
+	ConcurrencyQuotasRaw           []string `env:"CONCURRENCY_QUOTAS"`
+	ConcurrencyQuotas              map[string]int
+	ConcurrencyQuotaRetryDelay     time.Duration `env:"CONCURRENCY_QUOTA_RETRY_DELAY,default=30s"`
+	DispatchDedupTTL               time.Duration `env:"DISPATCH_DEDUP_TTL,default=24h"`
+	DispatchQueueWorkers           int           `env:"DISPATCH_QUEUE_WORKERS,default=0"`
+	DispatchQueueMaxAttempts       int           `env:"DISPATCH_QUEUE_MAX_ATTEMPTS,default=5"`
+	DispatchQueuePollInterval      time.Duration `env:"DISPATCH_QUEUE_POLL_INTERVAL,default=1s"`
+	DispatchQueueVisibilityTimeout time.Duration `env:"DISPATCH_QUEUE_VISIBILITY_TIMEOUT,default=5m"`
+	DispatchRecordTTL              time.Duration `env:"DISPATCH_RECORD_TTL,default=168h"`
+	DispatchPolicyFile             string        `env:"DISPATCH_POLICY_FILE"`
+	DryRun                         bool          `env:"DRY_RUN,default=false"`
 	Environment                    string        `env:"ENVIRONMENT,default=production"`
 	GitHubAPIBaseURL               string        `env:"GITHUB_API_BASE_URL,default=https://api.github.com"`
 	GitHubAppID                    string        `env:"GITHUB_APP_ID,required"`
+	GitHubAppsRaw                  []string      `env:"GITHUB_APPS"`
+	GitHubApps                     []*GitHubApp
+	GitHubEnterprise               string   `env:"GITHUB_ENTERPRISE"`
+	GitHubEnterpriseInstallationID int64    `env:"GITHUB_ENTERPRISE_INSTALLATION_ID"`
+	GitHubHostsRaw                 []string `env:"GITHUB_HOSTS"`
+	GitHubHosts                    []*GitHubHost
+	GitHubUploadURL                string        `env:"GITHUB_UPLOAD_URL"`
 	GitHubWebhookKeyMountPath      string        `env:"WEBHOOK_KEY_MOUNT_PATH,required"`
 	GitHubWebhookKeyName           string        `env:"WEBHOOK_KEY_NAME,required"`
+	GitHubWebhookExtraKeyNames     []string      `env:"WEBHOOK_EXTRA_KEY_NAMES"`
+	GitHubWebhookKeyReloadInterval time.Duration `env:"WEBHOOK_KEY_RELOAD_INTERVAL,default=1m"`
+	InFlightTrackingEnabled        bool          `env:"INFLIGHT_TRACKING_ENABLED,default=false"`
+	InFlightReconcileInterval      time.Duration `env:"INFLIGHT_RECONCILE_INTERVAL,default=5m"`
 	KMSAppPrivateKeyID             string        `env:"KMS_APP_PRIVATE_KEY_ID,required"`
 	MaxRetryAttempts               int           `env:"MAX_RETRY_ATTEMPTS,default=3"`
 	Port                           string        `env:"PORT,default=8080"`
@@ -66,11 +140,85 @@ type Config struct {
 	Runner404ProjectID             string        `env:"RUNNER_404_PROJECT_ID,required"`
 	Runner404ServiceAccount        string        `env:"RUNNER_404_SERVICE_ACCOUNT,required"`
 	Runner404WorkerPoolID          string        `env:"RUNNER_404_WORKER_POOL_ID"`
-	RunnerRegistryDefaultKeyPrefix string        `env:"RUNNER_REGISTRY_DEFAULT_KEY_PREFIX,default=default"`
-	RunnerLabelAliasesRaw          []string      `env:"RUNNER_LABEL_ALIASES"`
+	RunnerGroup                    string        `env:"RUNNER_GROUP"`
+	RunnerGroupsRaw                []string      `env:"RUNNER_GROUPS"`
+	RunnerGroups                   map[string]string
+	RunnerRegistrationScope        string   `env:"RUNNER_REGISTRATION_SCOPE,default=repo"`
+	RunnerRegistrationScopesRaw    []string `env:"RUNNER_REGISTRATION_SCOPES"`
+	RunnerRegistrationScopes       map[string]string
+	RunnerRegistryDefaultKeyPrefix string   `env:"RUNNER_REGISTRY_DEFAULT_KEY_PREFIX,default=default"`
+	RunnerLabelAliasesRaw          []string `env:"RUNNER_LABEL_ALIASES"`
 	RunnerLabelAliases             map[string]string
+	RunnerLabelPoliciesRaw         []string `env:"RUNNER_LABEL_POLICIES"`
+	RunnerLabelPolicies            map[string]*RunnerLabelPolicy
 	SupportedRunnerLabels          []string `env:"SUPPORTED_RUNNER_LABELS,required,delimiter=,"`
 	IgnoredRunnerLabels            []string `env:"IGNORED_RUNNER_LABELS,required,delimiter=,"`
+	WarmPoolSizesRaw               []string `env:"WARM_POOL_SIZES"`
+	WarmPoolLabelsRaw              []string `env:"WARM_POOL_LABELS"`
+	WarmPools                      []*WarmPoolConfig
+	WarmPoolReplenishInterval      time.Duration `env:"WARM_POOL_REPLENISH_INTERVAL,default=1m"`
+	WorkerPoolBreakerThreshold     int           `env:"WORKER_POOL_BREAKER_THRESHOLD,default=3"`
+	WorkerPoolBreakerCooldown      time.Duration `env:"WORKER_POOL_BREAKER_COOLDOWN,default=5m"`
+}
+
+// RunnerLabelPolicy controls how runners are provisioned for jobs dispatched
+// to pools of a label. Settings that are not overridden for a label take the
+// global value.
+type RunnerLabelPolicy struct {
+	ExtraRunnerCount              int `json:"extra_runner_count"`
+	RunnerIdleTimeoutSeconds      int `json:"idle_timeout_seconds"`
+	RunnerExecutionTimeoutSeconds int `json:"execution_timeout_seconds"`
+}
+
+// GitHubHost is a GitHub instance served in addition to the default one
+// configured by the top-level GitHub settings. Webhooks for a host are
+// delivered to /webhook/<name>, or to /webhook with its hostname in the
+// X-GitHub-Enterprise-Host header.
+type GitHubHost struct {
+	Name                 string   `json:"name"`
+	Hostname             string   `json:"hostname"`
+	APIBaseURL           string   `json:"api_base_url"`
+	UploadURL            string   `json:"upload_url"`
+	AppID                string   `json:"app_id"`
+	KMSAppPrivateKeyID   string   `json:"kms_app_private_key_id"`
+	WebhookKeyName       string   `json:"webhook_key_name"`
+	WebhookExtraKeyNames []string `json:"webhook_extra_key_names"`
+}
+
+// GitHubApp is a GitHub App served in addition to the default one configured by
+// the top-level app settings. Webhooks are routed to an app by the
+// X-GitHub-Hook-Installation-Target-ID header, which GitHub sets to the ID of
+// the app that the delivery is for.
+type GitHubApp struct {
+	Name                 string   `json:"name"`
+	AppID                string   `json:"app_id"`
+	KMSAppPrivateKeyID   string   `json:"kms_app_private_key_id"`
+	WebhookKeyName       string   `json:"webhook_key_name"`
+	WebhookExtraKeyNames []string `json:"webhook_extra_key_names"`
+}
+
+// Budget is the runner usage an org is allowed each calendar month.
+type Budget struct {
+	Unit  string  `json:"unit"`
+	Limit float64 `json:"limit"`
+}
+
+// WarmPoolConfig is the number of idle runners to keep warm for an org and
+// label. Warm runners are registered with the org, or with the enterprise if
+// the label is registered with the enterprise. Labels are registered on warm
+// runners in addition to Label, so that they can serve jobs that request them
+// too.
+type WarmPoolConfig struct {
+	Org    string   `json:"org"`
+	Label  string   `json:"label"`
+	Size   int      `json:"size"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// runnerLabels returns the labels that the warm pool's runners are registered
+// with.
+func (wp *WarmPoolConfig) runnerLabels() []string {
+	return append([]string{wp.Label}, wp.Labels...)
 }
 
 // Validate validates the webhook config after load.
@@ -91,10 +239,22 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("WEBHOOK_KEY_NAME is required")
 	}
 
+	if slices.Contains(cfg.GitHubWebhookExtraKeyNames, cfg.GitHubWebhookKeyName) {
+		return fmt.Errorf("WEBHOOK_EXTRA_KEY_NAMES must not contain WEBHOOK_KEY_NAME %q", cfg.GitHubWebhookKeyName)
+	}
+
+	if cfg.GitHubWebhookKeyReloadInterval < 0 {
+		return fmt.Errorf("WEBHOOK_KEY_RELOAD_INTERVAL must not be negative, got %s", cfg.GitHubWebhookKeyReloadInterval)
+	}
+
 	if cfg.KMSAppPrivateKeyID == "" {
 		return fmt.Errorf("KMS_APP_PRIVATE_KEY_ID is required")
 	}
 
+	if (cfg.AdminAPIKeyMountPath == "") != (cfg.AdminAPIKeyName == "") {
+		return fmt.Errorf("ADMIN_API_KEY_MOUNT_PATH and ADMIN_API_KEY_NAME must be set together")
+	}
+
 	if cfg.RunnerLocation == "" {
 		return fmt.Errorf("RUNNER_LOCATION is required")
 	}
@@ -148,6 +308,44 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("RUNNER_EXECUTION_TIMEOUT_SECONDS must be between %d (1 hour) and %d (24 hours) seconds, got %d", minRunnerExecutionTimeoutSeconds, maxRunnerExecutionTimeoutSeconds, cfg.RunnerExecutionTimeoutSeconds)
 	}
 
+	if cfg.DispatchDedupTTL < 0 {
+		return fmt.Errorf("DISPATCH_DEDUP_TTL must be non-negative, got %s", cfg.DispatchDedupTTL)
+	}
+
+	if cfg.DispatchRecordTTL < 0 {
+		return fmt.Errorf("DISPATCH_RECORD_TTL must be non-negative, got %s", cfg.DispatchRecordTTL)
+	}
+
+	if cfg.DispatchQueueWorkers < 0 {
+		return fmt.Errorf("DISPATCH_QUEUE_WORKERS must be non-negative, got %d", cfg.DispatchQueueWorkers)
+	}
+
+	if cfg.InFlightReconcileInterval < 0 {
+		return fmt.Errorf("INFLIGHT_RECONCILE_INTERVAL must be non-negative, got %s", cfg.InFlightReconcileInterval)
+	}
+
+	if cfg.WorkerPoolBreakerThreshold < 0 {
+		return fmt.Errorf("WORKER_POOL_BREAKER_THRESHOLD must be non-negative, got %d", cfg.WorkerPoolBreakerThreshold)
+	}
+
+	if cfg.WorkerPoolBreakerThreshold > 0 && cfg.WorkerPoolBreakerCooldown <= 0 {
+		return fmt.Errorf("WORKER_POOL_BREAKER_COOLDOWN must be positive, got %s", cfg.WorkerPoolBreakerCooldown)
+	}
+
+	if cfg.DispatchQueueWorkers > 0 {
+		if cfg.DispatchQueueMaxAttempts < 1 {
+			return fmt.Errorf("DISPATCH_QUEUE_MAX_ATTEMPTS must be at least 1, got %d", cfg.DispatchQueueMaxAttempts)
+		}
+
+		if cfg.DispatchQueuePollInterval <= 0 {
+			return fmt.Errorf("DISPATCH_QUEUE_POLL_INTERVAL must be positive, got %s", cfg.DispatchQueuePollInterval)
+		}
+
+		if cfg.DispatchQueueVisibilityTimeout <= 0 {
+			return fmt.Errorf("DISPATCH_QUEUE_VISIBILITY_TIMEOUT must be positive, got %s", cfg.DispatchQueueVisibilityTimeout)
+		}
+	}
+
 	if len(cfg.SupportedRunnerLabels) == 0 {
 		return fmt.Errorf("SUPPORTED_RUNNER_LABELS must be provided")
 	}
@@ -176,9 +374,483 @@ func (cfg *Config) Validate() error {
 		cfg.RunnerLabelAliases[aliasKey] = aliasTarget
 	}
 
+	cfg.RunnerLabelPolicies = make(map[string]*RunnerLabelPolicy)
+	for _, policyString := range cfg.RunnerLabelPoliciesRaw {
+		label, policy, err := cfg.parseRunnerLabelPolicy(policyString)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := supportedLabelsMap[label]; !ok {
+			return fmt.Errorf("runner label policy label %q is not present in SUPPORTED_RUNNER_LABELS", label)
+		}
+		if _, ok := cfg.RunnerLabelPolicies[label]; ok {
+			return fmt.Errorf("duplicate runner label policy for label %q", label)
+		}
+		cfg.RunnerLabelPolicies[label] = policy
+	}
+
+	if (cfg.GitHubEnterprise == "") != (cfg.GitHubEnterpriseInstallationID == 0) {
+		return fmt.Errorf("GITHUB_ENTERPRISE and GITHUB_ENTERPRISE_INSTALLATION_ID must be set together")
+	}
+
+	if err := cfg.validateRunnerRegistrationScope(cfg.RunnerRegistrationScope); err != nil {
+		return fmt.Errorf("RUNNER_REGISTRATION_SCOPE %w", err)
+	}
+
+	cfg.RunnerRegistrationScopes = make(map[string]string)
+	for _, scopeString := range cfg.RunnerRegistrationScopesRaw {
+		key, scope, ok := strings.Cut(scopeString, "=")
+		if !ok {
+			return fmt.Errorf("invalid runner registration scope format %q, expected org:label=scope", scopeString)
+		}
+		if err := validateOrgLabelKey(key, supportedLabelsMap); err != nil {
+			return fmt.Errorf("invalid runner registration scope %q: %w", scopeString, err)
+		}
+		if err := cfg.validateRunnerRegistrationScope(scope); err != nil {
+			return fmt.Errorf("runner registration scope for %q %w", key, err)
+		}
+		if _, ok := cfg.RunnerRegistrationScopes[key]; ok {
+			return fmt.Errorf("duplicate runner registration scope for %q", key)
+		}
+		cfg.RunnerRegistrationScopes[key] = scope
+	}
+
+	cfg.RunnerGroups = make(map[string]string)
+	for _, groupString := range cfg.RunnerGroupsRaw {
+		key, group, ok := strings.Cut(groupString, "=")
+		if !ok || group == "" {
+			return fmt.Errorf("invalid runner group format %q, expected org:label=group", groupString)
+		}
+		if err := validateOrgLabelKey(key, supportedLabelsMap); err != nil {
+			return fmt.Errorf("invalid runner group %q: %w", groupString, err)
+		}
+		if _, ok := cfg.RunnerGroups[key]; ok {
+			return fmt.Errorf("duplicate runner group for %q", key)
+		}
+		cfg.RunnerGroups[key] = group
+	}
+
+	cfg.GitHubApps = nil
+	seenApps := make(map[string]bool)
+	seenAppIDs := map[string]bool{cfg.GitHubAppID: true}
+	for _, appString := range cfg.GitHubAppsRaw {
+		app, err := cfg.parseGitHubApp(appString)
+		if err != nil {
+			return err
+		}
+		if seenApps[app.Name] {
+			return fmt.Errorf("duplicate github app %q", app.Name)
+		}
+		if seenAppIDs[app.AppID] {
+			return fmt.Errorf("duplicate github app id %q", app.AppID)
+		}
+		seenApps[app.Name] = true
+		seenAppIDs[app.AppID] = true
+		cfg.GitHubApps = append(cfg.GitHubApps, app)
+	}
+
+	cfg.GitHubHosts = nil
+	seenHosts := make(map[string]bool)
+	seenHostnames := make(map[string]bool)
+	for _, hostString := range cfg.GitHubHostsRaw {
+		host, err := cfg.parseGitHubHost(hostString)
+		if err != nil {
+			return err
+		}
+		if seenHosts[host.Name] {
+			return fmt.Errorf("duplicate github host %q", host.Name)
+		}
+		if seenHostnames[host.Hostname] {
+			return fmt.Errorf("duplicate github host hostname %q", host.Hostname)
+		}
+		seenHosts[host.Name] = true
+		seenHostnames[host.Hostname] = true
+		cfg.GitHubHosts = append(cfg.GitHubHosts, host)
+	}
+
+	cfg.WarmPools = nil
+	seenWarmPools := make(map[string]*WarmPoolConfig)
+	for _, warmPoolString := range cfg.WarmPoolSizesRaw {
+		key, rawSize, ok := strings.Cut(warmPoolString, "=")
+		org, label, okKey := strings.Cut(key, ":")
+		if !ok || !okKey || org == "" || label == "" {
+			return fmt.Errorf("invalid warm pool size format %q, expected org:label=size", warmPoolString)
+		}
+		size, err := strconv.Atoi(rawSize)
+		if err != nil || size < 1 {
+			return fmt.Errorf("warm pool size for %q must be a positive integer, got %q", key, rawSize)
+		}
+		if _, ok := supportedLabelsMap[label]; !ok {
+			return fmt.Errorf("warm pool label %q is not present in SUPPORTED_RUNNER_LABELS", label)
+		}
+		if seenWarmPools[key] != nil {
+			return fmt.Errorf("duplicate warm pool size for %q", key)
+		}
+		wp := &WarmPoolConfig{Org: org, Label: label, Size: size}
+		seenWarmPools[key] = wp
+		cfg.WarmPools = append(cfg.WarmPools, wp)
+	}
+
+	for _, warmPoolLabelsString := range cfg.WarmPoolLabelsRaw {
+		key, rawLabels, ok := strings.Cut(warmPoolLabelsString, "=")
+		if !ok || rawLabels == "" {
+			return fmt.Errorf("invalid warm pool labels format %q, expected org:label=label1;label2", warmPoolLabelsString)
+		}
+		wp := seenWarmPools[key]
+		if wp == nil {
+			return fmt.Errorf("warm pool labels for %q have no matching WARM_POOL_SIZES entry", key)
+		}
+		if len(wp.Labels) > 0 {
+			return fmt.Errorf("duplicate warm pool labels for %q", key)
+		}
+		wp.Labels = strings.Split(rawLabels, ";")
+	}
+
+	if len(cfg.WarmPools) > 0 && cfg.WarmPoolReplenishInterval <= 0 {
+		return fmt.Errorf("WARM_POOL_REPLENISH_INTERVAL must be positive, got %s", cfg.WarmPoolReplenishInterval)
+	}
+
+	cfg.ConcurrencyQuotas = make(map[string]int)
+	for _, quotaString := range cfg.ConcurrencyQuotasRaw {
+		key, rawLimit, ok := strings.Cut(quotaString, "=")
+		org, repo, hasRepo := strings.Cut(key, "/")
+		if !ok || org == "" || (hasRepo && (repo == "" || strings.Contains(repo, "/"))) {
+			return fmt.Errorf("invalid concurrency quota format %q, expected org=limit or org/repo=limit", quotaString)
+		}
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 {
+			return fmt.Errorf("concurrency quota for %q must be a positive integer, got %q", key, rawLimit)
+		}
+		if _, ok := cfg.ConcurrencyQuotas[key]; ok {
+			return fmt.Errorf("duplicate concurrency quota for %q", key)
+		}
+		cfg.ConcurrencyQuotas[key] = limit
+	}
+
+	// Jobs over quota are deferred by putting them back on the dispatch queue.
+	if len(cfg.ConcurrencyQuotas) > 0 {
+		if cfg.DispatchQueueWorkers <= 0 {
+			return fmt.Errorf("CONCURRENCY_QUOTAS requires DISPATCH_QUEUE_WORKERS to be positive")
+		}
+		if cfg.ConcurrencyQuotaRetryDelay <= 0 {
+			return fmt.Errorf("CONCURRENCY_QUOTA_RETRY_DELAY must be positive, got %s", cfg.ConcurrencyQuotaRetryDelay)
+		}
+	}
+
+	if err := cfg.validateBudgets(supportedLabelsMap); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// validateBudgets parses the org budgets, the per-minute rates of labels and
+// the fallback aliases used once a budget is exhausted.
+func (cfg *Config) validateBudgets(supportedLabels map[string]bool) error {
+	cfg.Budgets = make(map[string]*Budget)
+	for _, budgetString := range cfg.BudgetsRaw {
+		org, value, ok := strings.Cut(budgetString, "=")
+		unit, rawLimit, okValue := strings.Cut(value, ":")
+		if !ok || !okValue || org == "" {
+			return fmt.Errorf("invalid budget format %q, expected org=unit:limit", budgetString)
+		}
+		if unit != BudgetUnitMinutes && unit != BudgetUnitDollars {
+			return fmt.Errorf("budget unit for %q must be %q or %q, got %q", org, BudgetUnitMinutes, BudgetUnitDollars, unit)
+		}
+		limit, err := strconv.ParseFloat(rawLimit, 64)
+		if err != nil || limit <= 0 {
+			return fmt.Errorf("budget limit for %q must be a positive number, got %q", org, rawLimit)
+		}
+		if _, ok := cfg.Budgets[org]; ok {
+			return fmt.Errorf("duplicate budget for %q", org)
+		}
+		cfg.Budgets[org] = &Budget{Unit: unit, Limit: limit}
+	}
+
+	if cfg.BudgetDefaultRate < 0 {
+		return fmt.Errorf("BUDGET_DEFAULT_RATE must be non-negative, got %g", cfg.BudgetDefaultRate)
+	}
+
+	cfg.BudgetRates = make(map[string]float64)
+	for _, rateString := range cfg.BudgetRatesRaw {
+		label, rawRate, ok := strings.Cut(rateString, "=")
+		if !ok || label == "" {
+			return fmt.Errorf("invalid budget rate format %q, expected label=dollars_per_minute", rateString)
+		}
+		if !supportedLabels[label] {
+			return fmt.Errorf("budget rate label %q is not present in SUPPORTED_RUNNER_LABELS", label)
+		}
+		rate, err := strconv.ParseFloat(rawRate, 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("budget rate for %q must be a non-negative number, got %q", label, rawRate)
+		}
+		cfg.BudgetRates[label] = rate
+	}
+
+	// Budgets in dollars would never be used up by jobs on labels without a
+	// rate, so every label needs one.
+	if cfg.BudgetDefaultRate == 0 && slices.ContainsFunc(slices.Collect(maps.Values(cfg.Budgets)), func(b *Budget) bool {
+		return b.Unit == BudgetUnitDollars
+	}) {
+		var missing []string
+		for label := range supportedLabels {
+			if _, ok := cfg.BudgetRates[label]; !ok {
+				missing = append(missing, label)
+			}
+		}
+		if len(missing) > 0 {
+			slices.Sort(missing)
+			return fmt.Errorf("budgets in %s require a positive BUDGET_DEFAULT_RATE or a BUDGET_RATES entry for every supported label, missing %s",
+				BudgetUnitDollars, strings.Join(missing, ", "))
+		}
+	}
+
+	cfg.BudgetFallbackAliases = make(map[string]string)
+	for _, aliasString := range cfg.BudgetFallbackAliasesRaw {
+		label, target, ok := strings.Cut(aliasString, "=")
+		if !ok || label == "" {
+			return fmt.Errorf("invalid budget fallback alias format %q, expected label=cheaper_label", aliasString)
+		}
+		if !supportedLabels[target] {
+			return fmt.Errorf("budget fallback alias target %q is not present in SUPPORTED_RUNNER_LABELS", target)
+		}
+		cfg.BudgetFallbackAliases[label] = target
+	}
+
+	if len(cfg.Budgets) == 0 {
+		return nil
+	}
+	switch cfg.BudgetExhaustedAction {
+	case BudgetActionWarn:
+	case BudgetActionRunner404:
+		if !cfg.Runner404Enabled {
+			return fmt.Errorf("BUDGET_EXHAUSTED_ACTION %q requires RUNNER_404_ENABLED", BudgetActionRunner404)
+		}
+	case BudgetActionAlias:
+		if len(cfg.BudgetFallbackAliases) == 0 {
+			return fmt.Errorf("BUDGET_EXHAUSTED_ACTION %q requires BUDGET_FALLBACK_ALIASES", BudgetActionAlias)
+		}
+	default:
+		return fmt.Errorf("BUDGET_EXHAUSTED_ACTION must be one of %q, %q or %q, got %q",
+			BudgetActionWarn, BudgetActionRunner404, BudgetActionAlias, cfg.BudgetExhaustedAction)
+	}
+	return nil
+}
+
+// parseRunnerLabelPolicy parses a runner label policy of the form
+// "label=key=value;key=value", starting from the global settings.
+func (cfg *Config) parseRunnerLabelPolicy(policyString string) (string, *RunnerLabelPolicy, error) {
+	label, settings, ok := strings.Cut(policyString, "=")
+	if !ok || label == "" || settings == "" {
+		return "", nil, fmt.Errorf("invalid runner label policy format %q, expected label=key=value;key=value", policyString)
+	}
+
+	policy := &RunnerLabelPolicy{
+		ExtraRunnerCount:              cfg.ExtraRunnerCount,
+		RunnerIdleTimeoutSeconds:      cfg.RunnerIdleTimeoutSeconds,
+		RunnerExecutionTimeoutSeconds: cfg.RunnerExecutionTimeoutSeconds,
+	}
+	for setting := range strings.SplitSeq(settings, ";") {
+		key, rawValue, ok := strings.Cut(setting, "=")
+		if !ok {
+			return "", nil, fmt.Errorf("invalid runner label policy setting %q for label %q, expected key=value", setting, label)
+		}
+		value, err := strconv.Atoi(rawValue)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid runner label policy value for %s on label %q: %w", key, label, err)
+		}
+
+		switch key {
+		case "extra_runner_count":
+			if value < 0 {
+				return "", nil, fmt.Errorf("runner label policy extra_runner_count for label %q must be non-negative, got %d", label, value)
+			}
+			policy.ExtraRunnerCount = value
+		case "idle_timeout_seconds":
+			if value < minRunnerIdleTimeoutSeconds || value > maxRunnerIdleTimeoutSeconds {
+				return "", nil, fmt.Errorf("runner label policy idle_timeout_seconds for label %q must be between %d (5 minutes) and %d (24 hours) seconds, got %d", label, minRunnerIdleTimeoutSeconds, maxRunnerIdleTimeoutSeconds, value)
+			}
+			policy.RunnerIdleTimeoutSeconds = value
+		case "execution_timeout_seconds":
+			if value < minRunnerExecutionTimeoutSeconds || value > maxRunnerExecutionTimeoutSeconds {
+				return "", nil, fmt.Errorf("runner label policy execution_timeout_seconds for label %q must be between %d (1 hour) and %d (24 hours) seconds, got %d", label, minRunnerExecutionTimeoutSeconds, maxRunnerExecutionTimeoutSeconds, value)
+			}
+			policy.RunnerExecutionTimeoutSeconds = value
+		default:
+			return "", nil, fmt.Errorf("unknown runner label policy setting %q for label %q, expected one of extra_runner_count, idle_timeout_seconds or execution_timeout_seconds", key, label)
+		}
+	}
+	return label, policy, nil
+}
+
+// validateRunnerRegistrationScope validates a runner registration scope. The
+// enterprise scope requires an enterprise to be configured.
+func (cfg *Config) validateRunnerRegistrationScope(scope string) error {
+	switch scope {
+	case RunnerRegistrationScopeRepo, RunnerRegistrationScopeOrg:
+		return nil
+	case RunnerRegistrationScopeEnterprise:
+		if cfg.GitHubEnterprise == "" {
+			return fmt.Errorf("%q requires GITHUB_ENTERPRISE to be set", scope)
+		}
+		return nil
+	default:
+		return fmt.Errorf("must be one of %q, %q or %q, got %q", RunnerRegistrationScopeRepo, RunnerRegistrationScopeOrg, RunnerRegistrationScopeEnterprise, scope)
+	}
+}
+
+// validateOrgLabelKey validates a settings key of the form "org:label", where
+// either side may be the "*" wildcard.
+func validateOrgLabelKey(key string, supportedLabels map[string]bool) error {
+	org, label, ok := strings.Cut(key, ":")
+	if !ok || org == "" || label == "" {
+		return fmt.Errorf("expected key of the form org:label, got %q", key)
+	}
+	if label != orgLabelWildcard && !supportedLabels[label] {
+		return fmt.Errorf("label %q is not present in SUPPORTED_RUNNER_LABELS", label)
+	}
+	return nil
+}
+
+// parseGitHubHost parses a GitHub host of the form "name=key=value;key=value".
+// The app ID, KMS key and webhook secrets default to the top-level settings,
+// and the upload URL defaults to the API base URL.
+func (cfg *Config) parseGitHubHost(hostString string) (*GitHubHost, error) {
+	name, settings, ok := strings.Cut(hostString, "=")
+	if !ok || name == "" || settings == "" {
+		return nil, fmt.Errorf("invalid github host format %q, expected name=key=value;key=value", hostString)
+	}
+	if !validName(name) {
+		return nil, fmt.Errorf("github host name %q must contain only lowercase letters, digits and hyphens", name)
+	}
+
+	host := &GitHubHost{
+		Name:               name,
+		AppID:              cfg.GitHubAppID,
+		KMSAppPrivateKeyID: cfg.KMSAppPrivateKeyID,
+		WebhookKeyName:     cfg.GitHubWebhookKeyName,
+	}
+	var extraKeyNames []string
+	for setting := range strings.SplitSeq(settings, ";") {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid github host setting %q for host %q, expected key=value", setting, name)
+		}
+
+		switch key {
+		case "hostname":
+			host.Hostname = value
+		case "api_base_url":
+			host.APIBaseURL = value
+		case "upload_url":
+			host.UploadURL = value
+		case "app_id":
+			host.AppID = value
+		case "kms_app_private_key_id":
+			host.KMSAppPrivateKeyID = value
+		case "webhook_key_name":
+			host.WebhookKeyName = value
+		case "webhook_extra_key_name":
+			extraKeyNames = append(extraKeyNames, value)
+		default:
+			return nil, fmt.Errorf("unknown github host setting %q for host %q, expected one of hostname, api_base_url, upload_url, app_id, kms_app_private_key_id, webhook_key_name or webhook_extra_key_name", key, name)
+		}
+	}
+
+	keyNames, err := cfg.webhookExtraKeyNames(host.WebhookKeyName, extraKeyNames)
+	if err != nil {
+		return nil, fmt.Errorf("github host %q: %w", name, err)
+	}
+	host.WebhookExtraKeyNames = keyNames
+
+	if host.Hostname == "" {
+		return nil, fmt.Errorf("github host %q is missing hostname", name)
+	}
+	if host.APIBaseURL == "" {
+		return nil, fmt.Errorf("github host %q is missing api_base_url", name)
+	}
+	if host.UploadURL == "" {
+		host.UploadURL = host.APIBaseURL
+	}
+	return host, nil
+}
+
+// parseGitHubApp parses a GitHub app of the form "name=key=value;key=value".
+// The webhook secrets default to the top-level settings.
+func (cfg *Config) parseGitHubApp(appString string) (*GitHubApp, error) {
+	name, settings, ok := strings.Cut(appString, "=")
+	if !ok || name == "" || settings == "" {
+		return nil, fmt.Errorf("invalid github app format %q, expected name=key=value;key=value", appString)
+	}
+	if !validName(name) {
+		return nil, fmt.Errorf("github app name %q must contain only lowercase letters, digits and hyphens", name)
+	}
+
+	app := &GitHubApp{
+		Name:           name,
+		WebhookKeyName: cfg.GitHubWebhookKeyName,
+	}
+	var extraKeyNames []string
+	for setting := range strings.SplitSeq(settings, ";") {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid github app setting %q for app %q, expected key=value", setting, name)
+		}
+
+		switch key {
+		case "app_id":
+			app.AppID = value
+		case "kms_app_private_key_id":
+			app.KMSAppPrivateKeyID = value
+		case "webhook_key_name":
+			app.WebhookKeyName = value
+		case "webhook_extra_key_name":
+			extraKeyNames = append(extraKeyNames, value)
+		default:
+			return nil, fmt.Errorf("unknown github app setting %q for app %q, expected one of app_id, kms_app_private_key_id, webhook_key_name or webhook_extra_key_name", key, name)
+		}
+	}
+
+	keyNames, err := cfg.webhookExtraKeyNames(app.WebhookKeyName, extraKeyNames)
+	if err != nil {
+		return nil, fmt.Errorf("github app %q: %w", name, err)
+	}
+	app.WebhookExtraKeyNames = keyNames
+
+	if app.AppID == "" {
+		return nil, fmt.Errorf("github app %q is missing app_id", name)
+	}
+	if app.KMSAppPrivateKeyID == "" {
+		return nil, fmt.Errorf("github app %q is missing kms_app_private_key_id", name)
+	}
+	return app, nil
+}
+
+// webhookExtraKeyNames returns the names of the webhook keys that are accepted
+// alongside keyName while it is rotated. A host or app that shares the
+// top-level webhook key also shares its extra keys, unless it names its own.
+func (cfg *Config) webhookExtraKeyNames(keyName string, extraKeyNames []string) ([]string, error) {
+	if extraKeyNames == nil && keyName == cfg.GitHubWebhookKeyName {
+		return cfg.GitHubWebhookExtraKeyNames, nil
+	}
+	if slices.Contains(extraKeyNames, keyName) {
+		return nil, fmt.Errorf("webhook_extra_key_name must not be webhook_key_name %q", keyName)
+	}
+	return extraKeyNames, nil
+}
+
+// validName reports whether the name of a GitHub host or app can be used in
+// webhook paths, Redis keys and GCP project labels.
+func validName(name string) bool {
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
 // NewConfig creates a new Config from environment variables.
 func NewConfig(ctx context.Context) (*Config, error) {
 	return newConfig(ctx, envconfig.OsLookuper())
@@ -203,6 +875,13 @@ func (cfg *Config) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:  `The unique ID for an E2E test run, used for tagging builds.`,
 	})
 
+	f.BoolVar(&cli.BoolVar{
+		Name:   "dry-run",
+		Target: &cfg.DryRun,
+		EnvVar: "DRY_RUN",
+		Usage:  `Whether to skip generating JIT configs and creating builds. The build requests that would have been created, and the worker pools chosen for them, are logged and returned in the webhook response instead. No dispatcher state is stored in dry-run mode.`,
+	})
+
 	f.StringVar(&cli.StringVar{
 		Name:    "environment",
 		Target:  &cfg.Environment,
@@ -234,6 +913,20 @@ func (cfg *Config) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   `The GitHub API URL.`,
 	})
 
+	f.StringVar(&cli.StringVar{
+		Name:   "github-upload-url",
+		Target: &cfg.GitHubUploadURL,
+		EnvVar: "GITHUB_UPLOAD_URL",
+		Usage:  `The GitHub upload URL. Defaults to the GitHub API URL.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:   "github-hosts",
+		Target: &cfg.GitHubHostsRaw,
+		EnvVar: "GITHUB_HOSTS",
+		Usage:  `List of additional GitHub instances to serve, each with a hostname and api_base_url and optional upload_url, app_id, kms_app_private_key_id and webhook_key_name overrides of the top-level settings (e.g., "ghes1=hostname=github.example.com;api_base_url=https://github.example.com/api/v3"). webhook_extra_key_name may be repeated to accept more webhook keys while the host's secret is rotated. Webhooks are routed to a host by the /webhook/<name> path or the X-GitHub-Enterprise-Host header, and its registry keys are prefixed with "<name>/".`,
+	})
+
 	f.StringVar(&cli.StringVar{
 		Name:   "github-app-id",
 		Target: &cfg.GitHubAppID,
@@ -241,6 +934,27 @@ func (cfg *Config) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:  `The provisioned GitHub App reference.`,
 	})
 
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:   "github-apps",
+		Target: &cfg.GitHubAppsRaw,
+		EnvVar: "GITHUB_APPS",
+		Usage:  `List of additional GitHub Apps to serve on the default GitHub host, each with an app_id and kms_app_private_key_id and an optional webhook_key_name (e.g., "team-a=app_id=12345;kms_app_private_key_id=projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1;webhook_key_name=team-a-webhook-key"). webhook_extra_key_name may be repeated to accept more webhook keys while the app's secret is rotated. Webhooks are routed to an app by the X-GitHub-Hook-Installation-Target-ID header.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "github-enterprise",
+		Target: &cfg.GitHubEnterprise,
+		EnvVar: "GITHUB_ENTERPRISE",
+		Usage:  `The slug of the GitHub enterprise the app is installed on. When set, jobs from any org are routed to worker pools registered for the enterprise before falling back to the default pools.`,
+	})
+
+	f.Int64Var(&cli.Int64Var{
+		Name:   "github-enterprise-installation-id",
+		Target: &cfg.GitHubEnterpriseInstallationID,
+		EnvVar: "GITHUB_ENTERPRISE_INSTALLATION_ID",
+		Usage:  `The ID of the app's installation on the GitHub enterprise, used to register enterprise-level runners. The installation needs the enterprise "Self-hosted runners" permission.`,
+	})
+
 	f.StringVar(&cli.StringVar{
 		Name:   "kms-app-private-key-id",
 		Target: &cfg.KMSAppPrivateKeyID,
@@ -277,6 +991,21 @@ func (cfg *Config) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:  `GitHub webhook key name.`,
 	})
 
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:   "github-webhook-extra-key-names",
+		Target: &cfg.GitHubWebhookExtraKeyNames,
+		EnvVar: "WEBHOOK_EXTRA_KEY_NAMES",
+		Usage:  `Names of additional webhook keys in the webhook key mount path that are accepted while the webhook secret is rotated. Keys that are not mounted are skipped.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "github-webhook-key-reload-interval",
+		Target:  &cfg.GitHubWebhookKeyReloadInterval,
+		EnvVar:  "WEBHOOK_KEY_RELOAD_INTERVAL",
+		Default: time.Minute,
+		Usage:   `How often the webhook keys are re-read from the webhook key mount path so that new secret versions are used without a restart. Files in the mount path named after a webhook key with a "." and a version suffix (e.g., "webhook-key.2") are found on each reload and accepted as versions of that key. Set to 0 to disable.`,
+	})
+
 	f.StringVar(&cli.StringVar{
 		Name:    "runner-image-name",
 		Target:  &cfg.RunnerImageName,
@@ -337,6 +1066,22 @@ func (cfg *Config) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   `The timeout for the entire build in seconds. Must be between 3600 (1 hour) and 86400 (24 hours).`,
 	})
 
+	f.DurationVar(&cli.DurationVar{
+		Name:    "dispatch-dedup-ttl",
+		Target:  &cfg.DispatchDedupTTL,
+		EnvVar:  "DISPATCH_DEDUP_TTL",
+		Default: 24 * time.Hour,
+		Usage:   `How long dispatched deliveries and jobs are remembered so that redeliveries do not start additional runners. Set to 0 to disable.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "dispatch-record-ttl",
+		Target:  &cfg.DispatchRecordTTL,
+		EnvVar:  "DISPATCH_RECORD_TTL",
+		Default: 7 * 24 * time.Hour,
+		Usage:   `How long records of the runners and builds dispatched for each job are kept. The records are used to cancel the builds of jobs that are cancelled before they run. Set to 0 to disable.`,
+	})
+
 	f.StringSliceVar(&cli.StringSliceVar{
 		Name:   "runner-label-aliases",
 		Target: &cfg.RunnerLabelAliasesRaw,
@@ -344,6 +1089,42 @@ func (cfg *Config) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:  `List of user-provided labels aliasing to system labels (e.g., "key=value,key2=value2").`,
 	})
 
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:   "runner-label-policies",
+		Target: &cfg.RunnerLabelPoliciesRaw,
+		EnvVar: "RUNNER_LABEL_POLICIES",
+		Usage:  `List of per-label overrides of extra_runner_count, idle_timeout_seconds and execution_timeout_seconds, falling back to the global settings (e.g., "large=extra_runner_count=2;idle_timeout_seconds=600").`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "runner-group",
+		Target: &cfg.RunnerGroup,
+		EnvVar: "RUNNER_GROUP",
+		Usage:  `The runner group org-level runners are registered in, by ID or name. Runners are registered in the Default group when unset. Repository-level runners are always registered in the Default group.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:   "runner-groups",
+		Target: &cfg.RunnerGroupsRaw,
+		EnvVar: "RUNNER_GROUPS",
+		Usage:  `List of per-org and per-label overrides of the runner group, by ID or name, where either side may be "*" (e.g., "google:large=5,google:*=trusted-runners"). Numeric values are treated as IDs. Group names are resolved through the GitHub API.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "runner-registration-scope",
+		Target:  &cfg.RunnerRegistrationScope,
+		EnvVar:  "RUNNER_REGISTRATION_SCOPE",
+		Default: RunnerRegistrationScopeRepo,
+		Usage:   `Whether runners are registered with the job's repository ("repo"), organization ("org") or the configured enterprise ("enterprise") by default.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:   "runner-registration-scopes",
+		Target: &cfg.RunnerRegistrationScopesRaw,
+		EnvVar: "RUNNER_REGISTRATION_SCOPES",
+		Usage:  `List of per-org and per-label overrides of the runner registration scope, where either side may be "*" (e.g., "google:*=org,*:large=repo"). An org and label match takes precedence over an org match, which takes precedence over a label match.`,
+	})
+
 	f.StringSliceVar(&cli.StringSliceVar{
 		Name:   "supported-runner-labels",
 		Target: &cfg.SupportedRunnerLabels,
@@ -415,6 +1196,180 @@ func (cfg *Config) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:  `The private runner worker pool ID`,
 	})
 
+	qf := set.NewSection("DISPATCH QUEUE OPTIONS")
+
+	qf.IntVar(&cli.IntVar{
+		Name:    "dispatch-queue-workers",
+		Target:  &cfg.DispatchQueueWorkers,
+		EnvVar:  "DISPATCH_QUEUE_WORKERS",
+		Default: 0,
+		Usage:   `The number of workers dispatching queued jobs in the background. When greater than 0, queued events are persisted and acknowledged with a 202. When 0, runners are dispatched within the webhook request.`,
+	})
+
+	qf.IntVar(&cli.IntVar{
+		Name:    "dispatch-queue-max-attempts",
+		Target:  &cfg.DispatchQueueMaxAttempts,
+		EnvVar:  "DISPATCH_QUEUE_MAX_ATTEMPTS",
+		Default: 5,
+		Usage:   `The maximum number of attempts to dispatch a queued job before it is dropped. Deliveries that are not finished within the visibility timeout count as attempts.`,
+	})
+
+	qf.DurationVar(&cli.DurationVar{
+		Name:    "dispatch-queue-poll-interval",
+		Target:  &cfg.DispatchQueuePollInterval,
+		EnvVar:  "DISPATCH_QUEUE_POLL_INTERVAL",
+		Default: 1 * time.Second,
+		Usage:   `How long an idle worker waits before checking the queue again.`,
+	})
+
+	qf.DurationVar(&cli.DurationVar{
+		Name:    "dispatch-queue-visibility-timeout",
+		Target:  &cfg.DispatchQueueVisibilityTimeout,
+		EnvVar:  "DISPATCH_QUEUE_VISIBILITY_TIMEOUT",
+		Default: 5 * time.Minute,
+		Usage:   `How long a job claimed by a worker is hidden from other workers. A job that is not finished within this time is dispatched again.`,
+	})
+
+	wf := set.NewSection("WARM POOL OPTIONS")
+
+	wf.StringSliceVar(&cli.StringSliceVar{
+		Name:   "warm-pool-sizes",
+		Target: &cfg.WarmPoolSizesRaw,
+		EnvVar: "WARM_POOL_SIZES",
+		Usage:  `List of the number of idle runners to keep warm per org and label (e.g., "google:self-hosted=2"). Warm runners are registered with the org, or with the enterprise if the label's registration scope is "enterprise". Jobs that request only labels that warm runners are registered with are assigned to a warm runner when one is available.`,
+	})
+
+	wf.StringSliceVar(&cli.StringSliceVar{
+		Name:   "warm-pool-labels",
+		Target: &cfg.WarmPoolLabelsRaw,
+		EnvVar: "WARM_POOL_LABELS",
+		Usage:  `List of additional labels to register the runners of a warm pool with, per org and label (e.g., "google:large=self-hosted;linux;x64"). Warm runners are started on worker pools that advertise these labels, and can serve jobs that request any of them alongside the warm pool's label.`,
+	})
+
+	wf.DurationVar(&cli.DurationVar{
+		Name:    "warm-pool-replenish-interval",
+		Target:  &cfg.WarmPoolReplenishInterval,
+		EnvVar:  "WARM_POOL_REPLENISH_INTERVAL",
+		Default: 1 * time.Minute,
+		Usage:   `How often warm pools are topped up to their configured size.`,
+	})
+
+	inf := set.NewSection("IN-FLIGHT TRACKING OPTIONS")
+
+	inf.BoolVar(&cli.BoolVar{
+		Name:   "inflight-tracking-enabled",
+		Target: &cfg.InFlightTrackingEnabled,
+		EnvVar: "INFLIGHT_TRACKING_ENABLED",
+		Usage:  `Whether to track the builds running on each worker pool. When enabled, jobs are sent to the least-loaded pool and pools at their max concurrency are skipped.`,
+	})
+
+	inf.DurationVar(&cli.DurationVar{
+		Name:    "inflight-reconcile-interval",
+		Target:  &cfg.InFlightReconcileInterval,
+		EnvVar:  "INFLIGHT_RECONCILE_INTERVAL",
+		Default: 5 * time.Minute,
+		Usage:   `How often tracked builds are checked against Cloud Build so that builds which finished without a completed event stop counting against their pool. Set to 0 to disable.`,
+	})
+
+	pf := set.NewSection("WORKER POOL FAILOVER OPTIONS")
+
+	pf.IntVar(&cli.IntVar{
+		Name:    "worker-pool-breaker-threshold",
+		Target:  &cfg.WorkerPoolBreakerThreshold,
+		EnvVar:  "WORKER_POOL_BREAKER_THRESHOLD",
+		Default: 3,
+		Usage:   `The number of consecutive build failures after which a worker pool is not selected until its cooldown has passed. Jobs always fail over to the remaining pools when a build cannot be created. Set to 0 to disable the circuit breaker.`,
+	})
+
+	pf.DurationVar(&cli.DurationVar{
+		Name:    "worker-pool-breaker-cooldown",
+		Target:  &cfg.WorkerPoolBreakerCooldown,
+		EnvVar:  "WORKER_POOL_BREAKER_COOLDOWN",
+		Default: 5 * time.Minute,
+		Usage:   `How long a worker pool whose circuit breaker has opened is not selected.`,
+	})
+
+	cqf := set.NewSection("CONCURRENCY QUOTA OPTIONS")
+
+	cqf.StringSliceVar(&cli.StringSliceVar{
+		Name:   "concurrency-quotas",
+		Target: &cfg.ConcurrencyQuotasRaw,
+		EnvVar: "CONCURRENCY_QUOTAS",
+		Usage:  `List of the maximum number of runners started at once per org or repo (e.g., "google=200,google/big-matrix=20"). Each dispatched job holds a slot for each of its runners, including extra runners, until it completes. A job that starts more runners than a quota allows holds all of its slots. Jobs over quota are deferred on the dispatch queue, which must be enabled.`,
+	})
+
+	cqf.DurationVar(&cli.DurationVar{
+		Name:    "concurrency-quota-retry-delay",
+		Target:  &cfg.ConcurrencyQuotaRetryDelay,
+		EnvVar:  "CONCURRENCY_QUOTA_RETRY_DELAY",
+		Default: 30 * time.Second,
+		Usage:   `How long a job deferred by a concurrency quota waits before it is dispatched again.`,
+	})
+
+	bf := set.NewSection("BUDGET OPTIONS")
+
+	bf.StringSliceVar(&cli.StringSliceVar{
+		Name:   "budgets",
+		Target: &cfg.BudgetsRaw,
+		EnvVar: "BUDGETS",
+		Usage:  `List of the runner usage each org may use per calendar month, in "minutes" or estimated "dollars" (e.g., "google=minutes:50000,team-x=dollars:1200"). Usage is counted from the duration of completed jobs.`,
+	})
+
+	bf.StringSliceVar(&cli.StringSliceVar{
+		Name:   "budget-rates",
+		Target: &cfg.BudgetRatesRaw,
+		EnvVar: "BUDGET_RATES",
+		Usage:  `List of the estimated cost in dollars per runner-minute of the machines behind each label (e.g., "self-hosted=0.008,gpu=0.25"). Jobs are charged the highest rate of their labels.`,
+	})
+
+	bf.Float64Var(&cli.Float64Var{
+		Name:    "budget-default-rate",
+		Target:  &cfg.BudgetDefaultRate,
+		EnvVar:  "BUDGET_DEFAULT_RATE",
+		Default: 0,
+		Usage:   `The estimated cost in dollars per runner-minute of jobs whose labels have no rate. Budgets in dollars require it to be positive unless every supported label has a rate.`,
+	})
+
+	bf.StringVar(&cli.StringVar{
+		Name:    "budget-exhausted-action",
+		Target:  &cfg.BudgetExhaustedAction,
+		EnvVar:  "BUDGET_EXHAUSTED_ACTION",
+		Default: BudgetActionWarn,
+		Usage:   `What to do with the jobs of an org that has exhausted its budget: "warn" to only log a warning, "404" to send them to the 404 runner, or "alias" to dispatch them to the labels in BUDGET_FALLBACK_ALIASES.`,
+	})
+
+	bf.StringSliceVar(&cli.StringSliceVar{
+		Name:   "budget-fallback-aliases",
+		Target: &cfg.BudgetFallbackAliasesRaw,
+		EnvVar: "BUDGET_FALLBACK_ALIASES",
+		Usage:  `List of cheaper labels to dispatch jobs to once their org has exhausted its budget (e.g., "gpu=self-hosted").`,
+	})
+
+	dpf := set.NewSection("DISPATCH POLICY OPTIONS")
+
+	dpf.StringVar(&cli.StringVar{
+		Name:   "dispatch-policy-file",
+		Target: &cfg.DispatchPolicyFile,
+		EnvVar: "DISPATCH_POLICY_FILE",
+		Usage:  `The path to a JSON file of allow and deny rules that decide which organizations, repositories and workflows may use which runner labels. Denied jobs are handled like jobs with unsupported labels. Every job is allowed when unset.`,
+	})
+
+	af := set.NewSection("ADMIN API OPTIONS")
+
+	af.StringVar(&cli.StringVar{
+		Name:   "admin-api-key-mount-path",
+		Target: &cfg.AdminAPIKeyMountPath,
+		EnvVar: "ADMIN_API_KEY_MOUNT_PATH",
+		Usage:  `The directory the admin API bearer token is mounted in. The admin API is disabled when unset.`,
+	})
+
+	af.StringVar(&cli.StringVar{
+		Name:   "admin-api-key-name",
+		Target: &cfg.AdminAPIKeyName,
+		EnvVar: "ADMIN_API_KEY_NAME",
+		Usage:  `The file name of the admin API bearer token within the mount path.`,
+	})
+
 	rf := set.NewSection("RETRY OPTIONS")
 
 	rf.IntVar(&cli.IntVar{
@@ -16,6 +16,9 @@ package webhook
 
 import (
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 
 	"github.com/abcxyz/pkg/testutil"
 )
@@ -32,6 +35,7 @@ func generateValidConfig() *Config {
 		RunnerIdleTimeoutSeconds:      300,
 		RunnerLocation:                "test-location",
 		RunnerProjectID:               "test-project",
+		RunnerRegistrationScope:       "repo",
 		RunnerRepositoryID:            "test-repo",
 		RunnerServiceAccount:          "test-sa",
 		RunnerLabelAliasesRaw: []string{
@@ -65,6 +69,455 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expErr: "runner label alias target \"invalid-target\" is not present in SUPPORTED_RUNNER_LABELS",
 		},
+		{
+			name: "valid_runner_label_policy",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"self-hosted=extra_runner_count=2;idle_timeout_seconds=600;execution_timeout_seconds=7200"}
+			},
+		},
+		{
+			name: "runner_label_policy_invalid_format",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"self-hosted"}
+			},
+			expErr: `invalid runner label policy format "self-hosted", expected label=key=value;key=value`,
+		},
+		{
+			name: "runner_label_policy_label_not_in_supported_labels",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"gpu=extra_runner_count=1"}
+			},
+			expErr: `runner label policy label "gpu" is not present in SUPPORTED_RUNNER_LABELS`,
+		},
+		{
+			name: "runner_label_policy_duplicate_label",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"self-hosted=extra_runner_count=1", "self-hosted=extra_runner_count=2"}
+			},
+			expErr: `duplicate runner label policy for label "self-hosted"`,
+		},
+		{
+			name: "runner_label_policy_unknown_setting",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"self-hosted=machine_count=4"}
+			},
+			expErr: `unknown runner label policy setting "machine_count"`,
+		},
+		{
+			name: "runner_label_policy_invalid_value",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"self-hosted=extra_runner_count=many"}
+			},
+			expErr: `invalid runner label policy value for extra_runner_count on label "self-hosted"`,
+		},
+		{
+			name: "runner_label_policy_negative_extra_runner_count",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"self-hosted=extra_runner_count=-1"}
+			},
+			expErr: `runner label policy extra_runner_count for label "self-hosted" must be non-negative, got -1`,
+		},
+		{
+			name: "runner_label_policy_idle_timeout_out_of_bounds",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"self-hosted=idle_timeout_seconds=60"}
+			},
+			expErr: `runner label policy idle_timeout_seconds for label "self-hosted" must be between 300 (5 minutes) and 86400 (24 hours) seconds, got 60`,
+		},
+		{
+			name: "runner_label_policy_execution_timeout_out_of_bounds",
+			mutator: func(c *Config) {
+				c.RunnerLabelPoliciesRaw = []string{"self-hosted=execution_timeout_seconds=90000"}
+			},
+			expErr: `runner label policy execution_timeout_seconds for label "self-hosted" must be between 3600 (1 hour) and 86400 (24 hours) seconds, got 90000`,
+		},
+		{
+			name: "valid_runner_registration_scopes",
+			mutator: func(c *Config) {
+				c.RunnerRegistrationScope = "org"
+				c.RunnerRegistrationScopesRaw = []string{"google:*=repo", "*:self-hosted=org", "google:self-hosted=repo"}
+			},
+		},
+		{
+			name:    "invalid_runner_registration_scope",
+			mutator: func(c *Config) { c.RunnerRegistrationScope = "team" },
+			expErr:  `RUNNER_REGISTRATION_SCOPE must be one of "repo", "org" or "enterprise", got "team"`,
+		},
+		{
+			name: "valid_enterprise_registration_scope",
+			mutator: func(c *Config) {
+				c.GitHubEnterprise = "abcxyz"
+				c.GitHubEnterpriseInstallationID = 456
+				c.RunnerRegistrationScopesRaw = []string{"*:self-hosted=enterprise"}
+			},
+		},
+		{
+			name:    "enterprise_registration_scope_without_enterprise",
+			mutator: func(c *Config) { c.RunnerRegistrationScope = "enterprise" },
+			expErr:  `RUNNER_REGISTRATION_SCOPE "enterprise" requires GITHUB_ENTERPRISE to be set`,
+		},
+		{
+			name:    "enterprise_installation_id_missing",
+			mutator: func(c *Config) { c.GitHubEnterprise = "abcxyz" },
+			expErr:  "GITHUB_ENTERPRISE and GITHUB_ENTERPRISE_INSTALLATION_ID must be set together",
+		},
+		{
+			name:    "runner_registration_scope_invalid_format",
+			mutator: func(c *Config) { c.RunnerRegistrationScopesRaw = []string{"google:self-hosted"} },
+			expErr:  `invalid runner registration scope format "google:self-hosted", expected org:label=scope`,
+		},
+		{
+			name:    "runner_registration_scope_missing_label",
+			mutator: func(c *Config) { c.RunnerRegistrationScopesRaw = []string{"google=org"} },
+			expErr:  `invalid runner registration scope "google=org": expected key of the form org:label, got "google"`,
+		},
+		{
+			name:    "runner_registration_scope_label_not_in_supported_labels",
+			mutator: func(c *Config) { c.RunnerRegistrationScopesRaw = []string{"google:gpu=org"} },
+			expErr:  `invalid runner registration scope "google:gpu=org": label "gpu" is not present in SUPPORTED_RUNNER_LABELS`,
+		},
+		{
+			name:    "runner_registration_scope_invalid_scope",
+			mutator: func(c *Config) { c.RunnerRegistrationScopesRaw = []string{"google:*=team"} },
+			expErr:  `runner registration scope for "google:*" must be one of "repo", "org" or "enterprise", got "team"`,
+		},
+		{
+			name:    "runner_registration_scope_duplicate",
+			mutator: func(c *Config) { c.RunnerRegistrationScopesRaw = []string{"google:*=org", "google:*=repo"} },
+			expErr:  `duplicate runner registration scope for "google:*"`,
+		},
+		{
+			name:    "valid_runner_groups",
+			mutator: func(c *Config) { c.RunnerGroupsRaw = []string{"google:*=trusted-runners", "*:self-hosted=5"} },
+		},
+		{
+			name:    "runner_group_invalid_format",
+			mutator: func(c *Config) { c.RunnerGroupsRaw = []string{"google:*="} },
+			expErr:  `invalid runner group format "google:*=", expected org:label=group`,
+		},
+		{
+			name:    "runner_group_label_not_in_supported_labels",
+			mutator: func(c *Config) { c.RunnerGroupsRaw = []string{"google:gpu=5"} },
+			expErr:  `invalid runner group "google:gpu=5": label "gpu" is not present in SUPPORTED_RUNNER_LABELS`,
+		},
+		{
+			name:    "runner_group_duplicate",
+			mutator: func(c *Config) { c.RunnerGroupsRaw = []string{"google:*=5", "google:*=6"} },
+			expErr:  `duplicate runner group for "google:*"`,
+		},
+		{
+			name:    "webhook_extra_key_names_contains_primary",
+			mutator: func(c *Config) { c.GitHubWebhookExtraKeyNames = []string{"test-key-previous", "test-key"} },
+			expErr:  `WEBHOOK_EXTRA_KEY_NAMES must not contain WEBHOOK_KEY_NAME "test-key"`,
+		},
+		{
+			name:    "webhook_key_reload_interval_negative",
+			mutator: func(c *Config) { c.GitHubWebhookKeyReloadInterval = -time.Minute },
+			expErr:  "WEBHOOK_KEY_RELOAD_INTERVAL must not be negative, got -1m0s",
+		},
+		{
+			name:    "github_app_invalid_format",
+			mutator: func(c *Config) { c.GitHubAppsRaw = []string{"team-a"} },
+			expErr:  `invalid github app format "team-a", expected name=key=value;key=value`,
+		},
+		{
+			name:    "github_app_unknown_setting",
+			mutator: func(c *Config) { c.GitHubAppsRaw = []string{"team-a=app_id=12345;private_key=abc"} },
+			expErr:  `unknown github app setting "private_key" for app "team-a"`,
+		},
+		{
+			name:    "github_app_missing_kms_app_private_key_id",
+			mutator: func(c *Config) { c.GitHubAppsRaw = []string{"team-a=app_id=12345"} },
+			expErr:  `github app "team-a" is missing kms_app_private_key_id`,
+		},
+		{
+			name:    "github_app_duplicate_of_default_app_id",
+			mutator: func(c *Config) { c.GitHubAppsRaw = []string{"team-a=app_id=test-app-id;kms_app_private_key_id=k"} },
+			expErr:  `duplicate github app id "test-app-id"`,
+		},
+		{
+			name: "github_app_duplicate_name",
+			mutator: func(c *Config) {
+				c.GitHubAppsRaw = []string{
+					"team-a=app_id=1;kms_app_private_key_id=k1",
+					"team-a=app_id=2;kms_app_private_key_id=k2",
+				}
+			},
+			expErr: `duplicate github app "team-a"`,
+		},
+		{
+			name:    "github_host_invalid_format",
+			mutator: func(c *Config) { c.GitHubHostsRaw = []string{"ghes1"} },
+			expErr:  `invalid github host format "ghes1", expected name=key=value;key=value`,
+		},
+		{
+			name:    "github_host_invalid_name",
+			mutator: func(c *Config) { c.GitHubHostsRaw = []string{"GHES_1=hostname=github.example.com"} },
+			expErr:  `github host name "GHES_1" must contain only lowercase letters, digits and hyphens`,
+		},
+		{
+			name:    "github_host_unknown_setting",
+			mutator: func(c *Config) { c.GitHubHostsRaw = []string{"ghes1=hostname=github.example.com;token=abc"} },
+			expErr:  `unknown github host setting "token" for host "ghes1"`,
+		},
+		{
+			name:    "github_host_missing_api_base_url",
+			mutator: func(c *Config) { c.GitHubHostsRaw = []string{"ghes1=hostname=github.example.com"} },
+			expErr:  `github host "ghes1" is missing api_base_url`,
+		},
+		{
+			name: "github_host_extra_key_is_key",
+			mutator: func(c *Config) {
+				c.GitHubHostsRaw = []string{"ghes1=hostname=github.example.com;api_base_url=https://github.example.com/api/v3;webhook_key_name=ghes1-key;webhook_extra_key_name=ghes1-key"}
+			},
+			expErr: `github host "ghes1": webhook_extra_key_name must not be webhook_key_name "ghes1-key"`,
+		},
+		{
+			name: "github_host_duplicate_hostname",
+			mutator: func(c *Config) {
+				c.GitHubHostsRaw = []string{
+					"ghes1=hostname=github.example.com;api_base_url=https://github.example.com/api/v3",
+					"ghes2=hostname=github.example.com;api_base_url=https://github.example.com/api/v3",
+				}
+			},
+			expErr: `duplicate github host hostname "github.example.com"`,
+		},
+		{
+			name: "valid_warm_pool_sizes",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"google:self-hosted=2"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+		},
+		{
+			name: "warm_pool_size_invalid_format",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"self-hosted=2"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+			expErr: `invalid warm pool size format "self-hosted=2", expected org:label=size`,
+		},
+		{
+			name: "warm_pool_size_not_positive",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"google:self-hosted=0"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+			expErr: `warm pool size for "google:self-hosted" must be a positive integer, got "0"`,
+		},
+		{
+			name: "warm_pool_label_not_in_supported_labels",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"google:gpu=1"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+			expErr: `warm pool label "gpu" is not present in SUPPORTED_RUNNER_LABELS`,
+		},
+		{
+			name: "warm_pool_duplicate",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"google:self-hosted=1", "google:self-hosted=2"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+			expErr: `duplicate warm pool size for "google:self-hosted"`,
+		},
+		{
+			name: "valid_warm_pool_labels",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"google:self-hosted=2"}
+				c.WarmPoolLabelsRaw = []string{"google:self-hosted=linux;x64"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+		},
+		{
+			name: "warm_pool_labels_invalid_format",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"google:self-hosted=2"}
+				c.WarmPoolLabelsRaw = []string{"google:self-hosted"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+			expErr: `invalid warm pool labels format "google:self-hosted", expected org:label=label1;label2`,
+		},
+		{
+			name: "warm_pool_labels_without_size",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"google:self-hosted=2"}
+				c.WarmPoolLabelsRaw = []string{"other:self-hosted=linux"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+			expErr: `warm pool labels for "other:self-hosted" have no matching WARM_POOL_SIZES entry`,
+		},
+		{
+			name: "warm_pool_labels_duplicate",
+			mutator: func(c *Config) {
+				c.WarmPoolSizesRaw = []string{"google:self-hosted=2"}
+				c.WarmPoolLabelsRaw = []string{"google:self-hosted=linux", "google:self-hosted=x64"}
+				c.WarmPoolReplenishInterval = time.Minute
+			},
+			expErr: `duplicate warm pool labels for "google:self-hosted"`,
+		},
+		{
+			name:    "warm_pool_replenish_interval_not_positive",
+			mutator: func(c *Config) { c.WarmPoolSizesRaw = []string{"google:self-hosted=1"} },
+			expErr:  "WARM_POOL_REPLENISH_INTERVAL must be positive, got 0s",
+		},
+		{
+			name: "valid_concurrency_quotas",
+			mutator: func(c *Config) {
+				c.ConcurrencyQuotasRaw = []string{"google=100", "google/webhook=10"}
+				c.ConcurrencyQuotaRetryDelay = time.Minute
+				enableTestDispatchQueue(c)
+			},
+		},
+		{
+			name: "concurrency_quota_invalid_format",
+			mutator: func(c *Config) {
+				c.ConcurrencyQuotasRaw = []string{"google/webhook/extra=10"}
+				c.ConcurrencyQuotaRetryDelay = time.Minute
+				enableTestDispatchQueue(c)
+			},
+			expErr: `invalid concurrency quota format "google/webhook/extra=10", expected org=limit or org/repo=limit`,
+		},
+		{
+			name: "concurrency_quota_not_positive",
+			mutator: func(c *Config) {
+				c.ConcurrencyQuotasRaw = []string{"google=0"}
+				c.ConcurrencyQuotaRetryDelay = time.Minute
+				enableTestDispatchQueue(c)
+			},
+			expErr: `concurrency quota for "google" must be a positive integer, got "0"`,
+		},
+		{
+			name: "concurrency_quota_duplicate",
+			mutator: func(c *Config) {
+				c.ConcurrencyQuotasRaw = []string{"google=1", "google=2"}
+				c.ConcurrencyQuotaRetryDelay = time.Minute
+				enableTestDispatchQueue(c)
+			},
+			expErr: `duplicate concurrency quota for "google"`,
+		},
+		{
+			name: "concurrency_quotas_require_dispatch_queue",
+			mutator: func(c *Config) {
+				c.ConcurrencyQuotasRaw = []string{"google=1"}
+				c.ConcurrencyQuotaRetryDelay = time.Minute
+			},
+			expErr: "CONCURRENCY_QUOTAS requires DISPATCH_QUEUE_WORKERS to be positive",
+		},
+		{
+			name: "concurrency_quota_retry_delay_not_positive",
+			mutator: func(c *Config) {
+				c.ConcurrencyQuotasRaw = []string{"google=1"}
+				enableTestDispatchQueue(c)
+			},
+			expErr: "CONCURRENCY_QUOTA_RETRY_DELAY must be positive, got 0s",
+		},
+		{
+			name: "valid_budgets",
+			mutator: func(c *Config) {
+				c.BudgetsRaw = []string{"google=minutes:10000", "abcxyz=dollars:250.50"}
+				c.BudgetDefaultRate = 0.008
+				c.BudgetRatesRaw = []string{"ubuntu-24.04-n2d-standard-2=0.016"}
+				c.BudgetFallbackAliasesRaw = []string{"ubuntu-24.04-n2d-standard-2=ubuntu-20.04-e2-standard-2"}
+				c.BudgetExhaustedAction = BudgetActionAlias
+			},
+		},
+		{
+			name: "valid_dollar_budget_with_rate_for_every_label",
+			mutator: func(c *Config) {
+				c.BudgetsRaw = []string{"abcxyz=dollars:250.50"}
+				c.BudgetRatesRaw = []string{
+					"self-hosted=0.008",
+					"sh-ubuntu-latest=0.008",
+					"ubuntu-24.04-n2d-standard-2=0.016",
+					"ubuntu-20.04-e2-standard-2=0",
+				}
+				c.BudgetExhaustedAction = BudgetActionWarn
+			},
+		},
+		{
+			name: "dollar_budget_without_rates",
+			mutator: func(c *Config) {
+				c.BudgetsRaw = []string{"abcxyz=dollars:250.50"}
+				c.BudgetRatesRaw = []string{"ubuntu-24.04-n2d-standard-2=0.016"}
+				c.BudgetExhaustedAction = BudgetActionWarn
+			},
+			expErr: "budgets in dollars require a positive BUDGET_DEFAULT_RATE or a BUDGET_RATES entry for every supported label, missing self-hosted, sh-ubuntu-latest, ubuntu-20.04-e2-standard-2",
+		},
+		{
+			name: "minute_budget_without_rates",
+			mutator: func(c *Config) {
+				c.BudgetsRaw = []string{"google=minutes:10000"}
+				c.BudgetExhaustedAction = BudgetActionWarn
+			},
+		},
+		{
+			name:    "budget_invalid_format",
+			mutator: func(c *Config) { c.BudgetsRaw = []string{"google=10000"} },
+			expErr:  `invalid budget format "google=10000", expected org=unit:limit`,
+		},
+		{
+			name:    "budget_invalid_unit",
+			mutator: func(c *Config) { c.BudgetsRaw = []string{"google=hours:10"} },
+			expErr:  `budget unit for "google" must be "minutes" or "dollars", got "hours"`,
+		},
+		{
+			name:    "budget_limit_not_positive",
+			mutator: func(c *Config) { c.BudgetsRaw = []string{"google=minutes:0"} },
+			expErr:  `budget limit for "google" must be a positive number, got "0"`,
+		},
+		{
+			name: "budget_duplicate",
+			mutator: func(c *Config) {
+				c.BudgetsRaw = []string{"google=minutes:10", "google=dollars:10"}
+				c.BudgetExhaustedAction = BudgetActionWarn
+			},
+			expErr: `duplicate budget for "google"`,
+		},
+		{
+			name:    "budget_default_rate_negative",
+			mutator: func(c *Config) { c.BudgetDefaultRate = -1 },
+			expErr:  "BUDGET_DEFAULT_RATE must be non-negative, got -1",
+		},
+		{
+			name:    "budget_rate_label_not_in_supported_labels",
+			mutator: func(c *Config) { c.BudgetRatesRaw = []string{"gpu=1"} },
+			expErr:  `budget rate label "gpu" is not present in SUPPORTED_RUNNER_LABELS`,
+		},
+		{
+			name:    "budget_rate_invalid",
+			mutator: func(c *Config) { c.BudgetRatesRaw = []string{"self-hosted=cheap"} },
+			expErr:  `budget rate for "self-hosted" must be a non-negative number, got "cheap"`,
+		},
+		{
+			name:    "budget_fallback_alias_target_not_in_supported_labels",
+			mutator: func(c *Config) { c.BudgetFallbackAliasesRaw = []string{"self-hosted=gpu"} },
+			expErr:  `budget fallback alias target "gpu" is not present in SUPPORTED_RUNNER_LABELS`,
+		},
+		{
+			name: "budget_404_requires_runner_404",
+			mutator: func(c *Config) {
+				c.BudgetsRaw = []string{"google=minutes:10"}
+				c.BudgetExhaustedAction = BudgetActionRunner404
+			},
+			expErr: `BUDGET_EXHAUSTED_ACTION "404" requires RUNNER_404_ENABLED`,
+		},
+		{
+			name: "budget_alias_requires_fallback_aliases",
+			mutator: func(c *Config) {
+				c.BudgetsRaw = []string{"google=minutes:10"}
+				c.BudgetExhaustedAction = BudgetActionAlias
+			},
+			expErr: `BUDGET_EXHAUSTED_ACTION "alias" requires BUDGET_FALLBACK_ALIASES`,
+		},
+		{
+			name: "budget_invalid_action",
+			mutator: func(c *Config) {
+				c.BudgetsRaw = []string{"google=minutes:10"}
+				c.BudgetExhaustedAction = "block"
+			},
+			expErr: `BUDGET_EXHAUSTED_ACTION must be one of "warn", "404" or "alias", got "block"`,
+		},
 		{
 			name:    "invalid_environment",
 			mutator: func(c *Config) { c.Environment = "invalid" },
@@ -161,6 +614,92 @@ func TestConfig_Validate(t *testing.T) {
 			name:    "valid_runner_execution_timeout_seconds_max",
 			mutator: func(c *Config) { c.RunnerExecutionTimeoutSeconds = 86400 },
 		},
+		{
+			name:    "invalid_dispatch_dedup_ttl_negative",
+			mutator: func(c *Config) { c.DispatchDedupTTL = -1 * time.Second },
+			expErr:  "DISPATCH_DEDUP_TTL must be non-negative, got -1s",
+		},
+		{
+			name:    "valid_dispatch_dedup_ttl_disabled",
+			mutator: func(c *Config) { c.DispatchDedupTTL = 0 },
+		},
+		{
+			name:    "invalid_admin_api_key_name_missing",
+			mutator: func(c *Config) { c.AdminAPIKeyMountPath = "/etc/secrets/admin" },
+			expErr:  "ADMIN_API_KEY_MOUNT_PATH and ADMIN_API_KEY_NAME must be set together",
+		},
+		{
+			name: "valid_admin_api_key",
+			mutator: func(c *Config) {
+				c.AdminAPIKeyMountPath = "/etc/secrets/admin"
+				c.AdminAPIKeyName = "token"
+			},
+		},
+		{
+			name:    "invalid_dispatch_record_ttl_negative",
+			mutator: func(c *Config) { c.DispatchRecordTTL = -1 * time.Second },
+			expErr:  "DISPATCH_RECORD_TTL must be non-negative, got -1s",
+		},
+		{
+			name:    "invalid_inflight_reconcile_interval_negative",
+			mutator: func(c *Config) { c.InFlightReconcileInterval = -1 * time.Second },
+			expErr:  "INFLIGHT_RECONCILE_INTERVAL must be non-negative, got -1s",
+		},
+		{
+			name:    "invalid_worker_pool_breaker_threshold_negative",
+			mutator: func(c *Config) { c.WorkerPoolBreakerThreshold = -1 },
+			expErr:  "WORKER_POOL_BREAKER_THRESHOLD must be non-negative, got -1",
+		},
+		{
+			name: "invalid_worker_pool_breaker_cooldown",
+			mutator: func(c *Config) {
+				c.WorkerPoolBreakerThreshold = 3
+				c.WorkerPoolBreakerCooldown = 0
+			},
+			expErr: "WORKER_POOL_BREAKER_COOLDOWN must be positive, got 0s",
+		},
+		{
+			name:    "invalid_dispatch_queue_workers_negative",
+			mutator: func(c *Config) { c.DispatchQueueWorkers = -1 },
+			expErr:  "DISPATCH_QUEUE_WORKERS must be non-negative, got -1",
+		},
+		{
+			name: "invalid_dispatch_queue_max_attempts",
+			mutator: func(c *Config) {
+				c.DispatchQueueWorkers = 1
+				c.DispatchQueueMaxAttempts = 0
+				c.DispatchQueuePollInterval = time.Second
+				c.DispatchQueueVisibilityTimeout = time.Minute
+			},
+			expErr: "DISPATCH_QUEUE_MAX_ATTEMPTS must be at least 1, got 0",
+		},
+		{
+			name: "invalid_dispatch_queue_poll_interval",
+			mutator: func(c *Config) {
+				c.DispatchQueueWorkers = 1
+				c.DispatchQueueMaxAttempts = 1
+				c.DispatchQueueVisibilityTimeout = time.Minute
+			},
+			expErr: "DISPATCH_QUEUE_POLL_INTERVAL must be positive, got 0s",
+		},
+		{
+			name: "invalid_dispatch_queue_visibility_timeout",
+			mutator: func(c *Config) {
+				c.DispatchQueueWorkers = 1
+				c.DispatchQueueMaxAttempts = 1
+				c.DispatchQueuePollInterval = time.Second
+			},
+			expErr: "DISPATCH_QUEUE_VISIBILITY_TIMEOUT must be positive, got 0s",
+		},
+		{
+			name: "valid_dispatch_queue",
+			mutator: func(c *Config) {
+				c.DispatchQueueWorkers = 4
+				c.DispatchQueueMaxAttempts = 5
+				c.DispatchQueuePollInterval = time.Second
+				c.DispatchQueueVisibilityTimeout = time.Minute
+			},
+		},
 		{
 			name:    "missing_supported_runner_labels",
 			mutator: func(c *Config) { c.SupportedRunnerLabels = nil },
@@ -188,3 +727,91 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_RunnerLabelPolicies(t *testing.T) {
+	t.Parallel()
+
+	cfg := generateValidConfig()
+	cfg.ExtraRunnerCount = 1
+	cfg.RunnerLabelPoliciesRaw = []string{
+		"self-hosted=extra_runner_count=3;execution_timeout_seconds=7200",
+		"sh-ubuntu-latest=idle_timeout_seconds=900",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]*RunnerLabelPolicy{
+		"self-hosted": {
+			ExtraRunnerCount:              3,
+			RunnerIdleTimeoutSeconds:      300,
+			RunnerExecutionTimeoutSeconds: 7200,
+		},
+		"sh-ubuntu-latest": {
+			ExtraRunnerCount:              1,
+			RunnerIdleTimeoutSeconds:      900,
+			RunnerExecutionTimeoutSeconds: 3600,
+		},
+	}
+	if diff := cmp.Diff(want, cfg.RunnerLabelPolicies); diff != "" {
+		t.Errorf("RunnerLabelPolicies mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfig_GitHubHosts(t *testing.T) {
+	t.Parallel()
+
+	cfg := generateValidConfig()
+	cfg.GitHubWebhookExtraKeyNames = []string{"test-key-next"}
+	cfg.GitHubHostsRaw = []string{
+		"ghes1=hostname=github.example.com;api_base_url=https://github.example.com/api/v3",
+		"ghes2=hostname=ghes.example.com;api_base_url=https://ghes.example.com/api/v3;upload_url=https://ghes.example.com/api/uploads;app_id=other-app-id;kms_app_private_key_id=other-kms-key;webhook_key_name=other-key;webhook_extra_key_name=other-key-next;webhook_extra_key_name=other-key-old",
+		"ghes3=hostname=ghes3.example.com;api_base_url=https://ghes3.example.com/api/v3;webhook_key_name=ghes3-key",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*GitHubHost{
+		{
+			Name:                 "ghes1",
+			Hostname:             "github.example.com",
+			APIBaseURL:           "https://github.example.com/api/v3",
+			UploadURL:            "https://github.example.com/api/v3",
+			AppID:                "test-app-id",
+			KMSAppPrivateKeyID:   "test-kms-key",
+			WebhookKeyName:       "test-key",
+			WebhookExtraKeyNames: []string{"test-key-next"},
+		},
+		{
+			Name:                 "ghes2",
+			Hostname:             "ghes.example.com",
+			APIBaseURL:           "https://ghes.example.com/api/v3",
+			UploadURL:            "https://ghes.example.com/api/uploads",
+			AppID:                "other-app-id",
+			KMSAppPrivateKeyID:   "other-kms-key",
+			WebhookKeyName:       "other-key",
+			WebhookExtraKeyNames: []string{"other-key-next", "other-key-old"},
+		},
+		{
+			Name:               "ghes3",
+			Hostname:           "ghes3.example.com",
+			APIBaseURL:         "https://ghes3.example.com/api/v3",
+			UploadURL:          "https://ghes3.example.com/api/v3",
+			AppID:              "test-app-id",
+			KMSAppPrivateKeyID: "test-kms-key",
+			WebhookKeyName:     "ghes3-key",
+		},
+	}
+	if diff := cmp.Diff(want, cfg.GitHubHosts); diff != "" {
+		t.Errorf("GitHubHosts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// enableTestDispatchQueue sets a valid dispatch queue configuration.
+func enableTestDispatchQueue(c *Config) {
+	c.DispatchQueueWorkers = 1
+	c.DispatchQueueMaxAttempts = 5
+	c.DispatchQueuePollInterval = time.Second
+	c.DispatchQueueVisibilityTimeout = time.Minute
+}
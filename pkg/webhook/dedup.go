@@ -0,0 +1,204 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// dispatchKeyPrefix namespaces all dispatcher state stored in Redis. Keys
+	// under this prefix never contain a ':' so that they are not mistaken for
+	// runner registry keys ("<org>:<label>") by runner discovery.
+	dispatchKeyPrefix = "dispatcher"
+
+	dispatchStatusPending    = "pending"
+	dispatchStatusDispatched = "dispatched"
+
+	// dispatchClaimTTL bounds how long a pending claim on a job is held. It
+	// keeps a job from being blocked for the full dedup TTL if the server exits
+	// before the dispatch is recorded or released.
+	dispatchClaimTTL = 5 * time.Minute
+)
+
+// dispatchRecord is the ledger entry stored for a queued job once runners have
+// been dispatched for it.
+type dispatchRecord struct {
+	Status      string    `json:"status"`
+	DeliveryID  string    `json:"delivery_id,omitempty"`
+	JobID       string    `json:"job_id,omitempty"`
+	RunnerNames []string  `json:"runner_names,omitempty"`
+	GCBBuildIDs []string  `json:"gcb_build_ids,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+}
+
+// dedupJobKey returns the ledger key for a workflow job ID under a server's
+// key prefix.
+func dedupJobKey(keyPrefix, jobID string) string {
+	return fmt.Sprintf("%s/dedup/job/%s", keyPrefix, jobID)
+}
+
+// dedupDeliveryKey returns the ledger key for a GitHub delivery ID under a
+// server's key prefix.
+func dedupDeliveryKey(keyPrefix, deliveryID string) string {
+	return fmt.Sprintf("%s/dedup/delivery/%s", keyPrefix, deliveryID)
+}
+
+// dedupEnabled reports whether the dispatch ledger is available. Dedup is
+// skipped when there is no registry client, the TTL is not positive or the
+// server is in dry-run mode.
+func (s *Server) dedupEnabled() bool {
+	return s.rc != nil && s.config.DispatchDedupTTL > 0 && !s.config.DryRun
+}
+
+// claimDispatch checks the ledger for a previous dispatch of the given delivery
+// or job. When one is found it is returned and the caller must not dispatch
+// again. Otherwise the job is claimed so that concurrent deliveries of the same
+// job are not dispatched twice, and nil is returned.
+//
+// Redis failures are logged and treated as a miss so that the ledger never
+// blocks a job from being dispatched.
+func (s *Server) claimDispatch(ctx context.Context, deliveryID, jobID string) *dispatchRecord {
+	logger := logging.FromContext(ctx)
+
+	if !s.dedupEnabled() {
+		logger.DebugContext(ctx, "dispatch ledger not configured, skipping dedup")
+		return nil
+	}
+
+	if deliveryID != "" {
+		record, err := s.getDispatchRecord(ctx, dedupDeliveryKey(s.keyPrefix, deliveryID))
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to read dispatch ledger for delivery",
+				"error", err,
+				"delivery_id", deliveryID)
+		}
+		if record != nil {
+			return record
+		}
+	}
+
+	if jobID == "" {
+		return nil
+	}
+
+	pending, err := json.Marshal(&dispatchRecord{Status: dispatchStatusPending})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to marshal pending dispatch record", "error", err)
+		return nil
+	}
+
+	jobKey := dedupJobKey(s.keyPrefix, jobID)
+	claimed, err := s.rc.SetNX(ctx, jobKey, string(pending), min(dispatchClaimTTL, s.config.DispatchDedupTTL)).Result()
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to claim job in dispatch ledger",
+			"error", err,
+			"key", jobKey)
+		return nil
+	}
+	if claimed {
+		return nil
+	}
+
+	record, err := s.getDispatchRecord(ctx, jobKey)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to read dispatch ledger for job",
+			"error", err,
+			"key", jobKey)
+		return nil
+	}
+	return record
+}
+
+// recordDispatch stores the runners dispatched for a job under both the job and
+// delivery keys.
+func (s *Server) recordDispatch(ctx context.Context, deliveryID, jobID string, builds []*runnerBuild) {
+	logger := logging.FromContext(ctx)
+
+	if !s.dedupEnabled() {
+		return
+	}
+
+	record := &dispatchRecord{
+		Status:     dispatchStatusDispatched,
+		DeliveryID: deliveryID,
+		JobID:      jobID,
+		CreatedAt:  time.Now().UTC(),
+	}
+	for _, build := range builds {
+		record.RunnerNames = append(record.RunnerNames, build.RunnerName)
+		record.GCBBuildIDs = append(record.GCBBuildIDs, build.BuildID)
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to marshal dispatch record", "error", err)
+		return
+	}
+
+	keys := make([]string, 0, 2)
+	if jobID != "" {
+		keys = append(keys, dedupJobKey(s.keyPrefix, jobID))
+	}
+	if deliveryID != "" {
+		keys = append(keys, dedupDeliveryKey(s.keyPrefix, deliveryID))
+	}
+	for _, key := range keys {
+		if err := s.rc.Set(ctx, key, string(b), s.config.DispatchDedupTTL).Err(); err != nil {
+			logger.ErrorContext(ctx, "failed to write dispatch ledger",
+				"error", err,
+				"key", key)
+		}
+	}
+}
+
+// releaseDispatch removes the claim on a job after a failed dispatch so that a
+// redelivery is able to try again.
+func (s *Server) releaseDispatch(ctx context.Context, jobID string) {
+	if !s.dedupEnabled() || jobID == "" {
+		return
+	}
+
+	if err := s.rc.Del(ctx, dedupJobKey(s.keyPrefix, jobID)).Err(); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to release job in dispatch ledger",
+			"error", err,
+			"key", dedupJobKey(s.keyPrefix, jobID))
+	}
+}
+
+// getDispatchRecord reads a ledger entry, returning nil if it does not exist.
+func (s *Server) getDispatchRecord(ctx context.Context, key string) (*dispatchRecord, error) {
+	val, err := s.rc.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get dispatch record %s: %w", key, err)
+	}
+
+	var record dispatchRecord
+	if err := json.Unmarshal([]byte(val), &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dispatch record %s: %w", key, err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,314 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
+)
+
+func TestHandleQueuedEvent_Dedup(t *testing.T) {
+	t.Parallel()
+
+	const (
+		deliveryID = "delivery-id"
+		ttl        = time.Hour
+	)
+	jobID := int64(789)
+	deliveryKey := dedupDeliveryKey(dispatchKeyPrefix, deliveryID)
+	jobKey := dedupJobKey(dispatchKeyPrefix, fmt.Sprintf("%d", jobID))
+
+	pending, err := json.Marshal(&dispatchRecord{Status: dispatchStatusPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatched, err := json.Marshal(&dispatchRecord{
+		Status:      dispatchStatusDispatched,
+		DeliveryID:  "original-delivery-id",
+		JobID:       fmt.Sprintf("%d", jobID),
+		RunnerNames: []string{"original-runner"},
+		GCBBuildIDs: []string{"original-build-id"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools, err := json.Marshal([]registry.WorkerPoolInfo{
+		{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name             string
+		nilRedis         bool
+		createBuildErr   error
+		extraRunnerCount int
+		jitErrAfter      int
+		cancelBuildErr   error
+		setupRedis       func(m redismock.ClientMock)
+		expStatusCode    int
+		expMessage       string
+		expRunnerNames   []string
+		expGCBBuildIDs   []string
+		expRespBody      string
+		expectBuildCount int
+		expCancelBuilds  int
+	}{
+		{
+			name: "first_delivery_dispatches",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet(deliveryKey).RedisNil()
+				m.ExpectSetNX(jobKey, string(pending), dispatchClaimTTL).SetVal(true)
+				m.ExpectGet("google:self-hosted").SetVal(string(pools))
+				m.Regexp().ExpectSet(jobKey, `"status":"dispatched"`, ttl).SetVal("OK")
+				m.Regexp().ExpectSet(deliveryKey, `"status":"dispatched"`, ttl).SetVal("OK")
+			},
+			expStatusCode:    http.StatusOK,
+			expMessage:       runnerStartedMsg,
+			expGCBBuildIDs:   []string{testGCBBuildID},
+			expectBuildCount: 1,
+		},
+		{
+			name: "redelivery_returns_original_runners",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet(deliveryKey).SetVal(string(dispatched))
+			},
+			expStatusCode:    http.StatusOK,
+			expMessage:       runnerDuplicateMsg,
+			expRunnerNames:   []string{"original-runner"},
+			expGCBBuildIDs:   []string{"original-build-id"},
+			expectBuildCount: 0,
+		},
+		{
+			name: "new_delivery_for_dispatched_job_returns_original_runners",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet(deliveryKey).RedisNil()
+				m.ExpectSetNX(jobKey, string(pending), dispatchClaimTTL).SetVal(false)
+				m.ExpectGet(jobKey).SetVal(string(dispatched))
+			},
+			expStatusCode:    http.StatusOK,
+			expMessage:       runnerDuplicateMsg,
+			expRunnerNames:   []string{"original-runner"},
+			expGCBBuildIDs:   []string{"original-build-id"},
+			expectBuildCount: 0,
+		},
+		{
+			name: "dispatch_in_progress",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet(deliveryKey).RedisNil()
+				m.ExpectSetNX(jobKey, string(pending), dispatchClaimTTL).SetVal(false)
+				m.ExpectGet(jobKey).SetVal(string(pending))
+			},
+			expStatusCode:    http.StatusOK,
+			expRespBody:      "no action taken, dispatch already in progress for job",
+			expectBuildCount: 0,
+		},
+		{
+			name: "redis_errors_fail_open",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet(deliveryKey).SetErr(fmt.Errorf("connection refused"))
+				m.ExpectSetNX(jobKey, string(pending), dispatchClaimTTL).SetErr(fmt.Errorf("connection refused"))
+				m.ExpectGet("google:self-hosted").SetVal(string(pools))
+				m.Regexp().ExpectSet(jobKey, `"status":"dispatched"`, ttl).SetErr(fmt.Errorf("connection refused"))
+				m.Regexp().ExpectSet(deliveryKey, `"status":"dispatched"`, ttl).SetErr(fmt.Errorf("connection refused"))
+			},
+			expStatusCode:    http.StatusOK,
+			expMessage:       runnerStartedMsg,
+			expGCBBuildIDs:   []string{testGCBBuildID},
+			expectBuildCount: 1,
+		},
+		{
+			name:           "failed_dispatch_releases_claim",
+			createBuildErr: fmt.Errorf("build failed"),
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet(deliveryKey).RedisNil()
+				m.ExpectSetNX(jobKey, string(pending), dispatchClaimTTL).SetVal(true)
+				m.ExpectGet("google:self-hosted").SetVal(string(pools))
+				m.ExpectDel(jobKey).SetVal(1)
+			},
+			expStatusCode:    http.StatusInternalServerError,
+			expectBuildCount: 1,
+		},
+		{
+			name:             "partial_dispatch_cancels_started_builds",
+			extraRunnerCount: 1,
+			jitErrAfter:      1,
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet(deliveryKey).RedisNil()
+				m.ExpectSetNX(jobKey, string(pending), dispatchClaimTTL).SetVal(true)
+				m.ExpectGet("google:self-hosted").SetVal(string(pools))
+				m.ExpectDel(jobKey).SetVal(1)
+			},
+			expStatusCode:    http.StatusInternalServerError,
+			expectBuildCount: 1,
+			expCancelBuilds:  1,
+		},
+		{
+			name:             "partial_dispatch_keeps_uncancelled_builds",
+			extraRunnerCount: 1,
+			jitErrAfter:      1,
+			cancelBuildErr:   fmt.Errorf("cancel failed"),
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet(deliveryKey).RedisNil()
+				m.ExpectSetNX(jobKey, string(pending), dispatchClaimTTL).SetVal(true)
+				m.ExpectGet("google:self-hosted").SetVal(string(pools))
+				m.Regexp().ExpectSet(jobKey, `"gcb_build_ids":\["`+testGCBBuildID+`"\]`, ttl).SetVal("OK")
+				m.Regexp().ExpectSet(deliveryKey, `"gcb_build_ids":\["`+testGCBBuildID+`"\]`, ttl).SetVal("OK")
+			},
+			expStatusCode:    http.StatusInternalServerError,
+			expectBuildCount: 1,
+			expCancelBuilds:  1,
+		},
+		{
+			name:             "nil_registry_client_dispatches",
+			nilRedis:         true,
+			expStatusCode:    http.StatusOK,
+			expMessage:       runnerStartedMsg,
+			expGCBBuildIDs:   []string{testGCBBuildID},
+			expectBuildCount: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			action := "queued"
+			installationID := int64(123)
+			orgLoginVar := orgLogin
+			repoNameVar := repoName
+			event := &github.WorkflowJobEvent{
+				Action: &action,
+				WorkflowJob: &github.WorkflowJob{
+					Labels: []string{SelfHostedRunnerLabel},
+					ID:     &jobID,
+				},
+				Installation: &github.Installation{ID: &installationID},
+				Org:          &github.Organization{Login: &orgLoginVar},
+				Repo:         &github.Repository{Name: &repoNameVar},
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+			req.Header.Add(DeliveryIDHeader, deliveryID)
+			req.Header.Add(EventTypeHeader, "workflow_job")
+			req.Header.Add(ContentTypeHeader, "application/json")
+			req.Header.Add(SHA256SignatureHeader, fmt.Sprintf("sha256=%s", createSignature([]byte(serverGitHubWebhookSecret), payload)))
+
+			var rc *redis.Client
+			db, mockRedis := redismock.NewClientMock()
+			if !tc.nilRedis {
+				rc = db
+			}
+			if tc.setupRedis != nil {
+				tc.setupRedis(mockRedis)
+			}
+
+			encodedJitConfig := "Hello"
+			mockCloudBuildClient := &cloudbuild.MockClient{
+				CreateBuildID:  testGCBBuildID,
+				CreateBuildErr: tc.createBuildErr,
+				Builds:         map[string]*cloudbuildpb.Build{testGCBBuildID: {Id: testGCBBuildID}},
+				CancelBuildErr: tc.cancelBuildErr,
+			}
+			var mockGitHubClient *gh.MockClient
+			mockGitHubClient = &gh.MockClient{
+				GenerateRepoJITConfigF: func(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error) {
+					if tc.jitErrAfter > 0 && mockGitHubClient.GenerateRepoJITConfigCalls > tc.jitErrAfter {
+						return nil, fmt.Errorf("jit config failed")
+					}
+					return &github.JITRunnerConfig{EncodedJITConfig: &encodedJitConfig}, nil
+				},
+			}
+
+			cfg := &Config{
+				DispatchDedupTTL:              ttl,
+				ExtraRunnerCount:              tc.extraRunnerCount,
+				RunnerExecutionTimeoutSeconds: 3600,
+				RunnerIdleTimeoutSeconds:      300,
+				SupportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+				Runner404Location:             "us-central1",
+				Runner404ProjectID:            "404-project",
+				Runner404ServiceAccount:       "404-sa",
+			}
+			wco := &WebhookClientOptions{
+				CloudBuildClientOverride: mockCloudBuildClient,
+				GitHubClientOverride:     mockGitHubClient,
+			}
+
+			srv := newTestServer(t, cfg, rc, wco)
+
+			resp := httptest.NewRecorder()
+			srv.handleWebhook().ServeHTTP(resp, req)
+
+			if got, want := resp.Code, tc.expStatusCode; got != want {
+				t.Errorf("expected %d to be %d", got, want)
+			}
+
+			if tc.expMessage != "" {
+				var r runnersResponse
+				if err := json.Unmarshal(resp.Body.Bytes(), &r); err != nil {
+					t.Fatalf("failed to unmarshal JSON response: %v, body: %s", err, resp.Body.String())
+				}
+				if got, want := r.Message, tc.expMessage; got != want {
+					t.Errorf("expected message %q, got %q", want, got)
+				}
+				if tc.expRunnerNames != nil {
+					if diff := cmp.Diff(tc.expRunnerNames, r.RunnerNames); diff != "" {
+						t.Errorf("RunnerNames mismatch (-want +got):\n%s", diff)
+					}
+				}
+				if diff := cmp.Diff(tc.expGCBBuildIDs, r.GCBBuildIDs); diff != "" {
+					t.Errorf("GCBBuildIDs mismatch (-want +got):\n%s", diff)
+				}
+			} else if tc.expRespBody != "" {
+				if got, want := strings.TrimSpace(resp.Body.String()), tc.expRespBody; got != want {
+					t.Errorf("expected %q to be %q", got, want)
+				}
+			}
+
+			if got, want := len(mockCloudBuildClient.CreateBuildReqs), tc.expectBuildCount; got != want {
+				t.Errorf("expected %d build(s) to be created, got %d", want, got)
+			}
+			if got, want := len(mockCloudBuildClient.CancelBuildReqs), tc.expCancelBuilds; got != want {
+				t.Errorf("expected %d build(s) to be cancelled, got %d", want, got)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/backend"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const dryRunMsg = "dry run, runner not started"
+
+// dryRunBuild is the build that would have been created for a runner in
+// dry-run mode, and the worker pool it would have been created on.
+// CreateBuildRequest is the request the backend of the pool's type would have
+// sent to start the runner, or null if the backend cannot describe it.
+type dryRunBuild struct {
+	RunnerName         string          `json:"runnerName"`
+	WorkerPool         *dryRunPool     `json:"workerPool"`
+	CreateBuildRequest json.RawMessage `json:"createBuildRequest"`
+}
+
+// dryRunPool describes the worker pool chosen for a runner in dry-run mode.
+type dryRunPool struct {
+	Name           string `json:"name,omitempty"`
+	ProjectID      string `json:"projectID"`
+	Location       string `json:"location"`
+	Label          string `json:"label,omitempty"`
+	PoolType       string `json:"poolType,omitempty"`
+	Weight         int    `json:"weight,omitempty"`
+	Priority       int    `json:"priority,omitempty"`
+	MaxConcurrency int    `json:"maxConcurrency,omitempty"`
+	InFlight       int    `json:"inFlight,omitempty"`
+}
+
+type dryRunResponse struct {
+	Message     string         `json:"message"`
+	RunnerNames []string       `json:"runnerNames"`
+	Builds      []*dryRunBuild `json:"dryRunBuilds"`
+}
+
+// startDryRunRunner describes the request that the backend of a pool's type
+// would send to start a runner, without generating a JIT config or starting
+// the runner. The request is logged and returned with the build.
+func (s *Server) startDryRunRunner(ctx context.Context, runnerID, imageName, imageTag string, pool *workerPool) *runnerBuild {
+	logger := logging.FromContext(ctx)
+
+	req := s.runnerStartRequest(ctx, runnerID, "", imageName, imageTag, pool)
+	runner := &backend.Runner{Name: runnerID}
+	if req.Pool != nil {
+		runner.ProjectID = req.Pool.ProjectID
+		runner.Location = req.Pool.Location
+	}
+
+	reqJSON := json.RawMessage("null")
+	if describer, ok := s.runnerBackend(pool.poolType).(backend.Describer); ok {
+		described, describedJSON, err := describer.DescribeRunner(ctx, req)
+		if err != nil {
+			// Describing a request built by the server cannot fail in practice,
+			// so the build is still reported without it.
+			logger.ErrorContext(ctx, "failed to describe dry run build request", "error", err)
+		} else {
+			runner, reqJSON = described, describedJSON
+		}
+	}
+
+	build := newRunnerBuild(runner, pool)
+	build.dryRun = &dryRunBuild{
+		RunnerName: runnerID,
+		WorkerPool: &dryRunPool{
+			Name:           pool.name,
+			ProjectID:      build.ProjectID,
+			Location:       build.Location,
+			Label:          pool.label,
+			PoolType:       pool.poolType,
+			Weight:         pool.weight,
+			Priority:       pool.priority,
+			MaxConcurrency: pool.maxConcurrency,
+			InFlight:       pool.inFlight,
+		},
+		CreateBuildRequest: reqJSON,
+	}
+	logger.InfoContext(ctx, "dry run, skipped creating build",
+		"worker_pool", pool.name,
+		"dry_run_worker_pool", build.dryRun.WorkerPool,
+		"dry_run_create_build_request", build.dryRun.CreateBuildRequest)
+	return build
+}
+
+// dryRunResponse builds a JSON response listing the builds that would have
+// been created for a job.
+func (s *Server) dryRunResponse(builds []*runnerBuild) *apiResponse {
+	responsePayload := &dryRunResponse{
+		Message:     dryRunMsg,
+		RunnerNames: make([]string, 0, len(builds)),
+		Builds:      make([]*dryRunBuild, 0, len(builds)),
+	}
+	for _, build := range builds {
+		responsePayload.RunnerNames = append(responsePayload.RunnerNames, build.RunnerName)
+		if build.dryRun != nil {
+			responsePayload.Builds = append(responsePayload.Builds, build.dryRun)
+		}
+	}
+
+	responseBytes, err := json.Marshal(responsePayload)
+	if err != nil {
+		return &apiResponse{http.StatusInternalServerError, "failed to serialize response", err}
+	}
+	return &apiResponse{http.StatusOK, string(responseBytes), nil}
+}
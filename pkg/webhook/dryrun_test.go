@@ -0,0 +1,153 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestHandleQueuedEvent_DryRun(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		labels        []string
+		pools         string
+		expWorkerPool *dryRunPool
+	}{
+		{
+			name:   "registry_pool",
+			labels: []string{SelfHostedRunnerLabel},
+			pools:  testFailoverPools(t, "test-project"),
+			expWorkerPool: &dryRunPool{
+				Name:      testFailoverPoolName("test-project"),
+				ProjectID: "test-project",
+				Location:  "us-west1",
+				Label:     SelfHostedRunnerLabel,
+				Weight:    1,
+			},
+		},
+		{
+			name:   "runner_404",
+			labels: []string{"unsupported"},
+			expWorkerPool: &dryRunPool{
+				ProjectID: "404-project",
+				Location:  "us-central1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+			cfg := &Config{
+				DispatchDedupTTL:              dispatchClaimTTL,
+				DryRun:                        true,
+				RunnerExecutionTimeoutSeconds: 3600,
+				RunnerIdleTimeoutSeconds:      300,
+				RunnerProjectID:               "test-project",
+				SupportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+				Runner404Enabled:              true,
+				Runner404Location:             "us-central1",
+				Runner404ProjectID:            "404-project",
+				Runner404ServiceAccount:       "404-sa",
+			}
+			wco := &WebhookClientOptions{
+				CloudBuildClientOverride: mockCloudBuildClient,
+				GitHubClientOverride: &gh.MockClient{
+					GenerateRepoJITConfigF: func(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error) {
+						return nil, fmt.Errorf("JIT config generated in dry-run mode")
+					},
+				},
+			}
+
+			// The registry is read, but the dispatch ledger is not written.
+			db, mockRedis := redismock.NewClientMock()
+			if tc.pools != "" {
+				mockRedis.ExpectGet("google:self-hosted").SetVal(tc.pools)
+			}
+
+			srv := newTestServer(t, cfg, db, wco)
+
+			event := &github.WorkflowJobEvent{
+				WorkflowJob: &github.WorkflowJob{
+					ID:     github.Ptr(int64(1)),
+					Labels: tc.labels,
+				},
+				Installation: &github.Installation{ID: github.Ptr(int64(123))},
+				Org:          &github.Organization{Login: github.Ptr(orgLogin)},
+				Repo:         &github.Repository{Name: github.Ptr(repoName)},
+			}
+
+			resp := srv.handleQueuedEvent(ctx, event, "delivery-id", "1")
+			if got, want := resp.Code, http.StatusOK; got != want {
+				t.Fatalf("expected code %d, got %d: %s", want, got, resp.Message)
+			}
+			if got, want := len(mockCloudBuildClient.CreateBuildReqs), 0; got != want {
+				t.Errorf("expected %d builds to be created, got %d", want, got)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+
+			var got dryRunResponse
+			if err := json.Unmarshal([]byte(resp.Message), &got); err != nil {
+				t.Fatalf("failed to unmarshal response %q: %v", resp.Message, err)
+			}
+			if got, want := got.Message, dryRunMsg; got != want {
+				t.Errorf("expected message %q, got %q", want, got)
+			}
+			if got, want := len(got.Builds), 1; got != want {
+				t.Fatalf("expected %d dry run builds, got %d", want, got)
+			}
+			build := got.Builds[0]
+			if diff := cmp.Diff([]string{build.RunnerName}, got.RunnerNames); diff != "" {
+				t.Errorf("runner names (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expWorkerPool, build.WorkerPool); diff != "" {
+				t.Errorf("worker pool (-want, +got):\n%s", diff)
+			}
+
+			var buildReq cloudbuildpb.CreateBuildRequest
+			if err := protojson.Unmarshal(build.CreateBuildRequest, &buildReq); err != nil {
+				t.Fatalf("failed to unmarshal build request: %v", err)
+			}
+			if got, want := buildReq.GetProjectId(), tc.expWorkerPool.ProjectID; got != want {
+				t.Errorf("expected build request project %q, got %q", want, got)
+			}
+			if got, want := buildReq.GetBuild().GetOptions().GetPool().GetName(), tc.expWorkerPool.Name; got != want {
+				t.Errorf("expected build request worker pool %q, got %q", want, got)
+			}
+		})
+	}
+}
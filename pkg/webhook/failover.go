@@ -0,0 +1,199 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/google/uuid"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// poolBreaker is a circuit breaker that stops a worker pool from being
+// selected for a cooldown once builds have failed to be created on it a number
+// of times in a row. It is shared by every request served by the instance. A
+// nil poolBreaker never opens.
+type poolBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  map[string]int
+	openUntil map[string]time.Time
+}
+
+// newPoolBreaker creates a poolBreaker that opens after threshold consecutive
+// failures. It returns nil if threshold is not positive.
+func newPoolBreaker(threshold int, cooldown time.Duration) *poolBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &poolBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		failures:  make(map[string]int),
+		openUntil: make(map[string]time.Time),
+	}
+}
+
+// failure records that a build could not be created on a pool. It reports
+// whether the breaker opened for the pool as a result.
+func (b *poolBreaker) failure(pool string, now time.Time) bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures[pool]++
+	if b.failures[pool] < b.threshold {
+		return false
+	}
+	delete(b.failures, pool)
+	b.openUntil[pool] = now.Add(b.cooldown)
+	return true
+}
+
+// success records that a build was created on a pool.
+func (b *poolBreaker) success(pool string) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.failures, pool)
+	delete(b.openUntil, pool)
+}
+
+// isOpen reports whether a pool is excluded from selection.
+func (b *poolBreaker) isOpen(pool string, now time.Time) bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until, ok := b.openUntil[pool]
+	if !ok {
+		return false
+	}
+	if !now.Before(until) {
+		delete(b.openUntil, pool)
+		return false
+	}
+	return true
+}
+
+// poolSelector selects the worker pools that a job's runners are started on.
+// The job's registry scopes are searched in order, and pools on which a build
+// could not be created for the job are not selected again.
+type poolSelector struct {
+	orgName                 string
+	jobOriginalRunnerLabels []string
+	jobResolvedRunnerLabels []string
+	scopes                  []string
+	failed                  map[string]bool
+}
+
+// newPoolSelector returns a poolSelector for a job in an org. Pools are
+// selected from the org's registry scope, then the enterprise's and finally the
+// default scope, unless fallback to the default runners is disabled.
+func (s *Server) newPoolSelector(orgName string, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string) *poolSelector {
+	scopes := []string{orgName}
+	// Pools registered for the enterprise serve every org in it.
+	if s.config.GitHubEnterprise != "" {
+		scopes = append(scopes, enterpriseRegistryScope(s.config.GitHubEnterprise))
+	}
+	if !s.config.Runner404DefaultDisabled {
+		scopes = append(scopes, s.runnerRegistryDefaultKeyPrefix)
+	}
+	return &poolSelector{
+		orgName:                 orgName,
+		jobOriginalRunnerLabels: jobOriginalRunnerLabels,
+		jobResolvedRunnerLabels: jobResolvedRunnerLabels,
+		scopes:                  scopes,
+		failed:                  make(map[string]bool),
+	}
+}
+
+// next returns the next pool to start a runner on, or nil if every scope has
+// been exhausted.
+func (ps *poolSelector) next(ctx context.Context, s *Server) *workerPool {
+	for len(ps.scopes) > 0 {
+		if pool := s.selectWorkerPool(ctx, ps.scopes[0], ps.jobOriginalRunnerLabels, ps.jobResolvedRunnerLabels, ps.failed); pool != nil {
+			return pool
+		}
+		ps.scopes = ps.scopes[1:]
+	}
+	return nil
+}
+
+// startRunnerWithFailover starts a runner for the job on a pool, failing over
+// to the next pool from the selector whenever the build cannot be created. The
+// runner's JIT config is reused on pools of the same label, since the runner
+// it registers has not started. A new runner is registered for pools of
+// another label, whose runners may be registered elsewhere. It returns the
+// started build and the pool it was started on.
+func (s *Server) startRunnerWithFailover(ctx context.Context, event *github.WorkflowJobEvent, runnerID string, pool *workerPool, ps *poolSelector) (*runnerBuild, *workerPool, error) {
+	if s.config.DryRun {
+		return s.startDryRunRunner(ctx, runnerID, s.runnerImageName, s.runnerImageTag, pool), pool, nil
+	}
+
+	logger := logging.FromContext(ctx)
+
+	var jitConfig, jitLabel string
+	var merr error
+	for pool != nil {
+		if jitConfig == "" || pool.label != jitLabel {
+			if jitConfig != "" {
+				runnerID = uuid.New().String()
+				logger = logger.With("runner_id", runnerID)
+				ctx = logging.WithLogger(ctx, logger)
+			}
+			jit, err := s.generateJITConfig(ctx, event, runnerID, ps.jobOriginalRunnerLabels, pool)
+			if err != nil {
+				return nil, pool, fmt.Errorf("failed to generate JIT config: %w", err)
+			}
+			jitConfig, jitLabel = jit, pool.label
+		}
+
+		build, err := s.createRunnerBuild(ctx, runnerID, jitConfig, s.runnerImageName, s.runnerImageTag, pool)
+		if err == nil {
+			return build, pool, nil
+		}
+		merr = errors.Join(merr, fmt.Errorf("worker pool %s: %w", pool.name, err))
+
+		// Pools that are not from the registry have nothing to fail over to.
+		if pool.name == "" {
+			break
+		}
+		logger.WarnContext(ctx, "failed to create build, failing over to another worker pool",
+			"error", err,
+			"worker_pool", pool.name)
+		ps.failed[pool.name] = true
+		pool = ps.next(ctx, s)
+	}
+	return nil, nil, fmt.Errorf("failed to create build on any worker pool: %w", merr)
+}
@@ -0,0 +1,242 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPoolBreaker(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newPoolBreaker(2, time.Minute)
+
+	if b.failure("wp1", now) {
+		t.Errorf("expected breaker to stay closed after one failure")
+	}
+	b.success("wp1")
+	if b.failure("wp1", now) {
+		t.Errorf("expected success to reset failures")
+	}
+	if !b.failure("wp1", now) {
+		t.Errorf("expected breaker to open after two failures")
+	}
+	if !b.isOpen("wp1", now.Add(30*time.Second)) {
+		t.Errorf("expected breaker to be open during cooldown")
+	}
+	if b.isOpen("wp2", now) {
+		t.Errorf("expected breaker to be closed for another pool")
+	}
+	if b.isOpen("wp1", now.Add(time.Minute)) {
+		t.Errorf("expected breaker to close after cooldown")
+	}
+
+	disabled := newPoolBreaker(0, time.Minute)
+	if disabled.failure("wp1", now) || disabled.isOpen("wp1", now) {
+		t.Errorf("expected disabled breaker to never open")
+	}
+}
+
+func TestStartRunnersForJob_Failover(t *testing.T) {
+	t.Parallel()
+
+	orgPools := testFailoverPools(t, "org-project-1", "org-project-2")
+	defaultPools := testFailoverPools(t, "default-project")
+
+	cases := []struct {
+		name             string
+		setupRedis       func(m redismock.ClientMock)
+		buildErrs        map[string]error
+		expProjectIDs    []string
+		expBuildProjects []string
+		expErr           string
+	}{
+		{
+			name: "first_pool_succeeds",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+			},
+			expProjectIDs:    []string{"org-project-1"},
+			expBuildProjects: []string{"org-project-1"},
+		},
+		{
+			name: "fails_over_within_key",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+			},
+			buildErrs: map[string]error{
+				"org-project-1": fmt.Errorf("quota exceeded"),
+			},
+			expProjectIDs:    []string{"org-project-2"},
+			expBuildProjects: []string{"org-project-1", "org-project-2"},
+		},
+		{
+			name: "falls_back_to_default_key",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+				m.ExpectGet("default:self-hosted").SetVal(defaultPools)
+			},
+			buildErrs: map[string]error{
+				"org-project-1": fmt.Errorf("quota exceeded"),
+				"org-project-2": fmt.Errorf("permission denied"),
+			},
+			expProjectIDs:    []string{"default-project"},
+			expBuildProjects: []string{"org-project-1", "org-project-2", "default-project"},
+		},
+		{
+			name: "all_pools_fail",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+				m.ExpectGet("google:self-hosted").SetVal(orgPools)
+				m.ExpectGet("default:self-hosted").SetVal(defaultPools)
+				m.ExpectGet("default:self-hosted").SetVal(defaultPools)
+			},
+			buildErrs: map[string]error{
+				"org-project-1":   fmt.Errorf("quota exceeded"),
+				"org-project-2":   fmt.Errorf("permission denied"),
+				"default-project": fmt.Errorf("pool deleted"),
+			},
+			expBuildProjects: []string{"org-project-1", "org-project-2", "default-project"},
+			expErr:           "failed to create build on any worker pool",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			db, mockRedis := redismock.NewClientMock()
+			tc.setupRedis(mockRedis)
+
+			mockCloudBuildClient := &cloudbuild.MockClient{
+				CreateBuildID:          testGCBBuildID,
+				CreateBuildProjectErrs: tc.buildErrs,
+			}
+			mockGitHubClient := newWarmPoolGitHubClient()
+			srv := newTestInFlightServer(t, db, nil, mockCloudBuildClient)
+			srv.ghc = mockGitHubClient
+
+			labels := []string{SelfHostedRunnerLabel}
+			builds, err := srv.startRunnersForJob(ctx, testFailoverEvent(), labels, labels)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+
+			var gotProjectIDs []string
+			for _, b := range builds {
+				gotProjectIDs = append(gotProjectIDs, b.ProjectID)
+			}
+			if diff := cmp.Diff(tc.expProjectIDs, gotProjectIDs); diff != "" {
+				t.Errorf("started builds (-want, +got):\n%s", diff)
+			}
+
+			var gotBuildProjects []string
+			for _, req := range mockCloudBuildClient.CreateBuildReqs {
+				gotBuildProjects = append(gotBuildProjects, req.GetProjectId())
+			}
+			if diff := cmp.Diff(tc.expBuildProjects, gotBuildProjects); diff != "" {
+				t.Errorf("attempted builds (-want, +got):\n%s", diff)
+			}
+
+			// Every pool serves the same label, so the JIT config is reused.
+			if got, want := mockGitHubClient.GenerateRepoJITConfigCalls, 1; got != want {
+				t.Errorf("expected %d calls to GenerateRepoJITConfig, got %d", want, got)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestSelectWorkerPool_Breaker(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	pools := testFailoverPools(t, "project-1", "project-2")
+	db, mockRedis := redismock.NewClientMock()
+	mockRedis.ExpectGet("google:self-hosted").SetVal(pools)
+	mockRedis.ExpectGet("google:self-hosted").SetVal(pools)
+
+	srv := newTestInFlightServer(t, db, nil, &cloudbuild.MockClient{})
+	srv.breaker = newPoolBreaker(1, time.Hour)
+	labels := []string{SelfHostedRunnerLabel}
+
+	srv.breaker.failure(testFailoverPoolName("project-1"), time.Now())
+	if got, want := srv.selectWorkerPool(ctx, orgLogin, labels, labels, nil).projectID, "project-2"; got != want {
+		t.Errorf("expected selected pool %q to be %q", got, want)
+	}
+
+	// Open pools are still used when no other pool can serve the job.
+	srv.breaker.failure(testFailoverPoolName("project-2"), time.Now())
+	if got, want := srv.selectWorkerPool(ctx, orgLogin, labels, labels, nil).projectID, "project-1"; got != want {
+		t.Errorf("expected selected pool %q to be %q", got, want)
+	}
+	if err := mockRedis.ExpectationsWereMet(); err != nil {
+		t.Errorf("redis expectations not met: %v", err)
+	}
+}
+
+func testFailoverEvent() *github.WorkflowJobEvent {
+	return &github.WorkflowJobEvent{
+		WorkflowJob:  &github.WorkflowJob{ID: github.Ptr(int64(1))},
+		Org:          &github.Organization{Login: github.Ptr(orgLogin)},
+		Repo:         &github.Repository{Name: github.Ptr("repo")},
+		Installation: &github.Installation{ID: github.Ptr(int64(123))},
+	}
+}
+
+func testFailoverPoolName(projectID string) string {
+	return fmt.Sprintf("projects/%s/locations/us-west1/workerPools/wp", projectID)
+}
+
+// testFailoverPools returns the registry value for a pool in each project.
+func testFailoverPools(tb testing.TB, projectIDs ...string) string {
+	tb.Helper()
+
+	pools := make([]registry.WorkerPoolInfo, 0, len(projectIDs))
+	for _, projectID := range projectIDs {
+		pools = append(pools, registry.WorkerPoolInfo{
+			Name:      testFailoverPoolName(projectID),
+			ProjectID: projectID,
+			Location:  "us-west1",
+		})
+	}
+	b, err := json.Marshal(pools)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return string(b)
+}
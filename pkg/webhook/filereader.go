@@ -35,3 +35,12 @@ func (o OSFileReader) ReadFile(filename string) ([]byte, error) {
 	}
 	return res, nil
 }
+
+// ReadDir returns the entries of a directory, sorted by filename.
+func (o OSFileReader) ReadDir(name string) ([]os.DirEntry, error) {
+	res, err := os.ReadDir(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	return res, nil
+}
@@ -16,6 +16,7 @@ package webhook
 
 import (
 	"fmt"
+	"os"
 )
 
 type ReadFileResErr struct {
@@ -26,6 +27,7 @@ type ReadFileResErr struct {
 type MockFileReader struct {
 	ReadFileMock *ReadFileResErr
 	ReadFileFunc func(filename string) ([]byte, error)
+	ReadDirFunc  func(name string) ([]os.DirEntry, error)
 }
 
 func (m *MockFileReader) ReadFile(filename string) ([]byte, error) {
@@ -39,3 +41,11 @@ func (m *MockFileReader) ReadFile(filename string) ([]byte, error) {
 	}
 	return nil, fmt.Errorf("mock ReadFile not implemented")
 }
+
+// ReadDir returns no entries if ReadDirFunc is not provided.
+func (m *MockFileReader) ReadDir(name string) ([]os.DirEntry, error) {
+	if m.ReadDirFunc != nil {
+		return m.ReadDirFunc(name)
+	}
+	return nil, nil
+}
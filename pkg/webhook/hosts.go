@@ -0,0 +1,125 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// gitHubEnterpriseHostHeader is set by GitHub Enterprise Server on every
+	// webhook delivery to the hostname of the instance that sent it.
+	gitHubEnterpriseHostHeader = "X-GitHub-Enterprise-Host"
+
+	// gitHubHookInstallationTargetIDHeader is set by GitHub on every webhook
+	// delivery for a GitHub App to the app's ID.
+	gitHubHookInstallationTargetIDHeader = "X-GitHub-Hook-Installation-Target-ID"
+
+	// hostRegistryScopeSeparator separates a GitHub host name from the rest of
+	// its registry keys.
+	hostRegistryScopeSeparator = "/"
+)
+
+// hostKeyPrefix returns the Redis key prefix for the dispatcher state of a
+// GitHub host other than the default one.
+func hostKeyPrefix(name string) string {
+	return fmt.Sprintf("%s/hosts/%s", dispatchKeyPrefix, name)
+}
+
+// forHost returns a copy of the config for serving a GitHub host. Enterprise
+// registration, warm pools and the admin API are only served for the default
+// host.
+func (cfg *Config) forHost(host *GitHubHost) *Config {
+	hostCfg := *cfg
+	hostCfg.GitHubAPIBaseURL = host.APIBaseURL
+	hostCfg.GitHubUploadURL = host.UploadURL
+	hostCfg.GitHubAppID = host.AppID
+	hostCfg.KMSAppPrivateKeyID = host.KMSAppPrivateKeyID
+	hostCfg.GitHubWebhookExtraKeyNames = host.WebhookExtraKeyNames
+	hostCfg.GitHubWebhookKeyName = host.WebhookKeyName
+	hostCfg.GitHubEnterprise = ""
+	hostCfg.GitHubEnterpriseInstallationID = 0
+	hostCfg.GitHubApps = nil
+	hostCfg.GitHubHosts = nil
+	hostCfg.WarmPools = nil
+	hostCfg.AdminAPIKeyMountPath = ""
+	hostCfg.AdminAPIKeyName = ""
+	return &hostCfg
+}
+
+// serverContext returns a context whose logger records the GitHub host or app
+// served by s, if it is not the default one.
+func (s *Server) serverContext(ctx context.Context) context.Context {
+	logger := logging.FromContext(ctx)
+	if s.host != nil {
+		logger = logger.With("github_host", s.host.Name)
+	}
+	if s.app != nil {
+		logger = logger.With("github_app", s.app.Name)
+	}
+	return logging.WithLogger(ctx, logger)
+}
+
+// routeWebhook returns an http.Handler that passes each webhook to the server
+// for the GitHub host and app that sent it. The host is taken from the
+// /webhook/<name> path, or else from the X-GitHub-Enterprise-Host header.
+// Deliveries for the default host are passed to the app whose ID is in the
+// X-GitHub-Hook-Installation-Target-ID header. Deliveries from a hostname or
+// app that is not configured are served by the default host and app.
+func (s *Server) routeWebhook() http.Handler {
+	handlers := make(map[*Server]http.Handler, 1+len(s.hosts)+len(s.apps))
+	handlers[s] = s.handleWebhook()
+	for _, hs := range s.hosts {
+		handlers[hs] = hs.handleWebhook()
+	}
+	for _, as := range s.apps {
+		handlers[as] = as.handleWebhook()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv := s.webhookServer(r.PathValue("host"), r.Header)
+		if srv == nil {
+			ctx := r.Context()
+			logging.FromContext(ctx).WarnContext(ctx, "received webhook for unknown github host",
+				"github_host", r.PathValue("host"))
+			http.Error(w, "unknown github host", http.StatusNotFound)
+			return
+		}
+		handlers[srv].ServeHTTP(w, r)
+	})
+}
+
+// webhookServer returns the server for the GitHub host and app that sent a
+// webhook, as described by routeWebhook. It returns nil if name is not empty
+// and is not the name of a configured GitHub host.
+func (s *Server) webhookServer(name string, header http.Header) *Server {
+	if name != "" {
+		return s.hosts[name]
+	}
+	hostname := header.Get(gitHubEnterpriseHostHeader)
+	for _, hs := range s.hosts {
+		if hs.host.Hostname == hostname {
+			return hs
+		}
+	}
+	if as, ok := s.apps[header.Get(gitHubHookInstallationTargetIDHeader)]; ok {
+		return as
+	}
+	return s
+}
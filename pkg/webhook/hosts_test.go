@@ -0,0 +1,195 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v69/github"
+
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	//nolint:gosec // this is a test value
+	testGHESWebhookSecret = "test-ghes-webhook-secret"
+	//nolint:gosec // this is a test value
+	testGHESNextWebhookSecret = "test-ghes-next-webhook-secret"
+)
+
+func TestRouteWebhook(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		path          string
+		hostHeader    string
+		secret        string
+		expStatusCode int
+		expRespBody   string
+	}{
+		{
+			name:          "default_host",
+			path:          "/webhook",
+			secret:        serverGitHubWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "host_by_path",
+			path:          "/webhook/ghes1",
+			secret:        testGHESWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "host_by_path_extra_key",
+			path:          "/webhook/ghes1",
+			secret:        testGHESNextWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "host_extra_key_rejected_by_default_host",
+			path:          "/webhook",
+			secret:        testGHESNextWebhookSecret,
+			expStatusCode: http.StatusBadRequest,
+			expRespBody:   "failed to validate github payload",
+		},
+		{
+			name:          "host_by_header",
+			path:          "/webhook",
+			hostHeader:    "github.example.com",
+			secret:        testGHESWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "unknown_header_uses_default_host",
+			path:          "/webhook",
+			hostHeader:    "other.example.com",
+			secret:        serverGitHubWebhookSecret,
+			expStatusCode: http.StatusOK,
+			expRespBody:   `no action taken for action type: "waiting"`,
+		},
+		{
+			name:          "host_secret_rejected_by_default_host",
+			path:          "/webhook",
+			secret:        testGHESWebhookSecret,
+			expStatusCode: http.StatusBadRequest,
+			expRespBody:   "failed to validate github payload",
+		},
+		{
+			name:          "unknown_host_path",
+			path:          "/webhook/ghes2",
+			secret:        testGHESWebhookSecret,
+			expStatusCode: http.StatusNotFound,
+			expRespBody:   "unknown github host",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			srv := newTestHostsServer(t)
+
+			action := "waiting"
+			payload, err := json.Marshal(&github.WorkflowJobEvent{
+				Action:      &action,
+				WorkflowJob: &github.WorkflowJob{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader(payload))
+			req.Header.Add(DeliveryIDHeader, "delivery-id")
+			req.Header.Add(EventTypeHeader, "workflow_job")
+			req.Header.Add(ContentTypeHeader, "application/json")
+			req.Header.Add(SHA256SignatureHeader, fmt.Sprintf("sha256=%s", createSignature([]byte(tc.secret), payload)))
+			if tc.hostHeader != "" {
+				req.Header.Add(gitHubEnterpriseHostHeader, tc.hostHeader)
+			}
+
+			resp := httptest.NewRecorder()
+			srv.Routes(ctx).ServeHTTP(resp, req)
+
+			if got, want := resp.Code, tc.expStatusCode; got != want {
+				t.Errorf("expected %d to be %d: %s", got, want, resp.Body.String())
+			}
+			if got, want := resp.Body.String(), tc.expRespBody; !strings.Contains(got, want) {
+				t.Errorf("expected body %q to contain %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGetRunnerKey_Host(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	srv := newTestHostsServer(t)
+
+	if got, want := srv.getRunnerKey(ctx, orgLogin, SelfHostedRunnerLabel), "google:self-hosted"; got != want {
+		t.Errorf("expected default host key %q to be %q", got, want)
+	}
+	if got, want := srv.hosts["ghes1"].getRunnerKey(ctx, orgLogin, SelfHostedRunnerLabel), "ghes1/google:self-hosted"; got != want {
+		t.Errorf("expected host key %q to be %q", got, want)
+	}
+	if got, want := srv.hosts["ghes1"].keyPrefix, "dispatcher/hosts/ghes1"; got != want {
+		t.Errorf("expected host key prefix %q to be %q", got, want)
+	}
+}
+
+// newTestHostsServer returns a server for the default GitHub host and a
+// "ghes1" host, each with its own webhook secret. The "ghes1" host also
+// accepts a second secret while it is rotated.
+func newTestHostsServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	cfg := generateValidConfig()
+	cfg.GitHubHostsRaw = []string{
+		"ghes1=hostname=github.example.com;api_base_url=https://github.example.com/api/v3;webhook_key_name=ghes1-key;webhook_extra_key_name=ghes1-key-next",
+	}
+	if err := cfg.Validate(); err != nil {
+		tb.Fatal(err)
+	}
+
+	secrets := map[string]string{
+		"/tmp/test-key":       serverGitHubWebhookSecret,
+		"/tmp/ghes1-key":      testGHESWebhookSecret,
+		"/tmp/ghes1-key-next": testGHESNextWebhookSecret,
+	}
+	wco := &WebhookClientOptions{
+		GitHubClientOverride: &gh.MockClient{},
+		GitHubHostClientOverrides: map[string]gh.Client{
+			"ghes1": &gh.MockClient{},
+		},
+		OSFileReaderOverride: newTestFileReader(secrets),
+	}
+
+	return newTestServer(tb, cfg, nil, wco)
+}
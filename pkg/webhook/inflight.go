@@ -0,0 +1,187 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/backend"
+	"github.com/abcxyz/github-action-dispatcher/pkg/inflight"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// inFlightKeyPrefix is the Redis key prefix for the builds in flight on
+	// each worker pool. Pools are shared by every GitHub host and app, so
+	// their load is tracked under a single prefix.
+	inFlightKeyPrefix = dispatchKeyPrefix + "/inflight"
+
+	// inFlightLockKey ensures only one instance reconciles builds in flight at
+	// a time.
+	inFlightLockKey = inFlightKeyPrefix + "/lock"
+)
+
+// trackBuild records a build started on a worker pool from the registry so
+// that it counts against the pool's load. The build is tracked until its job
+// completes, its status shows it has finished, or its runner has exceeded its
+// execution and idle timeouts.
+func (s *Server) trackBuild(ctx context.Context, pool *workerPool, build *runnerBuild) {
+	if s.inFlight == nil || pool == nil || pool.name == "" {
+		return
+	}
+
+	policy := s.runnerLabelPolicy(pool.label)
+	timeout := time.Duration(policy.RunnerExecutionTimeoutSeconds+policy.RunnerIdleTimeoutSeconds) * time.Second
+	if err := s.inFlight.Add(ctx, &inflight.Build{
+		RunnerName: build.RunnerName,
+		BuildID:    build.BuildID,
+		ProjectID:  build.ProjectID,
+		Location:   build.Location,
+		Pool:       pool.name,
+		PoolType:   build.PoolType,
+		ExpiresAt:  time.Now().Add(timeout),
+	}); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to track build in flight",
+			"error", err,
+			"runner_name", build.RunnerName,
+			"worker_pool", pool.name)
+	}
+}
+
+// untrackRunner stops counting the build of a runner against its pool's load.
+func (s *Server) untrackRunner(ctx context.Context, runnerName string) {
+	if s.inFlight == nil || runnerName == "" {
+		return
+	}
+
+	if err := s.inFlight.Remove(ctx, runnerName); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to stop tracking build in flight",
+			"error", err,
+			"runner_name", runnerName)
+	}
+}
+
+// loadWorkerPools sets the number of builds in flight on each pool. Pools are
+// treated as idle if their load cannot be read.
+func (s *Server) loadWorkerPools(ctx context.Context, pools []*workerPool) {
+	if s.inFlight == nil {
+		return
+	}
+
+	names := make([]string, 0, len(pools))
+	for _, pool := range pools {
+		names = append(names, pool.name)
+	}
+	counts, err := s.inFlight.Counts(ctx, names, time.Now())
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to count builds in flight, ignoring worker pool load", "error", err)
+		return
+	}
+	for _, pool := range pools {
+		pool.inFlight = counts[pool.name]
+	}
+}
+
+// StartInFlightReconciler starts a background loop that stops tracking builds
+// which have finished without a completed event for their job, such as extra
+// runners that exited after their idle timeout. The loop runs until the context
+// is cancelled. It is a no-op when builds in flight are not tracked or the
+// interval is not positive.
+func (s *Server) StartInFlightReconciler(ctx context.Context) {
+	if s.inFlight == nil || s.config.InFlightReconcileInterval <= 0 {
+		return
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "starting in-flight build reconciler",
+		"interval", s.config.InFlightReconcileInterval.String())
+	go func() {
+		ticker := time.NewTicker(s.config.InFlightReconcileInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				logging.FromContext(ctx).InfoContext(ctx, "stopping in-flight build reconciler")
+				return
+			case <-ticker.C:
+			}
+
+			s.reconcileInFlight(ctx)
+		}
+	}()
+}
+
+// reconcileInFlight checks the status of every build in flight and stops
+// tracking the ones that have finished. It returns the names of the runners
+// that are no longer tracked. When a registry is configured, only one instance
+// reconciles per interval.
+func (s *Server) reconcileInFlight(ctx context.Context) []string {
+	logger := logging.FromContext(ctx)
+
+	if s.rc != nil {
+		locked, err := s.rc.SetNX(ctx, inFlightLockKey, "locked", s.config.InFlightReconcileInterval).Result()
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to acquire in-flight build lock", "error", err)
+			return nil
+		}
+		if !locked {
+			logger.DebugContext(ctx, "builds in flight are being reconciled by another instance")
+			return nil
+		}
+	}
+
+	builds, err := s.inFlight.List(ctx, time.Now())
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list builds in flight", "error", err)
+		return nil
+	}
+
+	var finished []string
+	for _, build := range builds {
+		status, err := s.runnerBackend(build.PoolType).RunnerStatus(ctx, &backend.Runner{
+			Name:      build.RunnerName,
+			ID:        build.BuildID,
+			ProjectID: build.ProjectID,
+			Location:  build.Location,
+		})
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to get build",
+				"error", err,
+				"runner_name", build.RunnerName,
+				gcbBuildIDKey, build.BuildID,
+				gcbProjectIDKey, build.ProjectID)
+			continue
+		}
+		if status.Active {
+			continue
+		}
+
+		if err := s.inFlight.Remove(ctx, build.RunnerName); err != nil {
+			logger.ErrorContext(ctx, "failed to stop tracking finished build",
+				"error", err,
+				"runner_name", build.RunnerName)
+			continue
+		}
+		finished = append(finished, build.RunnerName)
+	}
+
+	if len(finished) > 0 {
+		logger.InfoContext(ctx, "stopped tracking finished builds",
+			"runner_names", finished,
+			"builds_in_flight", len(builds)-len(finished))
+	}
+	return finished
+}
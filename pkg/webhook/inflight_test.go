@@ -0,0 +1,221 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/github-action-dispatcher/pkg/inflight"
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	testInFlightPool1 = "projects/1/locations/us-west1/workerPools/wp1"
+	testInFlightPool2 = "projects/2/locations/us-west1/workerPools/wp2"
+)
+
+func TestSelectWorkerPool_InFlight(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		maxPool1   int
+		inFlight1  int
+		inFlight2  int
+		expProject string
+	}{
+		{
+			name:       "least_loaded",
+			inFlight1:  1,
+			inFlight2:  3,
+			expProject: "project-1",
+		},
+		{
+			name:       "skips_pool_at_max_concurrency",
+			maxPool1:   2,
+			inFlight1:  2,
+			inFlight2:  2,
+			expProject: "project-2",
+		},
+		{
+			name:       "all_pools_at_max_concurrency",
+			maxPool1:   1,
+			inFlight1:  2,
+			inFlight2:  3,
+			expProject: "project-1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			pools, err := json.Marshal([]registry.WorkerPoolInfo{
+				{Name: testInFlightPool1, ProjectID: "project-1", MaxConcurrency: tc.maxPool1},
+				{Name: testInFlightPool2, ProjectID: "project-2", MaxConcurrency: 3},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			db, mockRedis := redismock.NewClientMock()
+			mockRedis.ExpectGet("google:self-hosted").SetVal(string(pools))
+
+			tracker := inflight.NewMemoryTracker()
+			addTestBuilds(t, tracker, testInFlightPool1, tc.inFlight1)
+			addTestBuilds(t, tracker, testInFlightPool2, tc.inFlight2)
+
+			srv := newTestInFlightServer(t, db, tracker, &cloudbuild.MockClient{})
+
+			labels := []string{SelfHostedRunnerLabel}
+			pool := srv.selectWorkerPool(ctx, orgLogin, labels, labels, nil)
+			if pool == nil {
+				t.Fatal("expected a worker pool to be selected")
+			}
+			if got, want := pool.projectID, tc.expProject; got != want {
+				t.Errorf("expected selected pool %q to be %q", got, want)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestTrackBuild(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	tracker := inflight.NewMemoryTracker()
+	srv := newTestInFlightServer(t, nil, tracker, &cloudbuild.MockClient{})
+
+	srv.trackBuild(ctx, &workerPool{name: testInFlightPool1, label: SelfHostedRunnerLabel}, &runnerBuild{RunnerName: "runner-1"})
+	srv.trackBuild(ctx, &workerPool{name: testInFlightPool1, label: SelfHostedRunnerLabel}, &runnerBuild{RunnerName: "runner-2"})
+	// Builds on pools that are not from the registry are not tracked.
+	srv.trackBuild(ctx, &workerPool{projectID: "runner-404"}, &runnerBuild{RunnerName: "runner-3"})
+	srv.untrackRunner(ctx, "runner-1")
+
+	counts, err := tracker.Counts(ctx, []string{testInFlightPool1, ""}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]int{testInFlightPool1: 1, "": 0}, counts); diff != "" {
+		t.Errorf("counts (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReconcileInFlight(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	tracker := inflight.NewMemoryTracker()
+	for _, b := range []*inflight.Build{
+		{RunnerName: "runner-working", BuildID: "build-working", Pool: testInFlightPool1},
+		{RunnerName: "runner-success", BuildID: "build-success", Pool: testInFlightPool1},
+		{RunnerName: "runner-unknown", BuildID: "build-unknown", Pool: testInFlightPool2},
+		{RunnerName: "runner-timeout", BuildID: "build-timeout", Pool: testInFlightPool2},
+	} {
+		b.ProjectID = "project"
+		b.Location = "us-west1"
+		b.ExpiresAt = time.Now().Add(time.Hour)
+		if err := tracker.Add(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mockCloudBuildClient := &cloudbuild.MockClient{
+		Builds: map[string]*cloudbuildpb.Build{
+			"build-working": {Status: cloudbuildpb.Build_WORKING},
+			"build-success": {Status: cloudbuildpb.Build_SUCCESS},
+			"build-timeout": {Status: cloudbuildpb.Build_TIMEOUT},
+		},
+	}
+	srv := newTestInFlightServer(t, nil, tracker, mockCloudBuildClient)
+
+	finished := srv.reconcileInFlight(ctx)
+	if diff := cmp.Diff([]string{"runner-success", "runner-timeout"}, finished); diff != "" {
+		t.Errorf("finished runners (-want, +got):\n%s", diff)
+	}
+
+	builds, err := tracker.List(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remaining []string
+	for _, b := range builds {
+		remaining = append(remaining, b.RunnerName)
+	}
+	if diff := cmp.Diff([]string{"runner-unknown", "runner-working"}, remaining); diff != "" {
+		t.Errorf("remaining runners (-want, +got):\n%s", diff)
+	}
+	if got, want := mockCloudBuildClient.GetBuildReqs[0].GetName(), "projects/project/locations/us-west1/builds/build-success"; got != want {
+		t.Errorf("expected build name %q to be %q", got, want)
+	}
+}
+
+// addTestBuilds tracks n builds in flight on a pool.
+func addTestBuilds(tb testing.TB, tracker inflight.Tracker, pool string, n int) {
+	tb.Helper()
+
+	for i := range n {
+		if err := tracker.Add(tb.Context(), &inflight.Build{
+			RunnerName: fmt.Sprintf("%s-%d", pool, i),
+			Pool:       pool,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+// newTestInFlightConfig returns the config of a server that tracks builds in
+// flight.
+func newTestInFlightConfig() *Config {
+	return &Config{
+		InFlightTrackingEnabled:        true,
+		InFlightReconcileInterval:      time.Minute,
+		RunnerExecutionTimeoutSeconds:  3600,
+		RunnerRegistryDefaultKeyPrefix: "default",
+		SupportedRunnerLabels:          []string{SelfHostedRunnerLabel},
+	}
+}
+
+// newTestInFlightServer returns a server that tracks builds in flight with the
+// given tracker.
+func newTestInFlightServer(tb testing.TB, db *redis.Client, tracker inflight.Tracker, cbc cloudbuild.Client) *Server {
+	tb.Helper()
+
+	return newTestServer(tb, newTestInFlightConfig(), db, &WebhookClientOptions{
+		CloudBuildClientOverride: cbc,
+		GitHubClientOverride:     &gh.MockClient{},
+		InFlightTrackerOverride:  tracker,
+		RandOverride:             fixedRand(0),
+	})
+}
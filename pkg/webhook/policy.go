@@ -0,0 +1,74 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/go-github/v69/github"
+
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/github-action-dispatcher/pkg/policy"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// allowedByPolicy reports whether the dispatch policy allows runners to be
+// started for the job. Every job is allowed when no policy is configured. An
+// error is returned when the policy cannot be evaluated yet, so that the job
+// can be retried rather than denied.
+// Rules are matched against both the labels the job requested and the labels
+// they resolve to, so that an alias cannot be used to get around a rule.
+func (s *Server) allowedByPolicy(ctx context.Context, event *github.WorkflowJobEvent, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string) (bool, error) {
+	if s.policy == nil {
+		return true, nil
+	}
+
+	logger := logging.FromContext(ctx)
+
+	labels := slices.Concat(jobOriginalRunnerLabels, jobResolvedRunnerLabels)
+	slices.Sort(labels)
+	job := &policy.Job{
+		Org:        event.GetOrg().GetLogin(),
+		Repo:       event.GetRepo().GetName(),
+		Visibility: event.GetRepo().GetVisibility(),
+		Labels:     slices.Compact(labels),
+	}
+
+	// The workflow file is not part of the event, so it is only looked up
+	// when a rule needs it. A job whose workflow run does not exist is
+	// denied, since a rule denying its workflow could otherwise not match.
+	if s.policy.UsesWorkflows() {
+		path, err := s.ghc.WorkflowRunPath(ctx, event.GetInstallation().GetID(), job.Org, job.Repo, event.GetWorkflowJob().GetRunID())
+		if err != nil {
+			if !errors.Is(err, gh.ErrNotFound) {
+				return false, fmt.Errorf("failed to look up workflow path for dispatch policy: %w", err)
+			}
+			logger.WarnContext(ctx, "workflow run not found for dispatch policy, denying job", "error", err)
+			return false, nil
+		}
+		job.WorkflowPath = path
+	}
+
+	decision := s.policy.Evaluate(job)
+	logger.InfoContext(ctx, "evaluated dispatch policy",
+		"policy_rule", decision.Rule,
+		"policy_effect", decision.Effect,
+		"workflow_path", job.WorkflowPath,
+		"repo_visibility", job.Visibility)
+	return decision.Allowed, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestHandleQueuedEvent_Policy(t *testing.T) {
+	t.Parallel()
+
+	const policyFile = "policy.json"
+	pools := testFailoverPools(t, "test-project")
+
+	cases := []struct {
+		name              string
+		policy            string
+		runner404Enabled  bool
+		workflowPath      string
+		workflowPathErr   error
+		expCode           int
+		expMessage        string
+		expBuildProjects  []string
+		expWorkflowLookup bool
+	}{
+		{
+			name:             "allowed_by_rule",
+			policy:           `{"default_effect": "deny", "rules": [{"name": "allow-org", "effect": "allow", "orgs": ["google"]}]}`,
+			expCode:          http.StatusOK,
+			expBuildProjects: []string{"test-project"},
+		},
+		{
+			name:       "denied_without_404_runner",
+			policy:     `{"rules": [{"name": "deny-repo", "effect": "deny", "repos": ["web*"]}]}`,
+			expCode:    http.StatusOK,
+			expMessage: fmt.Sprintf("no action taken, job denied by dispatch policy: %s", SelfHostedRunnerLabel),
+		},
+		{
+			name:             "denied_with_404_runner",
+			policy:           `{"rules": [{"name": "deny-public", "effect": "deny", "visibilities": ["public"]}]}`,
+			runner404Enabled: true,
+			expCode:          http.StatusOK,
+			expBuildProjects: []string{"404-project"},
+		},
+		{
+			name:              "denied_by_workflow",
+			policy:            `{"rules": [{"name": "deny-untrusted", "effect": "deny", "workflows": [".github/workflows/untrusted-*.yml"]}]}`,
+			workflowPath:      ".github/workflows/untrusted-build.yml",
+			expCode:           http.StatusOK,
+			expMessage:        fmt.Sprintf("no action taken, job denied by dispatch policy: %s", SelfHostedRunnerLabel),
+			expWorkflowLookup: true,
+		},
+		{
+			name:              "workflow_run_not_found_denies_job",
+			policy:            `{"rules": [{"name": "deny-untrusted", "effect": "deny", "workflows": [".github/workflows/untrusted-*.yml"]}]}`,
+			workflowPathErr:   fmt.Errorf("server responded with 404 status code: %w", gh.ErrNotFound),
+			expCode:           http.StatusOK,
+			expMessage:        fmt.Sprintf("no action taken, job denied by dispatch policy: %s", SelfHostedRunnerLabel),
+			expWorkflowLookup: true,
+		},
+		{
+			name:              "workflow_run_not_found_with_404_runner",
+			policy:            `{"rules": [{"name": "deny-untrusted", "effect": "deny", "workflows": [".github/workflows/untrusted-*.yml"]}]}`,
+			runner404Enabled:  true,
+			workflowPathErr:   fmt.Errorf("server responded with 404 status code: %w", gh.ErrNotFound),
+			expCode:           http.StatusOK,
+			expBuildProjects:  []string{"404-project"},
+			expWorkflowLookup: true,
+		},
+		{
+			name:              "workflow_lookup_failure_retries_job",
+			policy:            `{"rules": [{"name": "deny-untrusted", "effect": "deny", "workflows": [".github/workflows/untrusted-*.yml"]}]}`,
+			runner404Enabled:  true,
+			workflowPathErr:   fmt.Errorf("server responded with 503 status code"),
+			expCode:           http.StatusInternalServerError,
+			expWorkflowLookup: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			encodedJitConfig := "Hello"
+			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+			mockGitHubClient := &gh.MockClient{
+				GenerateRepoJITConfigF: func(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error) {
+					return &github.JITRunnerConfig{EncodedJITConfig: &encodedJitConfig}, nil
+				},
+				WorkflowRunPathF: func(ctx context.Context, installationID int64, org, repo string, runID int64) (string, error) {
+					return tc.workflowPath, tc.workflowPathErr
+				},
+			}
+
+			cfg := &Config{
+				DispatchPolicyFile:            policyFile,
+				RunnerExecutionTimeoutSeconds: 3600,
+				RunnerIdleTimeoutSeconds:      300,
+				RunnerProjectID:               "test-project",
+				SupportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+				Runner404Enabled:              tc.runner404Enabled,
+				Runner404Location:             "us-central1",
+				Runner404ProjectID:            "404-project",
+				Runner404ServiceAccount:       "404-sa",
+			}
+			wco := &WebhookClientOptions{
+				CloudBuildClientOverride: mockCloudBuildClient,
+				GitHubClientOverride:     mockGitHubClient,
+				OSFileReaderOverride: &MockFileReader{
+					ReadFileFunc: func(filename string) ([]byte, error) {
+						if filename == policyFile {
+							return []byte(tc.policy), nil
+						}
+						return []byte(serverGitHubWebhookSecret), nil
+					},
+				},
+			}
+
+			// The registry is only read for jobs that are allowed.
+			db, mockRedis := redismock.NewClientMock()
+			if slices.Contains(tc.expBuildProjects, "test-project") {
+				mockRedis.ExpectGet("google:self-hosted").SetVal(pools)
+			}
+
+			srv := newTestServer(t, cfg, db, wco)
+
+			event := &github.WorkflowJobEvent{
+				WorkflowJob: &github.WorkflowJob{
+					ID:     github.Ptr(int64(1)),
+					RunID:  github.Ptr(int64(2)),
+					Labels: []string{SelfHostedRunnerLabel},
+				},
+				Installation: &github.Installation{ID: github.Ptr(int64(123))},
+				Org:          &github.Organization{Login: github.Ptr(orgLogin)},
+				Repo:         &github.Repository{Name: github.Ptr(repoName), Visibility: github.Ptr("public")},
+			}
+
+			resp := srv.handleQueuedEvent(ctx, event, "delivery-id", "1")
+			if got, want := resp.Code, tc.expCode; got != want {
+				t.Errorf("expected code %d, got %d: %s", want, got, resp.Message)
+			}
+			if tc.expMessage != "" {
+				if got, want := resp.Message, tc.expMessage; got != want {
+					t.Errorf("expected message %q, got %q", want, got)
+				}
+			}
+
+			var gotBuildProjects []string
+			for _, req := range mockCloudBuildClient.CreateBuildReqs {
+				gotBuildProjects = append(gotBuildProjects, req.GetProjectId())
+			}
+			if diff := cmp.Diff(tc.expBuildProjects, gotBuildProjects); diff != "" {
+				t.Errorf("builds (-want, +got):\n%s", diff)
+			}
+
+			if got, want := mockGitHubClient.WorkflowRunPathCalls > 0, tc.expWorkflowLookup; got != want {
+				t.Errorf("expected workflow lookup to be %t, got %t", want, got)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewServer_InvalidPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	cfg := &Config{
+		GitHubWebhookKeyMountPath: "test-path",
+		GitHubWebhookKeyName:      "test-key",
+		DispatchPolicyFile:        "policy.json",
+	}
+	wco := &WebhookClientOptions{
+		CloudBuildClientOverride: &cloudbuild.MockClient{},
+		GitHubClientOverride:     &gh.MockClient{},
+		OSFileReaderOverride: &MockFileReader{
+			ReadFileMock: &ReadFileResErr{Res: []byte(`{"rules": [{"name": "r", "effect": "block"}]}`)},
+		},
+		KeyManagementClientOverride: &MockKMSClient{},
+	}
+
+	if _, err := NewServer(ctx, nil, cfg, nil, wco); err == nil {
+		t.Errorf("expected error loading invalid policy")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/quota"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// quotaKeyPath is appended to a server's key prefix to form the Redis key
+	// prefix for its concurrency quotas.
+	quotaKeyPath = "/quotas"
+
+	jobDeferredMsg = "job deferred, concurrency quota exceeded"
+)
+
+// errQuotaExceeded is returned for jobs that are deferred because a
+// concurrency quota is full.
+var errQuotaExceeded = errors.New(jobDeferredMsg)
+
+// quotasResponse is the usage of every configured concurrency quota.
+type quotasResponse struct {
+	Quotas []*quota.Usage `json:"quotas"`
+}
+
+// jobQuotaLimits returns the concurrency quotas that apply to a job: the quota
+// of its organization and the quota of its repository, when configured.
+func (s *Server) jobQuotaLimits(event *github.WorkflowJobEvent) []*quota.Limit {
+	orgName := event.GetOrg().GetLogin()
+	keys := []string{orgName, fmt.Sprintf("%s/%s", orgName, event.GetRepo().GetName())}
+
+	var limits []*quota.Limit
+	for _, key := range keys {
+		if limit, ok := s.config.ConcurrencyQuotas[key]; ok {
+			limits = append(limits, &quota.Limit{Key: key, Max: limit})
+		}
+	}
+	return limits
+}
+
+// acquireQuota takes a slot for each runner that could be started for the job,
+// including extra runners, under each concurrency quota that applies to it. It
+// returns a response deferring the job when a quota is full, or nil when the
+// job may be dispatched. Jobs are dispatched when the quotas cannot be read, so
+// that an outage of the registry does not stop every job.
+func (s *Server) acquireQuota(ctx context.Context, event *github.WorkflowJobEvent, jobID string, jobResolvedRunnerLabels []string) *apiResponse {
+	if s.quotas == nil {
+		return nil
+	}
+	limits := s.jobQuotaLimits(event)
+	if len(limits) == 0 {
+		return nil
+	}
+
+	logger := logging.FromContext(ctx)
+
+	// The slots are held until the job completes, or until every runner that
+	// could have been started for it has timed out. The pool the job is
+	// dispatched to is not known yet, so the label that starts the most
+	// runners is counted.
+	var timeoutSeconds, runners int
+	for _, label := range jobResolvedRunnerLabels {
+		policy := s.runnerLabelPolicy(label)
+		timeoutSeconds = max(timeoutSeconds, policy.RunnerExecutionTimeoutSeconds+policy.RunnerIdleTimeoutSeconds)
+		runners = max(runners, 1+policy.ExtraRunnerCount)
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(timeoutSeconds) * time.Second)
+
+	acquired, usage, err := s.quotas.Acquire(ctx, jobID, runners, limits, expiresAt, now)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to acquire concurrency quota, dispatching anyway", "error", err)
+		return nil
+	}
+
+	for _, u := range usage {
+		attrs := []any{
+			slog.String("quota_key", u.Key),
+			slog.Int("quota_limit", u.Limit),
+			slog.Int("quota_used", u.Used),
+			slog.Int("quota_runners", runners),
+		}
+		if acquired {
+			logger.InfoContext(ctx, "acquired concurrency quota", attrs...)
+		} else if u.Used >= u.Limit {
+			logger.WarnContext(ctx, "concurrency quota exceeded, deferring job", attrs...)
+		}
+	}
+	if acquired {
+		return nil
+	}
+	return &apiResponse{http.StatusTooManyRequests, jobDeferredMsg, errQuotaExceeded}
+}
+
+// releaseQuota gives up the job's slots under the concurrency quotas that
+// apply to it.
+func (s *Server) releaseQuota(ctx context.Context, event *github.WorkflowJobEvent, jobID string) {
+	if s.quotas == nil {
+		return
+	}
+	limits := s.jobQuotaLimits(event)
+	if len(limits) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(limits))
+	for _, limit := range limits {
+		keys = append(keys, limit.Key)
+	}
+	if err := s.quotas.Release(ctx, jobID, keys); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to release concurrency quota",
+			"error", err,
+			"quota_keys", keys)
+	}
+}
+
+// handleAdminQuotas lists the usage of every configured concurrency quota.
+func (s *Server) handleAdminQuotas(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if s.quotas == nil {
+		s.h.RenderJSON(w, http.StatusOK, &quotasResponse{Quotas: []*quota.Usage{}})
+		return
+	}
+
+	limits := make([]*quota.Limit, 0, len(s.config.ConcurrencyQuotas))
+	for key, limit := range s.config.ConcurrencyQuotas {
+		limits = append(limits, &quota.Limit{Key: key, Max: limit})
+	}
+	slices.SortFunc(limits, func(a, b *quota.Limit) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	usage, err := s.quotas.Usage(ctx, limits, time.Now())
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to get concurrency quota usage", "error", err)
+		s.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to get concurrency quota usage"))
+		return
+	}
+	s.h.RenderJSON(w, http.StatusOK, &quotasResponse{Quotas: usage})
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/github-action-dispatcher/pkg/queue"
+	"github.com/abcxyz/github-action-dispatcher/pkg/quota"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestDispatchQueue_Quota(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	// Another job in the repo already holds the only slot.
+	const otherJobID = int64(456)
+	quotas := quota.NewMemoryLimiter()
+	repoKey := orgLogin + "/" + repoName
+	if _, _, err := quotas.Acquire(ctx, "456", 1, []*quota.Limit{{Key: repoKey, Max: 1}}, time.Now().Add(time.Hour), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	q := queue.NewMemoryQueue()
+	mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+	srv := newTestQueueServer(t, q, mockCloudBuildClient, 1)
+	srv.config.ConcurrencyQuotas = map[string]int{repoKey: 1}
+	srv.config.ConcurrencyQuotaRetryDelay = time.Millisecond
+	srv.quotas = quotas
+
+	resp := httptest.NewRecorder()
+	srv.handleWebhook().ServeHTTP(resp, newQueuedRequest(t))
+	if got, want := resp.Code, http.StatusAccepted; got != want {
+		t.Fatalf("expected %d to be %d", got, want)
+	}
+
+	// The job is deferred without using up its only attempt.
+	if _, err := srv.dispatchNext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(mockCloudBuildClient.CreateBuildReqs), 0; got != want {
+		t.Errorf("expected %d builds to be created while over quota, got %d", want, got)
+	}
+	if got, want := q.Len(), 1; got != want {
+		t.Errorf("expected queue length %d to be %d", got, want)
+	}
+
+	// The other job completing frees its slot.
+	action := "completed"
+	resp = httptest.NewRecorder()
+	srv.handleWebhook().ServeHTTP(resp, newWebhookRequest(t, &github.WorkflowJobEvent{
+		Action:       &action,
+		WorkflowJob:  &github.WorkflowJob{ID: github.Ptr(otherJobID)},
+		Installation: &github.Installation{ID: github.Ptr(int64(123))},
+		Org:          &github.Organization{Login: github.Ptr(orgLogin)},
+		Repo:         &github.Repository{Name: github.Ptr(repoName)},
+	}))
+	if got, want := resp.Code, http.StatusOK; got != want {
+		t.Fatalf("expected %d to be %d", got, want)
+	}
+
+	time.Sleep(srv.config.ConcurrencyQuotaRetryDelay)
+	if _, err := srv.dispatchNext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(mockCloudBuildClient.CreateBuildReqs), 1; got != want {
+		t.Errorf("expected %d builds to be created, got %d", want, got)
+	}
+	if got, want := q.Len(), 0; got != want {
+		t.Errorf("expected queue length %d to be %d", got, want)
+	}
+
+	usage, err := quotas.Usage(ctx, []*quota.Limit{{Key: repoKey, Max: 1}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := usage[0].Used, 1; got != want {
+		t.Errorf("expected %d slots to be used, got %d", want, got)
+	}
+}
+
+func TestAcquireQuota_CountsRunners(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	quotas := quota.NewMemoryLimiter()
+	srv := newTestQueueServer(t, queue.NewMemoryQueue(), &cloudbuild.MockClient{}, 1)
+	srv.config.ConcurrencyQuotas = map[string]int{orgLogin: 5}
+	srv.extraRunnerCount = 2
+	srv.quotas = quotas
+
+	event := &github.WorkflowJobEvent{
+		Org:  &github.Organization{Login: github.Ptr(orgLogin)},
+		Repo: &github.Repository{Name: github.Ptr(repoName)},
+	}
+	labels := []string{SelfHostedRunnerLabel}
+
+	// Each job starts three runners, so only one fits under the quota.
+	if resp := srv.acquireQuota(ctx, event, "1", labels); resp != nil {
+		t.Fatalf("expected first job to acquire quota, got %d: %s", resp.Code, resp.Message)
+	}
+	resp := srv.acquireQuota(ctx, event, "2", labels)
+	if resp == nil {
+		t.Fatal("expected second job to be deferred")
+	}
+	if got, want := resp.Code, http.StatusTooManyRequests; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+
+	usage, err := quotas.Usage(ctx, []*quota.Limit{{Key: orgLogin, Max: 5}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := usage[0].Used, 3; got != want {
+		t.Errorf("expected %d slots to be used, got %d", want, got)
+	}
+}
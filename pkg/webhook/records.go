@@ -0,0 +1,133 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/dispatch"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// dispatchRecordKeyPath is appended to a server's key prefix to form the Redis
+// key prefix for its dispatch records.
+const dispatchRecordKeyPath = "/records"
+
+// putDispatchRecord stores a record of the runners started for a job. Runners
+// that were started before a failure are recorded so that their builds can
+// still be traced. Failures are logged and do not fail the dispatch.
+func (s *Server) putDispatchRecord(ctx context.Context, event *github.WorkflowJobEvent, deliveryID string, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string, builds []*runnerBuild) {
+	runners := make([]*dispatch.Runner, 0, len(builds))
+	for _, build := range builds {
+		runners = append(runners, &dispatch.Runner{
+			Name:      build.RunnerName,
+			BuildID:   build.BuildID,
+			ProjectID: build.ProjectID,
+			Location:  build.Location,
+			Pool:      build.poolName,
+			PoolType:  build.PoolType,
+			Label:     build.label,
+		})
+	}
+	s.putRecord(ctx, event, deliveryID, jobOriginalRunnerLabels, jobResolvedRunnerLabels, runners)
+}
+
+// putWarmRunnerRecord stores a record of the warm runner reserved for a job.
+func (s *Server) putWarmRunnerRecord(ctx context.Context, event *github.WorkflowJobEvent, deliveryID string, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string, wp *WarmPoolConfig, runnerName string) {
+	s.putRecord(ctx, event, deliveryID, jobOriginalRunnerLabels, jobResolvedRunnerLabels, []*dispatch.Runner{{
+		Name:  runnerName,
+		Label: wp.Label,
+		Warm:  true,
+	}})
+}
+
+// putRecord stores a record of the runners dispatched for a job.
+func (s *Server) putRecord(ctx context.Context, event *github.WorkflowJobEvent, deliveryID string, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string, runners []*dispatch.Runner) {
+	if s.records == nil || len(runners) == 0 {
+		return
+	}
+
+	job := event.WorkflowJob
+	record := &dispatch.Record{
+		JobID:          strconv.FormatInt(job.GetID(), 10),
+		JobName:        job.GetName(),
+		DeliveryID:     deliveryID,
+		Org:            event.GetOrg().GetLogin(),
+		Repo:           event.GetRepo().GetName(),
+		Labels:         jobOriginalRunnerLabels,
+		ResolvedLabels: jobResolvedRunnerLabels,
+		Runners:        runners,
+		Status:         dispatch.StatusDispatched,
+		DispatchedAt:   time.Now().UTC(),
+	}
+	if job.RunID != nil {
+		record.RunID = strconv.FormatInt(job.GetRunID(), 10)
+	}
+	if job.CreatedAt != nil {
+		record.QueuedAt = job.CreatedAt.UTC()
+	}
+
+	if err := s.records.Put(ctx, record); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to store dispatch record", "error", err)
+	}
+}
+
+// updateDispatchRecord applies the progress reported by an in_progress or
+// completed event to the job's dispatch record. Jobs without a record, such as
+// those dispatched by another service, are ignored.
+func (s *Server) updateDispatchRecord(ctx context.Context, event *github.WorkflowJobEvent, jobID string) {
+	if s.records == nil || jobID == "" {
+		return
+	}
+
+	logger := logging.FromContext(ctx)
+	job := event.WorkflowJob
+
+	record, err := s.records.Update(ctx, jobID, func(r *dispatch.Record) {
+		if runnerName := job.GetRunnerName(); runnerName != "" {
+			r.AssignedRunnerName = runnerName
+		}
+
+		switch event.GetAction() {
+		case "in_progress":
+			r.Status = dispatch.StatusInProgress
+			r.StartedAt = timestampOrNow(job.StartedAt)
+		case "completed":
+			r.Status = dispatch.StatusCompleted
+			r.Conclusion = job.GetConclusion()
+			r.CompletedAt = timestampOrNow(job.CompletedAt)
+		}
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to update dispatch record", "error", err)
+		return
+	}
+	if record == nil {
+		logger.DebugContext(ctx, "no dispatch record found for job")
+	}
+}
+
+// timestampOrNow returns the time of ts in UTC, or the current time if ts is
+// not set.
+func timestampOrNow(ts *github.Timestamp) time.Time {
+	if ts == nil || ts.IsZero() {
+		return time.Now().UTC()
+	}
+	return ts.UTC()
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/github-action-dispatcher/pkg/dispatch"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestDispatchRecords(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	store := dispatch.NewMemoryStore()
+	cfg := &Config{
+		RunnerExecutionTimeoutSeconds: 3600,
+		RunnerIdleTimeoutSeconds:      300,
+		SupportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+		Runner404Location:             "us-central1",
+		Runner404ProjectID:            "404-project",
+		Runner404ServiceAccount:       "404-sa",
+	}
+	wco := &WebhookClientOptions{
+		CloudBuildClientOverride:    &cloudbuild.MockClient{CreateBuildID: testGCBBuildID},
+		DispatchRecordStoreOverride: store,
+	}
+
+	srv := newTestServer(t, cfg, nil, wco)
+
+	resp := httptest.NewRecorder()
+	srv.handleWebhook().ServeHTTP(resp, newQueuedRequest(t))
+	if got, want := resp.Code, http.StatusOK; got != want {
+		t.Fatalf("expected %d to be %d: %s", got, want, resp.Body.String())
+	}
+
+	var runners runnersResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &runners); err != nil {
+		t.Fatal(err)
+	}
+	runnerName := runners.RunnerNames[0]
+
+	record, err := store.GetByBuildID(ctx, testGCBBuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record == nil {
+		t.Fatal("expected dispatch record to be stored")
+	}
+	if diff := cmp.Diff(&dispatch.Record{
+		JobID:          "789",
+		DeliveryID:     "delivery-id",
+		Org:            orgLogin,
+		Repo:           repoName,
+		Labels:         []string{SelfHostedRunnerLabel},
+		ResolvedLabels: []string{SelfHostedRunnerLabel},
+		Runners:        []*dispatch.Runner{{Name: runnerName, BuildID: testGCBBuildID, ProjectID: "404-project", Location: "us-central1"}},
+		Status:         dispatch.StatusDispatched,
+	}, record, cmpIgnoreDispatchedAt); diff != "" {
+		t.Errorf("dispatched record mismatch (-want +got):\n%s", diff)
+	}
+
+	startedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	completedAt := startedAt.Add(time.Minute)
+	for _, event := range []*github.WorkflowJobEvent{
+		newWorkflowJobEvent("in_progress", &github.WorkflowJob{
+			RunnerName: &runnerName,
+			StartedAt:  &github.Timestamp{Time: startedAt},
+		}),
+		newWorkflowJobEvent("completed", &github.WorkflowJob{
+			RunnerName:  &runnerName,
+			Conclusion:  github.Ptr("success"),
+			CompletedAt: &github.Timestamp{Time: completedAt},
+		}),
+	} {
+		resp := httptest.NewRecorder()
+		srv.handleWebhook().ServeHTTP(resp, newWebhookRequest(t, event))
+		if got, want := resp.Code, http.StatusOK; got != want {
+			t.Fatalf("expected %d to be %d: %s", got, want, resp.Body.String())
+		}
+	}
+
+	record, err = store.GetByRunnerName(ctx, runnerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := record.Status, dispatch.StatusCompleted; got != want {
+		t.Errorf("expected status %q to be %q", got, want)
+	}
+	if got, want := record.Conclusion, "success"; got != want {
+		t.Errorf("expected conclusion %q to be %q", got, want)
+	}
+	if got, want := record.AssignedRunnerName, runnerName; got != want {
+		t.Errorf("expected assigned runner %q to be %q", got, want)
+	}
+	if got, want := record.StartedAt, startedAt; !got.Equal(want) {
+		t.Errorf("expected started at %s to be %s", got, want)
+	}
+	if got, want := record.CompletedAt, completedAt; !got.Equal(want) {
+		t.Errorf("expected completed at %s to be %s", got, want)
+	}
+}
+
+func TestDispatchRecords_Failover(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	pools := testFailoverPools(t, "project-1", "project-2")
+	db, mockRedis := redismock.NewClientMock()
+	mockRedis.ExpectGet("google:self-hosted").SetVal(pools)
+	mockRedis.ExpectGet("google:self-hosted").SetVal(pools)
+
+	store := dispatch.NewMemoryStore()
+	srv := newTestServer(t, &Config{
+		RunnerExecutionTimeoutSeconds: 3600,
+		RunnerIdleTimeoutSeconds:      300,
+		SupportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+	}, db, &WebhookClientOptions{
+		CloudBuildClientOverride: &cloudbuild.MockClient{
+			CreateBuildID:          testGCBBuildID,
+			CreateBuildProjectErrs: map[string]error{"project-1": fmt.Errorf("quota exceeded")},
+		},
+		DispatchRecordStoreOverride: store,
+		RandOverride:                fixedRand(0),
+	})
+
+	resp := httptest.NewRecorder()
+	srv.handleWebhook().ServeHTTP(resp, newQueuedRequest(t))
+	if got, want := resp.Code, http.StatusOK; got != want {
+		t.Fatalf("expected %d to be %d: %s", got, want, resp.Body.String())
+	}
+
+	record, err := store.GetByBuildID(ctx, testGCBBuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record == nil {
+		t.Fatal("expected dispatch record to be stored")
+	}
+	// The runner is recorded with the pool its build was created on.
+	if diff := cmp.Diff([]*dispatch.Runner{{
+		Name:      record.Runners[0].Name,
+		BuildID:   testGCBBuildID,
+		ProjectID: "project-2",
+		Location:  "us-west1",
+		Pool:      testFailoverPoolName("project-2"),
+		Label:     SelfHostedRunnerLabel,
+	}}, record.Runners); diff != "" {
+		t.Errorf("recorded runners mismatch (-want +got):\n%s", diff)
+	}
+	if err := mockRedis.ExpectationsWereMet(); err != nil {
+		t.Errorf("redis expectations not met: %v", err)
+	}
+}
+
+var cmpIgnoreDispatchedAt = cmp.FilterPath(func(p cmp.Path) bool {
+	return p.String() == "DispatchedAt"
+}, cmp.Ignore())
+
+// newWorkflowJobEvent creates an event for job 789 with the given action.
+func newWorkflowJobEvent(action string, job *github.WorkflowJob) *github.WorkflowJobEvent {
+	job.ID = github.Ptr(int64(789))
+	job.Labels = []string{SelfHostedRunnerLabel}
+	return &github.WorkflowJobEvent{
+		Action:       &action,
+		WorkflowJob:  job,
+		Installation: &github.Installation{ID: github.Ptr(int64(123))},
+		Org:          &github.Organization{Login: github.Ptr(orgLogin)},
+		Repo:         &github.Repository{Name: github.Ptr(repoName)},
+	}
+}
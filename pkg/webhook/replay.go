@@ -0,0 +1,114 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// ReplayPayload is a saved webhook delivery.
+type ReplayPayload struct {
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// ReplayResult is the outcome of replaying a webhook delivery.
+type ReplayResult struct {
+	Code           int             `json:"code"`
+	Error          string          `json:"error,omitempty"`
+	GitHubHost     string          `json:"githubHost,omitempty"`
+	GitHubApp      string          `json:"githubApp,omitempty"`
+	ResolvedLabels []string        `json:"resolvedLabels,omitempty"`
+	Response       json.RawMessage `json:"response"`
+}
+
+// Replay processes a saved webhook delivery as if it had just been received
+// and returns the response. The server must be in dry-run mode, so the
+// response to a queued job holds the worker pools chosen and the build
+// requests that would have been created. The delivery is processed by the
+// server for the GitHub host and app named in its X-GitHub-Enterprise-Host and
+// X-GitHub-Hook-Installation-Target-ID headers, and the body is signed with
+// that server's webhook secret, so deliveries signed with another secret can
+// be replayed.
+func (s *Server) Replay(ctx context.Context, payload *ReplayPayload) (*ReplayResult, error) {
+	if !s.config.DryRun {
+		return nil, fmt.Errorf("webhooks can only be replayed in dry-run mode")
+	}
+	if len(payload.Body) == 0 {
+		return nil, fmt.Errorf("payload is missing a body")
+	}
+
+	header := make(http.Header, len(payload.Headers)+1)
+	for name, value := range payload.Headers {
+		header.Set(name, value)
+	}
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+	srv := s.webhookServer("", header)
+
+	versions := srv.webhookSecrets.active()
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no webhook secrets configured")
+	}
+
+	ctx = srv.serverContext(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/webhook", bytes.NewReader(payload.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header = header
+	req.Header.Del(github.SHA1SignatureHeader)
+	req.Header.Set(github.SHA256SignatureHeader, SignPayload(versions[0].value, payload.Body))
+
+	resp := srv.processRequest(req)
+
+	result := &ReplayResult{Code: resp.Code}
+	if srv.host != nil {
+		result.GitHubHost = srv.host.Name
+	}
+	if srv.app != nil {
+		result.GitHubApp = srv.app.Name
+	}
+	if resp.Error != nil {
+		result.Error = resp.Error.Error()
+	}
+	if strings.HasPrefix(resp.Message, "{") && json.Valid([]byte(resp.Message)) {
+		result.Response = json.RawMessage(resp.Message)
+	} else {
+		b, err := json.Marshal(resp.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal response: %w", err)
+		}
+		result.Response = b
+	}
+
+	// The resolved labels are reported for every queued job, including those
+	// that are not dispatched.
+	var event github.WorkflowJobEvent
+	if err := json.Unmarshal(payload.Body, &event); err == nil && event.GetAction() == "queued" && event.WorkflowJob != nil {
+		if labels, _, err := srv.resolveAndValidateRunnerLabels(ctx, event.WorkflowJob.Labels); err == nil {
+			result.ResolvedLabels = labels
+		}
+	}
+	return result, nil
+}
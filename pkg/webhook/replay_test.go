@@ -0,0 +1,116 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestReplay_Routing(t *testing.T) {
+	t.Parallel()
+
+	newHostsServer := func(tb testing.TB) *Server { return newTestHostsServer(tb) }
+	newAppsServer := func(tb testing.TB) *Server {
+		srv, _ := newTestAppsServer(tb)
+		return srv
+	}
+
+	cases := []struct {
+		name      string
+		newServer func(tb testing.TB) *Server
+		headers   map[string]string
+		exp       *ReplayResult
+	}{
+		{
+			name:      "default_host",
+			newServer: newHostsServer,
+			exp: &ReplayResult{
+				Code:     http.StatusOK,
+				Response: json.RawMessage(`"no action taken for action type: \"waiting\""`),
+			},
+		},
+		{
+			name:      "host_by_header",
+			newServer: newHostsServer,
+			headers:   map[string]string{gitHubEnterpriseHostHeader: "github.example.com"},
+			exp: &ReplayResult{
+				Code:       http.StatusOK,
+				GitHubHost: "ghes1",
+				Response:   json.RawMessage(`"no action taken for action type: \"waiting\""`),
+			},
+		},
+		{
+			name:      "unknown_host_uses_default_host",
+			newServer: newHostsServer,
+			headers:   map[string]string{gitHubEnterpriseHostHeader: "other.example.com"},
+			exp: &ReplayResult{
+				Code:     http.StatusOK,
+				Response: json.RawMessage(`"no action taken for action type: \"waiting\""`),
+			},
+		},
+		{
+			name:      "app_by_header",
+			newServer: newAppsServer,
+			headers:   map[string]string{gitHubHookInstallationTargetIDHeader: testAppID},
+			exp: &ReplayResult{
+				Code:      http.StatusOK,
+				GitHubApp: "team-a",
+				Response:  json.RawMessage(`"no action taken for action type: \"waiting\""`),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			srv := tc.newServer(t)
+			srv.config.DryRun = true
+
+			action := "waiting"
+			body, err := json.Marshal(&github.WorkflowJobEvent{
+				Action:      &action,
+				WorkflowJob: &github.WorkflowJob{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers := map[string]string{
+				DeliveryIDHeader: "delivery-id",
+				EventTypeHeader:  "workflow_job",
+			}
+			for name, value := range tc.headers {
+				headers[name] = value
+			}
+
+			got, err := srv.Replay(ctx, &ReplayPayload{Headers: headers, Body: body})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("Replay() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// runnerGroupCacheTTL is how long a runner group ID resolved from its name is
+// reused before it is looked up again.
+const runnerGroupCacheTTL = 10 * time.Minute
+
+// cachedRunnerGroupID is a runner group ID resolved from its name.
+type cachedRunnerGroupID struct {
+	id        int64
+	expiresAt time.Time
+}
+
+// runnerGroupID returns the ID of the runner group that org or enterprise-level
+// runners for jobs in an org that are dispatched to pools of a label are
+// registered in. Groups configured by name are resolved through the GitHub API
+// and cached.
+func (s *Server) runnerGroupID(ctx context.Context, scope string, installationID int64, orgName, label string) (int64, error) {
+	group, ok := lookupOrgLabel(s.config.RunnerGroups, orgName, label)
+	if !ok {
+		group = s.config.RunnerGroup
+	}
+	if group == "" {
+		return gh.DefaultRunnerGroupID, nil
+	}
+	if id, err := strconv.ParseInt(group, 10, 64); err == nil {
+		return id, nil
+	}
+
+	owner := orgName
+	if scope == RunnerRegistrationScopeEnterprise {
+		owner = s.config.GitHubEnterprise
+	}
+	cacheKey := scope + "/" + owner + "/" + group
+	if v, ok := s.runnerGroupIDs.Load(cacheKey); ok {
+		if cached, ok := v.(*cachedRunnerGroupID); ok && time.Now().Before(cached.expiresAt) {
+			return cached.id, nil
+		}
+	}
+
+	var id int64
+	var err error
+	if scope == RunnerRegistrationScopeEnterprise {
+		id, err = s.ghc.EnterpriseRunnerGroupIDByName(ctx, installationID, owner, group)
+	} else {
+		id, err = s.ghc.RunnerGroupIDByName(ctx, installationID, owner, group)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve runner group %q: %w", group, err)
+	}
+	logging.FromContext(ctx).DebugContext(ctx, "resolved runner group",
+		"registration_scope", scope,
+		"runner_group_owner", owner,
+		"runner_group", group,
+		"runner_group_id", id)
+
+	s.runnerGroupIDs.Store(cacheKey, &cachedRunnerGroupID{
+		id:        id,
+		expiresAt: time.Now().Add(runnerGroupCacheTTL),
+	})
+	return id, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRunnerGroupID(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name           string
+		runnerGroup    string
+		runnerGroups   map[string]string
+		org            string
+		label          string
+		lookups        int
+		expID          int64
+		expLookupCalls int
+		expErr         string
+	}{
+		{
+			name:           "default_group",
+			org:            orgLogin,
+			label:          SelfHostedRunnerLabel,
+			lookups:        1,
+			expID:          gh.DefaultRunnerGroupID,
+			expLookupCalls: 0,
+		},
+		{
+			name:           "global_group_id",
+			runnerGroup:    "7",
+			org:            orgLogin,
+			label:          SelfHostedRunnerLabel,
+			lookups:        1,
+			expID:          7,
+			expLookupCalls: 0,
+		},
+		{
+			name:           "org_and_label_takes_precedence",
+			runnerGroup:    "7",
+			runnerGroups:   map[string]string{"google:*": "8", "google:self-hosted": "9", "*:self-hosted": "10"},
+			org:            orgLogin,
+			label:          SelfHostedRunnerLabel,
+			lookups:        1,
+			expID:          9,
+			expLookupCalls: 0,
+		},
+		{
+			name:           "name_is_resolved_once",
+			runnerGroups:   map[string]string{"*:self-hosted": "trusted-runners"},
+			org:            orgLogin,
+			label:          SelfHostedRunnerLabel,
+			lookups:        3,
+			expID:          42,
+			expLookupCalls: 1,
+		},
+		{
+			name:           "unknown_name",
+			runnerGroup:    "missing",
+			org:            orgLogin,
+			label:          SelfHostedRunnerLabel,
+			lookups:        1,
+			expLookupCalls: 1,
+			expErr:         `failed to resolve runner group "missing"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			ghc := &gh.MockClient{
+				RunnerGroupIDByNameF: func(ctx context.Context, installationID int64, org, name string) (int64, error) {
+					if name == "trusted-runners" {
+						return 42, nil
+					}
+					return 0, fmt.Errorf("runner group %q not found in org %s", name, org)
+				},
+			}
+			srv := &Server{
+				config: &Config{
+					RunnerGroup:  tc.runnerGroup,
+					RunnerGroups: tc.runnerGroups,
+				},
+				ghc: ghc,
+			}
+
+			for range tc.lookups {
+				id, err := srv.runnerGroupID(ctx, RunnerRegistrationScopeOrg, 123, tc.org, tc.label)
+				if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+					t.Fatal(diff)
+				}
+				if got, want := id, tc.expID; got != want {
+					t.Errorf("expected runner group ID %d to be %d", got, want)
+				}
+			}
+
+			if got, want := ghc.RunnerGroupIDByNameCalls, tc.expLookupCalls; got != want {
+				t.Errorf("expected %d calls to RunnerGroupIDByName, got %d", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// webhookSecretVersion is an active webhook secret. Its version is the name
+// of the key it was read from.
+type webhookSecretVersion struct {
+	version string
+	value   []byte
+}
+
+// webhookSecrets holds the active webhook secrets read from the webhook key
+// mount path. The secrets can be reloaded while the server is running so that
+// a rotated secret is used without a restart.
+type webhookSecrets struct {
+	fr        FileReader
+	mountPath string
+	keyNames  []string
+
+	mu       sync.RWMutex
+	versions []*webhookSecretVersion
+}
+
+// newWebhookSecrets reads the webhook secrets with the given key names. The
+// first key is required, the others are skipped if they are not mounted.
+func newWebhookSecrets(ctx context.Context, fr FileReader, mountPath string, keyNames []string) (*webhookSecrets, error) {
+	ws := &webhookSecrets{
+		fr:        fr,
+		mountPath: mountPath,
+		keyNames:  keyNames,
+	}
+	versions, err := ws.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws.versions = versions
+	return ws, nil
+}
+
+// read reads every mounted webhook secret: the configured keys, followed by
+// any other version of them found in the mount path. A version is named after
+// its key with a "." and a version suffix, e.g. "webhook-key.2", so that
+// versions can be mounted and removed without a config change.
+func (ws *webhookSecrets) read(ctx context.Context) ([]*webhookSecretVersion, error) {
+	names := ws.keyNames
+	versionNames, err := ws.versionNames()
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to list webhook secret versions, using configured keys only", "error", err)
+	}
+	names = slices.Concat(names, versionNames)
+
+	versions := make([]*webhookSecretVersion, 0, len(names))
+	for i, name := range names {
+		value, err := ws.fr.ReadFile(fmt.Sprintf("%s/%s", ws.mountPath, name))
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("failed to read webhook secret %s: %w", name, err)
+			}
+			continue
+		}
+		versions = append(versions, &webhookSecretVersion{version: name, value: value})
+	}
+	return versions, nil
+}
+
+// versionNames returns the names of the files in the mount path that are
+// versions of a configured key, sorted by name.
+func (ws *webhookSecrets) versionNames() ([]string, error) {
+	entries, err := ws.fr.ReadDir(ws.mountPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook secrets in %s: %w", ws.mountPath, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || slices.Contains(ws.keyNames, name) {
+			continue
+		}
+		if slices.ContainsFunc(ws.keyNames, func(key string) bool {
+			return strings.HasPrefix(name, key+".")
+		}) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// reload re-reads the webhook secrets, including versions that were mounted
+// or removed since they were last read. The current secrets are kept if the
+// primary secret cannot be read.
+func (ws *webhookSecrets) reload(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	versions, err := ws.read(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to reload webhook secrets, keeping current secrets", "error", err)
+		return
+	}
+
+	ws.mu.Lock()
+	changed := !slices.EqualFunc(ws.versions, versions, func(a, b *webhookSecretVersion) bool {
+		return a.version == b.version && bytes.Equal(a.value, b.value)
+	})
+	ws.versions = versions
+	ws.mu.Unlock()
+
+	if changed {
+		names := make([]string, 0, len(versions))
+		for _, v := range versions {
+			names = append(names, v.version)
+		}
+		logger.InfoContext(ctx, "reloaded webhook secrets", "webhook_secret_versions", names)
+	}
+}
+
+// active returns the active webhook secrets.
+func (ws *webhookSecrets) active() []*webhookSecretVersion {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return ws.versions
+}
+
+// SignPayload returns the value of the X-Hub-Signature-256 header that GitHub
+// sends with a webhook payload signed with secret.
+func SignPayload(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validatePayload returns the payload of a webhook request if it is signed
+// with any of the given secrets, along with the version of the secret that
+// signed it.
+func validatePayload(r *http.Request, versions []*webhookSecretVersion) ([]byte, string, error) {
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if signature == "" {
+		signature = r.Header.Get(github.SHA1SignatureHeader)
+	}
+
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse content type: %w", err)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var merr error
+	for _, v := range versions {
+		payload, err := github.ValidatePayloadFromBody(contentType, bytes.NewReader(body), signature, v.value)
+		if err == nil {
+			return payload, v.version, nil
+		}
+		merr = errors.Join(merr, fmt.Errorf("secret %s: %w", v.version, err))
+	}
+	if merr == nil {
+		merr = fmt.Errorf("no webhook secrets configured")
+	}
+	return nil, "", merr
+}
+
+// StartWebhookSecretReloader starts a background loop that re-reads the
+// webhook secrets of every GitHub host and app on the configured interval. The
+// loop runs until the context is cancelled. It is a no-op when the interval is
+// not positive.
+func (s *Server) StartWebhookSecretReloader(ctx context.Context) {
+	if s.config.GitHubWebhookKeyReloadInterval <= 0 {
+		return
+	}
+
+	servers := []*Server{s}
+	for _, hs := range s.hosts {
+		servers = append(servers, hs)
+	}
+	for _, as := range s.apps {
+		servers = append(servers, as)
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "starting webhook secret reloader",
+		"interval", s.config.GitHubWebhookKeyReloadInterval.String())
+	go func() {
+		ticker := time.NewTicker(s.config.GitHubWebhookKeyReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				logging.FromContext(ctx).InfoContext(ctx, "stopping webhook secret reloader")
+				return
+			case <-ticker.C:
+			}
+
+			for _, srv := range servers {
+				srv.webhookSecrets.reload(srv.serverContext(ctx))
+			}
+		}
+	}()
+}
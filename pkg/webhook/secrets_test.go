@@ -0,0 +1,238 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestValidatePayload(t *testing.T) {
+	t.Parallel()
+
+	versions := []*webhookSecretVersion{
+		{version: "current", value: []byte("current-secret")},
+		{version: "previous", value: []byte("previous-secret")},
+	}
+	payload := []byte(`{"action":"queued"}`)
+
+	cases := []struct {
+		name       string
+		secret     string
+		versions   []*webhookSecretVersion
+		expVersion string
+		expErr     string
+	}{
+		{
+			name:       "current_secret",
+			secret:     "current-secret",
+			versions:   versions,
+			expVersion: "current",
+		},
+		{
+			name:       "previous_secret",
+			secret:     "previous-secret",
+			versions:   versions,
+			expVersion: "previous",
+		},
+		{
+			name:     "unknown_secret",
+			secret:   "other-secret",
+			versions: versions,
+			expErr:   "secret previous: payload signature check failed",
+		},
+		{
+			name:   "no_secrets",
+			secret: "current-secret",
+			expErr: "no webhook secrets configured",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+			req.Header.Add(ContentTypeHeader, "application/json")
+			req.Header.Add(SHA256SignatureHeader, fmt.Sprintf("sha256=%s", createSignature([]byte(tc.secret), payload)))
+
+			got, version, err := validatePayload(req, tc.versions)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got, want := version, tc.expVersion; got != want {
+				t.Errorf("expected secret version %q to be %q", got, want)
+			}
+			if tc.expErr == "" && !bytes.Equal(got, payload) {
+				t.Errorf("expected payload %s to be %s", got, payload)
+			}
+		})
+	}
+}
+
+func TestWebhookSecrets_Reload(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	var mu sync.Mutex
+	files := map[string]string{
+		"/secrets/webhook-key": "secret-v1",
+	}
+	fr := &MockFileReader{
+		ReadFileFunc: func(filename string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			v, ok := files[filename]
+			if !ok {
+				return nil, fmt.Errorf("file not found: %s", filename)
+			}
+			return []byte(v), nil
+		},
+	}
+	setFiles := func(m map[string]string) {
+		mu.Lock()
+		defer mu.Unlock()
+		files = m
+	}
+	activeSecrets := func(ws *webhookSecrets) map[string]string {
+		got := make(map[string]string)
+		for _, v := range ws.active() {
+			got[v.version] = string(v.value)
+		}
+		return got
+	}
+
+	ws, err := newWebhookSecrets(ctx, fr, "/secrets", []string{"webhook-key", "webhook-key-previous"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"webhook-key": "secret-v1"}, activeSecrets(ws)); diff != "" {
+		t.Errorf("initial secrets mismatch (-want +got):\n%s", diff)
+	}
+
+	// The new version is mounted as the primary key and the old one is kept
+	// active until GitHub has been updated.
+	setFiles(map[string]string{
+		"/secrets/webhook-key":          "secret-v2",
+		"/secrets/webhook-key-previous": "secret-v1",
+	})
+	ws.reload(ctx)
+	if diff := cmp.Diff(map[string]string{"webhook-key": "secret-v2", "webhook-key-previous": "secret-v1"}, activeSecrets(ws)); diff != "" {
+		t.Errorf("rotated secrets mismatch (-want +got):\n%s", diff)
+	}
+
+	// The current secrets are kept if the primary key cannot be read.
+	setFiles(map[string]string{})
+	ws.reload(ctx)
+	if diff := cmp.Diff(map[string]string{"webhook-key": "secret-v2", "webhook-key-previous": "secret-v1"}, activeSecrets(ws)); diff != "" {
+		t.Errorf("secrets after failed reload mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWebhookSecrets_ReloadVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	var mu sync.Mutex
+	files := fstest.MapFS{
+		"secrets/webhook-key": {Data: []byte("secret-v1")},
+	}
+	fr := &MockFileReader{
+		ReadFileFunc: func(filename string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return fs.ReadFile(files, strings.TrimPrefix(filename, "/"))
+		},
+		ReadDirFunc: func(name string) ([]os.DirEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return fs.ReadDir(files, strings.TrimPrefix(name, "/"))
+		},
+	}
+	setFiles := func(m fstest.MapFS) {
+		mu.Lock()
+		defer mu.Unlock()
+		files = m
+	}
+	activeVersions := func(ws *webhookSecrets) []string {
+		var got []string
+		for _, v := range ws.active() {
+			got = append(got, v.version)
+		}
+		return got
+	}
+
+	ws, err := newWebhookSecrets(ctx, fr, "/secrets", []string{"webhook-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"webhook-key"}, activeVersions(ws)); diff != "" {
+		t.Errorf("initial versions mismatch (-want +got):\n%s", diff)
+	}
+
+	// Versions of the key are picked up when they are mounted, and other
+	// files in the mount path are ignored.
+	setFiles(fstest.MapFS{
+		"secrets/webhook-key":         {Data: []byte("secret-v2")},
+		"secrets/webhook-key.2":       {Data: []byte("secret-v2")},
+		"secrets/webhook-key.1":       {Data: []byte("secret-v1")},
+		"secrets/admin-key":           {Data: []byte("admin-secret")},
+		"secrets/webhook-key-ghes":    {Data: []byte("ghes-secret")},
+		"secrets/webhook-key.d/other": {Data: []byte("other-secret")},
+	})
+	ws.reload(ctx)
+	if diff := cmp.Diff([]string{"webhook-key", "webhook-key.1", "webhook-key.2"}, activeVersions(ws)); diff != "" {
+		t.Errorf("rotated versions mismatch (-want +got):\n%s", diff)
+	}
+
+	// Versions are dropped when they are removed.
+	setFiles(fstest.MapFS{
+		"secrets/webhook-key":   {Data: []byte("secret-v2")},
+		"secrets/webhook-key.2": {Data: []byte("secret-v2")},
+	})
+	ws.reload(ctx)
+	if diff := cmp.Diff([]string{"webhook-key", "webhook-key.2"}, activeVersions(ws)); diff != "" {
+		t.Errorf("versions after removal mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewWebhookSecrets_MissingPrimary(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	fr := &MockFileReader{
+		ReadFileMock: &ReadFileResErr{Err: fmt.Errorf("file not found")},
+	}
+	_, err := newWebhookSecrets(ctx, fr, "/secrets", []string{"webhook-key"})
+	if diff := testutil.DiffErrString(err, "failed to read webhook secret webhook-key: file not found"); diff != "" {
+		t.Fatal(diff)
+	}
+}
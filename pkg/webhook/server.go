@@ -16,18 +16,32 @@
 package webhook
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"maps"
+	"math/rand"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sethvargo/go-gcpkms/pkg/gcpkms"
 	"google.golang.org/api/option"
 
+	"github.com/abcxyz/github-action-dispatcher/pkg/backend"
+	"github.com/abcxyz/github-action-dispatcher/pkg/budget"
 	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/github-action-dispatcher/pkg/dispatch"
 	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/github-action-dispatcher/pkg/inflight"
+	"github.com/abcxyz/github-action-dispatcher/pkg/policy"
+	"github.com/abcxyz/github-action-dispatcher/pkg/queue"
+	"github.com/abcxyz/github-action-dispatcher/pkg/quota"
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
 	"github.com/abcxyz/github-action-dispatcher/pkg/version"
+	"github.com/abcxyz/github-action-dispatcher/pkg/warmpool"
 	"github.com/abcxyz/pkg/githubauth"
 	"github.com/abcxyz/pkg/healthcheck"
 	"github.com/abcxyz/pkg/logging"
@@ -36,8 +50,12 @@ import (
 
 // Server provides the server implementation.
 type Server struct {
+	adminToken                     []byte
 	allowedLabels                  map[string]bool
+	backends                       map[string]backend.RunnerBackend
 	backoffInitialDelay            time.Duration
+	breaker                        *poolBreaker
+	budgets                        budget.Store
 	cbc                            cloudbuild.Client
 	config                         *Config
 	e2eTestRunID                   string // TODO remove this, post refactor it may no longer be needed
@@ -45,11 +63,23 @@ type Server struct {
 	extraRunnerCount               int
 	ghAPIBaseURL                   string
 	ghc                            gh.Client
+	app                            *GitHubApp
+	apps                           map[string]*Server
 	h                              *renderer.Renderer
+	host                           *GitHubHost
+	hosts                          map[string]*Server
+	inFlight                       inflight.Tracker
+	keyPrefix                      string
 	kmc                            KeyManagementClient
 	maxRetryAttempts               int
+	policy                         *policy.Policy
+	queue                          queue.Queue
+	quotas                         quota.Limiter
+	rand                           Rand
 	rc                             *redis.Client
+	records                        dispatch.Store
 	runnerExecutionTimeoutSeconds  int
+	runnerGroupIDs                 sync.Map
 	runnerIdleTimeoutSeconds       int
 	runnerLocation                 string
 	runnerProjectID                string
@@ -59,12 +89,32 @@ type Server struct {
 	runnerRepositoryID             string
 	runnerServiceAccount           string
 	runnerWorkerPoolID             string
-	webhookSecret                  []byte
+	warmPools                      warmpool.Tracker
+	warmPoolInstallations          sync.Map
+	warmPoolReplenish              chan *WarmPoolConfig
+	webhookSecrets                 *webhookSecrets
 }
 
-// FileReader can read a file and return the content.
+// FileReader can read a file and return the content, and list the entries of
+// a directory.
 type FileReader interface {
 	ReadFile(filename string) ([]byte, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+}
+
+// Rand is a source of random numbers used to select worker pools.
+type Rand interface {
+	// Intn returns a random number in [0, n).
+	Intn(n int) int
+}
+
+// globalRand is a Rand backed by the top-level math/rand functions, which are
+// safe for concurrent use.
+type globalRand struct{}
+
+// Intn implements the Rand interface.
+func (globalRand) Intn(n int) int {
+	return rand.Intn(n) //nolint:gosec // G404: Cryptographic randomness is not required for worker pool selection.
 }
 
 // KeyManagementClient adheres to the interaction the webhook service has with a subset of Key Management APIs.
@@ -81,20 +131,122 @@ type WebhookClientOptions struct {
 	OSFileReaderOverride        FileReader
 	CloudBuildClientOverride    cloudbuild.Client
 	GitHubClientOverride        gh.Client
+	GitHubHostClientOverrides   map[string]gh.Client
+	GitHubAppClientOverrides    map[string]gh.Client
 	KeyManagementClientOverride KeyManagementClient
+	DispatchQueueOverride       queue.Queue
+	DispatchRecordStoreOverride dispatch.Store
+	WarmPoolTrackerOverride     warmpool.Tracker
+	InFlightTrackerOverride     inflight.Tracker
+	QuotaLimiterOverride        quota.Limiter
+	BudgetStoreOverride         budget.Store
+	RandOverride                Rand
+
+	// RunnerBackendOverrides replace the backends that runners are started
+	// with, keyed by pool type. The backend under the empty pool type is used
+	// for pools whose type has no backend of its own.
+	RunnerBackendOverrides map[string]backend.RunnerBackend
 }
 
 // NewServer creates a new HTTP server implementation that will handle
 // receiving webhook payloads.
 func NewServer(ctx context.Context, h *renderer.Renderer, cfg *Config, rc *redis.Client, wco *WebhookClientOptions) (*Server, error) {
+	s, err := newServer(ctx, h, cfg, rc, wco, dispatchKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every additional GitHub host is served by its own server, which shares
+	// the Cloud Build and KMS clients but keeps its state under its own key
+	// prefix.
+	s.hosts = make(map[string]*Server, len(cfg.GitHubHosts))
+	for _, host := range cfg.GitHubHosts {
+		hs, err := newServer(ctx, h, cfg.forHost(host), rc, &WebhookClientOptions{
+			OSFileReaderOverride:        wco.OSFileReaderOverride,
+			CloudBuildClientOverride:    s.cbc,
+			GitHubClientOverride:        wco.GitHubHostClientOverrides[host.Name],
+			KeyManagementClientOverride: s.kmc,
+			InFlightTrackerOverride:     s.inFlight,
+			RandOverride:                s.rand,
+			RunnerBackendOverrides:      s.backends,
+		}, hostKeyPrefix(host.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create server for github host %s: %w", host.Name, err)
+		}
+		hs.host = host
+		hs.breaker = s.breaker
+		s.hosts[host.Name] = hs
+	}
+
+	// Every additional GitHub App is served by its own server, keyed by app
+	// ID. Apps share the default host's dispatcher state so that a job is only
+	// dispatched once, but each app has its own queue so that its jobs are
+	// dispatched with its own credentials.
+	s.apps = make(map[string]*Server, len(cfg.GitHubApps))
+	for _, app := range cfg.GitHubApps {
+		var q queue.Queue
+		if cfg.DispatchQueueWorkers > 0 && rc != nil {
+			q = queue.NewRedisQueue(rc, appQueueName(app.Name))
+		}
+		as, err := newServer(ctx, h, cfg.forApp(app), rc, &WebhookClientOptions{
+			OSFileReaderOverride:        wco.OSFileReaderOverride,
+			CloudBuildClientOverride:    s.cbc,
+			GitHubClientOverride:        wco.GitHubAppClientOverrides[app.Name],
+			KeyManagementClientOverride: s.kmc,
+			DispatchQueueOverride:       q,
+			DispatchRecordStoreOverride: s.records,
+			InFlightTrackerOverride:     s.inFlight,
+			QuotaLimiterOverride:        s.quotas,
+			BudgetStoreOverride:         s.budgets,
+			RandOverride:                s.rand,
+			RunnerBackendOverrides:      s.backends,
+		}, dispatchKeyPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create server for github app %s: %w", app.Name, err)
+		}
+		as.app = app
+		as.breaker = s.breaker
+		s.apps[app.AppID] = as
+	}
+	return s, nil
+}
+
+// newServer creates a server for a single GitHub host. Its dispatcher state is
+// stored under keyPrefix.
+func newServer(ctx context.Context, h *renderer.Renderer, cfg *Config, rc *redis.Client, wco *WebhookClientOptions, keyPrefix string) (*Server, error) {
 	fr := wco.OSFileReaderOverride
 	if fr == nil {
 		fr = NewOSFileReader()
 	}
 
-	webhookSecret, err := fr.ReadFile(fmt.Sprintf("%s/%s", cfg.GitHubWebhookKeyMountPath, cfg.GitHubWebhookKeyName))
+	webhookKeyNames := append([]string{cfg.GitHubWebhookKeyName}, cfg.GitHubWebhookExtraKeyNames...)
+	webhookSecrets, err := newWebhookSecrets(ctx, fr, cfg.GitHubWebhookKeyMountPath, webhookKeyNames)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read webhook secret: %w", err)
+		return nil, err
+	}
+
+	var adminToken []byte
+	if cfg.AdminAPIKeyMountPath != "" && cfg.AdminAPIKeyName != "" {
+		token, err := fr.ReadFile(fmt.Sprintf("%s/%s", cfg.AdminAPIKeyMountPath, cfg.AdminAPIKeyName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read admin api key: %w", err)
+		}
+		adminToken = bytes.TrimSpace(token)
+		if len(adminToken) == 0 {
+			return nil, fmt.Errorf("admin api key is empty")
+		}
+	}
+
+	var dispatchPolicy *policy.Policy
+	if cfg.DispatchPolicyFile != "" {
+		b, err := fr.ReadFile(cfg.DispatchPolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dispatch policy: %w", err)
+		}
+		dispatchPolicy, err = policy.Parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load dispatch policy: %w", err)
+		}
 	}
 
 	kmc := wco.KeyManagementClientOverride
@@ -120,7 +272,7 @@ func NewServer(ctx context.Context, h *renderer.Renderer, cfg *Config, rc *redis
 		if err != nil {
 			return nil, fmt.Errorf("failed to create github app client: %w", err)
 		}
-		ghc = gh.NewClient(appClient, cfg.GitHubAPIBaseURL, cfg.BackoffInitialDelay, cfg.MaxRetryAttempts)
+		ghc = gh.NewClient(appClient, cfg.GitHubAPIBaseURL, cfg.GitHubUploadURL, cfg.BackoffInitialDelay, cfg.MaxRetryAttempts)
 	}
 
 	cbc := wco.CloudBuildClientOverride
@@ -132,6 +284,97 @@ func NewServer(ctx context.Context, h *renderer.Renderer, cfg *Config, rc *redis
 		cbc = cb
 	}
 
+	// Runners are started on Cloud Build unless the backend for their pool
+	// type is overridden. Builds on trusted pools are created through the
+	// consumer API so that their remote config is applied.
+	backends := map[string]backend.RunnerBackend{
+		"":                       backend.NewCloudBuild(cbc, cfg.RunnerRepositoryID),
+		registry.PoolTypeTrusted: backend.NewTrustedCloudBuild(cbc, cfg.RunnerRepositoryID),
+	}
+	maps.Copy(backends, wco.RunnerBackendOverrides)
+
+	// The dispatch queue is only used when there are workers to drain it.
+	var q queue.Queue
+	if cfg.DispatchQueueWorkers > 0 {
+		q = wco.DispatchQueueOverride
+		if q == nil && rc != nil {
+			q = queue.NewRedisQueue(rc, keyPrefix+dispatchQueuePath)
+		}
+		if q == nil {
+			logging.FromContext(ctx).WarnContext(ctx, "registry not configured, queued jobs will not survive a restart")
+			q = queue.NewMemoryQueue()
+		}
+	}
+
+	records := wco.DispatchRecordStoreOverride
+	if records == nil && rc != nil && cfg.DispatchRecordTTL > 0 {
+		records = dispatch.NewRedisStore(rc, keyPrefix+dispatchRecordKeyPath, cfg.DispatchRecordTTL)
+	}
+
+	// Warm pools are only tracked when at least one is configured.
+	var warmPools warmpool.Tracker
+	if len(cfg.WarmPools) > 0 {
+		warmPools = wco.WarmPoolTrackerOverride
+		if warmPools == nil && rc != nil {
+			warmPools = warmpool.NewRedisTracker(rc, warmPoolKeyPrefix)
+		}
+		if warmPools == nil {
+			logging.FromContext(ctx).WarnContext(ctx, "registry not configured, warm runners are not shared between instances")
+			warmPools = warmpool.NewMemoryTracker()
+		}
+	}
+
+	// Builds in flight are only tracked when enabled.
+	var inFlight inflight.Tracker
+	if cfg.InFlightTrackingEnabled {
+		inFlight = wco.InFlightTrackerOverride
+		if inFlight == nil && rc != nil {
+			inFlight = inflight.NewRedisTracker(rc, inFlightKeyPrefix)
+		}
+		if inFlight == nil {
+			logging.FromContext(ctx).WarnContext(ctx, "registry not configured, worker pool load is not shared between instances")
+			inFlight = inflight.NewMemoryTracker()
+		}
+	}
+
+	// Concurrency is only limited when quotas are configured.
+	var quotas quota.Limiter
+	if len(cfg.ConcurrencyQuotas) > 0 {
+		quotas = wco.QuotaLimiterOverride
+		if quotas == nil && rc != nil {
+			quotas = quota.NewRedisLimiter(rc, keyPrefix+quotaKeyPath)
+		}
+		if quotas == nil {
+			logging.FromContext(ctx).WarnContext(ctx, "registry not configured, concurrency quotas are enforced per instance")
+			quotas = quota.NewMemoryLimiter()
+		}
+	}
+
+	// Usage is only accumulated for orgs with a budget.
+	var budgets budget.Store
+	if len(cfg.Budgets) > 0 {
+		budgets = wco.BudgetStoreOverride
+		if budgets == nil && rc != nil {
+			budgets = budget.NewRedisStore(rc, keyPrefix+budgetKeyPath)
+		}
+		if budgets == nil {
+			logging.FromContext(ctx).WarnContext(ctx, "registry not configured, budget usage is not shared between instances")
+			budgets = budget.NewMemoryStore()
+		}
+	}
+
+	// A dry-run server does not store dispatcher state, so that it can be run
+	// against production traffic with the registry of a production server.
+	if cfg.DryRun {
+		logging.FromContext(ctx).WarnContext(ctx, "dry-run mode enabled, no runners will be started")
+		q, records, warmPools, inFlight, quotas, budgets = nil, nil, nil, nil, nil, nil
+	}
+
+	rng := wco.RandOverride
+	if rng == nil {
+		rng = globalRand{}
+	}
+
 	// Pre-compute the set of allowed labels for efficient lookup.
 	allowedLabels := make(map[string]bool)
 
@@ -147,17 +390,28 @@ func NewServer(ctx context.Context, h *renderer.Renderer, cfg *Config, rc *redis
 	}
 
 	return &Server{
+		adminToken:                     adminToken,
 		backoffInitialDelay:            cfg.BackoffInitialDelay,
+		budgets:                        budgets,
+		breaker:                        newPoolBreaker(cfg.WorkerPoolBreakerThreshold, cfg.WorkerPoolBreakerCooldown),
 		cbc:                            cbc,
 		config:                         cfg,
 		allowedLabels:                  allowedLabels,
+		backends:                       backends,
 		environment:                    cfg.Environment,
 		ghAPIBaseURL:                   cfg.GitHubAPIBaseURL,
 		ghc:                            ghc,
 		h:                              h,
+		inFlight:                       inFlight,
+		keyPrefix:                      keyPrefix,
 		kmc:                            kmc,
 		maxRetryAttempts:               cfg.MaxRetryAttempts,
+		policy:                         dispatchPolicy,
+		queue:                          q,
+		quotas:                         quotas,
+		rand:                           rng,
 		rc:                             rc,
+		records:                        records,
 		runnerExecutionTimeoutSeconds:  cfg.RunnerExecutionTimeoutSeconds,
 		runnerIdleTimeoutSeconds:       cfg.RunnerIdleTimeoutSeconds,
 		runnerLocation:                 cfg.RunnerLocation,
@@ -167,7 +421,9 @@ func NewServer(ctx context.Context, h *renderer.Renderer, cfg *Config, rc *redis
 		runnerRepositoryID:             cfg.RunnerRepositoryID,
 		runnerServiceAccount:           cfg.RunnerServiceAccount,
 		runnerWorkerPoolID:             cfg.RunnerWorkerPoolID,
-		webhookSecret:                  webhookSecret,
+		warmPools:                      warmPools,
+		warmPoolReplenish:              make(chan *WarmPoolConfig, len(cfg.WarmPools)),
+		webhookSecrets:                 webhookSecrets,
 		e2eTestRunID:                   cfg.E2ETestRunID,
 		extraRunnerCount:               cfg.ExtraRunnerCount,
 		runnerRegistryDefaultKeyPrefix: cfg.RunnerRegistryDefaultKeyPrefix,
@@ -180,8 +436,12 @@ func (s *Server) Routes(ctx context.Context) http.Handler {
 	logger := logging.FromContext(ctx)
 	mux := http.NewServeMux()
 	mux.Handle("/healthz", healthcheck.HandleHTTPHealthCheck())
-	mux.Handle("/webhook", s.handleWebhook())
+	mux.Handle("/webhook", s.routeWebhook())
+	mux.Handle("/webhook/{host}", s.routeWebhook())
 	mux.Handle("/version", s.handleVersion())
+	if s.adminToken != nil {
+		mux.Handle("/admin/v1/", s.handleAdmin())
+	}
 
 	// Middleware
 	root := logging.HTTPInterceptor(logger, s.runnerProjectID)(mux)
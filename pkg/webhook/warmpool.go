@@ -0,0 +1,318 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/google/uuid"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
+	"github.com/abcxyz/github-action-dispatcher/pkg/warmpool"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// warmPoolKeyPrefix is the Redis key prefix for warm pool state.
+	warmPoolKeyPrefix = dispatchKeyPrefix + "/warm"
+
+	// warmPoolLockKey ensures only one instance replenishes warm pools at a
+	// time.
+	warmPoolLockKey = warmPoolKeyPrefix + "/lock"
+
+	// warmRunnerNamePrefix is prepended to the names of warm runners so that
+	// they can be told apart from runners dispatched for a job.
+	warmRunnerNamePrefix = "warm-"
+
+	warmRunnerMsg = "job assigned to warm runner"
+)
+
+// StartWarmPools starts a background loop that keeps every configured warm
+// pool topped up to its size. The loop runs until the context is cancelled. It
+// is a no-op when no warm pools are configured.
+func (s *Server) StartWarmPools(ctx context.Context) {
+	if s.warmPools == nil {
+		return
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "starting warm pool replenisher",
+		"warm_pools", s.config.WarmPools,
+		"interval", s.config.WarmPoolReplenishInterval.String())
+	go s.runWarmPools(ctx)
+}
+
+// runWarmPools replenishes the warm pools on every interval, and a single warm
+// pool whenever one of its runners is taken, until the context is cancelled.
+func (s *Server) runWarmPools(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(s.config.WarmPoolReplenishInterval)
+	defer ticker.Stop()
+
+	s.replenishWarmPools(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.InfoContext(ctx, "stopping warm pool replenisher")
+			return
+		case <-ticker.C:
+			s.replenishWarmPools(ctx)
+		case wp := <-s.warmPoolReplenish:
+			if _, err := s.replenishWarmPool(ctx, wp); err != nil {
+				logger.ErrorContext(ctx, "failed to replenish warm pool",
+					"error", err,
+					"org", wp.Org,
+					"label", wp.Label)
+			}
+		}
+	}
+}
+
+// requestWarmPoolReplenish asks the replenisher to top up a warm pool without
+// waiting for it, so that starting a replacement runner does not delay the
+// webhook response. The request is dropped if one is already pending, in which
+// case the pool is topped up by that request or on the next interval.
+func (s *Server) requestWarmPoolReplenish(ctx context.Context, wp *WarmPoolConfig) {
+	select {
+	case s.warmPoolReplenish <- wp:
+	default:
+		logging.FromContext(ctx).DebugContext(ctx, "warm pool replenish already pending")
+	}
+}
+
+// replenishWarmPools tops up every warm pool. When a registry is configured,
+// only one instance replenishes per interval.
+func (s *Server) replenishWarmPools(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	if s.rc != nil {
+		locked, err := s.rc.SetNX(ctx, warmPoolLockKey, "locked", s.config.WarmPoolReplenishInterval).Result()
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to acquire warm pool lock", "error", err)
+			return
+		}
+		if !locked {
+			logger.DebugContext(ctx, "warm pools are being replenished by another instance")
+			return
+		}
+	}
+
+	for _, wp := range s.config.WarmPools {
+		if _, err := s.replenishWarmPool(ctx, wp); err != nil {
+			logger.ErrorContext(ctx, "failed to replenish warm pool",
+				"error", err,
+				"org", wp.Org,
+				"label", wp.Label)
+		}
+	}
+}
+
+// replenishWarmPool starts runners until the warm pool has as many idle
+// runners as its size. It returns the number of runners started.
+func (s *Server) replenishWarmPool(ctx context.Context, wp *WarmPoolConfig) (int, error) {
+	key := warmpool.Key{Org: wp.Org, Label: wp.Label}
+
+	idle, err := s.warmPools.Count(ctx, key, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to count warm runners: %w", err)
+	}
+
+	started := 0
+	for range wp.Size - idle {
+		if err := s.startWarmRunner(ctx, wp); err != nil {
+			return started, err
+		}
+		started++
+	}
+	if started > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "replenished warm pool",
+			"org", wp.Org,
+			"label", wp.Label,
+			"idle_runners", idle,
+			"started_runners", started)
+	}
+	return started, nil
+}
+
+// startWarmRunner registers a runner with the warm pool's labels and starts it
+// on a worker pool selected for those labels from the same registry scopes as
+// a job's runners.
+func (s *Server) startWarmRunner(ctx context.Context, wp *WarmPoolConfig) error {
+	runnerName := warmRunnerNamePrefix + uuid.New().String()
+	labels := wp.runnerLabels()
+
+	logger := logging.FromContext(ctx).With(
+		"runner_id", runnerName,
+		"org", wp.Org,
+		"label", wp.Label)
+	ctx = logging.WithLogger(ctx, logger)
+
+	pool := s.newPoolSelector(wp.Org, labels, labels).next(ctx, s)
+	if pool == nil {
+		return fmt.Errorf("no worker pool found for warm pool %s:%s", wp.Org, wp.Label)
+	}
+
+	jitConfig, err := s.generateWarmRunnerJITConfig(ctx, wp, runnerName, labels)
+	if err != nil {
+		return err
+	}
+	build, err := s.createRunnerBuild(ctx, runnerName, jitConfig, s.runnerImageName, s.runnerImageTag, pool)
+	if err != nil {
+		return err
+	}
+
+	// The runner exits once it has been idle for its idle timeout.
+	idleTimeout := time.Duration(s.runnerLabelPolicy(pool.label).RunnerIdleTimeoutSeconds) * time.Second
+	if err := s.warmPools.Add(ctx, warmpool.Key{Org: wp.Org, Label: wp.Label}, runnerName, time.Now().Add(idleTimeout)); err != nil {
+		return fmt.Errorf("failed to track warm runner: %w", err)
+	}
+
+	logger.InfoContext(ctx, "started warm runner",
+		gcbBuildIDKey, build.BuildID,
+		gcbProjectIDKey, build.ProjectID)
+	return nil
+}
+
+// generateWarmRunnerJITConfig registers a warm runner with the enterprise if
+// the warm pool's label is registered with the enterprise, and otherwise with
+// the warm pool's org, since a warm runner is not started for a repository.
+func (s *Server) generateWarmRunnerJITConfig(ctx context.Context, wp *WarmPoolConfig, runnerName string, labels []string) (string, error) {
+	if s.runnerRegistrationScope(wp.Org, wp.Label) == RunnerRegistrationScopeEnterprise {
+		installationID := s.config.GitHubEnterpriseInstallationID
+		runnerGroupID, err := s.runnerGroupID(ctx, RunnerRegistrationScopeEnterprise, installationID, wp.Org, wp.Label)
+		if err != nil {
+			return "", err
+		}
+		jitConfig, err := s.ghc.GenerateEnterpriseJITConfig(ctx, installationID, s.config.GitHubEnterprise, runnerName, runnerGroupID, labels)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate enterprise JIT config: %w", err)
+		}
+		return jitConfig.GetEncodedJITConfig(), nil
+	}
+
+	installationID, err := s.warmPoolInstallationID(ctx, wp.Org)
+	if err != nil {
+		return "", err
+	}
+	runnerGroupID, err := s.runnerGroupID(ctx, RunnerRegistrationScopeOrg, installationID, wp.Org, wp.Label)
+	if err != nil {
+		return "", err
+	}
+	jitConfig, err := s.ghc.GenerateOrgJITConfig(ctx, installationID, wp.Org, runnerName, runnerGroupID, labels)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate org JIT config: %w", err)
+	}
+	return jitConfig.GetEncodedJITConfig(), nil
+}
+
+// warmPoolInstallationID returns the app installation ID for an org, looking
+// it up on first use.
+func (s *Server) warmPoolInstallationID(ctx context.Context, org string) (int64, error) {
+	if v, ok := s.warmPoolInstallations.Load(org); ok {
+		if id, ok := v.(int64); ok {
+			return id, nil
+		}
+	}
+
+	id, err := s.ghc.OrgInstallationID(ctx, org)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get installation id for org %s: %w", org, err)
+	}
+	s.warmPoolInstallations.Store(org, id)
+	return id, nil
+}
+
+// warmPoolForJob returns the warm pool that can serve a job, or nil if there
+// is none. GitHub assigns a job to a runner that is registered with every
+// label the job requested, so the requested labels are matched against the
+// warm runners' labels the way a worker pool's capabilities are matched. The
+// job's labels must also resolve to the pool's label, so that jobs sent to
+// fallback labels because their org has exhausted its budget are not given a
+// warm runner.
+func (s *Server) warmPoolForJob(orgName string, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string) *WarmPoolConfig {
+	for _, wp := range s.config.WarmPools {
+		if wp.Org != orgName || !slices.Contains(jobResolvedRunnerLabels, wp.Label) {
+			continue
+		}
+		registered := registry.WorkerPoolInfo{Labels: wp.runnerLabels()}
+		if !poolSatisfiesLabels(registered, wp.Label, jobOriginalRunnerLabels, jobOriginalRunnerLabels) {
+			continue
+		}
+		return wp
+	}
+	return nil
+}
+
+// takeWarmRunner reserves an idle warm runner for a queued job, records it as
+// the job's runner and requests a replacement. GitHub assigns the job to any idle runner with matching labels,
+// so the reservation only accounts for one of the pool's runners being used.
+// It returns the name of the reserved runner, or an empty string if the job
+// should be dispatched normally.
+func (s *Server) takeWarmRunner(ctx context.Context, event *github.WorkflowJobEvent, deliveryID string, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string) string {
+	if s.warmPools == nil {
+		return ""
+	}
+
+	wp := s.warmPoolForJob(event.GetOrg().GetLogin(), jobOriginalRunnerLabels, jobResolvedRunnerLabels)
+	if wp == nil {
+		return ""
+	}
+
+	logger := logging.FromContext(ctx).With("warm_pool_label", wp.Label)
+	ctx = logging.WithLogger(ctx, logger)
+
+	// The installation is known from the event, so there is no need to look
+	// it up when replenishing.
+	s.warmPoolInstallations.Store(wp.Org, event.GetInstallation().GetID())
+
+	runnerName, err := s.warmPools.Take(ctx, warmpool.Key{Org: wp.Org, Label: wp.Label}, time.Now())
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to take warm runner, dispatching a new runner", "error", err)
+		return ""
+	}
+	if runnerName == "" {
+		logger.InfoContext(ctx, "warm pool is empty, dispatching a new runner")
+		return ""
+	}
+
+	logger.InfoContext(ctx, warmRunnerMsg, "warm_runner_name", runnerName)
+	s.putWarmRunnerRecord(ctx, event, deliveryID, jobOriginalRunnerLabels, jobResolvedRunnerLabels, wp, runnerName)
+	s.requestWarmPoolReplenish(ctx, wp)
+	return runnerName
+}
+
+// releaseWarmRunner stops tracking a warm runner once it has picked up a job,
+// which may not be the runner reserved when the job was queued.
+func (s *Server) releaseWarmRunner(ctx context.Context, event *github.WorkflowJobEvent) {
+	runnerName := event.WorkflowJob.GetRunnerName()
+	if s.warmPools == nil || runnerName == "" {
+		return
+	}
+
+	for _, wp := range s.config.WarmPools {
+		if wp.Org != event.GetOrg().GetLogin() {
+			continue
+		}
+		if err := s.warmPools.Remove(ctx, warmpool.Key{Org: wp.Org, Label: wp.Label}, runnerName); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to release warm runner",
+				"error", err,
+				"runner_name", runnerName)
+		}
+	}
+}
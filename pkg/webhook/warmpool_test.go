@@ -0,0 +1,554 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/budget"
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/github-action-dispatcher/pkg/dispatch"
+	gh "github.com/abcxyz/github-action-dispatcher/pkg/github"
+	"github.com/abcxyz/github-action-dispatcher/pkg/quota"
+	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
+	"github.com/abcxyz/github-action-dispatcher/pkg/warmpool"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const testWarmPoolInterval = time.Minute
+
+var testWarmPoolKey = warmpool.Key{Org: orgLogin, Label: SelfHostedRunnerLabel}
+
+func TestReplenishWarmPools(t *testing.T) {
+	t.Parallel()
+
+	pools := testRegistryPools(t)
+
+	cases := []struct {
+		name             string
+		idleRunners      []string
+		warmPoolLabels   []string
+		setupRedis       func(m redismock.ClientMock)
+		expectBuildCount int
+		expIdleRunners   int
+		expJITLabels     []string
+	}{
+		{
+			name: "fills_empty_pool",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectSetNX(warmPoolLockKey, "locked", testWarmPoolInterval).SetVal(true)
+				m.ExpectGet("google:self-hosted").SetVal(pools)
+				m.ExpectGet("google:self-hosted").SetVal(pools)
+			},
+			expectBuildCount: 2,
+			expIdleRunners:   2,
+			expJITLabels:     []string{SelfHostedRunnerLabel},
+		},
+		{
+			name:           "registers_warm_pool_labels",
+			idleRunners:    []string{"warm-existing"},
+			warmPoolLabels: []string{"linux", "x64"},
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectSetNX(warmPoolLockKey, "locked", testWarmPoolInterval).SetVal(true)
+				m.ExpectGet("google:self-hosted").SetVal(pools)
+			},
+			expectBuildCount: 1,
+			expIdleRunners:   2,
+			expJITLabels:     []string{SelfHostedRunnerLabel, "linux", "x64"},
+		},
+		{
+			name:        "tops_up_partial_pool",
+			idleRunners: []string{"warm-existing"},
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectSetNX(warmPoolLockKey, "locked", testWarmPoolInterval).SetVal(true)
+				m.ExpectGet("google:self-hosted").SetVal(pools)
+			},
+			expectBuildCount: 1,
+			expIdleRunners:   2,
+		},
+		{
+			name:        "full_pool",
+			idleRunners: []string{"warm-1", "warm-2"},
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectSetNX(warmPoolLockKey, "locked", testWarmPoolInterval).SetVal(true)
+			},
+			expectBuildCount: 0,
+			expIdleRunners:   2,
+		},
+		{
+			name: "another_instance_holds_lock",
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectSetNX(warmPoolLockKey, "locked", testWarmPoolInterval).SetVal(false)
+			},
+			expectBuildCount: 0,
+			expIdleRunners:   0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			db, mockRedis := redismock.NewClientMock()
+			tc.setupRedis(mockRedis)
+
+			tracker := warmpool.NewMemoryTracker()
+			for _, name := range tc.idleRunners {
+				if err := tracker.Add(ctx, testWarmPoolKey, name, time.Now().Add(time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+			mockGitHubClient := newWarmPoolGitHubClient()
+			var gotJITLabels []string
+			mockGitHubClient.GenerateOrgJITConfigF = func(ctx context.Context, installationID int64, org, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
+				gotJITLabels = runnerLabels
+				encodedJitConfig := "Hello"
+				return &github.JITRunnerConfig{EncodedJITConfig: &encodedJitConfig}, nil
+			}
+			srv := newTestWarmPoolServer(t, db, tracker, mockCloudBuildClient, mockGitHubClient)
+			srv.config.WarmPools[0].Labels = tc.warmPoolLabels
+
+			srv.replenishWarmPools(ctx)
+
+			if got, want := len(mockCloudBuildClient.CreateBuildReqs), tc.expectBuildCount; got != want {
+				t.Errorf("expected %d build(s) to be created, got %d", want, got)
+			}
+			if got, want := mockGitHubClient.GenerateOrgJITConfigCalls, tc.expectBuildCount; got != want {
+				t.Errorf("expected %d calls to GenerateOrgJITConfig, got %d", want, got)
+			}
+			if tc.expectBuildCount > 0 {
+				if got, want := mockGitHubClient.OrgInstallationIDCalls, 1; got != want {
+					t.Errorf("expected %d calls to OrgInstallationID, got %d", want, got)
+				}
+			}
+			if tc.expJITLabels != nil {
+				if diff := cmp.Diff(tc.expJITLabels, gotJITLabels); diff != "" {
+					t.Errorf("registered labels mismatch (-want +got):\n%s", diff)
+				}
+			}
+
+			idle, err := tracker.Count(ctx, testWarmPoolKey, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := idle, tc.expIdleRunners; got != want {
+				t.Errorf("expected %d idle runners, got %d", want, got)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestReplenishWarmPools_Enterprise(t *testing.T) {
+	t.Parallel()
+
+	pools := testRegistryPools(t)
+
+	cases := []struct {
+		name                    string
+		runnerRegistrationScope string
+		expEnterpriseJITCalls   int
+		expOrgJITCalls          int
+	}{
+		{
+			name:                    "enterprise_registration_scope",
+			runnerRegistrationScope: RunnerRegistrationScopeEnterprise,
+			expEnterpriseJITCalls:   2,
+		},
+		{
+			name:                    "repo_registration_scope_registers_with_org",
+			runnerRegistrationScope: RunnerRegistrationScopeRepo,
+			expOrgJITCalls:          2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			// The org has no pools of its own, so runners are started on the
+			// enterprise's pools.
+			db, mockRedis := redismock.NewClientMock()
+			mockRedis.ExpectSetNX(warmPoolLockKey, "locked", testWarmPoolInterval).SetVal(true)
+			for range 2 {
+				mockRedis.ExpectGet("google:self-hosted").RedisNil()
+				mockRedis.ExpectGet("enterprise_abcxyz:self-hosted").SetVal(pools)
+			}
+
+			tracker := warmpool.NewMemoryTracker()
+			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+			mockGitHubClient := newWarmPoolGitHubClient()
+			var gotEnterprise string
+			var gotInstallationID int64
+			mockGitHubClient.GenerateEnterpriseJITConfigF = func(ctx context.Context, installationID int64, enterprise, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
+				gotInstallationID, gotEnterprise = installationID, enterprise
+				encodedJitConfig := "Hello"
+				return &github.JITRunnerConfig{EncodedJITConfig: &encodedJitConfig}, nil
+			}
+
+			cfg := &Config{
+				GitHubEnterprise:               "abcxyz",
+				GitHubEnterpriseInstallationID: 456,
+				RunnerExecutionTimeoutSeconds:  3600,
+				RunnerIdleTimeoutSeconds:       300,
+				RunnerRegistrationScope:        tc.runnerRegistrationScope,
+				SupportedRunnerLabels:          []string{SelfHostedRunnerLabel},
+				WarmPools:                      []*WarmPoolConfig{{Org: orgLogin, Label: SelfHostedRunnerLabel, Size: 2}},
+				WarmPoolReplenishInterval:      testWarmPoolInterval,
+			}
+			srv := newTestServer(t, cfg, db, &WebhookClientOptions{
+				CloudBuildClientOverride: mockCloudBuildClient,
+				GitHubClientOverride:     mockGitHubClient,
+				WarmPoolTrackerOverride:  tracker,
+			})
+
+			srv.replenishWarmPools(ctx)
+
+			if got, want := len(mockCloudBuildClient.CreateBuildReqs), 2; got != want {
+				t.Errorf("expected %d build(s) to be created, got %d", want, got)
+			}
+			if got, want := mockGitHubClient.GenerateEnterpriseJITConfigCalls, tc.expEnterpriseJITCalls; got != want {
+				t.Errorf("expected %d calls to GenerateEnterpriseJITConfig, got %d", want, got)
+			}
+			if got, want := mockGitHubClient.GenerateOrgJITConfigCalls, tc.expOrgJITCalls; got != want {
+				t.Errorf("expected %d calls to GenerateOrgJITConfig, got %d", want, got)
+			}
+			if tc.expEnterpriseJITCalls > 0 {
+				if got, want := gotEnterprise, "abcxyz"; got != want {
+					t.Errorf("expected runners to be registered with enterprise %q, got %q", want, got)
+				}
+				if got, want := gotInstallationID, int64(456); got != want {
+					t.Errorf("expected enterprise installation id %d, got %d", want, got)
+				}
+				if got, want := mockGitHubClient.OrgInstallationIDCalls, 0; got != want {
+					t.Errorf("expected %d calls to OrgInstallationID, got %d", want, got)
+				}
+			}
+
+			idle, err := tracker.Count(ctx, testWarmPoolKey, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := idle, 2; got != want {
+				t.Errorf("expected %d idle runners, got %d", want, got)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleQueuedEvent_WarmPool(t *testing.T) {
+	t.Parallel()
+
+	pools := testRegistryPools(t)
+
+	cases := []struct {
+		name            string
+		labels          []string
+		idleRunners     []string
+		setupServer     func(tb testing.TB, srv *Server)
+		setupRedis      func(m redismock.ClientMock)
+		expCode         int
+		expMessage      string
+		expRunnerNames  []string
+		expRepoJITCalls int
+		expIdleRunners  int
+		expReplenish    bool
+		expRecord       []*dispatch.Runner
+	}{
+		{
+			name:           "assigns_warm_runner_and_requests_replenish",
+			labels:         []string{SelfHostedRunnerLabel},
+			idleRunners:    []string{"warm-1"},
+			setupRedis:     func(m redismock.ClientMock) {},
+			expMessage:     warmRunnerMsg,
+			expRunnerNames: []string{"warm-1"},
+			expReplenish:   true,
+			expRecord:      []*dispatch.Runner{{Name: "warm-1", Label: SelfHostedRunnerLabel, Warm: true}},
+		},
+		{
+			name:   "empty_warm_pool_dispatches_runner",
+			labels: []string{SelfHostedRunnerLabel},
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet("google:self-hosted").SetVal(pools)
+			},
+			expMessage:      runnerStartedMsg,
+			expRepoJITCalls: 1,
+		},
+		{
+			name:        "multi_label_job_takes_warm_runner",
+			labels:      []string{SelfHostedRunnerLabel, "linux", "x64"},
+			idleRunners: []string{"warm-1"},
+			setupServer: func(tb testing.TB, srv *Server) {
+				tb.Helper()
+
+				srv.config.WarmPools[0].Labels = []string{"linux", "x64"}
+			},
+			setupRedis:     func(m redismock.ClientMock) {},
+			expMessage:     warmRunnerMsg,
+			expRunnerNames: []string{"warm-1"},
+			expReplenish:   true,
+			expRecord:      []*dispatch.Runner{{Name: "warm-1", Label: SelfHostedRunnerLabel, Warm: true}},
+		},
+		{
+			name:        "job_with_other_labels_dispatches_runner",
+			labels:      []string{SelfHostedRunnerLabel, "gpu"},
+			idleRunners: []string{"warm-1"},
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet("google:self-hosted").SetVal(pools)
+			},
+			expMessage:      runnerStartedMsg,
+			expRepoJITCalls: 1,
+			expIdleRunners:  1,
+		},
+		{
+			name:        "quota_exceeded_defers_job",
+			labels:      []string{SelfHostedRunnerLabel},
+			idleRunners: []string{"warm-1"},
+			setupServer: func(tb testing.TB, srv *Server) {
+				tb.Helper()
+
+				// Another job in the org already holds the only slot.
+				srv.config.ConcurrencyQuotas = map[string]int{orgLogin: 1}
+				srv.quotas = quota.NewMemoryLimiter()
+				if _, _, err := srv.quotas.Acquire(tb.Context(), "456", 1, []*quota.Limit{{Key: orgLogin, Max: 1}}, time.Now().Add(time.Hour), time.Now()); err != nil {
+					tb.Fatal(err)
+				}
+			},
+			setupRedis:     func(m redismock.ClientMock) {},
+			expCode:        http.StatusTooManyRequests,
+			expIdleRunners: 1,
+		},
+		{
+			name:        "budget_alias_dispatches_runner",
+			labels:      []string{SelfHostedRunnerLabel},
+			idleRunners: []string{"warm-1"},
+			setupServer: func(tb testing.TB, srv *Server) {
+				tb.Helper()
+
+				srv.config.SupportedRunnerLabels = append(srv.config.SupportedRunnerLabels, "self-hosted-small")
+				srv.allowedLabels["self-hosted-small"] = true
+				srv.config.Budgets = map[string]*Budget{orgLogin: {Unit: BudgetUnitMinutes, Limit: 60}}
+				srv.config.BudgetExhaustedAction = BudgetActionAlias
+				srv.config.BudgetFallbackAliases = map[string]string{SelfHostedRunnerLabel: "self-hosted-small"}
+				srv.budgets = budget.NewMemoryStore()
+				if _, err := srv.budgets.Add(tb.Context(), orgLogin, budget.Month(time.Now()), "456", &budget.Usage{Minutes: 60}); err != nil {
+					tb.Fatal(err)
+				}
+			},
+			setupRedis: func(m redismock.ClientMock) {
+				m.ExpectGet("google:self-hosted-small").SetVal(pools)
+			},
+			expMessage:      runnerStartedMsg,
+			expRepoJITCalls: 1,
+			expIdleRunners:  1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			db, mockRedis := redismock.NewClientMock()
+			tc.setupRedis(mockRedis)
+
+			tracker := warmpool.NewMemoryTracker()
+			for _, name := range tc.idleRunners {
+				if err := tracker.Add(ctx, testWarmPoolKey, name, time.Now().Add(time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+			mockGitHubClient := newWarmPoolGitHubClient()
+			srv := newTestWarmPoolServer(t, db, tracker, mockCloudBuildClient, mockGitHubClient)
+			srv.config.SupportedRunnerLabels = append(srv.config.SupportedRunnerLabels, "gpu")
+			srv.allowedLabels["gpu"] = true
+			srv.records = dispatch.NewMemoryStore()
+			if tc.setupServer != nil {
+				tc.setupServer(t, srv)
+			}
+
+			event := newWorkflowJobEvent("queued", &github.WorkflowJob{})
+			event.WorkflowJob.Labels = tc.labels
+
+			resp := httptest.NewRecorder()
+			srv.handleWebhook().ServeHTTP(resp, newWebhookRequest(t, event))
+			expCode := tc.expCode
+			if expCode == 0 {
+				expCode = http.StatusOK
+			}
+			if got, want := resp.Code, expCode; got != want {
+				t.Fatalf("expected %d to be %d: %s", got, want, resp.Body.String())
+			}
+
+			if tc.expMessage != "" {
+				var r runnersResponse
+				if err := json.Unmarshal(resp.Body.Bytes(), &r); err != nil {
+					t.Fatalf("failed to unmarshal JSON response: %v, body: %s", err, resp.Body.String())
+				}
+				if got, want := r.Message, tc.expMessage; got != want {
+					t.Errorf("expected message %q, got %q", want, got)
+				}
+				if tc.expRunnerNames != nil {
+					if diff := cmp.Diff(tc.expRunnerNames, r.RunnerNames); diff != "" {
+						t.Errorf("RunnerNames mismatch (-want +got):\n%s", diff)
+					}
+				}
+			}
+			if got, want := mockGitHubClient.GenerateRepoJITConfigCalls, tc.expRepoJITCalls; got != want {
+				t.Errorf("expected %d calls to GenerateRepoJITConfig, got %d", want, got)
+			}
+			// The replacement for a taken warm runner is started by the
+			// replenisher, not while the webhook is handled.
+			if got, want := mockGitHubClient.GenerateOrgJITConfigCalls, 0; got != want {
+				t.Errorf("expected %d calls to GenerateOrgJITConfig, got %d", want, got)
+			}
+			if got, want := len(mockCloudBuildClient.CreateBuildReqs), tc.expRepoJITCalls; got != want {
+				t.Errorf("expected %d build(s) to be created, got %d", want, got)
+			}
+			if got, want := len(srv.warmPoolReplenish) == 1, tc.expReplenish; got != want {
+				t.Errorf("expected replenish requested to be %t, got %t", want, got)
+			}
+			if got, want := mockGitHubClient.OrgInstallationIDCalls, 0; got != want {
+				t.Errorf("expected installation from event to be used, got %d OrgInstallationID calls", got)
+			}
+			if tc.expRecord != nil {
+				record, err := srv.records.Get(ctx, "789")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if record == nil {
+					t.Fatal("expected dispatch record to be stored")
+				}
+				if diff := cmp.Diff(tc.expRecord, record.Runners); diff != "" {
+					t.Errorf("recorded runners mismatch (-want +got):\n%s", diff)
+				}
+				if got, want := record.DeliveryID, "delivery-id"; got != want {
+					t.Errorf("expected delivery id %q to be %q", got, want)
+				}
+			}
+
+			idle, err := tracker.Count(ctx, testWarmPoolKey, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := idle, tc.expIdleRunners; got != want {
+				t.Errorf("expected %d idle runners, got %d", want, got)
+			}
+			if err := mockRedis.ExpectationsWereMet(); err != nil {
+				t.Errorf("redis expectations not met: %v", err)
+			}
+		})
+	}
+}
+
+func TestReleaseWarmRunner(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	tracker := warmpool.NewMemoryTracker()
+	for _, name := range []string{"warm-1", "warm-2"} {
+		if err := tracker.Add(ctx, testWarmPoolKey, name, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := newTestWarmPoolServer(t, nil, tracker, &cloudbuild.MockClient{}, newWarmPoolGitHubClient())
+
+	resp := httptest.NewRecorder()
+	srv.handleWebhook().ServeHTTP(resp, newWebhookRequest(t, newWorkflowJobEvent("in_progress", &github.WorkflowJob{
+		RunnerName: github.Ptr("warm-2"),
+	})))
+	if got, want := resp.Code, http.StatusOK; got != want {
+		t.Fatalf("expected %d to be %d: %s", got, want, resp.Body.String())
+	}
+
+	taken, err := tracker.Take(ctx, testWarmPoolKey, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := taken, "warm-1"; got != want {
+		t.Errorf("expected remaining runner %q to be %q", got, want)
+	}
+}
+
+func newTestWarmPoolServer(tb testing.TB, rc *redis.Client, tracker warmpool.Tracker, cbc cloudbuild.Client, ghc gh.Client) *Server {
+	tb.Helper()
+
+	cfg := &Config{
+		RunnerExecutionTimeoutSeconds: 3600,
+		RunnerIdleTimeoutSeconds:      300,
+		SupportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+		WarmPools:                     []*WarmPoolConfig{{Org: orgLogin, Label: SelfHostedRunnerLabel, Size: 2}},
+		WarmPoolReplenishInterval:     testWarmPoolInterval,
+	}
+	return newTestServer(tb, cfg, rc, &WebhookClientOptions{
+		CloudBuildClientOverride: cbc,
+		GitHubClientOverride:     ghc,
+		WarmPoolTrackerOverride:  tracker,
+	})
+}
+
+func newWarmPoolGitHubClient() *gh.MockClient {
+	encodedJitConfig := "Hello"
+	jit := &github.JITRunnerConfig{EncodedJITConfig: &encodedJitConfig}
+	return &gh.MockClient{
+		GenerateRepoJITConfigF: func(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error) {
+			return jit, nil
+		},
+		GenerateOrgJITConfigF: func(ctx context.Context, installationID int64, org, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
+			return jit, nil
+		},
+		OrgInstallationIDF: func(ctx context.Context, org string) (int64, error) {
+			return 123, nil
+		},
+	}
+}
+
+func testRegistryPools(tb testing.TB) string {
+	tb.Helper()
+
+	pools, err := json.Marshal([]registry.WorkerPoolInfo{
+		{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1", Location: "us-west1"},
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return string(pools)
+}
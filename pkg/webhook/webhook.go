@@ -15,26 +15,19 @@
 package webhook
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
-	"math/rand"
 	"net/http"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 
-	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/go-github/v69/github"
 	"github.com/google/uuid"
-	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/abcxyz/github-action-dispatcher/pkg/registry"
 	"github.com/abcxyz/pkg/logging"
@@ -42,9 +35,14 @@ import (
 
 const (
 	runnerStartedMsg      = "runner started"
+	runnerDuplicateMsg    = "runner already dispatched"
 	githubWebhookEventKey = "github_webhook_event"
 	gcbBuildIDKey         = "gcb_build_id"
 	gcbProjectIDKey       = "gcb_project_id"
+
+	// enterpriseRegistryScopePrefix prefixes the enterprise slug in registry
+	// keys of worker pools registered for an enterprise.
+	enterpriseRegistryScopePrefix = "enterprise_"
 )
 
 // apiResponse is a structure that contains a http status code,
@@ -62,11 +60,49 @@ type runnersResponse struct {
 	GCBBuildIDs []string `json:"gcbBuildIDs,omitempty"`
 }
 
+// runnerBuild identifies the build started for a runner by the backend of its
+// pool type.
+type runnerBuild struct {
+	RunnerName string `json:"runner_name"`
+	BuildID    string `json:"build_id"`
+	ProjectID  string `json:"project_id"`
+	Location   string `json:"location"`
+	PoolType   string `json:"pool_type,omitempty"`
+
+	// poolName and label are the name of the worker pool the runner was
+	// started on and the label the pool was selected for.
+	poolName string
+	label    string
+
+	// dryRun is the build that would have been created, in dry-run mode.
+	dryRun *dryRunBuild
+}
+
 type workerPool struct {
 	name           string
 	projectID      string
 	location       string
 	serviceAccount string
+
+	// label is the resolved runner label whose registry key the pool was
+	// found under. It is empty for pools that do not come from the registry.
+	label string
+
+	// weight and priority determine how often the pool is selected among the
+	// pools that can serve a job.
+	weight   int
+	priority int
+
+	// poolType and remoteConfig are set for trusted pools, whose builds are
+	// created through the Cloud Build consumer API.
+	poolType     string
+	remoteConfig string
+
+	// maxConcurrency is the number of builds the pool can run at once, or 0
+	// if it is unlimited. inFlight is the number of builds running on it when
+	// it was selected.
+	maxConcurrency int
+	inFlight       int
 }
 
 // handleWebhook returns an http.Handler that processes incoming GitHub webhook requests.
@@ -75,7 +111,8 @@ type workerPool struct {
 // based on the event type and action.
 func (s *Server) handleWebhook() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		ctx := s.serverContext(r.Context())
+		r = r.WithContext(ctx)
 		logger := logging.FromContext(ctx)
 
 		resp := s.processRequest(r)
@@ -107,23 +144,32 @@ func (s *Server) processRequest(r *http.Request) *apiResponse {
 	ctx := r.Context()
 	logger := logging.FromContext(ctx)
 
-	event, err := validateGitHubPayload(r, s.webhookSecret)
+	event, secretVersion, err := validateGitHubPayload(r, s.webhookSecrets.active())
 	if err != nil {
 		logger.ErrorContext(ctx, "failed to validate github payload", "error", err)
 		return &apiResponse{http.StatusBadRequest, "failed to validate github payload", err}
 	}
+	logger = logger.With("webhook_secret_version", secretVersion)
+	ctx = logging.WithLogger(ctx, logger)
+	logger.InfoContext(ctx, "validated github payload")
 	if event == nil {
 		return &apiResponse{http.StatusOK, "ignored event", nil}
 	}
 
+	deliveryID := github.DeliveryID(r)
 	jobID, attributes := extractLoggedAttributes(event)
-	logger = logger.With(attributes...)
+	logger = logger.With(attributes...).With("gh_delivery_id", deliveryID)
 	// Add to context so attributes are propagated down the stack.
 	ctx = logging.WithLogger(ctx, logger)
 
 	switch *event.Action {
 	case "queued":
-		return s.handleQueuedEvent(ctx, event, jobID)
+		// When the dispatch queue is enabled, runners are started by the
+		// dispatch workers so that the delivery can be acknowledged quickly.
+		if s.queue != nil {
+			return s.enqueueQueuedEvent(ctx, event, deliveryID)
+		}
+		return s.handleQueuedEvent(ctx, event, deliveryID, jobID)
 
 	case "in_progress":
 		if event.WorkflowJob.CreatedAt != nil && event.WorkflowJob.StartedAt != nil {
@@ -132,10 +178,19 @@ func (s *Server) processRequest(r *http.Request) *apiResponse {
 		}
 
 		logger.InfoContext(ctx, "Workflow job in progress")
+		s.releaseWarmRunner(ctx, event)
+		s.updateDispatchRecord(ctx, event, jobID)
 		return &apiResponse{http.StatusOK, "workflow job in progress event logged", nil}
 
 	case "completed":
 		logger.InfoContext(ctx, "Workflow job completed", extractCompletedLogAttributes(event)...)
+		s.updateDispatchRecord(ctx, event, jobID)
+		if cancelled := s.cancelUnusedBuilds(ctx, event, jobID); len(cancelled) > 0 {
+			logger.InfoContext(ctx, "cancelled unused builds for job", "gcb_build_ids", cancelled)
+		}
+		s.untrackRunner(ctx, event.WorkflowJob.GetRunnerName())
+		s.releaseQuota(ctx, event, jobID)
+		s.recordBudgetUsage(ctx, event, jobID)
 		return &apiResponse{http.StatusOK, "workflow job completed event logged", nil}
 
 	default:
@@ -147,19 +202,21 @@ func (s *Server) processRequest(r *http.Request) *apiResponse {
 
 // validateGitHubPayload validates the incoming HTTP request as a GitHub webhook payload.
 //
-// It uses `github.ValidatePayload` and `github.ParseWebHook` to ensure the payload is authentic
-// and correctly formatted. It specifically expects a `github.WorkflowJobEvent`. Other event types
+// It uses `validatePayload` and `github.ParseWebHook` to ensure the payload is authentic
+// and correctly formatted. The payload may be signed with any of the active webhook secrets.
+// It specifically expects a `github.WorkflowJobEvent`. Other event types
 // like `InstallationRepositoriesEvent` or `InstallationEvent` are logged and ignored.
-// It returns the parsed `github.WorkflowJobEvent` or an error if validation fails or the event type is unexpected.
-func validateGitHubPayload(r *http.Request, webhookSecret []byte) (*github.WorkflowJobEvent, error) {
-	payload, err := github.ValidatePayload(r, webhookSecret)
+// It returns the parsed `github.WorkflowJobEvent` and the version of the secret that signed it,
+// or an error if validation fails or the event type is unexpected.
+func validateGitHubPayload(r *http.Request, webhookSecrets []*webhookSecretVersion) (*github.WorkflowJobEvent, string, error) {
+	payload, secretVersion, err := validatePayload(r, webhookSecrets)
 	if err != nil {
-		return nil, fmt.Errorf("failed to validate payload: %w", err)
+		return nil, "", fmt.Errorf("failed to validate payload: %w", err)
 	}
 
 	rawEvent, err := github.ParseWebHook(github.WebHookType(r), payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse webhook: %w", err)
+		return nil, "", fmt.Errorf("failed to parse webhook: %w", err)
 	}
 
 	event, ok := rawEvent.(*github.WorkflowJobEvent)
@@ -168,9 +225,9 @@ func validateGitHubPayload(r *http.Request, webhookSecret []byte) (*github.Workf
 		case *github.InstallationRepositoriesEvent, *github.InstallationEvent:
 			// These are specific event types (like installation events) that are expected but do not require further processing, so we log and ignore them.
 			slog.InfoContext(r.Context(), "received event", "type", github.WebHookType(r))
-			return nil, nil
+			return nil, secretVersion, nil
 		default:
-			return nil, fmt.Errorf("unexpected event type dispatched from webhook, event type: %T", rawEvent)
+			return nil, "", fmt.Errorf("unexpected event type dispatched from webhook, event type: %T", rawEvent)
 		}
 	}
 
@@ -185,40 +242,38 @@ func validateGitHubPayload(r *http.Request, webhookSecret []byte) (*github.Workf
 		merr = errors.Join(merr, fmt.Errorf("event is missing required field: workflow_job"))
 	}
 	if merr != nil {
-		return nil, merr
+		return nil, "", merr
 	}
-	return event, nil
+	return event, secretVersion, nil
 }
 
 // startRunnersForJob contains the core logic for spawning runners for a given
-// queued job. It returns the names of the runners it successfully started and
+// queued job. It returns the builds of the runners it successfully started and
 // an error if anything went wrong.
-func (s *Server) startRunnersForJob(ctx context.Context, event *github.WorkflowJobEvent, jobOriginalRunnerLabel, jobResolvedRunnerLabel string) ([]string, []string, error) {
+func (s *Server) startRunnersForJob(ctx context.Context, event *github.WorkflowJobEvent, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string) ([]*runnerBuild, error) {
 	logger := logging.FromContext(ctx).With(
-		"original_label", jobOriginalRunnerLabel,
-		"resolved_label", jobResolvedRunnerLabel)
+		"original_labels", jobOriginalRunnerLabels,
+		"resolved_labels", jobResolvedRunnerLabels)
 
-	// These slices will hold the names and build IDs of runners we successfully create.
-	var startedRunnerNames []string
-	var gcbBuildIDs []string
+	// This slice will hold the builds of runners we successfully create.
+	var startedBuilds []*runnerBuild
 
-	pool := s.selectWorkerPool(ctx, *event.Org.Login, jobResolvedRunnerLabel)
+	ps := s.newPoolSelector(*event.Org.Login, jobOriginalRunnerLabels, jobResolvedRunnerLabels)
+	pool := ps.next(ctx, s)
 	// If we are running with default disabled send to 404.
 	if pool == nil && s.config.Runner404DefaultDisabled {
-		logger.WarnContext(ctx, "unable to find a pool to handle requested label - sending to 404 runner")
-		return s.start404RunnerForJob(ctx, event, jobOriginalRunnerLabel)
-	}
-	// Try to get a matching default runner when default fallback is enabled.
-	if pool == nil {
-		pool = s.selectWorkerPool(ctx, s.runnerRegistryDefaultKeyPrefix, jobResolvedRunnerLabel)
+		logger.WarnContext(ctx, "unable to find a pool to handle requested labels - sending to 404 runner")
+		return s.start404RunnerForJob(ctx, event, jobOriginalRunnerLabels)
 	}
 	// If the default
 	if pool == nil {
-		logger.WarnContext(ctx, "unable to find an org pool or default pool to handle requested label - sending to 404 runner")
-		return s.start404RunnerForJob(ctx, event, jobOriginalRunnerLabel)
+		logger.WarnContext(ctx, "unable to find an org pool or default pool to handle requested labels - sending to 404 runner")
+		return s.start404RunnerForJob(ctx, event, jobOriginalRunnerLabels)
 	}
+	logger = logger.With("resolved_label", pool.label)
 
-	for i := 1; i <= 1+s.extraRunnerCount; i++ {
+	policy := s.runnerLabelPolicy(pool.label)
+	for i := 1; i <= 1+policy.ExtraRunnerCount; i++ {
 		runnerID := uuid.New().String()
 
 		runnerLogger := logger.With("runner_id", runnerID)
@@ -226,27 +281,37 @@ func (s *Server) startRunnersForJob(ctx context.Context, event *github.WorkflowJ
 			runnerLogger.InfoContext(ctx, "Spawning extra runner")
 		}
 
+		// Extra runners are started on the pool that the previous runner was
+		// started on, which differs from the first pool selected if a build
+		// could not be created there.
 		runnerCtx := logging.WithLogger(ctx, runnerLogger)
-		buildID, projectID, err := s.startGitHubRunner(runnerCtx, event, runnerID, runnerLogger, s.runnerImageName, s.runnerImageTag, jobOriginalRunnerLabel, pool)
+		build, startedPool, err := s.startRunnerWithFailover(runnerCtx, event, runnerID, pool, ps)
 		if err != nil {
 			// If one fails, return the error and the list of any that succeeded before it.
-			return startedRunnerNames, gcbBuildIDs, fmt.Errorf("failed on runner %s: %w", runnerID, err)
+			return startedBuilds, fmt.Errorf("failed on runner %s: %w", runnerID, err)
+		}
+		pool = startedPool
+		// A new runner is registered when failing over to a pool of another
+		// label.
+		if build.RunnerName != runnerID {
+			runnerLogger = logger.With("runner_id", build.RunnerName, "failover_resolved_label", pool.label)
 		}
 
-		runnerLogger.InfoContext(ctx, runnerStartedMsg,
-			slog.Any(githubWebhookEventKey, event),
-			slog.String(gcbBuildIDKey, buildID),
-			slog.String(gcbProjectIDKey, projectID))
+		if build.dryRun == nil {
+			runnerLogger.InfoContext(ctx, runnerStartedMsg,
+				slog.Any(githubWebhookEventKey, event),
+				slog.String(gcbBuildIDKey, build.BuildID),
+				slog.String(gcbProjectIDKey, build.ProjectID))
+		}
 
-		startedRunnerNames = append(startedRunnerNames, runnerID)
-		gcbBuildIDs = append(gcbBuildIDs, buildID)
+		startedBuilds = append(startedBuilds, build)
 	}
 
-	return startedRunnerNames, gcbBuildIDs, nil
+	return startedBuilds, nil
 }
 
 // start404RunnerForJob starts a runner for the 404 runner.
-func (s *Server) start404RunnerForJob(ctx context.Context, event *github.WorkflowJobEvent, jobOriginalRunnerLabel string) ([]string, []string, error) {
+func (s *Server) start404RunnerForJob(ctx context.Context, event *github.WorkflowJobEvent, jobOriginalRunnerLabels []string) ([]*runnerBuild, error) {
 	logger := logging.FromContext(ctx)
 
 	runnerID := uuid.New().String()
@@ -258,31 +323,33 @@ func (s *Server) start404RunnerForJob(ctx context.Context, event *github.Workflo
 		location:       s.config.Runner404Location,
 		serviceAccount: s.config.Runner404ServiceAccount,
 	}
-	buildID, projectID, err := s.startGitHubRunner(runnerCtx, event, runnerID, runnerLogger, s.config.Runner404ImageName, s.config.Runner404ImageTag, jobOriginalRunnerLabel, runner404Pool)
+	build, err := s.startGitHubRunner(runnerCtx, event, runnerID, runnerLogger, s.config.Runner404ImageName, s.config.Runner404ImageTag, jobOriginalRunnerLabels, runner404Pool)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed on runner %s: %w", runnerID, err)
+		return nil, fmt.Errorf("failed on runner %s: %w", runnerID, err)
 	}
 
-	runnerLogger.InfoContext(ctx, runnerStartedMsg,
-		slog.Any(githubWebhookEventKey, event),
-		slog.String(gcbBuildIDKey, buildID),
-		slog.String(gcbProjectIDKey, projectID))
+	if build.dryRun == nil {
+		runnerLogger.InfoContext(ctx, runnerStartedMsg,
+			slog.Any(githubWebhookEventKey, event),
+			slog.String(gcbBuildIDKey, build.BuildID),
+			slog.String(gcbProjectIDKey, build.ProjectID))
+	}
 
-	return []string{runnerID}, []string{buildID}, nil
+	return []*runnerBuild{build}, nil
 }
 
-func (s *Server) handleQueuedEvent(ctx context.Context, event *github.WorkflowJobEvent, jobID string) *apiResponse {
+func (s *Server) handleQueuedEvent(ctx context.Context, event *github.WorkflowJobEvent, deliveryID, jobID string) *apiResponse {
 	logger := logging.FromContext(ctx)
 	logger.InfoContext(ctx, "Workflow job queued")
 
-	// We don't support jobs with multiple labels.
-	if len(event.WorkflowJob.Labels) != 1 {
-		logger.WarnContext(ctx, "no action taken, only accept single label jobs", "labels", event.WorkflowJob.Labels)
-		return &apiResponse{http.StatusOK, fmt.Sprintf("no action taken, only accept single label jobs, got: %s", event.WorkflowJob.Labels), nil}
+	// A job without any labels cannot be matched to a worker pool.
+	if len(event.WorkflowJob.Labels) == 0 {
+		logger.WarnContext(ctx, "no action taken, job did not request any labels")
+		return &apiResponse{http.StatusOK, "no action taken, job did not request any labels", nil}
 	}
 
-	incomingLabel := event.WorkflowJob.Labels[0]
-	jobOriginalRunnerLabel := incomingLabel // used in jit config request
+	jobOriginalRunnerLabels := event.WorkflowJob.Labels // used in jit config request
+	incomingLabels := strings.Join(jobOriginalRunnerLabels, ",")
 
 	var orgName, repoName string
 	if event.Org != nil && event.Org.Login != nil {
@@ -293,28 +360,30 @@ func (s *Server) handleQueuedEvent(ctx context.Context, event *github.WorkflowJo
 	}
 
 	logger = logger.With(
-		"original_label", jobOriginalRunnerLabel,
+		"original_labels", jobOriginalRunnerLabels,
 		"org", orgName,
 		"repo", repoName,
 	)
 
-	logger.InfoContext(ctx, "received user requested label", "label", incomingLabel)
+	logger.InfoContext(ctx, "received user requested labels", "labels", jobOriginalRunnerLabels)
 
-	jobResolvedRunnerLabel, canHandle, err := s.resolveAndValidateRunnerLabel(ctx, incomingLabel)
+	jobResolvedRunnerLabels, canHandle, err := s.resolveAndValidateRunnerLabels(ctx, jobOriginalRunnerLabels)
 	if err != nil {
-		logger.ErrorContext(ctx, "failed to resolve and validate runner label", "error", err)
+		logger.ErrorContext(ctx, "failed to resolve and validate runner labels", "error", err)
 		return &apiResponse{http.StatusInternalServerError, err.Error(), err}
 	}
 
-	logger = logger.With("resolved_label", jobResolvedRunnerLabel)
+	logger = logger.With("resolved_labels", jobResolvedRunnerLabels)
 
 	if !canHandle && !s.config.Runner404Enabled {
-		logger.WarnContext(ctx, "no action taken for label")
-		return &apiResponse{http.StatusOK, fmt.Sprintf("no action taken for label: %s", incomingLabel), nil}
+		logger.WarnContext(ctx, "no action taken for labels")
+		return &apiResponse{http.StatusOK, fmt.Sprintf("no action taken for labels: %s", incomingLabels), nil}
 	}
-	if slices.Contains(s.config.IgnoredRunnerLabels, jobOriginalRunnerLabel) {
+	if slices.ContainsFunc(jobOriginalRunnerLabels, func(label string) bool {
+		return slices.Contains(s.config.IgnoredRunnerLabels, label)
+	}) {
 		logger.InfoContext(ctx, "no action taken for ignored label")
-		return &apiResponse{http.StatusOK, fmt.Sprintf("no action taken for ignored label: %s", incomingLabel), nil}
+		return &apiResponse{http.StatusOK, fmt.Sprintf("no action taken for ignored label: %s", incomingLabels), nil}
 	}
 
 	if event.Installation == nil || event.Installation.ID == nil || event.Org == nil || event.Org.Login == nil || event.Repo == nil || event.Repo.Name == nil {
@@ -323,25 +392,103 @@ func (s *Server) handleQueuedEvent(ctx context.Context, event *github.WorkflowJo
 		return &apiResponse{http.StatusBadRequest, "unexpected event payload struture", err}
 	}
 
-	var runnerNames, gcbBuildIDs []string
+	// GitHub redelivers webhooks, so only dispatch runners the first time a
+	// delivery or job is seen.
+	if record := s.claimDispatch(ctx, deliveryID, jobID); record != nil {
+		return s.duplicateDispatchResponse(ctx, record)
+	}
+
+	// Jobs denied by the dispatch policy are handled like jobs with labels
+	// that are not supported. The policy is evaluated after the job is
+	// claimed so that redeliveries do not look up its workflow again.
+	if canHandle {
+		allowed, err := s.allowedByPolicy(ctx, event, jobOriginalRunnerLabels, jobResolvedRunnerLabels)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to evaluate dispatch policy", "error", err)
+			s.releaseDispatch(ctx, jobID)
+			return &apiResponse{http.StatusInternalServerError, err.Error(), err}
+		}
+		if !allowed {
+			if !s.config.Runner404Enabled {
+				logger.WarnContext(ctx, "no action taken, job denied by dispatch policy")
+				s.recordDispatch(ctx, deliveryID, jobID, nil)
+				return &apiResponse{http.StatusOK, fmt.Sprintf("no action taken, job denied by dispatch policy: %s", incomingLabels), nil}
+			}
+			canHandle = false
+		}
+	}
+
+	// Jobs of an org that has exhausted its budget are sent to the 404 runner
+	// or dispatched to cheaper labels, if configured.
+	if canHandle {
+		switch s.exhaustedBudgetAction(ctx, orgName) {
+		case BudgetActionRunner404:
+			canHandle = false
+		case BudgetActionAlias:
+			jobResolvedRunnerLabels = s.budgetFallbackLabels(jobResolvedRunnerLabels)
+			logger.InfoContext(ctx, "dispatching job to budget fallback labels",
+				"budget_fallback_labels", jobResolvedRunnerLabels)
+		}
+	}
+
+	var builds []*runnerBuild
 	if !canHandle && s.config.Runner404Enabled {
 		// This assumes that the dispatcher is responsible for enqueuing all
 		// jobs on the GH host. If another service will subscribe to the
 		// webhook and handle jobs then this should not be enabled.
-		runnerNames, gcbBuildIDs, err = s.start404RunnerForJob(ctx, event, jobOriginalRunnerLabel)
-		logger.WarnContext(ctx, "unable to handle requested label - sending to 404 runner")
+		builds, err = s.start404RunnerForJob(ctx, event, jobOriginalRunnerLabels)
+		logger.WarnContext(ctx, "unable to handle requested labels - sending to 404 runner")
+		s.putDispatchRecord(ctx, event, deliveryID, jobOriginalRunnerLabels, nil, builds)
 		if err != nil {
+			s.releaseDispatch(ctx, jobID)
 			return &apiResponse{http.StatusInternalServerError, err.Error(), err}
 		}
 	} else {
-		runnerNames, gcbBuildIDs, err = s.startRunnersForJob(ctx, event, jobOriginalRunnerLabel, jobResolvedRunnerLabel)
+		if resp := s.acquireQuota(ctx, event, jobID, jobResolvedRunnerLabels); resp != nil {
+			s.releaseDispatch(ctx, jobID)
+			return resp
+		}
+		if runnerName := s.takeWarmRunner(ctx, event, deliveryID, jobOriginalRunnerLabels, jobResolvedRunnerLabels); runnerName != "" {
+			s.recordDispatch(ctx, deliveryID, jobID, nil)
+			return s.runnersResponse(warmRunnerMsg, []string{runnerName}, nil)
+		}
+		builds, err = s.startRunnersForJob(ctx, event, jobOriginalRunnerLabels, jobResolvedRunnerLabels)
+		s.putDispatchRecord(ctx, event, deliveryID, jobOriginalRunnerLabels, jobResolvedRunnerLabels, builds)
 		if err != nil {
+			// The runners started before the failure are cancelled so that a
+			// retry does not start a second set. Runners that could not be
+			// cancelled may still pick up the job, so the dispatch is kept.
+			if s.cancelStartedBuilds(ctx, builds) {
+				s.releaseQuota(ctx, event, jobID)
+				s.releaseDispatch(ctx, jobID)
+			} else {
+				s.recordDispatch(ctx, deliveryID, jobID, builds)
+			}
 			return &apiResponse{http.StatusInternalServerError, err.Error(), err}
 		}
 	}
 
+	s.recordDispatch(ctx, deliveryID, jobID, builds)
+
+	if s.config.DryRun {
+		return s.dryRunResponse(builds)
+	}
+
+	runnerNames := make([]string, 0, len(builds))
+	gcbBuildIDs := make([]string, 0, len(builds))
+	for _, build := range builds {
+		runnerNames = append(runnerNames, build.RunnerName)
+		gcbBuildIDs = append(gcbBuildIDs, build.BuildID)
+	}
+
+	return s.runnersResponse(runnerStartedMsg, runnerNames, gcbBuildIDs)
+}
+
+// runnersResponse builds a JSON response listing the runners assigned to a
+// job.
+func (s *Server) runnersResponse(message string, runnerNames, gcbBuildIDs []string) *apiResponse {
 	responsePayload := &runnersResponse{
-		Message:     runnerStartedMsg,
+		Message:     message,
 		RunnerNames: runnerNames,
 		GCBBuildIDs: gcbBuildIDs,
 	}
@@ -355,13 +502,58 @@ func (s *Server) handleQueuedEvent(ctx context.Context, event *github.WorkflowJo
 	return &apiResponse{http.StatusOK, string(responseBytes), nil}
 }
 
-// resolveAndValidateRunnerLabel encapsulates the logic for resolving runner labels
-// and checking if they are provisionable based on the server's configuration.
-func (s *Server) resolveAndValidateRunnerLabel(ctx context.Context, incomingLabel string) (string, bool, error) {
+// duplicateDispatchResponse builds the response for a delivery or job that has
+// already been dispatched, returning the runners that were originally started.
+func (s *Server) duplicateDispatchResponse(ctx context.Context, record *dispatchRecord) *apiResponse {
+	logger := logging.FromContext(ctx)
+
+	if record.Status == dispatchStatusPending {
+		logger.InfoContext(ctx, "no action taken, dispatch already in progress for job")
+		return &apiResponse{http.StatusOK, "no action taken, dispatch already in progress for job", nil}
+	}
+
+	logger.InfoContext(ctx, "no action taken, runners already dispatched for job",
+		"original_delivery_id", record.DeliveryID,
+		"runner_names", record.RunnerNames,
+		"gcb_build_ids", record.GCBBuildIDs)
+
+	return s.runnersResponse(runnerDuplicateMsg, record.RunnerNames, record.GCBBuildIDs)
+}
+
+// resolveAndValidateRunnerLabels encapsulates the logic for resolving runner
+// labels and checking if they are provisionable based on the server's
+// configuration. The returned slice holds the resolved label for each incoming
+// label, in the same order. A job can be handled when at least one of its
+// labels resolves to a supported label; the remaining labels must be satisfied
+// by the capabilities of the selected worker pool.
+func (s *Server) resolveAndValidateRunnerLabels(ctx context.Context, incomingLabels []string) ([]string, bool, error) {
 	logger := logging.FromContext(ctx)
 
 	logger.DebugContext(ctx, "RunnerLabelAliases map content", "aliases", s.config.RunnerLabelAliases)
 
+	jobResolvedRunnerLabels := make([]string, 0, len(incomingLabels))
+	var canHandle bool
+	for _, incomingLabel := range incomingLabels {
+		jobResolvedRunnerLabel, err := s.resolveRunnerLabel(ctx, incomingLabel)
+		if err != nil {
+			return nil, false, err
+		}
+		jobResolvedRunnerLabels = append(jobResolvedRunnerLabels, jobResolvedRunnerLabel)
+
+		// Check if the jobResolvedRunnerLabel is in the combined allowlist.
+		if s.allowedLabels[jobResolvedRunnerLabel] {
+			canHandle = true
+		}
+	}
+
+	return jobResolvedRunnerLabels, canHandle, nil
+}
+
+// resolveRunnerLabel follows the configured runner label aliases for a single
+// label and returns the label it ultimately resolves to.
+func (s *Server) resolveRunnerLabel(ctx context.Context, incomingLabel string) (string, error) {
+	logger := logging.FromContext(ctx)
+
 	// Determine the lookup label for the worker pool after resolving aliases.
 	jobResolvedRunnerLabel := incomingLabel
 	visited := make(map[string]bool)
@@ -369,7 +561,7 @@ func (s *Server) resolveAndValidateRunnerLabel(ctx context.Context, incomingLabe
 		if visited[jobResolvedRunnerLabel] {
 			err := fmt.Errorf("detected alias cycle for label %q", incomingLabel)
 			logger.ErrorContext(ctx, err.Error(), "label", incomingLabel)
-			return "", false, err
+			return "", err
 		}
 		visited[jobResolvedRunnerLabel] = true
 
@@ -383,17 +575,26 @@ func (s *Server) resolveAndValidateRunnerLabel(ctx context.Context, incomingLabe
 		}
 	}
 
-	// Check if the jobResolvedRunnerLabel is in the combined allowlist.
-	canHandle := s.allowedLabels[jobResolvedRunnerLabel]
-
-	return jobResolvedRunnerLabel, canHandle, nil
+	return jobResolvedRunnerLabel, nil
 }
 
 // getRunnerKey creates a key for the runner in the format that the registry
 // expects. This key is used to lookup additional information about the runner,
-// such as the worker pool to use.
-func (s *Server) getRunnerKey(ctx context.Context, orgName, label string) string {
-	return fmt.Sprintf("%s:%s", orgName, label)
+// such as the worker pool to use. The scope is an org name, the default key
+// prefix, or an enterprise scope from enterpriseRegistryScope. Keys for GitHub
+// hosts other than the default one are prefixed with the host name.
+func (s *Server) getRunnerKey(ctx context.Context, scope, label string) string {
+	if s.host != nil {
+		return fmt.Sprintf("%s%s%s:%s", s.host.Name, hostRegistryScopeSeparator, scope, label)
+	}
+	return fmt.Sprintf("%s:%s", scope, label)
+}
+
+// enterpriseRegistryScope returns the registry scope of worker pools that serve
+// jobs from every org in an enterprise. GitHub org names cannot contain
+// underscores, so the scope cannot collide with an org's.
+func enterpriseRegistryScope(enterprise string) string {
+	return enterpriseRegistryScopePrefix + enterprise
 }
 
 // extractLoggedAttributes extracts common logging attributes from a GitHub WorkflowJobEvent.
@@ -465,170 +666,340 @@ func extractCompletedLogAttributes(event *github.WorkflowJobEvent) []any {
 	return completedAttributes
 }
 
-// compressAndBase64EncodeString compresses the input string using gzip
-// and then encodes the compressed data into a base64 string.
+// startGitHubRunner generates a JIT config and starts a GitHub runner with the backend of the pool.
 //
-// This is used to reduce the size of the JIT config for Cloud Build substitutions.
-// It returns the base64 encoded string or an error if compression or encoding fails.
-func compressAndBase64EncodeString(input string) (string, error) {
-	var compressedJIT bytes.Buffer
-	gzipWriter, err := gzip.NewWriterLevel(&compressedJIT, gzip.BestCompression)
-	if err != nil {
-		return "", fmt.Errorf("failed to create gzip writer: %w", err)
-	}
-	_, err = gzipWriter.Write([]byte(input))
-	if err != nil {
-		return "", fmt.Errorf("failed to write to gzip writer: %w", err)
+// It takes the GitHub WorkflowJobEvent, a unique runner ID, logger, image tag, runner labels, and pool.
+// It returns the started build on success, or an error if the JIT config generation or runner
+// start fails. In dry-run mode, neither is done and the build that would have been
+// created is returned.
+func (s *Server) startGitHubRunner(ctx context.Context, event *github.WorkflowJobEvent, runnerID string, logger *slog.Logger, imageName, imageTag string, jobOriginalRunnerLabels []string, pool *workerPool) (*runnerBuild, error) {
+	if s.config.DryRun {
+		return s.startDryRunRunner(ctx, runnerID, imageName, imageTag, pool), nil
 	}
-	err = gzipWriter.Close()
+	jitConfig, err := s.generateJITConfig(ctx, event, runnerID, jobOriginalRunnerLabels, pool)
 	if err != nil {
-		return "", fmt.Errorf("failed to close gzip writer: %w", err)
+		return nil, fmt.Errorf("failed to generate JIT config: %w", err)
 	}
-	return base64.StdEncoding.EncodeToString(compressedJIT.Bytes()), nil
+	return s.createRunnerBuild(ctx, runnerID, jitConfig, imageName, imageTag, pool)
 }
 
-// startGitHubRunner generates a JIT config and creates a Cloud Build job to start a GitHub runner.
-//
-// It takes the GitHub WorkflowJobEvent, a unique runner ID, logger, image tag, runner label, and pool.
-// It returns the build ID and project ID on success, or an error if the JIT config generation or Cloud Build
-// job creation fails.
-func (s *Server) startGitHubRunner(ctx context.Context, event *github.WorkflowJobEvent, runnerID string, logger *slog.Logger, imageName, imageTag, jobOriginalRunnerLabel string, pool *workerPool) (string, string, error) {
-	compressedJIT, err := s.generateAndCompressJITConfig(ctx, event, runnerID, jobOriginalRunnerLabel)
+// createRunnerBuild starts a runner with the given JIT config on a pool through
+// the backend of the pool's type. The outcome is recorded by the pool's
+// circuit breaker, and the build is tracked as in flight on the pool.
+func (s *Server) createRunnerBuild(ctx context.Context, runnerID, jitConfig, imageName, imageTag string, pool *workerPool) (*runnerBuild, error) {
+	req := s.runnerStartRequest(ctx, runnerID, jitConfig, imageName, imageTag, pool)
+
+	runner, err := s.runnerBackend(pool.poolType).StartRunner(ctx, req)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate and compress JIT config: %w", err)
+		if pool.name != "" && s.breaker.failure(pool.name, time.Now()) {
+			logging.FromContext(ctx).WarnContext(ctx, "worker pool circuit breaker opened",
+				"worker_pool", pool.name,
+				"cooldown", s.config.WorkerPoolBreakerCooldown.String())
+		}
+		return nil, fmt.Errorf("failed to create build: %w", err)
+	}
+	if pool.name != "" {
+		s.breaker.success(pool.name)
 	}
 
-	buildReq := s.buildCloudBuildRequest(ctx, compressedJIT, imageName, imageTag, pool)
+	build := newRunnerBuild(runner, pool)
+	s.trackBuild(ctx, pool, build)
+	return build, nil
+}
 
-	buildID, err := s.cbc.CreateBuild(ctx, buildReq)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create build: %w", err)
+// generateJITConfig generates the encoded JIT config for a runner.
+// The runner is registered with the job's repository, organization or the
+// enterprise depending on the registration scope configured for the org and the
+// pool's label.
+// The runner is registered with the full set of labels requested by the job so
+// that GitHub can assign the job to it.
+func (s *Server) generateJITConfig(ctx context.Context, event *github.WorkflowJobEvent, runnerID string, jobOriginalRunnerLabels []string, pool *workerPool) (string, error) {
+	var label string
+	if pool != nil {
+		label = pool.label
 	}
-	return buildID, buildReq.GetProjectId(), nil
-}
+	scope := s.runnerRegistrationScope(*event.Org.Login, label)
+	logger := logging.FromContext(ctx).With("registration_scope", scope)
+
+	var jitConfig *github.JITRunnerConfig
+	var err error
+	switch scope {
+	case RunnerRegistrationScopeOrg, RunnerRegistrationScopeEnterprise:
+		installationID := *event.Installation.ID
+		if scope == RunnerRegistrationScopeEnterprise {
+			installationID = s.config.GitHubEnterpriseInstallationID
+		}
 
-// generateAndCompressJITConfig handles the logic of generating and compressing the JIT config.
-func (s *Server) generateAndCompressJITConfig(ctx context.Context, event *github.WorkflowJobEvent, runnerID, jobOriginalRunnerLabel string) (string, error) {
-	logger := logging.FromContext(ctx)
-	jitConfig, err := s.ghc.GenerateRepoJITConfig(ctx, *event.Installation.ID, *event.Org.Login, *event.Repo.Name, runnerID, jobOriginalRunnerLabel)
+		var runnerGroupID int64
+		runnerGroupID, err = s.runnerGroupID(ctx, scope, installationID, *event.Org.Login, label)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to resolve runner group", "error", err)
+			return "", err
+		}
+		logger = logger.With("runner_group_id", runnerGroupID)
+
+		if scope == RunnerRegistrationScopeEnterprise {
+			jitConfig, err = s.ghc.GenerateEnterpriseJITConfig(ctx, installationID, s.config.GitHubEnterprise, runnerID, runnerGroupID, jobOriginalRunnerLabels)
+		} else {
+			jitConfig, err = s.ghc.GenerateOrgJITConfig(ctx, installationID, *event.Org.Login, runnerID, runnerGroupID, jobOriginalRunnerLabels)
+		}
+	default:
+		jitConfig, err = s.ghc.GenerateRepoJITConfig(ctx, *event.Installation.ID, *event.Org.Login, *event.Repo.Name, runnerID, jobOriginalRunnerLabels)
+	}
 	if err != nil {
 		logger.ErrorContext(ctx, "failed to generate JIT config", "error", err)
 		return "", fmt.Errorf("error generating jitconfig: %w", err)
 	}
-
-	// Sometimes JITConfig has exceeded the 4,000-character limit for
-	// substitutions. It has nested base64 encoded data, so it is very
-	// compressible.
-	compressedJIT, err := compressAndBase64EncodeString(*jitConfig.EncodedJITConfig)
-	if err != nil {
-		return "", fmt.Errorf("failed to compress JIT config: %w", err)
-	}
-	return compressedJIT, nil
+	return jitConfig.GetEncodedJITConfig(), nil
 }
 
-// selectWorkerPool selects a worker pool for the job. It returns a specific
-// *registry.WorkerPoolInfo if a pool is found in the registry, otherwise it
-// returns nil.
-func (s *Server) selectWorkerPool(ctx context.Context, orgName, jobResolvedRunnerLabel string) *workerPool {
+// selectWorkerPool selects a worker pool for the job. Every resolved label
+// that is supported by the dispatcher is used as a registry key, and only pools
+// whose capabilities satisfy all of the job's labels and that are not in
+// excluded are considered. It returns a specific *registry.WorkerPoolInfo if a
+// pool is found in the registry, otherwise it returns nil.
+func (s *Server) selectWorkerPool(ctx context.Context, orgName string, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string, excluded map[string]bool) *workerPool {
 	logger := logging.FromContext(ctx)
-	pools := s.getWorkerPools(ctx, orgName, jobResolvedRunnerLabel)
+
+	var pools []*workerPool
+	for _, label := range registryLabels(jobResolvedRunnerLabels, s.allowedLabels) {
+		for _, pool := range s.getWorkerPools(ctx, orgName, label) {
+			if excluded[pool.Name] {
+				logger.DebugContext(ctx, "skipping worker pool that failed for job",
+					"org_name", orgName,
+					"label", label,
+					"worker_pool", pool.Name)
+				continue
+			}
+			if !poolSatisfiesLabels(pool, label, jobOriginalRunnerLabels, jobResolvedRunnerLabels) {
+				logger.DebugContext(ctx, "worker pool does not satisfy requested labels",
+					"org_name", orgName,
+					"label", label,
+					"worker_pool", pool.Name,
+					"worker_pool_labels", pool.Labels)
+				continue
+			}
+			weight := pool.Weight
+			if weight <= 0 {
+				weight = registry.DefaultWorkerPoolWeight
+			}
+			pools = append(pools, &workerPool{
+				name:           pool.Name,
+				projectID:      pool.ProjectID,
+				location:       pool.Location,
+				serviceAccount: fmt.Sprintf("runner-sa@%s.iam.gserviceaccount.com", pool.ProjectID),
+				label:          label,
+				weight:         weight,
+				priority:       pool.Priority,
+				maxConcurrency: pool.MaxConcurrency,
+				poolType:       pool.PoolType,
+				remoteConfig:   pool.RemoteConfig,
+			})
+		}
+	}
 
 	if len(pools) > 0 {
-		// Use random selection to select a pool.
-		randomIndex := rand.Intn(len(pools)) //nolint:gosec // G404: Cryptographic randomness is not required for worker pool selection.
-		selectedPool := pools[randomIndex]
+		s.loadWorkerPools(ctx, pools)
+
+		// Pools whose circuit breaker is open are only used when no other pool
+		// can serve the job, so that the job is still attempted.
+		now := time.Now()
+		available := filterWorkerPools(pools, func(pool *workerPool) bool {
+			return !s.breaker.isOpen(pool.name, now)
+		})
+		if len(available) == 0 {
+			logger.WarnContext(ctx, "all worker pools have an open circuit breaker",
+				"org_name", orgName,
+				"labels", jobResolvedRunnerLabels,
+				"total_worker_pools_found", len(pools))
+			available = pools
+		}
+
+		// Cloud Build queues builds beyond a pool's capacity, so the job is
+		// still sent to the least-loaded pool rather than dropped.
+		withCapacity := filterWorkerPools(available, func(pool *workerPool) bool {
+			return pool.maxConcurrency <= 0 || pool.inFlight < pool.maxConcurrency
+		})
+		if len(withCapacity) == 0 {
+			logger.WarnContext(ctx, "all worker pools at max concurrency",
+				"org_name", orgName,
+				"labels", jobResolvedRunnerLabels,
+				"total_worker_pools_found", len(pools))
+			withCapacity = available
+		}
+		available = withCapacity
+
+		selectedPool, tierSize := chooseWorkerPool(available, s.rand)
 		logger.InfoContext(
 			ctx,
 			"found worker pool in registry",
 			"org_name", orgName,
-			"label", jobResolvedRunnerLabel,
-			"worker_pool", selectedPool.Name,
+			"label", selectedPool.label,
+			"worker_pool", selectedPool.name,
+			"worker_pool_weight", selectedPool.weight,
+			"worker_pool_priority", selectedPool.priority,
+			"worker_pool_builds_in_flight", selectedPool.inFlight,
+			"worker_pool_max_concurrency", selectedPool.maxConcurrency,
+			"worker_pools_in_priority_tier", tierSize,
 			"total_worker_pools_found", len(pools),
 		)
-		return &workerPool{
-			name:           selectedPool.Name,
-			projectID:      selectedPool.ProjectID,
-			location:       selectedPool.Location,
-			serviceAccount: fmt.Sprintf("runner-sa@%s.iam.gserviceaccount.com", selectedPool.ProjectID),
-		}
+		return selectedPool
 	}
 
 	logger.InfoContext(
 		ctx,
-		"no worker pools found in registry for label",
+		"no worker pools found in registry for labels",
 		"org_name", orgName,
-		"label", jobResolvedRunnerLabel,
+		"labels", jobResolvedRunnerLabels,
 	)
 	return nil
 }
 
-// buildCloudBuildRequest creates a cloud build request.
-func (s *Server) buildCloudBuildRequest(ctx context.Context, compressedJIT, imageName, imageTag string, pool *workerPool) *cloudbuildpb.CreateBuildRequest {
-	logger := logging.FromContext(ctx)
-	build := &cloudbuildpb.Build{
-		Timeout: durationpb.New(time.Duration(s.runnerExecutionTimeoutSeconds) * time.Second),
-		Steps: []*cloudbuildpb.BuildStep{
-			{
-				Id:   "run",
-				Name: "$_REPOSITORY_ID/$_IMAGE_NAME:$_IMAGE_TAG",
-				Env: []string{
-					"ENCODED_JIT_CONFIG=${_ENCODED_JIT_CONFIG}",
-					"IDLE_TIMEOUT_SECONDS=${_IDLE_TIMEOUT_SECONDS}",
-					"CREATE_BUILD_REQUEST_TIME_UTC=${_CREATE_BUILD_REQUEST_TIME_UTC}",
-				},
-			},
-		},
-		Options: &cloudbuildpb.BuildOptions{
-			Logging: cloudbuildpb.BuildOptions_CLOUD_LOGGING_ONLY,
-		},
-		Substitutions: map[string]string{
-			"_ENCODED_JIT_CONFIG":            compressedJIT,
-			"_IDLE_TIMEOUT_SECONDS":          strconv.Itoa(s.runnerIdleTimeoutSeconds),
-			"_REPOSITORY_ID":                 s.runnerRepositoryID,
-			"_IMAGE_NAME":                    imageName,
-			"_IMAGE_TAG":                     imageTag,
-			"_CREATE_BUILD_REQUEST_TIME_UTC": time.Now().UTC().Format(time.RFC3339),
-		},
-	}
-
-	// Check if this is an E2E test run and add appropriate tags.
-	if s.e2eTestRunID != "" {
-		build.Tags = []string{"e2e-test", fmt.Sprintf("e2e-run-id-%s", s.e2eTestRunID)}
-	}
-
-	var projectID, location, serviceAccount string
-
-	if pool != nil && pool.projectID != "" {
-		// Use configuration from the registry.
-		projectID = pool.projectID
-		// Use location from pool, but fall back to server default if it's missing
-		// for safety during transitions.
-		location = pool.location
-		if location == "" {
-			location = s.runnerLocation
-			logger.WarnContext(ctx, "worker pool from registry is missing location, falling back to default",
-				"pool_name", pool.name,
-				"default_location", location)
-		}
-
-		serviceAccount = pool.serviceAccount
-		if pool.name != "" {
-			build.Options.Pool = &cloudbuildpb.BuildOptions_PoolOption{Name: pool.name}
-		}
-	}
-
-	// Ensure the service account is in the full resource name format.
-	if serviceAccount != "" && !strings.HasPrefix(serviceAccount, "projects/") {
-		serviceAccount = fmt.Sprintf("projects/%s/serviceAccounts/%s", projectID, serviceAccount)
-	}
-
-	build.ServiceAccount = serviceAccount
-
-	return &cloudbuildpb.CreateBuildRequest{
-		Parent:    fmt.Sprintf("projects/%s/locations/%s", projectID, location),
-		ProjectId: projectID,
-		Build:     build,
+// chooseWorkerPool selects one of the pools with the highest priority. Among
+// those, only the least-loaded pools are considered, where a pool's load is the
+// number of builds in flight on it relative to its weight. One of them is
+// selected with a probability proportional to its weight. It returns the
+// selected pool and the number of pools with the highest priority.
+func chooseWorkerPool(pools []*workerPool, rng Rand) (*workerPool, int) {
+	priority := pools[0].priority
+	for _, pool := range pools[1:] {
+		priority = max(priority, pool.priority)
+	}
+
+	var tier []*workerPool
+	for _, pool := range pools {
+		if pool.priority == priority {
+			tier = append(tier, pool)
+		}
+	}
+
+	var leastLoaded []*workerPool
+	totalWeight := 0
+	for _, pool := range tier {
+		if len(leastLoaded) > 0 {
+			switch c := compareLoad(pool, leastLoaded[0]); {
+			case c > 0:
+				continue
+			case c < 0:
+				leastLoaded = leastLoaded[:0]
+				totalWeight = 0
+			}
+		}
+		leastLoaded = append(leastLoaded, pool)
+		totalWeight += pool.weight
+	}
+
+	n := rng.Intn(totalWeight)
+	for _, pool := range leastLoaded {
+		if n < pool.weight {
+			return pool, len(tier)
+		}
+		n -= pool.weight
+	}
+	return leastLoaded[len(leastLoaded)-1], len(tier)
+}
+
+// compareLoad compares the builds in flight per unit of weight of two pools.
+func compareLoad(a, b *workerPool) int {
+	return a.inFlight*b.weight - b.inFlight*a.weight
+}
+
+// filterWorkerPools returns the pools for which keep returns true.
+func filterWorkerPools(pools []*workerPool, keep func(*workerPool) bool) []*workerPool {
+	filtered := make([]*workerPool, 0, len(pools))
+	for _, pool := range pools {
+		if keep(pool) {
+			filtered = append(filtered, pool)
+		}
+	}
+	return filtered
+}
+
+// registryLabels returns the unique resolved labels, in order, that are
+// supported by the dispatcher and can therefore be used as registry keys.
+func registryLabels(jobResolvedRunnerLabels []string, allowedLabels map[string]bool) []string {
+	labels := make([]string, 0, len(jobResolvedRunnerLabels))
+	for _, label := range jobResolvedRunnerLabels {
+		if allowedLabels[label] && !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// poolSatisfiesLabels reports whether a pool found under the given registry
+// label can serve every label requested by a job. A requested label is
+// satisfied when either its original or resolved form is the registry label
+// or one of the pool's advertised capabilities.
+//
+// Entries written before pools advertised their capabilities have no labels.
+// They are matched on the registry label alone until discovery rewrites them.
+func poolSatisfiesLabels(pool registry.WorkerPoolInfo, registryLabel string, jobOriginalRunnerLabels, jobResolvedRunnerLabels []string) bool {
+	if len(pool.Labels) == 0 {
+		return slices.Contains(jobOriginalRunnerLabels, registryLabel) || slices.Contains(jobResolvedRunnerLabels, registryLabel)
+	}
+	for i, original := range jobOriginalRunnerLabels {
+		resolved := jobResolvedRunnerLabels[i]
+		if original == registryLabel || resolved == registryLabel {
+			continue
+		}
+		if slices.Contains(pool.Labels, original) || slices.Contains(pool.Labels, resolved) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// runnerLabelPolicy returns the provisioning policy for pools of the given
+// resolved label, falling back to the global settings when the label has no
+// policy of its own.
+func (s *Server) runnerLabelPolicy(label string) *RunnerLabelPolicy {
+	if policy, ok := s.config.RunnerLabelPolicies[label]; ok {
+		return policy
+	}
+	return &RunnerLabelPolicy{
+		ExtraRunnerCount:              s.extraRunnerCount,
+		RunnerIdleTimeoutSeconds:      s.runnerIdleTimeoutSeconds,
+		RunnerExecutionTimeoutSeconds: s.runnerExecutionTimeoutSeconds,
+	}
+}
+
+// runnerRegistrationScope returns whether runners for jobs in an org that are
+// dispatched to pools of a label are registered with the job's repository or
+// organization.
+func (s *Server) runnerRegistrationScope(orgName, label string) string {
+	scope, ok := lookupOrgLabel(s.config.RunnerRegistrationScopes, orgName, label)
+	if !ok {
+		scope = s.config.RunnerRegistrationScope
+	}
+	if scope == "" {
+		return RunnerRegistrationScopeRepo
+	}
+	// Only the default GitHub host has an enterprise configured, so runners on
+	// other hosts are registered with the org instead.
+	if scope == RunnerRegistrationScopeEnterprise && s.config.GitHubEnterprise == "" {
+		return RunnerRegistrationScopeOrg
+	}
+	return scope
+}
+
+// lookupOrgLabel returns the value of a setting keyed by "org:label". An org
+// and label match takes precedence over an org match, which takes precedence
+// over a label match.
+func lookupOrgLabel[T any](settings map[string]T, orgName, label string) (T, bool) {
+	keys := []string{
+		orgName + ":" + label,
+		orgName + ":" + orgLabelWildcard,
+		orgLabelWildcard + ":" + label,
+	}
+	for _, key := range keys {
+		if v, ok := settings[key]; ok {
+			return v, true
+		}
 	}
+	var zero T
+	return zero, false
 }
 
 // getWorkerPools determines the appropriate worker pools for a given runner label.
@@ -24,10 +24,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
+	"github.com/go-redis/redis/v8"
 	"github.com/go-redis/redismock/v8"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/v69/github"
@@ -52,6 +55,8 @@ const (
 	// Webhook event details.
 	orgLogin = "google"
 	repoName = "webhook"
+
+	testEnterpriseInstallationID = 456
 )
 
 func TestHandleWebhook(t *testing.T) {
@@ -87,13 +92,25 @@ func TestHandleWebhook(t *testing.T) {
 		expRespBody          string // This is now only for plain text responses.
 		expectBuildCount     int
 		expGCBBuildIDs       []string
+		expJITLabels         []string
 		exp404               bool
 
 		runnerExecutionTimeoutSeconds  int
 		runnerIdleTimeoutSeconds       int
 		runnerLabelAliases             map[string]string
+		runnerLabelPolicies            map[string]*RunnerLabelPolicy
+		runnerRegistrationScope        string
+		runnerRegistrationScopes       map[string]string
+		runnerGroups                   map[string]string
+		githubEnterprise               string
+		expOrgJIT                      bool
+		expEnterpriseJIT               bool
+		expRunnerGroupID               int64
+		expBuildTimeoutSeconds         int
+		expIdleTimeoutSeconds          int
 		supportedRunnerLabels          []string
 		runnerRegistryDefaultKeyPrefix string
+		registryMissingKeys            []string
 		registryWorkerPools            map[string][]registry.WorkerPoolInfo
 	}{
 		{
@@ -183,6 +200,249 @@ func TestHandleWebhook(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:                 "Workflow Job Queued - Runner Label Policy",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expectBuildCount:     2,
+			expGCBBuildIDs:       []string{testGCBBuildID, testGCBBuildID},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			runnerLabelPolicies: map[string]*RunnerLabelPolicy{
+				SelfHostedRunnerLabel: {
+					ExtraRunnerCount:              1,
+					RunnerIdleTimeoutSeconds:      900,
+					RunnerExecutionTimeoutSeconds: 10800,
+				},
+			},
+			expBuildTimeoutSeconds: 10800,
+			expIdleTimeoutSeconds:  900,
+			supportedRunnerLabels:  []string{SelfHostedRunnerLabel},
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Org Registration Scope",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+			expJITLabels:         []string{SelfHostedRunnerLabel},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			runnerRegistrationScope:       RunnerRegistrationScopeOrg,
+			expOrgJIT:                     true,
+			supportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Org Registration Scope For Org And Label",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+			expJITLabels:         []string{SelfHostedRunnerLabel},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			runnerRegistrationScope:       RunnerRegistrationScopeRepo,
+			runnerRegistrationScopes: map[string]string{
+				"google:self-hosted": RunnerRegistrationScopeOrg,
+				"*:self-hosted":      RunnerRegistrationScopeRepo,
+			},
+			expOrgJIT:             true,
+			supportedRunnerLabels: []string{SelfHostedRunnerLabel},
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Repo Registration Scope For Label",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+			expJITLabels:         []string{SelfHostedRunnerLabel},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			runnerRegistrationScope:       RunnerRegistrationScopeOrg,
+			runnerRegistrationScopes: map[string]string{
+				"other-org:*":   RunnerRegistrationScopeOrg,
+				"*:self-hosted": RunnerRegistrationScopeRepo,
+			},
+			supportedRunnerLabels: []string{SelfHostedRunnerLabel},
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Runner Group By ID",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			runnerRegistrationScope:       RunnerRegistrationScopeOrg,
+			runnerGroups: map[string]string{
+				"google:self-hosted": "5",
+				"google:*":           "trusted-runners",
+			},
+			expOrgJIT:             true,
+			expRunnerGroupID:      5,
+			supportedRunnerLabels: []string{SelfHostedRunnerLabel},
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Runner Group By Name",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			runnerRegistrationScope:       RunnerRegistrationScopeOrg,
+			runnerGroups: map[string]string{
+				"google:*": "trusted-runners",
+			},
+			expOrgJIT:             true,
+			expRunnerGroupID:      42,
+			supportedRunnerLabels: []string{SelfHostedRunnerLabel},
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Enterprise Registration Scope",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+			expJITLabels:         []string{SelfHostedRunnerLabel},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			githubEnterprise:              "abcxyz",
+			runnerRegistrationScope:       RunnerRegistrationScopeEnterprise,
+			runnerGroups: map[string]string{
+				"*:self-hosted": "enterprise-runners",
+			},
+			expEnterpriseJIT:      true,
+			expRunnerGroupID:      43,
+			supportedRunnerLabels: []string{SelfHostedRunnerLabel},
+			registryMissingKeys:   []string{"google:self-hosted"},
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"enterprise_abcxyz:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Enterprise Pool With Repo Registration Scope",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+			expJITLabels:         []string{SelfHostedRunnerLabel},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			githubEnterprise:              "abcxyz",
+			runnerRegistrationScope:       RunnerRegistrationScopeRepo,
+			supportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+			registryMissingKeys:           []string{"google:self-hosted"},
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"enterprise_abcxyz:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
 		{
 			name:                 "Workflow Job Queued - Multiple Builds Spawned",
 			payloadType:          payloadType,
@@ -212,10 +472,70 @@ func TestHandleWebhook(t *testing.T) {
 			},
 		},
 		{
-			name:                 "Workflow Job Queued - Multiple Label Fails",
+			name:                 "Workflow Job Queued - Multiple Labels Matched By Pool Capabilities",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel, "gpu"},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			startedAt:            nil,
+			completedAt:          nil,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expRespBody:          "",
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+			expJITLabels:         []string{SelfHostedRunnerLabel, "gpu"},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			supportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1", Labels: []string{"gpu", SelfHostedRunnerLabel}},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Multiple Labels Matched By Pool Without Labels",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{SelfHostedRunnerLabel, "gpu"},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			startedAt:            nil,
+			completedAt:          nil,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expRespBody:          "",
+			expectBuildCount:     1,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+			expJITLabels:         []string{SelfHostedRunnerLabel, "gpu"},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			supportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+
+			// Entries written before discovery recorded pool capabilities
+			// are matched on their registry label alone.
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1"},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - Multiple Labels Missing Pool Capability",
 			payloadType:          payloadType,
 			action:               queuedAction,
-			runnerLabels:         []string{"self-hosted", "custom-label"},
+			runnerLabels:         []string{SelfHostedRunnerLabel, "gpu"},
 			payloadWebhookSecret: serverGitHubWebhookSecret,
 			contentType:          contentType,
 			createdAt:            &queuedTime,
@@ -225,7 +545,37 @@ func TestHandleWebhook(t *testing.T) {
 			jobID:                &jobID,
 			jobName:              &jobName,
 			expStatusCode:        200,
-			expRespBody:          "no action taken, only accept single label jobs, got: [self-hosted custom-label]",
+			expRespBody:          "",
+			expectBuildCount:     1,
+			exp404:               true,
+			expGCBBuildIDs:       []string{testGCBBuildID},
+			expJITLabels:         []string{SelfHostedRunnerLabel, "gpu"},
+
+			runnerExecutionTimeoutSeconds: 7200,
+			runnerIdleTimeoutSeconds:      300,
+			supportedRunnerLabels:         []string{SelfHostedRunnerLabel},
+
+			registryWorkerPools: map[string][]registry.WorkerPoolInfo{
+				"google:self-hosted": {
+					{Name: "projects/12345-test-project-1/locations/us-west1/workerPools/wp1", ProjectID: "test-project-1", ProjectNumber: "12345-test-project-1", Labels: []string{SelfHostedRunnerLabel}},
+				},
+			},
+		},
+		{
+			name:                 "Workflow Job Queued - No Labels",
+			payloadType:          payloadType,
+			action:               queuedAction,
+			runnerLabels:         []string{},
+			payloadWebhookSecret: serverGitHubWebhookSecret,
+			contentType:          contentType,
+			createdAt:            &queuedTime,
+			startedAt:            nil,
+			completedAt:          nil,
+			runID:                &runID,
+			jobID:                &jobID,
+			jobName:              &jobName,
+			expStatusCode:        200,
+			expRespBody:          "no action taken, job did not request any labels",
 			expectBuildCount:     0,
 		},
 		{
@@ -417,6 +767,9 @@ func TestHandleWebhook(t *testing.T) {
 			if buildTimeoutForTest == 0 {
 				buildTimeoutForTest = 3600
 			}
+			if tc.expBuildTimeoutSeconds != 0 {
+				buildTimeoutForTest = tc.expBuildTimeoutSeconds
+			}
 			expectedBuildTimeout := time.Duration(buildTimeoutForTest) * time.Second
 
 			installationID := int64(123)
@@ -464,6 +817,9 @@ func TestHandleWebhook(t *testing.T) {
 
 			// Mock Redis client for registry operations
 			db, mockRedis := redismock.NewClientMock()
+			for _, key := range tc.registryMissingKeys {
+				mockRedis.ExpectGet(key).RedisNil()
+			}
 			if tc.registryWorkerPools != nil {
 				for key, pools := range tc.registryWorkerPools {
 					poolsJSON, err := json.Marshal(pools)
@@ -474,14 +830,39 @@ func TestHandleWebhook(t *testing.T) {
 				}
 			}
 
+			var gotJITLabels []string
+			var gotRunnerGroupID int64
 			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
 			mockGitHubClient := &gh.MockClient{
-				GenerateRepoJITConfigF: func(ctx context.Context, installationID int64, org, repo, runnerName, runnerLabel string) (*github.JITRunnerConfig, error) {
+				GenerateRepoJITConfigF: func(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error) {
+					gotJITLabels = runnerLabels
 					return jit, nil
 				},
-				GenerateOrgJITConfigF: func(ctx context.Context, installationID int64, org, runnerName, runnerLabel string) (*github.JITRunnerConfig, error) {
+				GenerateOrgJITConfigF: func(ctx context.Context, installationID int64, org, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
+					gotJITLabels = runnerLabels
+					gotRunnerGroupID = runnerGroupID
 					return jit, nil
 				},
+				GenerateEnterpriseJITConfigF: func(ctx context.Context, installationID int64, enterprise, runnerName string, runnerGroupID int64, runnerLabels []string) (*github.JITRunnerConfig, error) {
+					if installationID != testEnterpriseInstallationID {
+						return nil, fmt.Errorf("unexpected installation %d for enterprise %s", installationID, enterprise)
+					}
+					gotJITLabels = runnerLabels
+					gotRunnerGroupID = runnerGroupID
+					return jit, nil
+				},
+				EnterpriseRunnerGroupIDByNameF: func(ctx context.Context, installationID int64, enterprise, name string) (int64, error) {
+					if enterprise == "abcxyz" && name == "enterprise-runners" {
+						return 43, nil
+					}
+					return 0, fmt.Errorf("runner group %q not found", name)
+				},
+				RunnerGroupIDByNameF: func(ctx context.Context, installationID int64, org, name string) (int64, error) {
+					if name == "trusted-runners" {
+						return 42, nil
+					}
+					return 0, fmt.Errorf("runner group %q not found", name)
+				},
 			}
 
 			cfg := &Config{
@@ -500,6 +881,12 @@ func TestHandleWebhook(t *testing.T) {
 				Environment:                    testEnv,
 				GitHubAPIBaseURL:               "http://github-api-base-url",
 				RunnerLabelAliases:             tc.runnerLabelAliases,
+				RunnerLabelPolicies:            tc.runnerLabelPolicies,
+				RunnerRegistrationScope:        tc.runnerRegistrationScope,
+				RunnerRegistrationScopes:       tc.runnerRegistrationScopes,
+				RunnerGroups:                   tc.runnerGroups,
+				GitHubEnterprise:               tc.githubEnterprise,
+				GitHubEnterpriseInstallationID: testEnterpriseInstallationID,
 				SupportedRunnerLabels:          tc.supportedRunnerLabels,
 				RunnerRegistryDefaultKeyPrefix: tc.runnerRegistryDefaultKeyPrefix,
 				BackoffInitialDelay:            1 * time.Second,
@@ -534,7 +921,7 @@ func TestHandleWebhook(t *testing.T) {
 			}
 
 			// The test requires directly setting these, as NewServer sets up its own.
-			srv.webhookSecret = []byte(tc.payloadWebhookSecret)
+			srv.webhookSecrets.versions = []*webhookSecretVersion{{version: cfg.GitHubWebhookKeyName, value: []byte(tc.payloadWebhookSecret)}}
 			srv.handleWebhook().ServeHTTP(resp, req)
 
 			if got, want := resp.Code, tc.expStatusCode; got != want {
@@ -578,6 +965,11 @@ func TestHandleWebhook(t *testing.T) {
 					if got, want := buildReq.GetBuild().GetTimeout().AsDuration(), expectedBuildTimeout; got != want {
 						t.Errorf("expected build timeout %v to be %v", got, want)
 					}
+					if tc.expIdleTimeoutSeconds != 0 {
+						if got, want := buildReq.GetBuild().GetSubstitutions()["_IDLE_TIMEOUT_SECONDS"], strconv.Itoa(tc.expIdleTimeoutSeconds); got != want {
+							t.Errorf("expected idle timeout %q to be %q", got, want)
+						}
+					}
 				}
 			} else {
 				t.Errorf("expected %d build(s) to be created, but %d build(s) were created with requests: %v",
@@ -586,9 +978,36 @@ func TestHandleWebhook(t *testing.T) {
 					mockCloudBuildClient.CreateBuildReqs,
 				)
 			}
-			if got, want := mockGitHubClient.GenerateRepoJITConfigCalls, tc.expectBuildCount; got != want {
+			expRepoJITCalls, expOrgJITCalls, expEnterpriseJITCalls := tc.expectBuildCount, 0, 0
+			if tc.expOrgJIT {
+				expRepoJITCalls, expOrgJITCalls = 0, tc.expectBuildCount
+			}
+			if tc.expEnterpriseJIT {
+				expRepoJITCalls, expEnterpriseJITCalls = 0, tc.expectBuildCount
+			}
+			if got, want := mockGitHubClient.GenerateRepoJITConfigCalls, expRepoJITCalls; got != want {
 				t.Errorf("expected %d calls to GenerateRepoJITConfig, but got %d", want, got)
 			}
+			if got, want := mockGitHubClient.GenerateOrgJITConfigCalls, expOrgJITCalls; got != want {
+				t.Errorf("expected %d calls to GenerateOrgJITConfig, but got %d", want, got)
+			}
+			if got, want := mockGitHubClient.GenerateEnterpriseJITConfigCalls, expEnterpriseJITCalls; got != want {
+				t.Errorf("expected %d calls to GenerateEnterpriseJITConfig, but got %d", want, got)
+			}
+			if tc.expOrgJIT || tc.expEnterpriseJIT {
+				expRunnerGroupID := tc.expRunnerGroupID
+				if expRunnerGroupID == 0 {
+					expRunnerGroupID = gh.DefaultRunnerGroupID
+				}
+				if got, want := gotRunnerGroupID, expRunnerGroupID; got != want {
+					t.Errorf("expected runner group ID %d to be %d", got, want)
+				}
+			}
+			if tc.expJITLabels != nil {
+				if diff := cmp.Diff(tc.expJITLabels, gotJITLabels); diff != "" {
+					t.Errorf("JIT config labels mismatch (-want +got):\n%s", diff)
+				}
+			}
 			if err := mockRedis.ExpectationsWereMet(); err != nil {
 				t.Errorf("redis expectations not met: %v", err)
 			}
@@ -596,6 +1015,289 @@ func TestHandleWebhook(t *testing.T) {
 	}
 }
 
+// fixedRand is a Rand that always returns the same number, capped to n-1.
+type fixedRand int
+
+func (r fixedRand) Intn(n int) int {
+	return min(int(r), n-1)
+}
+
+// newTestFileReader returns a file reader that reads the given files, keyed by
+// path.
+func newTestFileReader(files map[string]string) *MockFileReader {
+	return &MockFileReader{
+		ReadFileFunc: func(filename string) ([]byte, error) {
+			v, ok := files[filename]
+			if !ok {
+				return nil, fmt.Errorf("unexpected file %s", filename)
+			}
+			return []byte(v), nil
+		},
+	}
+}
+
+// newTestServer returns a server for cfg and rc. The webhook secret is read from
+// test-path/test-key unless cfg names its own, and any client wco does not
+// override is a mock: builds get testGCBBuildID and repository runners get a
+// JIT config.
+func newTestServer(tb testing.TB, cfg *Config, rc *redis.Client, wco *WebhookClientOptions) *Server {
+	tb.Helper()
+
+	ctx := logging.WithLogger(tb.Context(), logging.TestLogger(tb))
+
+	if cfg.GitHubWebhookKeyMountPath == "" {
+		cfg.GitHubWebhookKeyMountPath = "test-path"
+		cfg.GitHubWebhookKeyName = "test-key"
+	}
+	if wco == nil {
+		wco = &WebhookClientOptions{}
+	}
+	if wco.CloudBuildClientOverride == nil {
+		wco.CloudBuildClientOverride = &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+	}
+	if wco.GitHubClientOverride == nil {
+		encodedJitConfig := "Hello"
+		wco.GitHubClientOverride = &gh.MockClient{
+			GenerateRepoJITConfigF: func(ctx context.Context, installationID int64, org, repo, runnerName string, runnerLabels []string) (*github.JITRunnerConfig, error) {
+				return &github.JITRunnerConfig{EncodedJITConfig: &encodedJitConfig}, nil
+			},
+		}
+	}
+	if wco.OSFileReaderOverride == nil {
+		wco.OSFileReaderOverride = &MockFileReader{
+			ReadFileMock: &ReadFileResErr{Res: []byte(serverGitHubWebhookSecret)},
+		}
+	}
+	if wco.KeyManagementClientOverride == nil {
+		wco.KeyManagementClientOverride = &MockKMSClient{}
+	}
+
+	srv, err := NewServer(ctx, nil, cfg, rc, wco)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv
+}
+
+func TestChooseWorkerPool(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		pools       []*workerPool
+		rand        fixedRand
+		expPool     string
+		expTierSize int
+	}{
+		{
+			name: "uniform_weights",
+			pools: []*workerPool{
+				{name: "wp1", weight: 1},
+				{name: "wp2", weight: 1},
+				{name: "wp3", weight: 1},
+			},
+			rand:        1,
+			expPool:     "wp2",
+			expTierSize: 3,
+		},
+		{
+			name: "weighted_first",
+			pools: []*workerPool{
+				{name: "wp1", weight: 3},
+				{name: "wp2", weight: 1},
+			},
+			rand:        2,
+			expPool:     "wp1",
+			expTierSize: 2,
+		},
+		{
+			name: "weighted_second",
+			pools: []*workerPool{
+				{name: "wp1", weight: 3},
+				{name: "wp2", weight: 1},
+			},
+			rand:        3,
+			expPool:     "wp2",
+			expTierSize: 2,
+		},
+		{
+			name: "highest_priority_tier_only",
+			pools: []*workerPool{
+				{name: "expensive", weight: 10, priority: 0},
+				{name: "cheap-1", weight: 1, priority: 5},
+				{name: "cheap-2", weight: 1, priority: 5},
+			},
+			rand:        9,
+			expPool:     "cheap-2",
+			expTierSize: 2,
+		},
+		{
+			name: "least_loaded",
+			pools: []*workerPool{
+				{name: "wp1", weight: 1, inFlight: 4},
+				{name: "wp2", weight: 1, inFlight: 1},
+				{name: "wp3", weight: 1, inFlight: 2},
+			},
+			rand:        0,
+			expPool:     "wp2",
+			expTierSize: 3,
+		},
+		{
+			name: "least_loaded_relative_to_weight",
+			pools: []*workerPool{
+				{name: "wp1", weight: 4, inFlight: 4},
+				{name: "wp2", weight: 1, inFlight: 2},
+			},
+			rand:        0,
+			expPool:     "wp1",
+			expTierSize: 2,
+		},
+		{
+			name: "least_loaded_ties_weighted",
+			pools: []*workerPool{
+				{name: "wp1", weight: 2, inFlight: 2},
+				{name: "wp2", weight: 1, inFlight: 1},
+				{name: "wp3", weight: 1, inFlight: 5},
+			},
+			rand:        2,
+			expPool:     "wp2",
+			expTierSize: 3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, tierSize := chooseWorkerPool(tc.pools, tc.rand)
+			if got, want := got.name, tc.expPool; got != want {
+				t.Errorf("expected pool %q to be %q", got, want)
+			}
+			if got, want := tierSize, tc.expTierSize; got != want {
+				t.Errorf("expected tier size %d to be %d", got, want)
+			}
+		})
+	}
+}
+
+func TestSelectWorkerPool_Priority(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	pools, err := json.Marshal([]registry.WorkerPoolInfo{
+		{Name: "projects/1/locations/us-east1/workerPools/expensive", ProjectID: "expensive", Weight: 10},
+		{Name: "projects/2/locations/us-west1/workerPools/cheap-1", ProjectID: "cheap-1", Priority: 1},
+		{Name: "projects/3/locations/us-west1/workerPools/cheap-2", ProjectID: "cheap-2", Priority: 1, Weight: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, mockRedis := redismock.NewClientMock()
+	mockRedis.ExpectGet("google:self-hosted").SetVal(string(pools))
+
+	cfg := &Config{
+		SupportedRunnerLabels: []string{SelfHostedRunnerLabel},
+	}
+	wco := &WebhookClientOptions{
+		CloudBuildClientOverride: &cloudbuild.MockClient{},
+		GitHubClientOverride:     &gh.MockClient{},
+		RandOverride:             fixedRand(1),
+	}
+	srv := newTestServer(t, cfg, db, wco)
+
+	labels := []string{SelfHostedRunnerLabel}
+	pool := srv.selectWorkerPool(ctx, orgLogin, labels, labels, nil)
+	if pool == nil {
+		t.Fatal("expected a worker pool to be selected")
+	}
+	if got, want := pool.projectID, "cheap-2"; got != want {
+		t.Errorf("expected selected pool %q to be %q", got, want)
+	}
+	if err := mockRedis.ExpectationsWereMet(); err != nil {
+		t.Errorf("redis expectations not met: %v", err)
+	}
+}
+
+func TestCreateRunnerBuild_PoolType(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name            string
+		pool            *workerPool
+		expConsumer     bool
+		expRemoteConfig string
+	}{
+		{
+			name: "private_pool",
+			pool: &workerPool{
+				name:      "projects/1/locations/us-west1/workerPools/private",
+				projectID: "private-project",
+				location:  "us-west1",
+				poolType:  "private",
+			},
+		},
+		{
+			name: "trusted_pool",
+			pool: &workerPool{
+				name:         "projects/2/locations/us-west1/workerPools/trusted",
+				projectID:    "trusted-project",
+				location:     "us-west1",
+				poolType:     registry.PoolTypeTrusted,
+				remoteConfig: "https://example.com/trusted-config.yaml",
+			},
+			expConsumer:     true,
+			expRemoteConfig: "https://example.com/trusted-config.yaml",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+			srv := newTestInFlightServer(t, nil, nil, mockCloudBuildClient)
+
+			build, err := srv.createRunnerBuild(ctx, "runner-1", "jit", "image", "tag", tc.pool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := build.BuildID, testGCBBuildID; got != want {
+				t.Errorf("expected build id %q to be %q", got, want)
+			}
+			if got, want := build.ProjectID, tc.pool.projectID; got != want {
+				t.Errorf("expected project id %q to be %q", got, want)
+			}
+
+			var gotBuild *cloudbuildpb.Build
+			if tc.expConsumer {
+				if got, want := len(mockCloudBuildClient.CreateBuildConsumerReqs), 1; got != want {
+					t.Fatalf("expected %d consumer builds, got %d", want, got)
+				}
+				req := mockCloudBuildClient.CreateBuildConsumerReqs[0]
+				if got, want := req.GetParent(), "projects/trusted-project/locations/us-west1"; got != want {
+					t.Errorf("expected parent %q to be %q", got, want)
+				}
+				gotBuild = req.GetBuild()
+			} else {
+				if got, want := len(mockCloudBuildClient.CreateBuildReqs), 1; got != want {
+					t.Fatalf("expected %d builds, got %d", want, got)
+				}
+				gotBuild = mockCloudBuildClient.CreateBuildReqs[0].GetBuild()
+			}
+			if got, want := gotBuild.GetRemoteConfig(), tc.expRemoteConfig; got != want {
+				t.Errorf("expected remote config %q to be %q", got, want)
+			}
+			if got, want := gotBuild.GetOptions().GetPool().GetName(), tc.pool.name; got != want {
+				t.Errorf("expected pool %q to be %q", got, want)
+			}
+		})
+	}
+}
+
 // createSignature creates a HMAC 256 signature for the test request payload.
 func createSignature(key, payload []byte) string {
 	mac := hmac.New(sha256.New, key)
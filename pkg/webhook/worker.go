@@ -0,0 +1,231 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/google/uuid"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/queue"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// dispatchQueuePath is appended to a server's key prefix to form the Redis
+	// key prefix for its dispatch queue.
+	dispatchQueuePath = "/queue"
+
+	// maxDispatchRetryDelay caps the backoff between dispatch attempts.
+	maxDispatchRetryDelay = 5 * time.Minute
+
+	// maxDeferredJobAge is how long a job deferred by a concurrency quota is
+	// kept on the queue. GitHub cancels jobs that have been queued for longer.
+	maxDeferredJobAge = 24 * time.Hour
+
+	jobQueuedMsg = "workflow job queued for dispatch"
+)
+
+// enqueueQueuedEvent persists a validated queued event so that it can be
+// dispatched by a worker outside of the webhook request.
+func (s *Server) enqueueQueuedEvent(ctx context.Context, event *github.WorkflowJobEvent, deliveryID string) *apiResponse {
+	logger := logging.FromContext(ctx)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to marshal workflow job event", "error", err)
+		return &apiResponse{http.StatusInternalServerError, "failed to queue workflow job", err}
+	}
+
+	msg := &queue.Message{
+		ID:         uuid.New().String(),
+		DeliveryID: deliveryID,
+		Payload:    payload,
+		EnqueuedAt: time.Now().UTC(),
+	}
+	if err := s.queue.Enqueue(ctx, msg); err != nil {
+		logger.ErrorContext(ctx, "failed to enqueue workflow job", "error", err)
+		return &apiResponse{http.StatusInternalServerError, "failed to queue workflow job", err}
+	}
+
+	logger.InfoContext(ctx, jobQueuedMsg, "queue_message_id", msg.ID)
+	return &apiResponse{http.StatusAccepted, jobQueuedMsg, nil}
+}
+
+// StartDispatchWorkers starts the configured number of workers that dispatch
+// runners for queued events. Workers run until the context is cancelled. It is
+// a no-op when the dispatch queue is disabled.
+func (s *Server) StartDispatchWorkers(ctx context.Context) {
+	if s.queue == nil {
+		return
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "starting dispatch workers",
+		"workers", s.config.DispatchQueueWorkers)
+	for i := range s.config.DispatchQueueWorkers {
+		go s.runDispatchWorker(ctx, i)
+	}
+
+	// Every GitHub host and app has its own queue.
+	for _, hs := range s.hosts {
+		hs.StartDispatchWorkers(hs.serverContext(ctx))
+	}
+	for _, as := range s.apps {
+		as.StartDispatchWorkers(as.serverContext(ctx))
+	}
+}
+
+// runDispatchWorker processes messages from the dispatch queue until the
+// context is cancelled, waiting for the poll interval whenever the queue is
+// empty.
+func (s *Server) runDispatchWorker(ctx context.Context, id int) {
+	logger := logging.FromContext(ctx).With("dispatch_worker", id)
+	ctx = logging.WithLogger(ctx, logger)
+
+	for {
+		processed, err := s.dispatchNext(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to process dispatch queue", "error", err)
+		}
+		if processed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.InfoContext(ctx, "stopping dispatch worker")
+			return
+		case <-time.After(s.config.DispatchQueuePollInterval):
+		}
+	}
+}
+
+// dispatchNext claims a single message from the dispatch queue and dispatches
+// runners for it. It reports whether a message was claimed.
+//
+// Messages are acknowledged once they have been handled, including when the
+// job is rejected. Server errors are retried with exponential backoff until
+// the maximum number of attempts is reached.
+func (s *Server) dispatchNext(ctx context.Context) (bool, error) {
+	msg, err := s.queue.Dequeue(ctx, s.config.DispatchQueueVisibilityTimeout)
+	if err != nil {
+		return false, fmt.Errorf("failed to dequeue message: %w", err)
+	}
+	if msg == nil {
+		return false, nil
+	}
+
+	logger := logging.FromContext(ctx).With(
+		"queue_message_id", msg.ID,
+		"gh_delivery_id", msg.DeliveryID,
+		"attempt", msg.Attempts+1)
+
+	// Deliveries whose worker exited or hung past the visibility timeout are
+	// counted as failed attempts, so a message that keeps taking down its
+	// worker is eventually dropped.
+	if msg.Attempts >= s.config.DispatchQueueMaxAttempts {
+		logger.ErrorContext(ctx, "dropping queue message redelivered after reaching max dispatch attempts",
+			"attempts", msg.Attempts)
+		if err := s.queue.Ack(ctx, msg); err != nil {
+			return true, fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+		}
+		return true, nil
+	}
+
+	var event github.WorkflowJobEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil || event.Action == nil || event.WorkflowJob == nil {
+		logger.ErrorContext(ctx, "dropping malformed queue message", "error", err)
+		if err := s.queue.Ack(ctx, msg); err != nil {
+			return true, fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+		}
+		return true, nil
+	}
+
+	jobID, attributes := extractLoggedAttributes(&event)
+	logger = logger.With(attributes...)
+	ctx = logging.WithLogger(ctx, logger)
+
+	logger.InfoContext(ctx, "dispatching queued workflow job",
+		"duration_in_queue_seconds", time.Since(msg.EnqueuedAt).Seconds())
+
+	resp := s.handleQueuedEvent(ctx, &event, msg.DeliveryID, jobID)
+
+	// Jobs over a concurrency quota are not failing, so they are retried
+	// without using up an attempt until GitHub would have cancelled them.
+	if errors.Is(resp.Error, errQuotaExceeded) {
+		if age := time.Since(msg.EnqueuedAt); age > maxDeferredJobAge {
+			logger.ErrorContext(ctx, "dropping workflow job deferred by concurrency quota for too long",
+				"duration_in_queue_seconds", age.Seconds())
+			if err := s.queue.Ack(ctx, msg); err != nil {
+				return true, fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+			}
+			return true, nil
+		}
+
+		logger.InfoContext(ctx, "deferred workflow job over concurrency quota",
+			"retry_delay", s.config.ConcurrencyQuotaRetryDelay.String())
+		if err := s.queue.Nack(ctx, msg, s.config.ConcurrencyQuotaRetryDelay); err != nil {
+			return true, fmt.Errorf("failed to nack message %s: %w", msg.ID, err)
+		}
+		return true, nil
+	}
+
+	if resp.Code < http.StatusInternalServerError {
+		logger.InfoContext(ctx, "dispatched queued workflow job",
+			"code", resp.Code,
+			"body", resp.Message)
+		if err := s.queue.Ack(ctx, msg); err != nil {
+			return true, fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+		}
+		return true, nil
+	}
+
+	msg.Attempts++
+	if msg.Attempts >= s.config.DispatchQueueMaxAttempts {
+		logger.ErrorContext(ctx, "dropping workflow job after reaching max dispatch attempts",
+			"error", resp.Error,
+			"attempts", msg.Attempts)
+		if err := s.queue.Ack(ctx, msg); err != nil {
+			return true, fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+		}
+		return true, nil
+	}
+
+	delay := s.dispatchRetryDelay(msg.Attempts)
+	logger.WarnContext(ctx, "failed to dispatch workflow job, retrying",
+		"error", resp.Error,
+		"attempts", msg.Attempts,
+		"retry_delay", delay.String())
+	if err := s.queue.Nack(ctx, msg, delay); err != nil {
+		return true, fmt.Errorf("failed to nack message %s: %w", msg.ID, err)
+	}
+	return true, nil
+}
+
+// dispatchRetryDelay returns the exponential backoff delay after the given
+// number of failed attempts.
+func (s *Server) dispatchRetryDelay(attempts int) time.Duration {
+	delay := s.backoffInitialDelay
+	for i := 1; i < attempts && delay < maxDispatchRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDispatchRetryDelay)
+}
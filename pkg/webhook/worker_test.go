@@ -0,0 +1,273 @@
+// Copyright 2025 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+
+	"github.com/abcxyz/github-action-dispatcher/pkg/cloudbuild"
+	"github.com/abcxyz/github-action-dispatcher/pkg/queue"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestDispatchQueue(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name             string
+		maxAttempts      int
+		createBuildErr   error
+		expectBuildCount int
+		expQueueLen      int
+		expAttempts      int
+	}{
+		{
+			name:             "dispatches_queued_job",
+			maxAttempts:      3,
+			expectBuildCount: 1,
+			expQueueLen:      0,
+		},
+		{
+			name:             "retries_failed_dispatch",
+			maxAttempts:      3,
+			createBuildErr:   fmt.Errorf("build failed"),
+			expectBuildCount: 1,
+			expQueueLen:      1,
+			expAttempts:      1,
+		},
+		{
+			name:             "drops_job_after_max_attempts",
+			maxAttempts:      1,
+			createBuildErr:   fmt.Errorf("build failed"),
+			expectBuildCount: 1,
+			expQueueLen:      0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+			q := queue.NewMemoryQueue()
+			mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID, CreateBuildErr: tc.createBuildErr}
+			srv := newTestQueueServer(t, q, mockCloudBuildClient, tc.maxAttempts)
+
+			resp := httptest.NewRecorder()
+			srv.handleWebhook().ServeHTTP(resp, newQueuedRequest(t))
+
+			if got, want := resp.Code, http.StatusAccepted; got != want {
+				t.Errorf("expected %d to be %d", got, want)
+			}
+			if got, want := strings.TrimSpace(resp.Body.String()), jobQueuedMsg; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+			if got, want := len(mockCloudBuildClient.CreateBuildReqs), 0; got != want {
+				t.Errorf("expected %d builds to be created before dispatch, got %d", want, got)
+			}
+
+			processed, err := srv.dispatchNext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !processed {
+				t.Fatal("expected a message to be processed")
+			}
+
+			if got, want := len(mockCloudBuildClient.CreateBuildReqs), tc.expectBuildCount; got != want {
+				t.Errorf("expected %d build(s) to be created, got %d", want, got)
+			}
+			if got, want := q.Len(), tc.expQueueLen; got != want {
+				t.Errorf("expected queue length %d to be %d", got, want)
+			}
+
+			if tc.expAttempts > 0 {
+				// A retried message becomes visible again once its backoff has elapsed.
+				time.Sleep(srv.dispatchRetryDelay(tc.expAttempts))
+				msg, err := q.Dequeue(ctx, time.Minute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if msg == nil {
+					t.Fatal("expected retried message to become visible")
+				}
+				if got, want := msg.Attempts, tc.expAttempts; got != want {
+					t.Errorf("expected attempts %d to be %d", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatchQueue_MalformedMessage(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	q := queue.NewMemoryQueue()
+	if err := q.Enqueue(ctx, &queue.Message{ID: "bad", Payload: json.RawMessage(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+	srv := newTestQueueServer(t, q, mockCloudBuildClient, 3)
+
+	processed, err := srv.dispatchNext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !processed {
+		t.Fatal("expected a message to be processed")
+	}
+	if got, want := q.Len(), 0; got != want {
+		t.Errorf("expected queue length %d to be %d", got, want)
+	}
+	if got, want := len(mockCloudBuildClient.CreateBuildReqs), 0; got != want {
+		t.Errorf("expected %d build(s) to be created, got %d", want, got)
+	}
+}
+
+func TestDispatchQueue_RedeliveredAfterVisibilityTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(t.Context(), logging.TestLogger(t))
+
+	q := queue.NewMemoryQueue()
+	mockCloudBuildClient := &cloudbuild.MockClient{CreateBuildID: testGCBBuildID}
+	srv := newTestQueueServer(t, q, mockCloudBuildClient, 2)
+
+	resp := httptest.NewRecorder()
+	srv.handleWebhook().ServeHTTP(resp, newQueuedRequest(t))
+	if got, want := resp.Code, http.StatusAccepted; got != want {
+		t.Fatalf("expected %d to be %d", got, want)
+	}
+
+	// Workers that exit while holding the message never acknowledge it, so it
+	// is redelivered once their claims expire.
+	for range 2 {
+		msg, err := q.Dequeue(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg == nil {
+			t.Fatal("expected the message to be visible")
+		}
+	}
+
+	processed, err := srv.dispatchNext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !processed {
+		t.Fatal("expected a message to be processed")
+	}
+	if got, want := len(mockCloudBuildClient.CreateBuildReqs), 0; got != want {
+		t.Errorf("expected %d build(s) to be created, got %d", want, got)
+	}
+	if got, want := q.Len(), 0; got != want {
+		t.Errorf("expected queue length %d to be %d", got, want)
+	}
+}
+
+func TestDispatchRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{backoffInitialDelay: time.Second}
+
+	cases := []struct {
+		attempts int
+		exp      time.Duration
+	}{
+		{attempts: 1, exp: 1 * time.Second},
+		{attempts: 2, exp: 2 * time.Second},
+		{attempts: 4, exp: 8 * time.Second},
+		{attempts: 20, exp: maxDispatchRetryDelay},
+	}
+
+	for _, tc := range cases {
+		if got, want := srv.dispatchRetryDelay(tc.attempts), tc.exp; got != want {
+			t.Errorf("dispatchRetryDelay(%d): expected %s to be %s", tc.attempts, got, want)
+		}
+	}
+}
+
+func newTestQueueServer(tb testing.TB, q queue.Queue, cbc cloudbuild.Client, maxAttempts int) *Server {
+	tb.Helper()
+
+	cfg := &Config{
+		BackoffInitialDelay:            time.Millisecond,
+		DispatchQueueWorkers:           1,
+		DispatchQueueMaxAttempts:       maxAttempts,
+		DispatchQueuePollInterval:      time.Millisecond,
+		DispatchQueueVisibilityTimeout: time.Minute,
+		RunnerExecutionTimeoutSeconds:  3600,
+		RunnerIdleTimeoutSeconds:       300,
+		SupportedRunnerLabels:          []string{SelfHostedRunnerLabel},
+		Runner404Location:              "us-central1",
+		Runner404ProjectID:             "404-project",
+		Runner404ServiceAccount:        "404-sa",
+	}
+	return newTestServer(tb, cfg, nil, &WebhookClientOptions{
+		CloudBuildClientOverride: cbc,
+		DispatchQueueOverride:    q,
+	})
+}
+
+func newQueuedRequest(tb testing.TB) *http.Request {
+	tb.Helper()
+
+	action := "queued"
+	installationID := int64(123)
+	jobID := int64(789)
+	orgLoginVar := orgLogin
+	repoNameVar := repoName
+	return newWebhookRequest(tb, &github.WorkflowJobEvent{
+		Action: &action,
+		WorkflowJob: &github.WorkflowJob{
+			Labels: []string{SelfHostedRunnerLabel},
+			ID:     &jobID,
+		},
+		Installation: &github.Installation{ID: &installationID},
+		Org:          &github.Organization{Login: &orgLoginVar},
+		Repo:         &github.Repository{Name: &repoNameVar},
+	})
+}
+
+// newWebhookRequest creates a signed workflow_job webhook request for event.
+func newWebhookRequest(tb testing.TB, event *github.WorkflowJobEvent) *http.Request {
+	tb.Helper()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.Header.Add(DeliveryIDHeader, "delivery-id")
+	req.Header.Add(EventTypeHeader, "workflow_job")
+	req.Header.Add(ContentTypeHeader, "application/json")
+	req.Header.Add(SHA256SignatureHeader, fmt.Sprintf("sha256=%s", createSignature([]byte(serverGitHubWebhookSecret), payload)))
+	return req
+}
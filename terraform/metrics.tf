@@ -197,3 +197,227 @@ resource "google_logging_metric" "error_processing_event_count" {
     "service_name" = "EXTRACT(resource.labels.service_name)"
   }
 }
+
+resource "google_logging_metric" "concurrency_quota_used" {
+  project = var.project_id
+
+  name            = "${replace(local.cloud_run_service_name, "-", "_")}-concurrency_quota_used"
+  description     = "Number of runners holding a slot under a concurrency quota when another job acquires slots."
+  value_extractor = "EXTRACT(jsonPayload.quota_used)"
+
+  filter = <<-EOT
+    resource.type="${local.resource_type}"
+    resource.labels.service_name="${local.cloud_run_service_name}"
+    logName="projects/${var.project_id}/logs/${local.metric_root}%2F${local.log_source_suffix}"
+    severity="INFO"
+    jsonPayload.message="acquired concurrency quota"
+  EOT
+
+  bucket_options {
+    exponential_buckets {
+      growth_factor      = 1.2
+      num_finite_buckets = 50
+      scale              = 1.0
+    }
+  }
+
+  metric_descriptor {
+    metric_kind = "DELTA"
+    value_type  = "DISTRIBUTION"
+    unit        = "1"
+
+    labels {
+      key         = "service_name"
+      value_type  = "STRING"
+      description = "Name of the Cloud Run service."
+    }
+    labels {
+      key         = "quota_key"
+      value_type  = "STRING"
+      description = "The org or org/repo the concurrency quota applies to."
+    }
+    labels {
+      key         = "quota_limit"
+      value_type  = "INT64"
+      description = "The maximum number of runners the concurrency quota allows at once."
+    }
+  }
+
+  label_extractors = {
+    "service_name" = "EXTRACT(resource.labels.service_name)"
+    "quota_key"    = "EXTRACT(jsonPayload.quota_key)"
+    "quota_limit"  = "EXTRACT(jsonPayload.quota_limit)"
+  }
+}
+
+resource "google_logging_metric" "concurrency_quota_deferred_count" {
+  project = var.project_id
+
+  name        = "${replace(local.cloud_run_service_name, "-", "_")}-concurrency_quota_deferred_count"
+  description = "Counter of jobs deferred because a concurrency quota was full."
+
+  filter = <<-EOT
+    resource.type="${local.resource_type}"
+    resource.labels.service_name="${local.cloud_run_service_name}"
+    logName="projects/${var.project_id}/logs/${local.metric_root}%2F${local.log_source_suffix}"
+    severity="WARNING"
+    jsonPayload.message="concurrency quota exceeded, deferring job"
+  EOT
+
+  metric_descriptor {
+    metric_kind = "DELTA"
+    value_type  = "INT64"
+
+    labels {
+      key         = "service_name"
+      value_type  = "STRING"
+      description = "Name of the Cloud Run service."
+    }
+    labels {
+      key         = "quota_key"
+      value_type  = "STRING"
+      description = "The org or org/repo the concurrency quota applies to."
+    }
+  }
+
+  label_extractors = {
+    "service_name" = "EXTRACT(resource.labels.service_name)"
+    "quota_key"    = "EXTRACT(jsonPayload.quota_key)"
+  }
+}
+
+resource "google_logging_metric" "budget_usage_minutes" {
+  project = var.project_id
+
+  name            = "${replace(local.cloud_run_service_name, "-", "_")}-budget_usage_minutes"
+  description     = "Runner-minutes of completed jobs counted against org budgets."
+  value_extractor = "EXTRACT(jsonPayload.budget_minutes)"
+
+  filter = <<-EOT
+    resource.type="${local.resource_type}"
+    resource.labels.service_name="${local.cloud_run_service_name}"
+    logName="projects/${var.project_id}/logs/${local.metric_root}%2F${local.log_source_suffix}"
+    severity="INFO"
+    jsonPayload.message="recorded budget usage"
+  EOT
+
+  bucket_options {
+    exponential_buckets {
+      growth_factor      = 1.2
+      num_finite_buckets = 60
+      scale              = 0.1
+    }
+  }
+
+  metric_descriptor {
+    metric_kind = "DELTA"
+    value_type  = "DISTRIBUTION"
+    unit        = "min"
+
+    labels {
+      key         = "service_name"
+      value_type  = "STRING"
+      description = "Name of the Cloud Run service."
+    }
+    labels {
+      key         = "org"
+      value_type  = "STRING"
+      description = "The org the usage is counted against."
+    }
+  }
+
+  label_extractors = {
+    "service_name" = "EXTRACT(resource.labels.service_name)"
+    "org"          = "EXTRACT(jsonPayload.org)"
+  }
+}
+
+resource "google_logging_metric" "budget_exhausted_count" {
+  project = var.project_id
+
+  name        = "${replace(local.cloud_run_service_name, "-", "_")}-budget_exhausted_count"
+  description = "Counter of jobs queued by orgs that have exhausted their budget."
+
+  filter = <<-EOT
+    resource.type="${local.resource_type}"
+    resource.labels.service_name="${local.cloud_run_service_name}"
+    logName="projects/${var.project_id}/logs/${local.metric_root}%2F${local.log_source_suffix}"
+    severity="WARNING"
+    jsonPayload.message="org budget exhausted"
+  EOT
+
+  metric_descriptor {
+    metric_kind = "DELTA"
+    value_type  = "INT64"
+
+    labels {
+      key         = "service_name"
+      value_type  = "STRING"
+      description = "Name of the Cloud Run service."
+    }
+    labels {
+      key         = "org"
+      value_type  = "STRING"
+      description = "The org whose budget is exhausted."
+    }
+    labels {
+      key         = "budget_action"
+      value_type  = "STRING"
+      description = "The action taken for the job."
+    }
+  }
+
+  label_extractors = {
+    "service_name"  = "EXTRACT(resource.labels.service_name)"
+    "org"           = "EXTRACT(jsonPayload.org)"
+    "budget_action" = "EXTRACT(jsonPayload.budget_action)"
+  }
+}
+
+resource "google_logging_metric" "webhook_secret_version_count" {
+  project = var.project_id
+
+  name        = "${replace(local.cloud_run_service_name, "-", "_")}-webhook_secret_version_count"
+  description = "Counter of webhook deliveries by the version of the webhook secret that validated them."
+
+  filter = <<-EOT
+    resource.type="${local.resource_type}"
+    resource.labels.service_name="${local.cloud_run_service_name}"
+    logName="projects/${var.project_id}/logs/${local.metric_root}%2F${local.log_source_suffix}"
+    severity="INFO"
+    jsonPayload.message="validated github payload"
+  EOT
+
+  metric_descriptor {
+    metric_kind = "DELTA"
+    value_type  = "INT64"
+
+    labels {
+      key         = "service_name"
+      value_type  = "STRING"
+      description = "Name of the Cloud Run service."
+    }
+    labels {
+      key         = "webhook_secret_version"
+      value_type  = "STRING"
+      description = "The name of the webhook key that validated the delivery."
+    }
+    labels {
+      key         = "github_host"
+      value_type  = "STRING"
+      description = "The GitHub host the delivery was for, or empty for the default host."
+    }
+    labels {
+      key         = "github_app"
+      value_type  = "STRING"
+      description = "The GitHub App the delivery was for, or empty for the default app."
+    }
+  }
+
+  label_extractors = {
+    "service_name"           = "EXTRACT(resource.labels.service_name)"
+    "webhook_secret_version" = "EXTRACT(jsonPayload.webhook_secret_version)"
+    "github_host"            = "EXTRACT(jsonPayload.github_host)"
+    "github_app"             = "EXTRACT(jsonPayload.github_app)"
+  }
+}